@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProviderTarget is where a resource lives: the provider configuration that
+// manages it and the AWS account, Google Cloud project or Azure subscription
+// and region that configuration points at.
+type ProviderTarget struct {
+	Provider string `json:"provider"`
+	Account  string `json:"account,omitempty"`
+	Region   string `json:"region,omitempty"`
+}
+
+// String describes the target, e.g. "aws.prod → 123456789012 (eu-west-1)".
+func (t ProviderTarget) String() string {
+	s := t.Provider
+	if t.Account != "" {
+		s += " → " + t.Account
+	}
+	if t.Region != "" {
+		s += " (" + t.Region + ")"
+	}
+	return s
+}
+
+// providerTargetAttrs are the provider settings that name the account and
+// region, as paths into the provider's expressions.
+var providerTargetAttrs = map[string]struct {
+	account []string
+	region  []string
+}{
+	"aws":     {[]string{"assume_role.role_arn", "allowed_account_ids"}, []string{"region"}},
+	"google":  {[]string{"project"}, []string{"region"}},
+	"azurerm": {[]string{"subscription_id"}, nil},
+}
+
+// markTargets sets the target of every resource from the configuration of
+// its provider. Values of the resource itself, such as its ARN, take
+// precedence, as they show where the object really is.
+func markTargets(analyzed *AnalyzedPlan, config PlanConfiguration) {
+	keys := map[string]string{}
+	var walk func(mod ConfigModule, prefix string)
+	walk = func(mod ConfigModule, prefix string) {
+		for _, res := range mod.Resources {
+			keys[prefix+res.Address] = res.ProviderConfigKey
+		}
+		for _, name := range sortedKeys(mod.ModuleCalls) {
+			walk(mod.ModuleCalls[name].Module, prefix+"module."+name+".")
+		}
+	}
+	walk(config.RootModule, "")
+
+	configured := map[string]ProviderTarget{}
+	for key, p := range config.ProviderConfig {
+		configured[key] = providerTarget(p)
+	}
+
+	for i := range analyzed.Modules {
+		resources := analyzed.Modules[i].Resources
+		for j := range resources {
+			r := &resources[j]
+			key := keys[stripIndex(r.Address)]
+			t, ok := configured[key]
+			if !ok {
+				name := strings.SplitN(r.Type, "_", 2)[0]
+				t, key = configured[name], name
+			}
+			if t.Provider == "" {
+				t.Provider = key
+			}
+			values := r.After
+			if values == nil {
+				values = r.Before
+			}
+			if account, region := resourceTarget(values); account != "" {
+				t.Account = account
+				if region != "" {
+					t.Region = region
+				}
+			}
+			if t.Account != "" || t.Region != "" || strings.Contains(t.Provider, ".") {
+				r.Target = &t
+			}
+		}
+	}
+}
+
+// providerTarget reads the account and region a provider configuration
+// uses, when they are constants in the configuration.
+func providerTarget(p ConfigProvider) ProviderTarget {
+	t := ProviderTarget{Provider: p.Name}
+	if p.Alias != "" {
+		t.Provider += "." + p.Alias
+	}
+	attrs := providerTargetAttrs[p.Name]
+	for _, path := range attrs.account {
+		v := constantAt(p.Expressions, path)
+		if list, ok := v.([]interface{}); ok && len(list) == 1 {
+			v = list[0]
+		}
+		s, ok := v.(string)
+		if !ok || s == "" {
+			continue
+		}
+		t.Account = s
+		if strings.HasPrefix(s, "arn:") {
+			t.Account, _ = arnTarget(s)
+		}
+		if t.Account != "" {
+			break
+		}
+	}
+	for _, path := range attrs.region {
+		if s, ok := constantAt(p.Expressions, path).(string); ok && s != "" {
+			t.Region = s
+			break
+		}
+	}
+	return t
+}
+
+// constantAt follows a dotted path into configuration expressions, through
+// the first element of nested blocks, and returns its constant value, if
+// it has one.
+func constantAt(expressions map[string]interface{}, path string) interface{} {
+	var v interface{} = expressions
+	for _, key := range strings.Split(path, ".") {
+		if list, ok := v.([]interface{}); ok && len(list) > 0 {
+			v = list[0]
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	if expr, ok := v.(map[string]interface{}); ok {
+		return expr["constant_value"]
+	}
+	return nil
+}
+
+// resourceTarget finds the account and region in a resource's own values:
+// its ARN on AWS, its project on Google Cloud and the subscription in its
+// ID on Azure.
+func resourceTarget(values map[string]interface{}) (account, region string) {
+	if arn, ok := values["arn"].(string); ok {
+		if account, region := arnTarget(arn); account != "" {
+			return account, region
+		}
+	}
+	if project, ok := values["project"].(string); ok && project != "" {
+		region, _ := values["region"].(string)
+		return project, region
+	}
+	if id, ok := values["id"].(string); ok && strings.HasPrefix(strings.ToLower(id), "/subscriptions/") {
+		parts := strings.Split(id, "/")
+		if len(parts) > 2 {
+			return parts[2], ""
+		}
+	}
+	return "", ""
+}
+
+// arnTarget reads the account and region from an ARN such as
+// arn:aws:sqs:eu-west-1:123456789012:queue. Global services leave the
+// region empty, and S3 buckets the account too.
+func arnTarget(arn string) (account, region string) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return "", ""
+	}
+	return parts[4], parts[3]
+}
+
+// accountCount is how many changes a plan makes in one account.
+type accountCount struct {
+	account string
+	changes int
+}
+
+// changedAccounts counts the changes per account, in order of first
+// appearance.
+func changedAccounts(analyzed AnalyzedPlan) []accountCount {
+	var counts []accountCount
+	index := map[string]int{}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			if r.Target == nil || r.Target.Account == "" || r.Action == "no-op" || r.Action == "read" {
+				continue
+			}
+			i, ok := index[r.Target.Account]
+			if !ok {
+				i = len(counts)
+				index[r.Target.Account] = i
+				counts = append(counts, accountCount{account: r.Target.Account})
+			}
+			counts[i].changes++
+		}
+	}
+	return counts
+}
+
+// checkAccounts flags changes outside the expected accounts. Without a
+// list of expected accounts, a plan that changes more than one is flagged,
+// as it usually means a provider alias or assumed role points at the
+// wrong place.
+func checkAccounts(analyzed AnalyzedPlan, expected []string) []Finding {
+	counts := changedAccounts(analyzed)
+	var attributes []FindingValue
+	var unexpected []string
+	for _, c := range counts {
+		attributes = append(attributes, FindingValue{Name: c.account, Value: plural(c.changes, "change")})
+		if len(expected) > 0 && !matchesAnyPattern(expected, c.account) {
+			unexpected = append(unexpected, c.account)
+		}
+	}
+	switch {
+	case len(unexpected) > 0:
+		return []Finding{{
+			Category:   "accounts",
+			Severity:   "High",
+			Address:    strings.Join(unexpected, ", "),
+			Title:      fmt.Sprintf("Plan changes resources in %s outside the expected accounts", plural(len(unexpected), "account")),
+			Detail:     "Check the provider aliases and assumed roles of these resources. Add the accounts to the accounts setting if the changes are intended.",
+			Attributes: attributes,
+		}}
+	case len(expected) == 0 && len(counts) > 1:
+		return []Finding{{
+			Category:   "accounts",
+			Severity:   "Medium",
+			Address:    counts[0].account,
+			Title:      fmt.Sprintf("Plan changes resources in %d accounts", len(counts)),
+			Detail:     "Make sure every change is meant for the account it is made in. List the accounts in the accounts setting to flag only unexpected ones.",
+			Attributes: attributes,
+		}}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkTargets(t *testing.T) {
+	config := PlanConfiguration{
+		ProviderConfig: map[string]ConfigProvider{
+			"aws": {Name: "aws", Expressions: map[string]interface{}{
+				"region": map[string]interface{}{"constant_value": "eu-west-1"},
+			}},
+			"aws.prod": {Name: "aws", Alias: "prod", Expressions: map[string]interface{}{
+				"region":      map[string]interface{}{"constant_value": "us-east-1"},
+				"assume_role": []interface{}{map[string]interface{}{"role_arn": map[string]interface{}{"constant_value": "arn:aws:iam::222222222222:role/deploy"}}},
+			}},
+		},
+		RootModule: ConfigModule{
+			Resources: []ConfigResource{{Address: "aws_s3_bucket.logs", ProviderConfigKey: "aws"}},
+			ModuleCalls: map[string]ConfigModuleCall{"app": {Module: ConfigModule{
+				Resources: []ConfigResource{{Address: "aws_sqs_queue.jobs", ProviderConfigKey: "aws.prod"}},
+			}}},
+		},
+	}
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{
+		{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Action: "update",
+			After: map[string]interface{}{"arn": "arn:aws:s3:::logs"}},
+		{Address: "module.app.aws_sqs_queue.jobs[0]", Type: "aws_sqs_queue", Action: "create"},
+		{Address: "aws_iam_role.ci", Type: "aws_iam_role", Action: "update",
+			Before: map[string]interface{}{"arn": "arn:aws:iam::111111111111:role/ci"}},
+	}}}}
+
+	markTargets(&analyzed, config)
+	resources := analyzed.Modules[0].Resources
+	for i, want := range []string{"aws (eu-west-1)", "aws.prod → 222222222222 (us-east-1)", "aws → 111111111111 (eu-west-1)"} {
+		if resources[i].Target == nil || resources[i].Target.String() != want {
+			t.Errorf("target of %s = %v, want %s", resources[i].Address, resources[i].Target, want)
+		}
+	}
+
+	findings := checkAccounts(analyzed, nil)
+	if len(findings) != 1 || findings[0].Title != "Plan changes resources in 2 accounts" {
+		t.Fatalf("expected a finding for the second account, got %+v", findings)
+	}
+	if findings := checkAccounts(analyzed, []string{"111111111111", "222222222222"}); len(findings) != 0 {
+		t.Errorf("expected no finding when both accounts are expected, got %+v", findings)
+	}
+	findings = checkAccounts(analyzed, []string{"2222*"})
+	if len(findings) != 1 || findings[0].Severity != "High" || findings[0].Address != "111111111111" {
+		t.Errorf("expected a high severity finding for the unexpected account, got %+v", findings)
+	}
+
+	html := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+	if !strings.Contains(html, `<span class="provider-target" title="Provider configuration, account and region">aws.prod → 222222222222 (us-east-1)</span>`) {
+		t.Error("report does not show the resource's account")
+	}
+}
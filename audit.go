@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditEvent is one line of the append-only audit log (JSON Lines).
+type auditEvent struct {
+	Time       string `json:"time"`
+	Event      string `json:"event"`
+	Report     string `json:"report"`
+	User       string `json:"user,omitempty"`
+	RemoteAddr string `json:"remote_addr"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Status     int    `json:"status"`
+	UserAgent  string `json:"user_agent,omitempty"`
+}
+
+type auditLog struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// openAuditLog opens path for appending only; existing entries are never
+// rewritten.
+func openAuditLog(path string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening audit log: %v", err)
+	}
+	return &auditLog{f: f}, nil
+}
+
+func (l *auditLog) record(ev auditEvent) {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.f.Write(append(line, '\n')); err != nil {
+		fmt.Printf("⚠️  Could not write audit log: %v\n", err)
+	}
+}
+
+func (l *auditLog) Close() error {
+	return l.f.Close()
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// auditHandler records every request to the report server. With
+// trustProxy, the user is taken from the headers set by common
+// authenticating proxies, which any client could send otherwise; requests
+// admitted by a share link are attributed to it.
+func auditHandler(log *auditLog, report string, trustProxy bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		event := "view"
+		switch rec.status {
+		case http.StatusUnauthorized:
+			event = "denied"
+		case http.StatusGone:
+			event = "expired"
+		}
+		user := ""
+		if trustProxy {
+			user = requestUser(r)
+		}
+		if user == "" && event == "view" && (r.URL.Query().Get("token") != "" || hasCookie(r, shareCookieName)) {
+			user = "share-link"
+		}
+
+		log.record(auditEvent{
+			Time:       time.Now().UTC().Format(time.RFC3339),
+			Event:      event,
+			Report:     report,
+			User:       user,
+			RemoteAddr: r.RemoteAddr,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Status:     rec.status,
+			UserAgent:  r.UserAgent(),
+		})
+	})
+}
+
+// requestUser returns the user set by common authenticating proxies. Only
+// use it with --trust-proxy-headers, when such a proxy is the only way to
+// reach the server.
+func requestUser(r *http.Request) string {
+	if user := r.Header.Get("X-Forwarded-User"); user != "" {
+		return user
+	}
+	return r.Header.Get("X-Auth-Request-User")
+}
+
+func hasCookie(r *http.Request, name string) bool {
+	_, err := r.Cookie(name)
+	return err == nil
+}
+
+// handleAudit exports an audit log as JSON or CSV.
+func handleAudit(args []string) error {
+	logFile, format := "", "json"
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name != "--log" && name != "--format" {
+			return fmt.Errorf("unknown flag for audit: %s", args[i])
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return fmt.Errorf("flag %s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		if name == "--log" {
+			logFile = value
+		} else {
+			format = value
+		}
+	}
+	if logFile == "" {
+		return fmt.Errorf("usage: tfviz audit --log <file> [--format json|csv]")
+	}
+
+	f, err := os.Open(logFile)
+	if err != nil {
+		return fmt.Errorf("error opening audit log: %v", err)
+	}
+	defer f.Close()
+	events, err := readAuditLog(f)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		out, err := json.MarshalIndent(events, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	case "csv":
+		return writeAuditCSV(os.Stdout, events)
+	default:
+		return fmt.Errorf("unsupported audit format %q (use json or csv)", format)
+	}
+}
+
+func readAuditLog(r io.Reader) ([]auditEvent, error) {
+	events := []auditEvent{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var ev auditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return nil, fmt.Errorf("audit log line %d: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+	return events, scanner.Err()
+}
+
+func writeAuditCSV(w io.Writer, events []auditEvent) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "event", "report", "user", "remote_addr", "method", "path", "status", "user_agent"})
+	for _, ev := range events {
+		cw.Write([]string{ev.Time, ev.Event, ev.Report, ev.User, ev.RemoteAddr, ev.Method, ev.Path, strconv.Itoa(ev.Status), ev.UserAgent})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuditHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := openAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gate := &shareGate{token: "secret", expires: time.Now().Add(time.Hour), next: newReportPage("<html></html>")}
+	handler := auditHandler(log, "abc123", true, gate)
+
+	req := httptest.NewRequest(http.MethodGet, "/?token=secret", nil)
+	req.Header.Set("X-Forwarded-User", "alice@example.com")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?token=wrong", nil))
+	log.Close()
+
+	// Reopening must append rather than truncate
+	log, err = openAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without --trust-proxy-headers a client cannot name itself
+	req = httptest.NewRequest(http.MethodGet, "/?token=secret", nil)
+	req.Header.Set("X-Forwarded-User", "mallory@example.com")
+	auditHandler(log, "abc123", false, gate).ServeHTTP(httptest.NewRecorder(), req)
+	log.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	events, err := readAuditLog(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	if e := events[0]; e.Event != "view" || e.User != "alice@example.com" || e.Report != "abc123" || e.Status != http.StatusOK {
+		t.Errorf("first event = %+v", e)
+	}
+	if e := events[1]; e.Event != "denied" || e.Status != http.StatusUnauthorized || e.User != "" {
+		t.Errorf("second event = %+v", e)
+	}
+	if e := events[2]; e.User != "share-link" {
+		t.Errorf("third event user = %q, want share-link", e.User)
+	}
+}
+
+func TestWriteAuditCSV(t *testing.T) {
+	var buf bytes.Buffer
+	events := []auditEvent{{Time: "2024-01-01T00:00:00Z", Event: "view", Report: "abc", User: "bob", RemoteAddr: "10.0.0.1:5000", Method: "GET", Path: "/", Status: 200, UserAgent: "curl, 8"}}
+	if err := writeAuditCSV(&buf, events); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1][3] != "bob" || records[1][7] != "200" || records[1][8] != "curl, 8" {
+		t.Errorf("unexpected CSV records: %v", records)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// shieldsBadge follows the shields.io endpoint badge schema:
+// https://shields.io/badges/endpoint-badge
+type shieldsBadge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+func buildBadge(analyzed AnalyzedPlan) shieldsBadge {
+	badge := shieldsBadge{SchemaVersion: 1, Label: "terraform plan"}
+
+	add := analyzed.Summary.Actions["create"]
+	change := analyzed.Summary.Actions["update"]
+	destroy := analyzed.Summary.Actions["delete"]
+
+	var parts []string
+	if add > 0 {
+		parts = append(parts, fmt.Sprintf("%d to add", add))
+	}
+	if change > 0 {
+		parts = append(parts, fmt.Sprintf("%d to change", change))
+	}
+	if destroy > 0 {
+		parts = append(parts, fmt.Sprintf("%d to destroy", destroy))
+	}
+	if len(parts) == 0 {
+		badge.Message = "no changes"
+		badge.Color = "brightgreen"
+		return badge
+	}
+	badge.Message = strings.Join(parts, ", ")
+
+	switch highestImpact(analyzed) {
+	case "High":
+		badge.Color = "red"
+	case "Medium":
+		badge.Color = "yellow"
+	default:
+		badge.Color = "green"
+	}
+	return badge
+}
+
+func highestImpact(analyzed AnalyzedPlan) string {
+	highest := ""
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			if r.Action == "no-op" {
+				continue
+			}
+			if impactRank[r.Impact] > impactRank[highest] {
+				highest = r.Impact
+			}
+		}
+	}
+	return highest
+}
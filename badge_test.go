@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestBuildBadge(t *testing.T) {
+	analyzed := AnalyzedPlan{
+		Summary: PlanSummary{Actions: map[string]int{"create": 3, "delete": 1, "no-op": 5}},
+		Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{
+			{Action: "create", Impact: "Low"},
+			{Action: "delete", Impact: "High"},
+		}}},
+	}
+	badge := buildBadge(analyzed)
+	if badge.SchemaVersion != 1 || badge.Message != "3 to add, 1 to destroy" || badge.Color != "red" {
+		t.Errorf("unexpected badge: %+v", badge)
+	}
+
+	empty := buildBadge(AnalyzedPlan{Summary: PlanSummary{Actions: map[string]int{"no-op": 2}}})
+	if empty.Message != "no changes" || empty.Color != "brightgreen" {
+		t.Errorf("unexpected badge for empty plan: %+v", empty)
+	}
+}
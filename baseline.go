@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultBaselineFile is read from the current directory unless --baseline
+// names another file. It is meant to be committed with the configuration.
+const defaultBaselineFile = ".tfviz-baseline.json"
+
+// baselineEntry acknowledges a finding, or a deletion or replacement, that
+// the team accepted, so that it no longer fails CI or fills the report.
+// Address and Title are only there for the people reading the file;
+// entries match by Fingerprint.
+type baselineEntry struct {
+	Fingerprint string `json:"fingerprint"`
+	Kind        string `json:"kind"`
+	Address     string `json:"address"`
+	Title       string `json:"title"`
+	Reason      string `json:"reason,omitempty"`
+	Added       string `json:"added"`
+}
+
+type baseline struct {
+	Entries []baselineEntry `json:"entries"`
+}
+
+// readBaseline reads the baseline file; a missing file is an empty
+// baseline.
+func readBaseline(path string) (baseline, error) {
+	if path == "" {
+		path = defaultBaselineFile
+	}
+	var b baseline
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return b, fmt.Errorf("error reading baseline: %v", err)
+	}
+	if err := json.Unmarshal(data, &b); err != nil {
+		return b, fmt.Errorf("error parsing baseline %s: %v", path, err)
+	}
+	return b, nil
+}
+
+func (b baseline) has(fingerprint string) bool {
+	for _, e := range b.Entries {
+		if e.Fingerprint == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBaseline moves acknowledged findings out of the findings of the
+// report into Acknowledged.
+func applyBaseline(analyzed *AnalyzedPlan, b baseline) {
+	var open []Finding
+	for _, f := range analyzed.Findings {
+		if b.has(f.Fingerprint()) {
+			analyzed.Acknowledged = append(analyzed.Acknowledged, f)
+		} else {
+			open = append(open, f)
+		}
+	}
+	analyzed.Findings = open
+}
+
+// withoutAcknowledged drops the acknowledged deletions and replacements of
+// analyzed.
+func withoutAcknowledged(changes []destructiveChange, analyzed AnalyzedPlan, b baseline) []destructiveChange {
+	fingerprints := changeFingerprints(analyzed)
+	var result []destructiveChange
+	for _, c := range changes {
+		if !b.has(fingerprints[c.Address]) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// addToBaseline adds the findings and destructive changes of analyzed that
+// are not acknowledged yet, and returns how many it added.
+func addToBaseline(b *baseline, analyzed AnalyzedPlan, reason string, now time.Time) int {
+	var entries []baselineEntry
+	for _, f := range analyzed.Findings {
+		entries = append(entries, baselineEntry{Fingerprint: f.Fingerprint(), Kind: "finding", Address: f.Address, Title: f.Title})
+	}
+	changes := destructiveChanges(analyzed)
+	fingerprints := changeFingerprints(analyzed)
+	for _, address := range sortedKeys(changes) {
+		entries = append(entries, baselineEntry{Fingerprint: fingerprints[address], Kind: "change", Address: address, Title: changes[address]})
+	}
+
+	added := 0
+	for _, e := range entries {
+		if b.has(e.Fingerprint) {
+			continue
+		}
+		e.Reason = reason
+		e.Added = now.Format("2006-01-02")
+		b.Entries = append(b.Entries, e)
+		added++
+	}
+	sort.SliceStable(b.Entries, func(i, j int) bool { return b.Entries[i].Address < b.Entries[j].Address })
+	return added
+}
+
+// handleBaseline acknowledges everything the current plan reports, for a
+// project adopting tfviz with known risks or after a team reviewed them.
+func handleBaseline(ctx context.Context, args []string) error {
+	planFile, reason := "", ""
+	rest := []string{}
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name != "--plan" && name != "--reason" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return fmt.Errorf("flag %s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		if name == "--plan" {
+			planFile = value
+		} else {
+			reason = value
+		}
+	}
+
+	opts, tfArgs, err := parseOptions(rest)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if planFile != "" {
+		data, err = readPlanFile(planFile)
+	} else {
+		data, err = runTerraformPlan(ctx, "", tfArgs)
+	}
+	if err != nil {
+		return err
+	}
+	plan, err := parsePlanJSON(data)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(opts.configFile, opts.profile)
+	if err != nil {
+		return err
+	}
+	analyzed := analyzePlan(plan)
+	if err := applyConfig(&analyzed, cfg, time.Now()); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+
+	path := opts.baselineFile
+	if path == "" {
+		path = defaultBaselineFile
+	}
+	b, err := readBaseline(path)
+	if err != nil {
+		return err
+	}
+	added := addToBaseline(&b, analyzed, reason, time.Now())
+	out, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(out, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing baseline: %v", err)
+	}
+	fmt.Printf("📌 Added %d entries to %s (%d in total)\n", added, path, len(b.Entries))
+	return nil
+}
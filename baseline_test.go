@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBaseline(t *testing.T) {
+	dataLoss := Finding{Category: "data-loss", Address: "aws_db_instance.main", Title: "Possible data loss: aws_db_instance will be deleted", Detail: "skip_final_snapshot is true"}
+	iam := Finding{Category: "iam", Address: "aws_iam_policy.admin", Title: "Policy allows *"}
+	analyzed := AnalyzedPlan{
+		Findings: []Finding{dataLoss},
+		Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{
+			{Address: "aws_db_instance.main", Action: "delete"},
+			{Address: "aws_instance.web", Action: "update"},
+		}}},
+	}
+
+	var b baseline
+	now := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	if added := addToBaseline(&b, analyzed, "legacy database, see #42", now); added != 2 {
+		t.Fatalf("added %d entries, want 2: %+v", added, b.Entries)
+	}
+	if added := addToBaseline(&b, analyzed, "", now); added != 0 {
+		t.Errorf("adding the same plan again added %d entries", added)
+	}
+	if e := b.Entries[0]; e.Reason != "legacy database, see #42" || e.Added != "2026-10-16" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+
+	// Details that depend on the plan's values do not change the fingerprint.
+	dataLoss.Detail = "the data it holds is destroyed"
+	later := AnalyzedPlan{Findings: []Finding{dataLoss, iam}}
+	applyBaseline(&later, b)
+	if len(later.Findings) != 1 || later.Findings[0].Address != "aws_iam_policy.admin" || len(later.Acknowledged) != 1 {
+		t.Errorf("findings = %+v, acknowledged = %+v", later.Findings, later.Acknowledged)
+	}
+
+	replan := AnalyzedPlan{Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{
+		{Address: "aws_db_instance.main", Action: "delete"},
+		{Address: "aws_db_instance.main2", Action: "delete"},
+	}}}}
+	changes := newDestructiveChanges(AnalyzedPlan{}, replan)
+	if got := withoutAcknowledged(changes, replan, b); len(got) != 1 || got[0].Address != "aws_db_instance.main2" {
+		t.Errorf("withoutAcknowledged() = %+v", got)
+	}
+	replan.Modules[0].Resources[0] = ResourceAnalysis{Address: "aws_db_instance.main", Action: "delete", Replace: true}
+	if got := withoutAcknowledged(changes, replan, b); len(got) != 2 {
+		t.Errorf("a replacement should not match an acknowledged deletion, got %+v", got)
+	}
+}
+
+func TestHandleBaseline(t *testing.T) {
+	dir := t.TempDir()
+	planFile := filepath.Join(dir, "plan.json")
+	os.WriteFile(planFile, []byte(`{"format_version":"1.2","resource_changes":[
+		{"address":"aws_db_instance.main","mode":"managed","type":"aws_db_instance","name":"main","change":{"actions":["delete"],"before":{"skip_final_snapshot":true},"after":null}}
+	]}`), 0o644)
+	file := filepath.Join(dir, "baseline.json")
+
+	if err := handleBaseline(t.Context(), []string{"--plan", planFile, "--reason=accepted", "--baseline", file}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := readBaseline(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Entries) != 2 || b.Entries[0].Kind != "finding" || b.Entries[1].Kind != "change" || b.Entries[1].Title != "delete" {
+		t.Errorf("unexpected baseline %+v", b.Entries)
+	}
+
+	if b, err := readBaseline(filepath.Join(dir, "missing.json")); err != nil || len(b.Entries) != 0 {
+		t.Errorf("a missing baseline should be empty, got %+v, %v", b, err)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type siteEnv struct {
+	Name   string
+	Source string
+}
+
+// planJSON reports whether the environment's source is plan JSON rather
+// than a terraform directory.
+func (e siteEnv) planJSON() bool {
+	return e.Source == "-" || strings.HasSuffix(e.Source, ".json")
+}
+
+type siteEnvPage struct {
+	Name    string
+	File    string
+	Summary PlanSummary
+	Badge   shieldsBadge
+	Error   string
+}
+
+var envNameRe = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// parseEnvList parses "dev,stage=envs/stage,prod=prod.json". A bare name uses
+// the directory of the same name. Sources ending in .json, and "-" for
+// stdin, are treated as pre-generated plan JSON, anything else as a
+// terraform directory to plan.
+func parseEnvList(list string) ([]siteEnv, error) {
+	var envs []siteEnv
+	seen := map[string]bool{}
+	stdin := false
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, source, ok := strings.Cut(item, "=")
+		if !ok {
+			source = name
+		}
+		if !envNameRe.MatchString(name) {
+			return nil, fmt.Errorf("invalid environment name %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("environment %q listed twice", name)
+		}
+		seen[name] = true
+		if source == "-" {
+			if stdin {
+				return nil, fmt.Errorf("stdin (-) can only be read once")
+			}
+			stdin = true
+		}
+		envs = append(envs, siteEnv{Name: name, Source: source})
+	}
+	if len(envs) == 0 {
+		return nil, fmt.Errorf("--envs requires at least one environment")
+	}
+	return envs, nil
+}
+
+func handleBuild(ctx context.Context, args []string) error {
+	envList := ""
+	outDir := ""
+	rest := []string{}
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name != "--envs" && name != "--out" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return fmt.Errorf("flag %s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		if name == "--envs" {
+			envList = value
+		} else {
+			outDir = value
+		}
+	}
+	if envList == "" || outDir == "" {
+		return fmt.Errorf("usage: tfviz build --envs dev,stage,prod --out site/")
+	}
+
+	opts, tfArgs, err := parseOptions(rest)
+	if err != nil {
+		return err
+	}
+	envs, err := parseEnvList(envList)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(opts.configFile, opts.profile)
+	if err != nil {
+		return err
+	}
+	accepted, err := readBaseline(opts.baselineFile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("error creating output directory: %v", err)
+	}
+
+	registry := newRegistryClient()
+	var pages []siteEnvPage
+	var written []string
+	failed := 0
+	for _, env := range envs {
+		fmt.Printf("🌍 Building %s from %s...\n", env.Name, env.Source)
+		page := siteEnvPage{Name: env.Name, File: env.Name + ".html"}
+
+		plan, err := loadEnvPlan(ctx, env, tfArgs)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			fmt.Printf("❌ %s: %v\n", env.Name, err)
+			page.Error = err.Error()
+			page.File = ""
+			failed++
+			pages = append(pages, page)
+			continue
+		}
+
+		analyzed := analyzePlanWith(plan, opts.analysis)
+		if err := opts.graph.validate(analyzed); err != nil {
+			fmt.Printf("⚠️  %s: %v\n", env.Name, err)
+		}
+		if err := applyConfig(&analyzed, cfg, time.Now()); err != nil {
+			fmt.Printf("⚠️  %s: %v\n", env.Name, err)
+		}
+		applyBaseline(&analyzed, accepted)
+		if !env.planJSON() {
+			applyLockedVersions(analyzed.ProviderVersions, readLockedProviders(env.Source))
+			analyzed.Git = gitContext(ctx, env.Source)
+			applySourceLocations(&analyzed, plan.Configuration, env.Source)
+			analyzed.Reviewers = findReviewers(analyzed, cfg.Owners, env.Source)
+		}
+		if opts.checkUpdates {
+			checkUpdates(ctx, registry, &analyzed, cfg)
+		}
+		if opts.summarize {
+			addNarrative(ctx, &analyzed, cfg.Summary)
+		}
+		page.Summary = analyzed.Summary
+		page.Badge = buildBadge(analyzed)
+
+		sortBy := opts.sort
+		if sortBy == "" {
+			sortBy = cfg.Sort
+		}
+		html := renderPlan(plan, analyzed, opts.showGraph, opts.graph, sortBy)(reportView{})
+		if err := os.WriteFile(filepath.Join(outDir, page.File), []byte(html), 0644); err != nil {
+			return fmt.Errorf("error writing report for %s: %v", env.Name, err)
+		}
+		badge, _ := json.Marshal(page.Badge)
+		if err := os.WriteFile(filepath.Join(outDir, env.Name+"-badge.json"), badge, 0644); err != nil {
+			return fmt.Errorf("error writing badge for %s: %v", env.Name, err)
+		}
+		written = append(written, page.File, env.Name+"-badge.json")
+		pages = append(pages, page)
+	}
+
+	index, err := generateSiteIndex(pages)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "index.html"), []byte(index), 0644); err != nil {
+		return fmt.Errorf("error writing index: %v", err)
+	}
+	written = append(written, "index.html")
+	if err := writeChecksumManifest(outDir, written, opts.signKey); err != nil {
+		return fmt.Errorf("error writing checksums: %v", err)
+	}
+	fmt.Printf("✅ Dashboard written to %s\n", filepath.Join(outDir, "index.html"))
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d environments failed", failed, len(envs))
+	}
+	return nil
+}
+
+func loadEnvPlan(ctx context.Context, env siteEnv, tfArgs []string) (TerraformPlan, error) {
+	var data []byte
+	var err error
+	if env.planJSON() {
+		data, err = readPlanFile(env.Source)
+		if err != nil {
+			return TerraformPlan{}, err
+		}
+	} else {
+		data, err = runTerraformPlan(ctx, env.Source, tfArgs)
+		if err != nil {
+			return TerraformPlan{}, err
+		}
+	}
+	return parsePlanJSON(data)
+}
+
+func generateSiteIndex(pages []siteEnvPage) (string, error) {
+	data := struct {
+		Envs      []siteEnvPage
+		Timestamp string
+	}{pages, time.Now().Format("2006-01-02 15:04:05")}
+
+	htmlTemplate := `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1" />
+  <title>Infrastructure Changes</title>
+  <style>
+` + reportStyles + `    .env-badge { display: inline-block; padding: 2px 8px; border-radius: 10px; color: white; font-size: 12px; }
+    .env-badge.red { background-color: var(--delete-color); }
+    .env-badge.yellow { background-color: var(--update-color); }
+    .env-badge.green, .env-badge.brightgreen { background-color: var(--create-color); }
+    .resource a { color: inherit; text-decoration: none; }
+  </style>
+</head>
+<body>
+  <div class="container">
+    <div class="header">
+      <h1>Infrastructure Changes</h1>
+      <div class="subtitle">{{.Timestamp}}</div>
+    </div>
+    <div class="resource-list">
+      {{range .Envs}}
+      <div class="resource">
+        <div class="resource-header">
+          <div class="resource-info">
+            {{if .File}}
+            <h3><a href="{{.File}}">{{.Name}}</a></h3>
+            <p>{{.Summary.TotalResources}} resources · <span class="env-badge {{.Badge.Color}}">{{.Badge.Message}}</span></p>
+            {{else}}
+            <h3>{{.Name}}</h3>
+            <p style="color: var(--delete-color)">{{.Error}}</p>
+            {{end}}
+          </div>
+        </div>
+      </div>
+      {{end}}
+    </div>
+  </div>
+</body>
+</html>`
+
+	tmpl, err := template.New("site").Parse(htmlTemplate)
+	if err != nil {
+		return "", fmt.Errorf("error parsing HTML template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error executing template: %v", err)
+	}
+	return buf.String(), nil
+}
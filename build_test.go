@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEnvList(t *testing.T) {
+	envs, err := parseEnvList("dev, stage=envs/stage,prod=plans/prod.json")
+	if err != nil {
+		t.Fatalf("parseEnvList: %v", err)
+	}
+	want := []siteEnv{{"dev", "dev"}, {"stage", "envs/stage"}, {"prod", "plans/prod.json"}}
+	if len(envs) != len(want) {
+		t.Fatalf("got %d envs, want %d", len(envs), len(want))
+	}
+	for i := range want {
+		if envs[i] != want[i] {
+			t.Errorf("env[%d] = %+v, want %+v", i, envs[i], want[i])
+		}
+	}
+
+	if !envs[2].planJSON() || envs[1].planJSON() || !(siteEnv{"ci", "-"}).planJSON() {
+		t.Error("only .json sources and stdin are plan JSON")
+	}
+
+	for _, bad := range []string{"", "dev,dev", "../escape=dev", "dev=-,prod=-"} {
+		if _, err := parseEnvList(bad); err == nil {
+			t.Errorf("parseEnvList(%q): expected error", bad)
+		}
+	}
+}
+
+func TestGenerateSiteIndex(t *testing.T) {
+	html, err := generateSiteIndex([]siteEnvPage{
+		{Name: "dev", File: "dev.html", Badge: shieldsBadge{Message: "2 to add", Color: "green"}},
+		{Name: "prod", Error: "terraform plan failed"},
+	})
+	if err != nil {
+		t.Fatalf("generateSiteIndex: %v", err)
+	}
+	for _, want := range []string{`href="dev.html"`, "2 to add", "terraform plan failed"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("index missing %q", want)
+		}
+	}
+}
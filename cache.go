@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultCacheTTL = time.Hour
+
+// configHash fingerprints everything that determines a plan without talking
+// to the backend: the directory and workspace, Terraform sources, variable
+// files, the dependency lock file, the terraform arguments and TF_VAR_*
+// environment variables.
+func configHash(dir string, args []string, environ []string) (string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	h := sha256.New()
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "dir:%s\n", abs)
+	fmt.Fprintf(h, "workspace:%s\n", terraformWorkspace(dir, environ))
+
+	var files []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (d.Name() == ".terraform" || d.Name() == ".git") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		if name == ".terraform.lock.hcl" ||
+			strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json") ||
+			strings.HasSuffix(name, ".tfvars") || strings.HasSuffix(name, ".tfvars.json") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	for _, path := range files {
+		rel, _ := filepath.Rel(dir, path)
+		fmt.Fprintf(h, "file:%s\n", filepath.ToSlash(rel))
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte{0})
+	}
+
+	for _, a := range args {
+		fmt.Fprintf(h, "arg:%s\n", a)
+	}
+
+	var tfVars []string
+	for _, kv := range environ {
+		if strings.HasPrefix(kv, "TF_VAR_") {
+			tfVars = append(tfVars, kv)
+		}
+	}
+	sort.Strings(tfVars)
+	for _, kv := range tfVars {
+		fmt.Fprintf(h, "env:%s\n", kv)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// terraformWorkspace resolves the workspace terraform uses in dir the way
+// terraform does: TF_WORKSPACE, then the workspace selected with terraform
+// workspace select, then "default".
+func terraformWorkspace(dir string, environ []string) string {
+	dataDir := filepath.Join(dir, ".terraform")
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if name == "TF_WORKSPACE" && value != "" {
+			return value
+		}
+		if name == "TF_DATA_DIR" && value != "" {
+			dataDir = value
+			if !filepath.IsAbs(dataDir) {
+				dataDir = filepath.Join(dir, dataDir)
+			}
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dataDir, "environment")); err == nil {
+		if ws := strings.TrimSpace(string(data)); ws != "" {
+			return ws
+		}
+	}
+	return "default"
+}
+
+func planCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "tfviz", "plans"), nil
+}
+
+// cachedTerraformPlan returns the plan JSON cached for the current
+// configuration hash when it is younger than ttl, and otherwise runs
+// terraform plan and stores the result. Cache problems never fail the run.
+func cachedTerraformPlan(ctx context.Context, dir string, args []string, ttl time.Duration) ([]byte, error) {
+	cacheDir, err := planCacheDir()
+	if err != nil {
+		fmt.Printf("⚠️  Plan cache unavailable: %v\n", err)
+		return runTerraformPlan(ctx, dir, args)
+	}
+	key, err := configHash(dir, args, os.Environ())
+	if err != nil {
+		fmt.Printf("⚠️  Could not hash configuration, skipping plan cache: %v\n", err)
+		return runTerraformPlan(ctx, dir, args)
+	}
+	cacheFile := filepath.Join(cacheDir, key+".json")
+
+	passphrase := encryptionPassphrase()
+	if info, err := os.Stat(cacheFile); err == nil && time.Since(info.ModTime()) < ttl {
+		if data, err := os.ReadFile(cacheFile); err == nil {
+			plain, err := openData(data, passphrase)
+			if err == nil {
+				fmt.Printf("⚡ Configuration unchanged, reusing plan from %s\n", info.ModTime().Format("2006-01-02 15:04:05"))
+				return plain, nil
+			}
+			fmt.Printf("⚠️  Ignoring cached plan: %v\n", err)
+		}
+	}
+
+	data, err := runTerraformPlan(ctx, dir, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Plans can contain secrets, so keep the cache private to the user.
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		fmt.Printf("⚠️  Could not write plan cache: %v\n", err)
+		return data, nil
+	}
+	sealed, err := sealData(data, passphrase)
+	if err != nil {
+		fmt.Printf("⚠️  Could not encrypt plan cache: %v\n", err)
+		return data, nil
+	}
+	if err := os.WriteFile(cacheFile, sealed, 0600); err != nil {
+		fmt.Printf("⚠️  Could not write plan cache: %v\n", err)
+	}
+	return data, nil
+}
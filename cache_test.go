@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigHash(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("main.tf", `resource "aws_s3_bucket" "b" {}`)
+	write("prod.tfvars", `region = "eu-west-1"`)
+	write(".terraform/providers/ignored.tf", "ignored")
+
+	base, err := configHash(dir, nil, nil)
+	if err != nil {
+		t.Fatalf("configHash: %v", err)
+	}
+
+	// Files inside .terraform and unrelated files do not affect the hash
+	write(".terraform/providers/ignored.tf", "changed")
+	write("README.md", "docs")
+	if h, _ := configHash(dir, nil, nil); h != base {
+		t.Error("hash changed for files that do not affect the plan")
+	}
+
+	if h, _ := configHash(dir, []string{"-var-file=prod.tfvars"}, nil); h == base {
+		t.Error("hash should depend on terraform arguments")
+	}
+	if h, _ := configHash(dir, nil, []string{"TF_VAR_region=us-east-1", "HOME=/root"}); h == base {
+		t.Error("hash should depend on TF_VAR_ environment variables")
+	}
+	if h, _ := configHash(dir, nil, []string{"HOME=/root"}); h != base {
+		t.Error("hash should ignore unrelated environment variables")
+	}
+
+	// Each workspace has state of its own, and so plans of its own
+	write(".terraform/environment", "staging")
+	staging, _ := configHash(dir, nil, nil)
+	if staging == base {
+		t.Error("hash should depend on the selected workspace")
+	}
+	if h, _ := configHash(dir, nil, []string{"TF_WORKSPACE=prod"}); h == base || h == staging {
+		t.Error("hash should depend on TF_WORKSPACE, which overrides the selected workspace")
+	}
+	if h, _ := configHash(dir, nil, []string{"TF_WORKSPACE=staging"}); h != staging {
+		t.Error("TF_WORKSPACE naming the selected workspace should not change the hash")
+	}
+	os.Remove(filepath.Join(dir, ".terraform", "environment"))
+
+	other := t.TempDir()
+	data, _ := os.ReadFile(filepath.Join(dir, "main.tf"))
+	os.WriteFile(filepath.Join(other, "main.tf"), data, 0644)
+	os.WriteFile(filepath.Join(other, "prod.tfvars"), []byte(`region = "eu-west-1"`), 0644)
+	if h, _ := configHash(other, nil, nil); h == base {
+		t.Error("hash should depend on the directory")
+	}
+
+	write("modules/net/main.tf", `resource "aws_vpc" "v" {}`)
+	if h, _ := configHash(dir, nil, nil); h == base {
+		t.Error("hash should include local module sources")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CapacityChange is a change to how much compute a service runs on: the
+// size of an Auto Scaling group, ECS service or node pool, or the instance
+// types and launch template versions it uses.
+type CapacityChange struct {
+	Address  string          `json:"address"`
+	Kind     string          `json:"kind"`
+	Action   string          `json:"action"`
+	Settings []SettingChange `json:"settings,omitempty"`
+}
+
+// CapacityTotal adds up the desired size of the changed groups that count
+// in the same unit, such as instances or tasks, before and after the plan.
+type CapacityTotal struct {
+	Unit   string  `json:"unit"`
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+	// Unknown counts the groups whose desired size is not known until
+	// apply, or is left to autoscaling, and so is not in the totals.
+	Unknown int `json:"unknown,omitempty"`
+}
+
+// Delta is the change in total capacity, e.g. "+4" or "-2".
+func (t CapacityTotal) Delta() string {
+	return fmt.Sprintf("%+g", t.After-t.Before)
+}
+
+// CapacitySummary is the capacity panel of the report.
+type CapacitySummary struct {
+	Changes []CapacityChange `json:"changes"`
+	Totals  []CapacityTotal  `json:"totals,omitempty"`
+}
+
+// capacitySettings are the settings shown per resource type. desired is the
+// path of the group's desired size, if it has one, which is added to the
+// totals of unit.
+var capacitySettings = map[string]struct {
+	kind     string
+	unit     string
+	desired  string
+	settings []string
+}{
+	"aws_autoscaling_group":     {"Auto Scaling group", "instances", "desired_capacity", []string{"desired_capacity", "min_size", "max_size", "launch_template.version", "launch_template.id", "launch_template.name", "mixed_instances_policy.launch_template.launch_template_specification.version", "mixed_instances_policy.launch_template.override.instance_type", "launch_configuration"}},
+	"aws_launch_template":       {"launch template", "", "", []string{"instance_type", "image_id", "default_version"}},
+	"aws_launch_configuration":  {"launch configuration", "", "", []string{"instance_type", "image_id"}},
+	"aws_ecs_service":           {"ECS service", "tasks", "desired_count", []string{"desired_count", "launch_type", "capacity_provider_strategy.capacity_provider", "capacity_provider_strategy.weight"}},
+	"aws_appautoscaling_target": {"scaling target", "", "", []string{"min_capacity", "max_capacity"}},
+	"aws_eks_node_group":        {"EKS node group", "nodes", "scaling_config.desired_size", []string{"scaling_config.desired_size", "scaling_config.min_size", "scaling_config.max_size", "instance_types", "capacity_type", "launch_template.version"}},
+
+	"google_container_node_pool":                   {"GKE node pool", "nodes", "node_count", []string{"node_count", "autoscaling.min_node_count", "autoscaling.max_node_count", "autoscaling.total_min_node_count", "autoscaling.total_max_node_count", "node_config.machine_type", "node_config.spot"}},
+	"google_compute_instance_group_manager":        {"instance group", "instances", "target_size", []string{"target_size", "version.instance_template"}},
+	"google_compute_region_instance_group_manager": {"instance group", "instances", "target_size", []string{"target_size", "version.instance_template"}},
+	"google_compute_autoscaler":                    {"autoscaler", "", "", []string{"autoscaling_policy.min_replicas", "autoscaling_policy.max_replicas"}},
+
+	"azurerm_kubernetes_cluster_node_pool":      {"AKS node pool", "nodes", "node_count", []string{"node_count", "min_count", "max_count", "vm_size", "priority"}},
+	"azurerm_linux_virtual_machine_scale_set":   {"scale set", "instances", "instances", []string{"instances", "sku"}},
+	"azurerm_windows_virtual_machine_scale_set": {"scale set", "instances", "instances", []string{"instances", "sku"}},
+}
+
+// Capacity summarizes the plan's capacity changes, or returns nil when it
+// has none.
+func (a AnalyzedPlan) Capacity() *CapacitySummary {
+	var summary CapacitySummary
+	totals := map[string]*CapacityTotal{}
+	for _, m := range a.Modules {
+		for _, r := range m.Resources {
+			spec, ok := capacitySettings[r.Type]
+			if !ok || r.Action == "no-op" || r.Action == "read" {
+				continue
+			}
+			c := CapacityChange{Address: r.Address, Kind: spec.kind, Action: r.Action, Settings: changedSettings(r, spec.settings)}
+			if r.Replace {
+				c.Action = "replace"
+			}
+			if len(c.Settings) == 0 && c.Action == "update" {
+				continue
+			}
+			summary.Changes = append(summary.Changes, c)
+
+			if spec.desired == "" {
+				continue
+			}
+			t := totals[spec.unit]
+			if t == nil {
+				t = &CapacityTotal{Unit: spec.unit}
+				totals[spec.unit] = t
+			}
+			before, beforeKnown := desiredSize(r.Before, spec.desired)
+			after, afterKnown := desiredSize(r.After, spec.desired)
+			if !beforeKnown || !afterKnown {
+				t.Unknown++
+				continue
+			}
+			t.Before += before
+			t.After += after
+		}
+	}
+	if len(summary.Changes) == 0 {
+		return nil
+	}
+	for _, unit := range sortedKeys(totals) {
+		summary.Totals = append(summary.Totals, *totals[unit])
+	}
+	sort.SliceStable(summary.Changes, func(i, j int) bool { return summary.Changes[i].Kind < summary.Changes[j].Kind })
+	return &summary
+}
+
+// desiredSize reads a group's desired size. A group that does not exist on
+// one side of the change has a size of zero.
+func desiredSize(values map[string]interface{}, path string) (float64, bool) {
+	if values == nil {
+		return 0, true
+	}
+	n, ok := valueAt(values, path).(float64)
+	return n, ok
+}
+
+// String describes the totals, e.g. "instances 4 → 6 (+2); tasks 3 → 1 (-2)".
+func (s CapacitySummary) String() string {
+	var parts []string
+	for _, t := range s.Totals {
+		part := fmt.Sprintf("%s %g → %g (%s)", t.Unit, t.Before, t.After, t.Delta())
+		if t.Unknown > 0 {
+			part += ", " + plural(t.Unknown, "group") + " not known until apply"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCapacity(t *testing.T) {
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{
+		{Address: "aws_autoscaling_group.web", Type: "aws_autoscaling_group", Action: "update",
+			Before: map[string]interface{}{"desired_capacity": 4.0, "min_size": 2.0, "max_size": 6.0, "launch_template": []interface{}{map[string]interface{}{"id": "lt-1", "version": "3"}}},
+			After:  map[string]interface{}{"desired_capacity": 6.0, "min_size": 2.0, "max_size": 10.0, "launch_template": []interface{}{map[string]interface{}{"id": "lt-1", "version": "4"}}}},
+		{Address: "aws_autoscaling_group.batch", Type: "aws_autoscaling_group", Action: "create",
+			After: map[string]interface{}{"min_size": 0.0, "max_size": 20.0}},
+		{Address: "aws_ecs_service.api", Type: "aws_ecs_service", Action: "delete",
+			Before: map[string]interface{}{"desired_count": 3.0, "launch_type": "FARGATE"}},
+		{Address: "aws_eks_node_group.main", Type: "aws_eks_node_group", Action: "update",
+			Before: map[string]interface{}{"scaling_config": []interface{}{map[string]interface{}{"desired_size": 3.0}}, "tags": map[string]interface{}{"a": "1"}},
+			After:  map[string]interface{}{"scaling_config": []interface{}{map[string]interface{}{"desired_size": 3.0}}, "tags": map[string]interface{}{"a": "2"}}},
+	}}}}
+
+	capacity := analyzed.Capacity()
+	if capacity == nil || len(capacity.Changes) != 3 {
+		t.Fatalf("expected the groups and the service, but not the node group's tag change, got %+v", capacity)
+	}
+	if capacity.Changes[0].Address != "aws_autoscaling_group.web" || capacity.Changes[2].Address != "aws_ecs_service.api" {
+		t.Fatalf("unexpected order: %+v", capacity.Changes)
+	}
+	want := []SettingChange{
+		{Name: "desired_capacity", Before: "4", After: "6"},
+		{Name: "max_size", Before: "6", After: "10"},
+		{Name: "launch_template.version", Before: "3", After: "4"},
+	}
+	if got := capacity.Changes[0].Settings; !reflect.DeepEqual(got, want) {
+		t.Errorf("settings =\n%+v\nwant\n%+v", got, want)
+	}
+
+	wantTotals := []CapacityTotal{
+		{Unit: "instances", Before: 4, After: 6, Unknown: 1},
+		{Unit: "tasks", Before: 3, After: 0},
+	}
+	if !reflect.DeepEqual(capacity.Totals, wantTotals) {
+		t.Errorf("totals =\n%+v\nwant\n%+v", capacity.Totals, wantTotals)
+	}
+	if got := capacity.String(); got != "instances 4 → 6 (+2), 1 group not known until apply; tasks 3 → 0 (-3)" {
+		t.Errorf("String() = %q", got)
+	}
+
+	html := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+	if !strings.Contains(html, "Capacity (3) · instances 4 → 6") {
+		t.Error("report does not summarize the capacity change")
+	}
+	if (AnalyzedPlan{}).Capacity() != nil {
+		t.Error("expected no capacity panel without capacity changes")
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// cdktfOutDir is where cdktf synth writes the synthesized stacks.
+const cdktfOutDir = "cdktf.out"
+
+type cdktfManifest struct {
+	Stacks map[string]cdktfStack `json:"stacks"`
+}
+
+type cdktfStack struct {
+	Name                 string `json:"name"`
+	SynthesizedStackPath string `json:"synthesizedStackPath"`
+	WorkingDirectory     string `json:"workingDirectory"`
+}
+
+// stackPlan is the plan of one synthesized stack.
+type stackPlan struct {
+	Name string
+	Plan TerraformPlan
+	// ConstructPaths maps addresses in the stack to the CDK construct that
+	// defined them, e.g. aws_instance.web_1A2B3C → my-stack/web/instance.
+	ConstructPaths map[string]string
+}
+
+func handleCDKTF(ctx context.Context, args []string) error {
+	appDir := "."
+	stackList := ""
+	synth := true
+	rest := []string{}
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name == "--skip-synth" {
+			synth = false
+			continue
+		}
+		if name != "--app-dir" && name != "--stacks" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return fmt.Errorf("flag %s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		if name == "--app-dir" {
+			appDir = value
+		} else {
+			stackList = value
+		}
+	}
+	opts, tfArgs, err := parseOptions(rest)
+	if err != nil {
+		return err
+	}
+
+	if synth {
+		fmt.Println("🧱 Running cdktf synth...")
+		cmd := exec.CommandContext(ctx, "cdktf", "synth")
+		cmd.Dir = appDir
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("cdktf synth interrupted")
+			}
+			return fmt.Errorf("error running cdktf synth: %v", err)
+		}
+	}
+	outDir := filepath.Join(appDir, cdktfOutDir)
+	stacks, err := readCDKTFManifest(outDir, stackList)
+	if err != nil {
+		return err
+	}
+
+	var plans []stackPlan
+	for _, stack := range stacks {
+		fmt.Printf("📦 Planning stack %s...\n", stack.Name)
+		data, err := runTerraformPlan(ctx, filepath.Join(outDir, stack.WorkingDirectory), tfArgs)
+		if err != nil {
+			return fmt.Errorf("stack %s: %v", stack.Name, err)
+		}
+		plan, err := parsePlanJSON(data)
+		if err != nil {
+			return fmt.Errorf("stack %s: %v", stack.Name, err)
+		}
+		paths, err := readConstructPaths(filepath.Join(outDir, stack.SynthesizedStackPath))
+		if err != nil {
+			fmt.Printf("⚠️  Stack %s: %v\n", stack.Name, err)
+		}
+		plans = append(plans, stackPlan{Name: stack.Name, Plan: plan, ConstructPaths: paths})
+	}
+
+	plan, paths := mergeStackPlans(plans)
+	opts.analysis.constructPaths = paths
+	return presentPlan(ctx, plan, opts)
+}
+
+// readCDKTFManifest returns the synthesized stacks in name order, or only
+// those in the comma-separated list.
+func readCDKTFManifest(outDir, list string) ([]cdktfStack, error) {
+	data, err := os.ReadFile(filepath.Join(outDir, "manifest.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading cdktf manifest: %v", err)
+	}
+	var manifest cdktfManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("error parsing cdktf manifest: %v", err)
+	}
+	if len(manifest.Stacks) == 0 {
+		return nil, fmt.Errorf("cdktf synth produced no stacks")
+	}
+
+	names := sortedKeys(manifest.Stacks)
+	if list != "" {
+		names = nil
+		for _, name := range strings.Split(list, ",") {
+			name = strings.TrimSpace(name)
+			if _, ok := manifest.Stacks[name]; !ok {
+				return nil, fmt.Errorf("unknown stack %q (synthesized: %s)", name, strings.Join(sortedKeys(manifest.Stacks), ", "))
+			}
+			names = append(names, name)
+		}
+	}
+	var stacks []cdktfStack
+	for _, name := range names {
+		stack := manifest.Stacks[name]
+		if stack.Name == "" {
+			stack.Name = name
+		}
+		if stack.WorkingDirectory == "" {
+			stack.WorkingDirectory = filepath.Join("stacks", name)
+		}
+		if stack.SynthesizedStackPath == "" {
+			stack.SynthesizedStackPath = filepath.Join(stack.WorkingDirectory, "cdk.tf.json")
+		}
+		stacks = append(stacks, stack)
+	}
+	return stacks, nil
+}
+
+// readConstructPaths reads the construct path cdktf records in the "//"
+// metadata of every resource, data source and module block of a stack.
+func readConstructPaths(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading synthesized stack: %v", err)
+	}
+	type block struct {
+		Meta struct {
+			Metadata struct {
+				Path string `json:"path"`
+			} `json:"metadata"`
+		} `json:"//"`
+	}
+	var stack struct {
+		Resource map[string]map[string]block `json:"resource"`
+		Data     map[string]map[string]block `json:"data"`
+		Module   map[string]block            `json:"module"`
+	}
+	if err := json.Unmarshal(data, &stack); err != nil {
+		return nil, fmt.Errorf("error parsing synthesized stack: %v", err)
+	}
+
+	paths := map[string]string{}
+	add := func(address string, b block) {
+		if p := b.Meta.Metadata.Path; p != "" {
+			paths[address] = p
+		}
+	}
+	for typ, blocks := range stack.Resource {
+		for name, b := range blocks {
+			add(typ+"."+name, b)
+		}
+	}
+	for typ, blocks := range stack.Data {
+		for name, b := range blocks {
+			add("data."+typ+"."+name, b)
+		}
+	}
+	for name, b := range stack.Module {
+		add("module."+name, b)
+	}
+	return paths, nil
+}
+
+// mergeStackPlans combines the stacks into one plan in which each stack is a
+// module named after it, so the report groups resources by stack. It returns
+// the construct paths keyed by the combined addresses.
+func mergeStackPlans(stacks []stackPlan) (TerraformPlan, map[string]string) {
+	var merged TerraformPlan
+	merged.Configuration.RootModule.ModuleCalls = map[string]ConfigModuleCall{}
+	merged.Configuration.ProviderConfig = map[string]ConfigProvider{}
+	paths := map[string]string{}
+	for _, s := range stacks {
+		prefix := "module." + s.Name
+		if merged.FormatVersion == "" {
+			merged.FormatVersion = s.Plan.FormatVersion
+			merged.TerraformVersion = s.Plan.TerraformVersion
+		}
+		for _, rc := range s.Plan.ResourceChanges {
+			merged.ResourceChanges = append(merged.ResourceChanges, prefixResourceChange(prefix, rc))
+		}
+		for _, rc := range s.Plan.ResourceDrift {
+			merged.ResourceDrift = append(merged.ResourceDrift, prefixResourceChange(prefix, rc))
+		}
+		root := prefixPlannedModule(prefix, s.Plan.PlannedValues.RootModule)
+		root.Address = prefix
+		merged.PlannedValues.RootModule.ChildModules = append(merged.PlannedValues.RootModule.ChildModules, root)
+		merged.Configuration.RootModule.ModuleCalls[s.Name] = ConfigModuleCall{
+			Source: "cdktf stack " + s.Name,
+			Module: s.Plan.Configuration.RootModule,
+		}
+		for key, p := range s.Plan.Configuration.ProviderConfig {
+			if p.ModuleAddress == "" {
+				p.ModuleAddress = prefix
+			} else {
+				p.ModuleAddress = prefix + "." + p.ModuleAddress
+			}
+			merged.Configuration.ProviderConfig[prefix+":"+key] = p
+		}
+		for addr, p := range s.ConstructPaths {
+			paths[prefix+"."+addr] = p
+		}
+	}
+	return merged, paths
+}
+
+func prefixResourceChange(prefix string, rc ResourceChange) ResourceChange {
+	rc.Address = prefix + "." + rc.Address
+	if rc.ModuleAddress == "" {
+		rc.ModuleAddress = prefix
+	} else {
+		rc.ModuleAddress = prefix + "." + rc.ModuleAddress
+	}
+	return rc
+}
+
+func prefixPlannedModule(prefix string, m Module) Module {
+	out := Module{Address: m.Address}
+	if out.Address != "" {
+		out.Address = prefix + "." + out.Address
+	}
+	for _, r := range m.Resources {
+		r.Address = prefix + "." + r.Address
+		out.Resources = append(out.Resources, r)
+	}
+	for _, child := range m.ChildModules {
+		out.ChildModules = append(out.ChildModules, prefixPlannedModule(prefix, child))
+	}
+	return out
+}
+
+// constructPath finds the construct that defined a resource: its own, or
+// that of the module block it is in.
+func constructPath(paths map[string]string, address string) string {
+	if p, ok := paths[stripIndex(address)]; ok {
+		return p
+	}
+	for i := strings.LastIndex(address, ".module."); i >= 0; i = strings.LastIndex(address[:i], ".module.") {
+		name, _, _ := strings.Cut(address[i+len(".module."):], ".")
+		if p, ok := paths[address[:i]+".module."+stripIndex(name)]; ok {
+			return p
+		}
+	}
+	return ""
+}
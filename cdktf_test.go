@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCDKTFStacks(t *testing.T) {
+	out := t.TempDir()
+	os.MkdirAll(filepath.Join(out, "stacks", "network"), 0755)
+	os.WriteFile(filepath.Join(out, "manifest.json"), []byte(`{"version": "0.20.0", "stacks": {
+  "network": {"name": "network", "synthesizedStackPath": "stacks/network/cdk.tf.json", "workingDirectory": "stacks/network"},
+  "app": {"name": "app", "synthesizedStackPath": "stacks/app/cdk.tf.json", "workingDirectory": "stacks/app"}
+}}`), 0644)
+	os.WriteFile(filepath.Join(out, "stacks", "network", "cdk.tf.json"), []byte(`{
+  "resource": {"aws_vpc": {"main_A1B2": {"//": {"metadata": {"path": "network/main", "uniqueId": "main"}}, "cidr_block": "10.0.0.0/16"}}},
+  "module": {"subnets": {"//": {"metadata": {"path": "network/subnets"}}, "source": "./subnets"}}
+}`), 0644)
+
+	stacks, err := readCDKTFManifest(out, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stacks) != 2 || stacks[0].Name != "app" || stacks[1].Name != "network" {
+		t.Fatalf("unexpected stacks %+v", stacks)
+	}
+	if _, err := readCDKTFManifest(out, "network,db"); err == nil {
+		t.Error("expected error for an unknown stack")
+	}
+
+	paths, err := readConstructPaths(filepath.Join(out, stacks[1].SynthesizedStackPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	network := TerraformPlan{ResourceChanges: []ResourceChange{
+		{Address: "aws_vpc.main_A1B2", Type: "aws_vpc", Name: "main_A1B2", Change: Change{Actions: []string{"update"}}},
+		{Address: `module.subnets.aws_subnet.this["a"]`, ModuleAddress: "module.subnets", Type: "aws_subnet", Name: "this", Change: Change{Actions: []string{"create"}}},
+	}}
+	app := TerraformPlan{ResourceChanges: []ResourceChange{
+		{Address: "aws_instance.web", Type: "aws_instance", Name: "web", Change: Change{Actions: []string{"create"}}},
+	}}
+	plan, constructs := mergeStackPlans([]stackPlan{{Name: "app", Plan: app}, {Name: "network", Plan: network, ConstructPaths: paths}})
+
+	analyzed := analyzePlanWith(plan, analysisOptions{constructPaths: constructs})
+	got := map[string]string{}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			got[r.Address] = m.Address + " " + r.ConstructPath
+		}
+	}
+	want := map[string]string{
+		"module.app.aws_instance.web":                        "module.app ",
+		"module.network.aws_vpc.main_A1B2":                   "module.network network/main",
+		`module.network.module.subnets.aws_subnet.this["a"]`: "module.network.module.subnets network/subnets",
+	}
+	for addr, w := range want {
+		if got[addr] != w {
+			t.Errorf("%s: got %q, want %q", addr, got[addr], w)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// certificateExpiryWarning is how close to its expiry an unchanged
+// certificate must be to be listed with the plan's certificate changes.
+const certificateExpiryWarning = 30 * 24 * time.Hour
+
+// certificateAttrs says where a certificate resource keeps its domain
+// names, its expiry and the values other resources use to refer to it.
+type certificateAttrs struct {
+	Domains    []string
+	Expiry     []string
+	Validation string
+	IDs        []string
+}
+
+var certificateTypes = map[string]certificateAttrs{
+	"aws_acm_certificate": {
+		Domains:    []string{"domain_name", "subject_alternative_names"},
+		Expiry:     []string{"not_after"},
+		Validation: "validation_method",
+		IDs:        []string{"arn"},
+	},
+	"aws_iam_server_certificate": {
+		Domains: []string{"name"},
+		Expiry:  []string{"expiration"},
+		IDs:     []string{"arn"},
+	},
+	"tls_self_signed_cert": {
+		Domains: []string{"subject.common_name", "dns_names"},
+		Expiry:  []string{"validity_end_time"},
+		IDs:     []string{"cert_pem"},
+	},
+	"tls_locally_signed_cert": {
+		Expiry: []string{"validity_end_time"},
+		IDs:    []string{"cert_pem"},
+	},
+	"acme_certificate": {
+		Domains: []string{"common_name", "subject_alternative_names"},
+		Expiry:  []string{"certificate_not_after"},
+		IDs:     []string{"certificate_pem"},
+	},
+	"google_compute_managed_ssl_certificate": {
+		Domains: []string{"managed.domains"},
+		Expiry:  []string{"expire_time"},
+		IDs:     []string{"self_link", "id"},
+	},
+	"google_compute_ssl_certificate": {
+		Expiry: []string{"expire_time"},
+		IDs:    []string{"self_link", "id"},
+	},
+	"google_certificate_manager_certificate": {
+		Domains: []string{"managed.domains", "san_dnsnames"},
+		IDs:     []string{"id"},
+	},
+	"azurerm_key_vault_certificate": {
+		Domains: []string{"certificate_policy.x509_certificate_properties.subject_alternative_names.dns_names"},
+		Expiry:  []string{"certificate_attribute.expires"},
+		IDs:     []string{"versionless_id", "id", "secret_id", "versionless_secret_id"},
+	},
+}
+
+// CertificateChange is a TLS certificate the plan touches. Replacing or
+// deleting a certificate that listeners or distributions still serve breaks
+// TLS for their clients, and an expiring one is easy to forget.
+type CertificateChange struct {
+	Address          string   `json:"address"`
+	Action           string   `json:"action"`
+	Domains          []string `json:"domains,omitempty"`
+	DomainsBefore    []string `json:"domains_before,omitempty"`
+	ValidationBefore string   `json:"validation_before,omitempty"`
+	ValidationAfter  string   `json:"validation_after,omitempty"`
+	// Expires is the current certificate's expiry, when the provider
+	// reports it.
+	Expires     *time.Time `json:"expires,omitempty"`
+	Remaining   string     `json:"remaining,omitempty"`
+	ExpiresSoon bool       `json:"expires_soon,omitempty"`
+	InUseBy     []string   `json:"in_use_by,omitempty"`
+}
+
+// ValidationChanged reports whether the validation method changes.
+func (c CertificateChange) ValidationChanged() bool {
+	return c.ValidationBefore != "" && c.ValidationAfter != "" && c.ValidationBefore != c.ValidationAfter
+}
+
+// findCertificates lists the certificates the plan changes, and unchanged
+// ones that expire within certificateExpiryWarning of now.
+func findCertificates(plan TerraformPlan, now time.Time) []CertificateChange {
+	var certs []CertificateChange
+	for _, rc := range plan.ResourceChanges {
+		attrs, ok := certificateTypes[rc.Type]
+		if !ok || rc.Mode == "data" {
+			continue
+		}
+		action := certificateAction(rc.Change.Actions)
+		if action == "read" {
+			continue
+		}
+		before, after := rc.Change.Before, rc.Change.After
+		c := CertificateChange{
+			Address:          rc.Address,
+			Action:           action,
+			DomainsBefore:    certificateDomains(before, attrs),
+			Domains:          certificateDomains(after, attrs),
+			ValidationBefore: settingValue(valueAt(before, attrs.Validation)),
+			ValidationAfter:  settingValue(valueAt(after, attrs.Validation)),
+		}
+		if action == "delete" {
+			c.Domains, c.DomainsBefore = c.DomainsBefore, nil
+		} else if strings.Join(c.DomainsBefore, ",") == strings.Join(c.Domains, ",") {
+			c.DomainsBefore = nil
+		}
+		if attrs.Validation == "" {
+			c.ValidationBefore, c.ValidationAfter = "", ""
+		}
+		if expires, ok := certificateExpiry(before, attrs); ok {
+			c.Expires = &expires
+			c.Remaining = remainingValidity(expires, now)
+			c.ExpiresSoon = expires.Sub(now) < certificateExpiryWarning
+		}
+		if action == "delete" || action == "replace" {
+			c.InUseBy = certificateUsers(plan, rc, attrs)
+		}
+		if action == "no-op" && !c.ExpiresSoon {
+			continue
+		}
+		certs = append(certs, c)
+	}
+	sort.SliceStable(certs, func(i, j int) bool { return certs[i].Address < certs[j].Address })
+	return certs
+}
+
+func certificateAction(actions []string) string {
+	if len(actions) == 2 {
+		return "replace"
+	}
+	if len(actions) == 1 {
+		return actions[0]
+	}
+	return "no-op"
+}
+
+func certificateDomains(values map[string]interface{}, attrs certificateAttrs) []string {
+	var domains []string
+	seen := map[string]bool{}
+	for _, path := range attrs.Domains {
+		var items []interface{}
+		switch v := valueAt(values, path).(type) {
+		case string:
+			items = []interface{}{v}
+		case []interface{}:
+			items = v
+		}
+		for _, item := range items {
+			if s, ok := item.(string); ok && s != "" && !seen[s] {
+				seen[s] = true
+				domains = append(domains, s)
+			}
+		}
+	}
+	return domains
+}
+
+// certificateExpiry reads the certificate's expiry, which providers report
+// in RFC 3339.
+func certificateExpiry(values map[string]interface{}, attrs certificateAttrs) (time.Time, bool) {
+	for _, path := range attrs.Expiry {
+		s, ok := valueAt(values, path).(string)
+		if !ok || s == "" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// remainingValidity describes how long a certificate is still valid, e.g.
+// "expires in 42 days" or "expired 3 days ago".
+func remainingValidity(expires, now time.Time) string {
+	days := int(expires.Sub(now).Hours() / 24)
+	switch {
+	case expires.Before(now):
+		return fmt.Sprintf("expired %s ago", plural(-days, "day"))
+	case days == 0:
+		return "expires today"
+	}
+	return "expires in " + plural(days, "day")
+}
+
+// certificateUsers lists the resources whose current values refer to the
+// certificate, leaving out those the plan deletes along with it.
+func certificateUsers(plan TerraformPlan, cert ResourceChange, attrs certificateAttrs) []string {
+	var ids []string
+	for _, attr := range attrs.IDs {
+		if s, ok := cert.Change.Before[attr].(string); ok && s != "" {
+			ids = append(ids, s)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	var users []string
+	for _, rc := range plan.ResourceChanges {
+		if rc.Address == cert.Address || rc.Mode == "data" || rc.Change.Before == nil {
+			continue
+		}
+		if len(rc.Change.Actions) == 1 && rc.Change.Actions[0] == "delete" {
+			continue
+		}
+		if containsAnyString(rc.Change.Before, ids) {
+			users = append(users, rc.Address)
+		}
+	}
+	return users
+}
+
+// containsAnyString reports whether a string in v, at any depth, equals
+// one of ids.
+func containsAnyString(v interface{}, ids []string) bool {
+	switch v := v.(type) {
+	case string:
+		for _, id := range ids {
+			if v == id {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if containsAnyString(item, ids) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			if containsAnyString(item, ids) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// detectCertificatesInUse flags certificates the plan deletes or replaces
+// while other resources still serve them. A replacement created before the
+// old certificate is destroyed is safe, as Terraform moves the users over
+// in between.
+func detectCertificatesInUse(plan TerraformPlan) []Finding {
+	var findings []Finding
+	for _, rc := range plan.ResourceChanges {
+		attrs, ok := certificateTypes[rc.Type]
+		if !ok || rc.Mode == "data" || !changeDeletes(rc.Change) || rc.Change.Actions[0] == "create" {
+			continue
+		}
+		users := certificateUsers(plan, rc, attrs)
+		if len(users) == 0 {
+			continue
+		}
+		verb := "deleted"
+		detail := "Point them at another certificate before applying, or the apply fails while the certificate is in use and clients get TLS errors once it is gone."
+		if len(rc.Change.Actions) == 2 {
+			verb = "replaced"
+			detail = "The old certificate is deleted before its replacement exists, which fails while it is in use or leaves clients with TLS errors. Add create_before_destroy to the certificate's lifecycle."
+		}
+		f := Finding{
+			Category: "certificate",
+			Severity: "High",
+			Address:  rc.Address,
+			Title:    fmt.Sprintf("Certificate in use by %s is %s", plural(len(users), "resource"), verb),
+			Detail:   detail,
+		}
+		for _, u := range users {
+			f.Attributes = append(f.Attributes, FindingValue{Name: "used by", Value: u})
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindCertificates(t *testing.T) {
+	arn := "arn:aws:acm:eu-west-1:123:certificate/old"
+	plan := TerraformPlan{ResourceChanges: []ResourceChange{
+		{Address: "aws_acm_certificate.api", Mode: "managed", Type: "aws_acm_certificate", Change: Change{
+			Actions: []string{"delete", "create"},
+			Before:  map[string]interface{}{"arn": arn, "domain_name": "api.example.com", "validation_method": "EMAIL", "not_after": "2026-11-05T00:00:00Z"},
+			After:   map[string]interface{}{"domain_name": "api.example.com", "subject_alternative_names": []interface{}{"www.example.com"}, "validation_method": "DNS"},
+		}},
+		{Address: "aws_lb_listener.https", Mode: "managed", Type: "aws_lb_listener", Change: Change{
+			Actions: []string{"no-op"},
+			Before:  map[string]interface{}{"port": 443.0, "certificate_arn": arn},
+			After:   map[string]interface{}{"port": 443.0, "certificate_arn": arn},
+		}},
+		{Address: "aws_lb_listener.old", Mode: "managed", Type: "aws_lb_listener", Change: Change{
+			Actions: []string{"delete"},
+			Before:  map[string]interface{}{"certificate_arn": arn},
+		}},
+		{Address: "tls_self_signed_cert.internal", Mode: "managed", Type: "tls_self_signed_cert", Change: Change{
+			Actions: []string{"no-op"},
+			Before:  map[string]interface{}{"cert_pem": "PEM", "dns_names": []interface{}{"internal.local"}, "validity_end_time": "2027-06-01T00:00:00Z"},
+		}},
+	}}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	certs := findCertificates(plan, now)
+	if len(certs) != 1 {
+		t.Fatalf("expected only the replaced certificate, got %+v", certs)
+	}
+	c := certs[0]
+	if c.Action != "replace" || strings.Join(c.Domains, ",") != "api.example.com,www.example.com" || c.DomainsBefore[0] != "api.example.com" {
+		t.Errorf("unexpected action or domains: %+v", c)
+	}
+	if !c.ValidationChanged() || c.Remaining != "expires in 19 days" || !c.ExpiresSoon {
+		t.Errorf("expected the validation change and remaining validity, got %+v", c)
+	}
+	if strings.Join(c.InUseBy, ",") != "aws_lb_listener.https" {
+		t.Errorf("InUseBy = %v, want the listener that stays", c.InUseBy)
+	}
+
+	findings := detectCertificatesInUse(plan)
+	if len(findings) != 1 || findings[0].Title != "Certificate in use by 1 resource is replaced" {
+		t.Fatalf("expected a finding for the certificate in use, got %+v", findings)
+	}
+
+	plan.ResourceChanges[0].Change.Actions = []string{"create", "delete"}
+	if findings := detectCertificatesInUse(plan); len(findings) != 0 {
+		t.Errorf("a certificate created before the old one is destroyed is safe, got %+v", findings)
+	}
+
+	if got := remainingValidity(now.Add(-72*time.Hour), now); got != "expired 3 days ago" {
+		t.Errorf("remainingValidity = %q", got)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// changeWindow is a weekly period in which applying changes is allowed, e.g.
+// {"days": ["mon","tue","wed","thu"], "start": "10:00", "end": "16:00"}.
+// A window whose end is not after its start runs past midnight into the
+// following day; days always refer to the day the window opens.
+type changeWindow struct {
+	Days     []string `json:"days"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Timezone string   `json:"timezone,omitempty"`
+
+	days       map[time.Weekday]bool
+	start, end int // minutes after midnight
+	loc        *time.Location
+}
+
+// ChangeWindowStatus is attached to an analysis when change windows are
+// configured, so the report can show whether the plan may be applied now.
+type ChangeWindowStatus struct {
+	Inside  bool     `json:"inside"`
+	Checked string   `json:"checked"`
+	Windows []string `json:"windows"`
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func (w *changeWindow) parse() error {
+	if len(w.Days) == 0 {
+		return fmt.Errorf("days must not be empty")
+	}
+	w.days = map[time.Weekday]bool{}
+	for _, d := range w.Days {
+		day, ok := weekdayNames[strings.ToLower(d)]
+		if !ok {
+			return fmt.Errorf("unknown day %q (use mon, tue, ...)", d)
+		}
+		w.days[day] = true
+	}
+
+	var err error
+	if w.start, err = parseClock(w.Start); err != nil {
+		return fmt.Errorf("start: %v", err)
+	}
+	if w.end, err = parseClock(w.End); err != nil {
+		return fmt.Errorf("end: %v", err)
+	}
+
+	w.loc = time.Local
+	if w.Timezone != "" {
+		if w.loc, err = time.LoadLocation(w.Timezone); err != nil {
+			return fmt.Errorf("timezone: %v", err)
+		}
+	}
+	return nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (use HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (w changeWindow) contains(t time.Time) bool {
+	t = t.In(w.loc)
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return w.days[t.Weekday()] && minute >= w.start && minute < w.end
+	}
+	// Overnight window: the evening part belongs to today, the morning part
+	// to the window opened yesterday.
+	if minute >= w.start {
+		return w.days[t.Weekday()]
+	}
+	return minute < w.end && w.days[t.AddDate(0, 0, -1).Weekday()]
+}
+
+func (w changeWindow) String() string {
+	s := strings.Join(w.Days, ",") + " " + w.Start + "-" + w.End
+	if w.Timezone != "" {
+		s += " " + w.Timezone
+	}
+	return s
+}
+
+// checkChangeWindows reports whether t falls inside any of windows. It
+// returns nil when no windows are configured.
+func checkChangeWindows(windows []changeWindow, t time.Time) *ChangeWindowStatus {
+	if len(windows) == 0 {
+		return nil
+	}
+	status := &ChangeWindowStatus{Checked: t.Format("2006-01-02 15:04 MST")}
+	for _, w := range windows {
+		status.Windows = append(status.Windows, w.String())
+		if w.contains(t) {
+			status.Inside = true
+		}
+	}
+	return status
+}
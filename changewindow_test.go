@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChangeWindowContains(t *testing.T) {
+	weekdays := changeWindow{Days: []string{"mon", "tue", "wed", "thu"}, Start: "10:00", End: "16:00", Timezone: "UTC"}
+	overnight := changeWindow{Days: []string{"Sat"}, Start: "22:00", End: "02:00", Timezone: "UTC"}
+	for _, w := range []*changeWindow{&weekdays, &overnight} {
+		if err := w.parse(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 2024-01-01 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name   string
+		window changeWindow
+		t      time.Time
+		want   bool
+	}{
+		{"monday inside", weekdays, at(1, 12, 0), true},
+		{"monday at start", weekdays, at(1, 10, 0), true},
+		{"monday at end", weekdays, at(1, 16, 0), false},
+		{"friday", weekdays, at(5, 12, 0), false},
+		{"other timezone", weekdays, time.Date(2024, 1, 1, 20, 0, 0, 0, time.FixedZone("KST", 9*3600)), true},
+		{"saturday evening", overnight, at(6, 23, 0), true},
+		{"sunday early morning", overnight, at(7, 1, 0), true},
+		{"saturday early morning", overnight, at(6, 1, 0), false},
+	}
+	for _, tt := range tests {
+		if got := tt.window.contains(tt.t); got != tt.want {
+			t.Errorf("%s: contains(%s) = %v, want %v", tt.name, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestCheckChangeWindows(t *testing.T) {
+	if checkChangeWindows(nil, time.Now()) != nil {
+		t.Error("expected no status without configured windows")
+	}
+	w := changeWindow{Days: []string{"mon"}, Start: "10:00", End: "16:00", Timezone: "UTC"}
+	if err := w.parse(); err != nil {
+		t.Fatal(err)
+	}
+	status := checkChangeWindows([]changeWindow{w}, time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC))
+	if status == nil || status.Inside || len(status.Windows) != 1 || status.Windows[0] != "mon 10:00-16:00 UTC" {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
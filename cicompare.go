@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// errNoPreviousAnalysis marks a missing previous artifact, which is expected
+// on the first pipeline run and is not treated as a failure.
+var errNoPreviousAnalysis = errors.New("previous analysis not found")
+
+type destructiveChange struct {
+	Address string
+	Kind    string
+}
+
+// handleCICompare compares the current plan against the analysis artifact of
+// a previous pipeline run and fails when destructive changes appear that the
+// previous run did not already contain.
+func handleCICompare(ctx context.Context, args []string) error {
+	previous, planFile, saveFile := "", "", ""
+	failOnStateful := false
+	rest := []string{}
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name == "--fail-on-stateful" {
+			failOnStateful = true
+			continue
+		}
+		if name != "--previous" && name != "--plan" && name != "--save" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return fmt.Errorf("flag %s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "--previous":
+			previous = value
+		case "--plan":
+			planFile = value
+		default:
+			saveFile = value
+		}
+	}
+	if previous == "" {
+		return fmt.Errorf("usage: tfviz ci-compare --previous <file|url> [--plan <json>] [--save <file>] [--fail-on-stateful]")
+	}
+	if previous == "-" && planFile == "-" {
+		return fmt.Errorf("stdin (-) can only be read once")
+	}
+
+	opts, tfArgs, err := parseOptions(rest)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if planFile != "" {
+		data, err = readPlanFile(planFile)
+		if err != nil {
+			return err
+		}
+	} else {
+		data, err = runTerraformPlan(ctx, "", tfArgs)
+		if err != nil {
+			return err
+		}
+	}
+	plan, err := parsePlanJSON(data)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(opts.configFile, opts.profile)
+	if err != nil {
+		return err
+	}
+	current := analyzePlan(plan)
+	if err := applyConfig(&current, cfg, time.Now()); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	accepted, err := readBaseline(opts.baselineFile)
+	if err != nil {
+		return err
+	}
+	applyBaseline(&current, accepted)
+
+	if saveFile != "" {
+		out, err := json.MarshalIndent(current, "", "  ")
+		if err != nil {
+			return err
+		}
+		out, err = sealData(out, encryptionPassphrase())
+		if err != nil {
+			return fmt.Errorf("error encrypting analysis: %v", err)
+		}
+		if err := writeChecksummed(saveFile, out, 0600, opts.signKey); err != nil {
+			return fmt.Errorf("error writing analysis: %v", err)
+		}
+		fmt.Printf("💾 Analysis saved to %s\n", saveFile)
+	}
+
+	prev, err := loadAnalysis(ctx, previous)
+	if errors.Is(err, errNoPreviousAnalysis) {
+		fmt.Printf("ℹ️  No previous analysis at %s, comparing against an empty baseline\n", previous)
+	} else if err != nil {
+		return err
+	}
+
+	introduced := withoutAcknowledged(newDestructiveChanges(prev, current), current, accepted)
+	if len(introduced) == 0 {
+		fmt.Println("✅ No newly introduced destructive changes")
+	} else {
+		fmt.Printf("⚠️  %d destructive changes are new since the previous run:\n", len(introduced))
+		for _, c := range introduced {
+			fmt.Printf("  %s (%s)\n", c.Address, c.Kind)
+		}
+	}
+
+	// Stateful destroys fail the run whether or not they are new, unless
+	// they are acknowledged in the baseline.
+	var stateful []ResourceAnalysis
+	for _, r := range current.StatefulDestroys() {
+		if !accepted.has(changeFingerprint(r)) {
+			stateful = append(stateful, r)
+		}
+	}
+	if failOnStateful && len(stateful) > 0 {
+		fmt.Printf("⛔ %d stateful resources are deleted or replaced:\n", len(stateful))
+		for _, r := range stateful {
+			fmt.Printf("  %s\n", r.Address)
+		}
+		return fmt.Errorf("stateful resources would be destroyed")
+	}
+	if len(introduced) > 0 {
+		return fmt.Errorf("new destructive changes detected")
+	}
+	return nil
+}
+
+// loadAnalysis reads an AnalyzedPlan JSON artifact from a local path, an
+// http(s) URL or stdin ("-").
+func loadAnalysis(ctx context.Context, source string) (AnalyzedPlan, error) {
+	var analyzed AnalyzedPlan
+	var data []byte
+
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return analyzed, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return analyzed, fmt.Errorf("error downloading previous analysis: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return analyzed, errNoPreviousAnalysis
+		}
+		if resp.StatusCode != http.StatusOK {
+			return analyzed, fmt.Errorf("error downloading previous analysis: %s", resp.Status)
+		}
+		data, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return analyzed, fmt.Errorf("error downloading previous analysis: %v", err)
+		}
+	} else {
+		var err error
+		data, err = readPlanFile(source)
+		if errors.Is(err, fs.ErrNotExist) {
+			return analyzed, errNoPreviousAnalysis
+		}
+		if err != nil {
+			return analyzed, err
+		}
+	}
+
+	data, err := openData(data, encryptionPassphrase())
+	if err != nil {
+		return analyzed, fmt.Errorf("error reading previous analysis: %v", err)
+	}
+	if err := json.Unmarshal(data, &analyzed); err != nil {
+		return analyzed, fmt.Errorf("error parsing previous analysis: %v", err)
+	}
+	return analyzed, nil
+}
+
+func destructiveChanges(analyzed AnalyzedPlan) map[string]string {
+	result := map[string]string{}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			if kind := destructiveKind(r); kind != "" {
+				result[r.Address] = kind
+			}
+		}
+	}
+	return result
+}
+
+// destructiveKind is "delete" or "replace" for the changes that destroy r,
+// and "" for others. Replace is set for create_before_destroy replacements
+// too, whose actions are ["create","delete"].
+func destructiveKind(r ResourceAnalysis) string {
+	if r.Action == "delete" {
+		return "delete"
+	} else if r.Replace {
+		return "replace"
+	}
+	return ""
+}
+
+// newDestructiveChanges returns destructive changes in current that prev did
+// not plan in the same way, compared by changeFingerprint: a replacement
+// caused by other attributes than before counts as new.
+func newDestructiveChanges(prev, current AnalyzedPlan) []destructiveChange {
+	before := map[string]bool{}
+	for _, fp := range changeFingerprints(prev) {
+		before[fp] = true
+	}
+	fingerprints := changeFingerprints(current)
+	var introduced []destructiveChange
+	for addr, kind := range destructiveChanges(current) {
+		if !before[fingerprints[addr]] {
+			introduced = append(introduced, destructiveChange{Address: addr, Kind: kind})
+		}
+	}
+	sort.Slice(introduced, func(i, j int) bool { return introduced[i].Address < introduced[j].Address })
+	return introduced
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewDestructiveChanges(t *testing.T) {
+	prev := AnalyzedPlan{Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{
+		{Address: "aws_instance.old", Action: "delete"},
+		{Address: "aws_db_instance.db", Action: "update"},
+	}}}}
+	current := AnalyzedPlan{Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{
+		{Address: "aws_instance.old", Action: "delete"},
+		{Address: "aws_db_instance.db", Action: "update", Replace: true},
+		{Address: "aws_s3_bucket.logs", Action: "delete"},
+		{Address: "aws_iam_role.r", Action: "create"},
+	}}}}
+
+	got := newDestructiveChanges(prev, current)
+	want := []destructiveChange{
+		{Address: "aws_db_instance.db", Kind: "replace"},
+		{Address: "aws_s3_bucket.logs", Kind: "delete"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDestructiveChanges_CreateBeforeDestroy(t *testing.T) {
+	plan := TerraformPlan{ResourceChanges: []ResourceChange{
+		{Address: "aws_instance.web", Type: "aws_instance", Change: Change{Actions: []string{"create", "delete"}}},
+		{Address: "aws_instance.api", Type: "aws_instance", Change: Change{Actions: []string{"delete", "create"}}},
+		{Address: "aws_instance.new", Type: "aws_instance", Change: Change{Actions: []string{"create"}}},
+	}}
+	got := destructiveChanges(analyzePlan(plan))
+	if len(got) != 2 || got["aws_instance.web"] != "replace" || got["aws_instance.api"] != "replace" {
+		t.Errorf("expected both replacements whatever the order of their actions, got %v", got)
+	}
+}
+
+func TestLoadAnalysis_Missing(t *testing.T) {
+	_, err := loadAnalysis(context.Background(), filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.Is(err, errNoPreviousAnalysis) {
+		t.Errorf("missing file: got %v, want errNoPreviousAnalysis", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/analysis.json" {
+			w.Write([]byte(`{"summary":{"total_resources":1},"modules":[{"address":"root","resources":[{"address":"aws_instance.web","action":"delete"}]}]}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	if _, err := loadAnalysis(context.Background(), srv.URL+"/gone.json"); !errors.Is(err, errNoPreviousAnalysis) {
+		t.Errorf("404 URL: got %v, want errNoPreviousAnalysis", err)
+	}
+	analyzed, err := loadAnalysis(context.Background(), srv.URL+"/analysis.json")
+	if err != nil {
+		t.Fatalf("loadAnalysis: %v", err)
+	}
+	if destructiveChanges(analyzed)["aws_instance.web"] != "delete" {
+		t.Errorf("unexpected analysis: %+v", analyzed)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+	w.WriteString(`{"modules":[{"address":"root","resources":[{"address":"aws_s3_bucket.logs","action":"delete"}]}]}`)
+	w.Close()
+	if analyzed, err := loadAnalysis(context.Background(), "-"); err != nil || destructiveChanges(analyzed)["aws_s3_bucket.logs"] != "delete" {
+		t.Errorf("stdin: got %+v, %v", analyzed, err)
+	}
+	if err := handleCICompare(context.Background(), []string{"--previous", "-", "--plan", "-"}); err == nil || !strings.Contains(err.Error(), "only be read once") {
+		t.Errorf("got %v, want an error for reading stdin twice", err)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// CollectionSize is the element count of a list, set or map attribute before
+// and after a change, to gauge its magnitude without reading the nested diff.
+type CollectionSize struct {
+	Before int `json:"before"`
+	After  int `json:"after"`
+}
+
+// String formats the counts, e.g. "4 → 7 (+3)".
+func (s CollectionSize) String() string {
+	return fmt.Sprintf("%d → %d (%+d)", s.Before, s.After, s.After-s.Before)
+}
+
+// collectionSizes counts the elements of a collection attribute. A missing
+// or null value counts as empty; values that are not collections, or change
+// to something that is not, have no size.
+func collectionSizes(before, after interface{}) *CollectionSize {
+	b, beforeOK := collectionLen(before)
+	a, afterOK := collectionLen(after)
+	if (!beforeOK && !afterOK) || (!beforeOK && before != nil) || (!afterOK && after != nil) {
+		return nil
+	}
+	return &CollectionSize{Before: b, After: a}
+}
+
+func collectionLen(v interface{}) (int, bool) {
+	switch x := v.(type) {
+	case []interface{}:
+		return len(x), true
+	case map[string]interface{}:
+		return len(x), true
+	}
+	return 0, false
+}
+
+// SizeChanges lists the collection attributes of an update whose element
+// count changes, sorted by name. Creates and deletes change every count.
+func (r ResourceAnalysis) SizeChanges() []ChangeDetail {
+	if r.Action != "update" {
+		return nil
+	}
+	var changes []ChangeDetail
+	for _, c := range r.Changes {
+		if c.Size != nil && c.Size.Before != c.Size.After {
+			changes = append(changes, c)
+		}
+	}
+	slices.SortFunc(changes, func(a, b ChangeDetail) int { return strings.Compare(a.Field, b.Field) })
+	return changes
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCollectionSizes(t *testing.T) {
+	tests := []struct {
+		before, after interface{}
+		want          string
+	}{
+		{[]interface{}{1, 2, 3, 4}, []interface{}{1, 2, 3, 4, 5, 6, 7}, "4 → 7 (+3)"},
+		{map[string]interface{}{"a": "1", "b": "2"}, map[string]interface{}{"a": "1"}, "2 → 1 (-1)"},
+		{nil, []interface{}{"x"}, "0 → 1 (+1)"},
+		{[]interface{}{"x"}, nil, "1 → 0 (-1)"},
+		{"t3.small", "t3.large", ""},
+		{"x", []interface{}{"x"}, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if size := collectionSizes(tt.before, tt.after); size != nil {
+			got = size.String()
+		}
+		if got != tt.want {
+			t.Errorf("collectionSizes(%v, %v) = %q, want %q", tt.before, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestSizeChanges(t *testing.T) {
+	rule := func(port float64) interface{} { return map[string]interface{}{"from_port": port} }
+	plan := TerraformPlan{ResourceChanges: []ResourceChange{
+		{Address: "aws_security_group.web", Mode: "managed", Type: "aws_security_group", Name: "web",
+			Change: Change{Actions: []string{"update"},
+				Before: map[string]interface{}{
+					"ingress": []interface{}{rule(22), rule(80), rule(443), rule(8080)},
+					"tags":    map[string]interface{}{"team": "web"},
+					"name":    "web",
+				},
+				After: map[string]interface{}{
+					"ingress": []interface{}{rule(22), rule(80), rule(443), rule(8080), rule(8443), rule(9000), rule(9090)},
+					"tags":    map[string]interface{}{"team": "platform"},
+					"name":    "web-sg",
+				}}},
+	}}
+	analyzed := analyzePlan(plan)
+	r := analyzed.Modules[0].Resources[0]
+
+	changes := r.SizeChanges()
+	if len(changes) != 1 || changes[0].Field != "ingress" || changes[0].Size.String() != "4 → 7 (+3)" {
+		t.Fatalf("SizeChanges() = %+v, want only ingress 4 → 7 (+3)", changes)
+	}
+
+	html := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+	if !strings.Contains(html, "<code>ingress</code> 4 → 7 (&#43;3)") {
+		t.Error("report does not show the element count delta of ingress")
+	}
+}
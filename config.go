@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultConfigFile is read from the working directory when --config is not
+// given. It is optional.
+const defaultConfigFile = ".tfviz.json"
+
+// tfvizConfig holds project settings that are too structured for flags.
+type tfvizConfig struct {
+	ChangeWindows []changeWindow        `json:"change_windows,omitempty"`
+	Runbooks      []runbookLink         `json:"runbooks,omitempty"`
+	RiskNotes     []riskNote            `json:"risk_notes,omitempty"`
+	Descriptions  []descriptionTemplate `json:"descriptions,omitempty"`
+
+	CriticalAttributes []criticalAttributeRule `json:"critical_attributes,omitempty"`
+	StatefulTypes      []string                `json:"stateful_types,omitempty"`
+	ProviderLag        *providerLag            `json:"provider_lag,omitempty"`
+	Quotas             []serviceQuota          `json:"quotas,omitempty"`
+	Durations          []durationEstimate      `json:"durations,omitempty"`
+	Parallelism        int                     `json:"parallelism,omitempty"`
+	// Accounts are the accounts, projects and subscriptions plans are
+	// expected to change; see checkAccounts.
+	Accounts []string `json:"accounts,omitempty"`
+	// Sort is the default order of the resource list, see resourceSorts.
+	Sort    string         `json:"sort,omitempty"`
+	Summary *summaryConfig `json:"summary,omitempty"`
+	// ServiceNow is where --servicenow opens change requests.
+	ServiceNow *serviceNowConfig `json:"servicenow,omitempty"`
+	// Tunnels define providers for --share besides the built-in ones.
+	Tunnels map[string]tunnelConfig `json:"tunnels,omitempty"`
+	// Owners add reviewers by module and resource type to those found in
+	// CODEOWNERS.
+	Owners []ownerRule `json:"owners,omitempty"`
+
+	// Profiles are named sets of settings that replace the ones above when
+	// selected with --profile, e.g. a stricter "prod-review".
+	Profiles map[string]json.RawMessage `json:"profiles,omitempty"`
+}
+
+// loadConfig reads the config file at path, or defaultConfigFile when path is
+// empty, with the settings of the named profile, if any, applied on top. Only
+// an explicitly named file is required to exist.
+func loadConfig(path, profile string) (tfvizConfig, error) {
+	var cfg tfvizConfig
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigFile
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		if profile != "" {
+			return cfg, fmt.Errorf("profile %q needs a config file, but %s does not exist", profile, path)
+		}
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("error reading config: %v", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("error parsing config %s: %v", path, err)
+	}
+	if profile != "" {
+		settings, ok := cfg.Profiles[profile]
+		if !ok {
+			return cfg, fmt.Errorf("config %s: unknown profile %q (defined: %s)", path, profile, strings.Join(sortedKeys(cfg.Profiles), ", "))
+		}
+		if err := json.Unmarshal(settings, &cfg); err != nil {
+			return cfg, fmt.Errorf("error parsing config %s: profiles.%s: %v", path, profile, err)
+		}
+	}
+	for i := range cfg.ChangeWindows {
+		if err := cfg.ChangeWindows[i].parse(); err != nil {
+			return cfg, fmt.Errorf("config %s: change_windows[%d]: %v", path, i, err)
+		}
+	}
+	if cfg.Parallelism < 0 {
+		return cfg, fmt.Errorf("config %s: parallelism must not be negative", path)
+	}
+	for i := range cfg.Durations {
+		if err := cfg.Durations[i].parse(); err != nil {
+			return cfg, fmt.Errorf("config %s: durations[%d]: %v", path, i, err)
+		}
+	}
+	for i := range cfg.Descriptions {
+		if err := cfg.Descriptions[i].parse(); err != nil {
+			return cfg, fmt.Errorf("config %s: descriptions[%d]: %v", path, i, err)
+		}
+	}
+	if err := validateRunbooks(cfg.Runbooks); err != nil {
+		return cfg, fmt.Errorf("config %s: %v", path, err)
+	}
+	if err := validateRiskNotes(cfg.RiskNotes); err != nil {
+		return cfg, fmt.Errorf("config %s: %v", path, err)
+	}
+	if err := validateCriticalAttributes(cfg.CriticalAttributes); err != nil {
+		return cfg, fmt.Errorf("config %s: %v", path, err)
+	}
+	if err := validatePatterns("stateful_types", cfg.StatefulTypes); err != nil {
+		return cfg, fmt.Errorf("config %s: %v", path, err)
+	}
+	if err := validatePatterns("accounts", cfg.Accounts); err != nil {
+		return cfg, fmt.Errorf("config %s: %v", path, err)
+	}
+	if err := validateQuotas(cfg.Quotas); err != nil {
+		return cfg, fmt.Errorf("config %s: %v", path, err)
+	}
+	if err := validateSort(cfg.Sort); err != nil {
+		return cfg, fmt.Errorf("config %s: %v", path, err)
+	}
+	if err := validateSummaryConfig(cfg.Summary); err != nil {
+		return cfg, fmt.Errorf("config %s: %v", path, err)
+	}
+	if err := validateServiceNowConfig(cfg.ServiceNow); err != nil {
+		return cfg, fmt.Errorf("config %s: %v", path, err)
+	}
+	if err := validateTunnels(cfg.Tunnels); err != nil {
+		return cfg, fmt.Errorf("config %s: %v", path, err)
+	}
+	if err := validateOwnerRules(cfg.Owners); err != nil {
+		return cfg, fmt.Errorf("config %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// applyConfig adjusts a fresh analysis with the project settings. Errors in
+// user templates are reported but leave the analysis usable.
+func applyConfig(analyzed *AnalyzedPlan, cfg tfvizConfig, now time.Time) error {
+	analyzed.ChangeWindow = checkChangeWindows(cfg.ChangeWindows, now)
+	applyCriticalAttributes(analyzed, cfg.CriticalAttributes)
+	markStateful(analyzed, cfg.StatefulTypes)
+	analyzed.Findings = append(analyzed.Findings, checkQuotas(*analyzed, cfg.Quotas)...)
+	analyzed.Findings = append(analyzed.Findings, checkAccounts(*analyzed, cfg.Accounts)...)
+	applyRunbooks(analyzed, cfg.Runbooks)
+	applyRiskNotes(analyzed, cfg.RiskNotes)
+	analyzed.Timeline = buildTimeline(*analyzed, cfg.Durations, cfg.Parallelism)
+	return applyDescriptions(analyzed, cfg.Descriptions)
+}
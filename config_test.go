@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	// The default file is optional
+	cfg, err := loadConfig("", "")
+	if err != nil || len(cfg.ChangeWindows) != 0 {
+		t.Fatalf("loadConfig without file = %+v, %v", cfg, err)
+	}
+	// An explicitly named file is not
+	if _, err := loadConfig(filepath.Join(dir, "missing.json"), ""); err == nil {
+		t.Error("expected error for missing --config file")
+	}
+
+	os.WriteFile(defaultConfigFile, []byte(`{"change_windows": [{"days": ["mon", "tue"], "start": "10:00", "end": "16:00", "timezone": "UTC"}]}`), 0644)
+	cfg, err = loadConfig("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.ChangeWindows) != 1 || cfg.ChangeWindows[0].loc == nil {
+		t.Errorf("change window not parsed: %+v", cfg.ChangeWindows)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	os.WriteFile(bad, []byte(`{"change_windows": [{"days": ["someday"], "start": "10:00", "end": "16:00"}]}`), 0644)
+	if _, err := loadConfig(bad, ""); err == nil {
+		t.Error("expected error for unknown day")
+	}
+}
+
+func TestLoadConfig_Profile(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	if _, err := loadConfig("", "ci"); err == nil {
+		t.Error("expected error for a profile without a config file")
+	}
+
+	os.WriteFile(defaultConfigFile, []byte(`{
+  "sort": "type",
+  "stateful_types": ["aws_db_*"],
+  "profiles": {
+    "prod-review": {"sort": "impact", "critical_attributes": [{"type": "aws_instance", "attributes": ["ami"], "impact": "High"}]},
+    "ci": {"parallelism": -1}
+  }
+}`), 0644)
+	cfg, err := loadConfig("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Sort != "type" || len(cfg.CriticalAttributes) != 0 {
+		t.Errorf("profile applied without --profile: %+v", cfg)
+	}
+
+	cfg, err = loadConfig("", "prod-review")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Sort != "impact" || len(cfg.CriticalAttributes) != 1 || len(cfg.StatefulTypes) != 1 {
+		t.Errorf("profile not applied on top of the file: %+v", cfg)
+	}
+
+	// Profile settings are validated like the rest of the file
+	if _, err := loadConfig("", "ci"); err == nil {
+		t.Error("expected error for negative parallelism in a profile")
+	}
+	if _, err := loadConfig("", "local"); err == nil || !strings.Contains(err.Error(), "ci, prod-review") {
+		t.Errorf("got %v, want an error listing the profiles", err)
+	}
+}
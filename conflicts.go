@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// uniqueNameAttributes are the attributes that must be unique per resource
+// type, within an account or globally. Two planned resources sharing the
+// value fail at apply time.
+var uniqueNameAttributes = map[string][]string{
+	"aws_s3_bucket":               {"bucket"},
+	"aws_iam_role":                {"name"},
+	"aws_iam_user":                {"name"},
+	"aws_iam_group":               {"name"},
+	"aws_iam_policy":              {"name"},
+	"aws_iam_instance_profile":    {"name"},
+	"aws_security_group":          {"vpc_id", "name"},
+	"aws_sqs_queue":               {"name"},
+	"aws_sns_topic":               {"name"},
+	"aws_lambda_function":         {"function_name"},
+	"aws_dynamodb_table":          {"name"},
+	"aws_db_instance":             {"identifier"},
+	"aws_ecr_repository":          {"name"},
+	"aws_cloudwatch_log_group":    {"name"},
+	"google_storage_bucket":       {"name"},
+	"google_service_account":      {"account_id"},
+	"azurerm_storage_account":     {"name"},
+	"azurerm_resource_group":      {"name"},
+	"aws_route53_zone":            {"name"},
+	"aws_elasticache_cluster":     {"cluster_id"},
+	"aws_secretsmanager_secret":   {"name"},
+	"aws_ssm_parameter":           {"name"},
+	"aws_kms_alias":               {"name"},
+	"aws_cloudwatch_metric_alarm": {"alarm_name"},
+}
+
+// plannedValues returns the values a resource will have after apply, or nil
+// when it is deleted or is a data source.
+func plannedValues(rc ResourceChange) map[string]interface{} {
+	if rc.Mode == "data" || rc.Change.After == nil {
+		return nil
+	}
+	return rc.Change.After
+}
+
+func isChanging(rc ResourceChange) bool {
+	return len(rc.Change.Actions) > 0 && rc.Change.Actions[0] != "no-op" && rc.Change.Actions[0] != "read"
+}
+
+// detectConflicts finds planned resources that would collide with each
+// other: equal unique names and overlapping subnets in the same VPC. At
+// least one of the resources involved must be changing, since existing
+// resources evidently do not conflict.
+func detectConflicts(plan TerraformPlan) []Finding {
+	var findings []Finding
+
+	byName := map[string][]ResourceChange{}
+	var keys []string
+	for _, rc := range plan.ResourceChanges {
+		values := plannedValues(rc)
+		attrs, ok := uniqueNameAttributes[rc.Type]
+		if values == nil || !ok {
+			continue
+		}
+		var parts []string
+		for _, a := range attrs {
+			v, ok := values[a].(string)
+			if !ok || v == "" {
+				parts = nil
+				break
+			}
+			parts = append(parts, v)
+		}
+		if parts == nil {
+			continue
+		}
+		key := rc.Type + "\x00" + strings.Join(parts, "\x00")
+		if _, seen := byName[key]; !seen {
+			keys = append(keys, key)
+		}
+		byName[key] = append(byName[key], rc)
+	}
+	for _, key := range keys {
+		group := byName[key]
+		if len(group) < 2 || !anyChanges(group) {
+			continue
+		}
+		parts := strings.Split(key, "\x00")
+		attrs := uniqueNameAttributes[parts[0]]
+		f := Finding{
+			Category: "conflict",
+			Severity: "High",
+			Address:  group[0].Address,
+			Title:    fmt.Sprintf("Conflicting %s: %s %q is used %d times", parts[0], attrs[len(attrs)-1], parts[len(parts)-1], len(group)),
+			Detail:   "Also declared by " + joinAddresses(group[1:]) + ".",
+		}
+		findings = append(findings, f)
+	}
+
+	return append(findings, detectSubnetOverlaps(plan)...)
+}
+
+func anyChanges(group []ResourceChange) bool {
+	for _, rc := range group {
+		if isChanging(rc) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinAddresses(group []ResourceChange) string {
+	var addrs []string
+	for _, rc := range group {
+		addrs = append(addrs, rc.Address)
+	}
+	return strings.Join(addrs, ", ")
+}
+
+type plannedSubnet struct {
+	rc     ResourceChange
+	prefix netip.Prefix
+}
+
+// detectSubnetOverlaps reports subnets of the same VPC whose CIDR blocks
+// overlap. The VPC is the known vpc_id or, for VPCs that do not exist yet,
+// the resource the configuration references.
+func detectSubnetOverlaps(plan TerraformPlan) []Finding {
+	vpcRefs := subnetVPCReferences(plan.Configuration)
+	byVPC := map[string][]plannedSubnet{}
+	for _, rc := range plan.ResourceChanges {
+		values := plannedValues(rc)
+		if rc.Type != "aws_subnet" || values == nil {
+			continue
+		}
+		cidr, _ := values["cidr_block"].(string)
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		vpc, _ := values["vpc_id"].(string)
+		if vpc == "" {
+			vpc = vpcRefs[stripIndex(rc.Address)]
+		}
+		if vpc == "" {
+			continue
+		}
+		byVPC[vpc] = append(byVPC[vpc], plannedSubnet{rc, prefix.Masked()})
+	}
+
+	var findings []Finding
+	for _, vpc := range sortedKeys(byVPC) {
+		subnets := byVPC[vpc]
+		for i := 0; i < len(subnets); i++ {
+			for j := i + 1; j < len(subnets); j++ {
+				a, b := subnets[i], subnets[j]
+				if !a.prefix.Overlaps(b.prefix) || (!isChanging(a.rc) && !isChanging(b.rc)) {
+					continue
+				}
+				findings = append(findings, Finding{
+					Category: "conflict",
+					Severity: "High",
+					Address:  a.rc.Address,
+					Title:    fmt.Sprintf("Overlapping subnets: %s and %s", a.prefix, b.prefix),
+					Detail:   fmt.Sprintf("%s overlaps %s in the same VPC.", a.rc.Address, b.rc.Address),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// subnetVPCReferences maps aws_subnet configuration addresses to the
+// resource their vpc_id refers to, e.g. module.net.aws_vpc.main.
+func subnetVPCReferences(config PlanConfiguration) map[string]string {
+	refs := map[string]string{}
+	var walk func(mod ConfigModule, prefix string)
+	walk = func(mod ConfigModule, prefix string) {
+		for _, res := range mod.Resources {
+			if res.Type != "aws_subnet" {
+				continue
+			}
+			for _, ref := range extractReferences(res.Expressions["vpc_id"]) {
+				if strings.HasPrefix(ref, "var.") || strings.HasPrefix(ref, "local.") {
+					continue
+				}
+				refs[prefix+res.Address] = prefix + normalizeRef(ref)
+				break
+			}
+		}
+		for _, name := range sortedKeys(mod.ModuleCalls) {
+			walk(mod.ModuleCalls[name].Module, prefix+"module."+name+".")
+		}
+	}
+	walk(config.RootModule, "")
+	return refs
+}
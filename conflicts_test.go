@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDetectConflicts_Names(t *testing.T) {
+	plan := TerraformPlan{ResourceChanges: []ResourceChange{
+		{Address: "aws_s3_bucket.logs", Mode: "managed", Type: "aws_s3_bucket",
+			Change: Change{Actions: []string{"no-op"}, After: map[string]interface{}{"bucket": "acme-logs"}}},
+		{Address: "module.audit.aws_s3_bucket.this", Mode: "managed", Type: "aws_s3_bucket",
+			Change: Change{Actions: []string{"create"}, After: map[string]interface{}{"bucket": "acme-logs"}}},
+		{Address: "aws_iam_role.a", Mode: "managed", Type: "aws_iam_role",
+			Change: Change{Actions: []string{"no-op"}, After: map[string]interface{}{"name": "ci"}}},
+		{Address: "aws_iam_role.b", Mode: "managed", Type: "aws_iam_role",
+			Change: Change{Actions: []string{"delete"}, Before: map[string]interface{}{"name": "ci"}}},
+		{Address: "aws_iam_role.c", Mode: "managed", Type: "aws_iam_role",
+			Change: Change{Actions: []string{"no-op"}, After: map[string]interface{}{"name": "deploy"}}},
+		{Address: "aws_iam_role.d", Mode: "managed", Type: "aws_iam_role",
+			Change: Change{Actions: []string{"no-op"}, After: map[string]interface{}{"name": "deploy"}}},
+	}}
+	findings := detectConflicts(plan)
+	if len(findings) != 1 {
+		t.Fatalf("got %d findings, want 1: %+v", len(findings), findings)
+	}
+	if f := findings[0]; f.Address != "aws_s3_bucket.logs" || !strings.Contains(f.Title, `"acme-logs"`) || !strings.Contains(f.Detail, "module.audit.aws_s3_bucket.this") {
+		t.Errorf("unexpected finding %+v", f)
+	}
+}
+
+func TestDetectConflicts_SubnetOverlap(t *testing.T) {
+	var config PlanConfiguration
+	json.Unmarshal([]byte(`{"root_module": {"resources": [
+	  {"address": "aws_subnet.a", "type": "aws_subnet", "expressions": {"vpc_id": {"references": ["aws_vpc.main.id", "aws_vpc.main"]}}},
+	  {"address": "aws_subnet.b", "type": "aws_subnet", "expressions": {"vpc_id": {"references": ["aws_vpc.main.id", "aws_vpc.main"]}}}
+	], "module_calls": {"other": {"module": {"resources": [
+	  {"address": "aws_subnet.c", "type": "aws_subnet", "expressions": {"vpc_id": {"references": ["aws_vpc.main.id", "aws_vpc.main"]}}}
+	]}}}}}`), &config)
+
+	subnet := func(addr, cidr string, vpc interface{}) ResourceChange {
+		after := map[string]interface{}{"cidr_block": cidr}
+		if vpc != nil {
+			after["vpc_id"] = vpc
+		}
+		return ResourceChange{Address: addr, Mode: "managed", Type: "aws_subnet", Change: Change{Actions: []string{"create"}, After: after}}
+	}
+	plan := TerraformPlan{Configuration: config, ResourceChanges: []ResourceChange{
+		subnet("aws_subnet.a[0]", "10.0.0.0/24", nil),
+		subnet("aws_subnet.b", "10.0.0.128/25", nil),
+		// Same CIDR, but in a different (module-local) VPC
+		subnet("module.other.aws_subnet.c", "10.0.0.0/24", nil),
+		subnet("aws_subnet.x", "10.1.0.0/16", "vpc-123"),
+		subnet("aws_subnet.y", "10.1.4.0/22", "vpc-123"),
+		subnet("aws_subnet.z", "10.1.4.0/22", "vpc-456"),
+	}}
+	findings := detectConflicts(plan)
+	if len(findings) != 2 {
+		t.Fatalf("got %d findings, want 2: %+v", len(findings), findings)
+	}
+	if !strings.Contains(findings[0].Detail, "aws_subnet.a[0] overlaps aws_subnet.b") {
+		t.Errorf("unexpected first finding %+v", findings[0])
+	}
+	if !strings.Contains(findings[1].Title, "10.1.0.0/16 and 10.1.4.0/22") {
+		t.Errorf("unexpected second finding %+v", findings[1])
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// isCosmetic reports whether two attribute values differ only in ways the
+// provider normalises away: surrounding or repeated whitespace, a trailing
+// newline, key order and indentation of JSON documents, and the case of
+// enum-like values such as "ENABLED" and "Enabled".
+func isCosmetic(before, after interface{}) bool {
+	b, ok1 := before.(string)
+	a, ok2 := after.(string)
+	if !ok1 || !ok2 || a == b {
+		return false
+	}
+	if strings.Join(strings.Fields(a), " ") == strings.Join(strings.Fields(b), " ") {
+		return true
+	}
+	if isEnumLike(a) && isEnumLike(b) && strings.EqualFold(a, b) {
+		return true
+	}
+	var bv, av interface{}
+	if json.Unmarshal([]byte(b), &bv) != nil || json.Unmarshal([]byte(a), &av) != nil {
+		return false
+	}
+	switch av.(type) {
+	case map[string]interface{}, []interface{}:
+		return deepEqual(bv, av)
+	}
+	return false
+}
+
+// isEnumLike matches short single-word values; free text and names with
+// spaces are never compared case-insensitively.
+func isEnumLike(s string) bool {
+	if s == "" || len(s) > 64 {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// CosmeticOnly reports an update whose every changed attribute differs only
+// by formatting. Such updates are kept at Low impact and can be hidden in
+// the report.
+func (r ResourceAnalysis) CosmeticOnly() bool {
+	if r.Action != "update" || len(r.Changes) == 0 {
+		return false
+	}
+	for _, c := range r.Changes {
+		if !c.Cosmetic {
+			return false
+		}
+	}
+	return true
+}
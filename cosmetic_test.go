@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestIsCosmetic(t *testing.T) {
+	tests := []struct {
+		before, after interface{}
+		want          bool
+	}{
+		{"echo hi\n", "echo hi", true},
+		{"a  b\tc", "a b c", true},
+		{`{"b":1,"a":[1,2]}`, "{\n  \"a\": [1, 2],\n  \"b\": 1\n}", true},
+		{"ENABLED", "Enabled", true},
+		{"Hello World", "hello world", false},
+		{`{"a":1}`, `{"a":2}`, false},
+		{"t3.small", "t3.large", false},
+		{"1", "1.0", false},
+		{1.0, 2.0, false},
+	}
+	for _, tt := range tests {
+		if got := isCosmetic(tt.before, tt.after); got != tt.want {
+			t.Errorf("isCosmetic(%q, %q) = %v, want %v", tt.before, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzePlan_CosmeticOnlyUpdate(t *testing.T) {
+	plan := TerraformPlan{ResourceChanges: []ResourceChange{
+		{Address: "aws_iam_policy.read", Mode: "managed", Type: "aws_iam_policy", Name: "read",
+			Change: Change{Actions: []string{"update"},
+				Before: map[string]interface{}{"name": "read", "policy": `{"Version":"2012-10-17","Statement":[]}`},
+				After:  map[string]interface{}{"name": "read", "policy": "{\n  \"Statement\": [],\n  \"Version\": \"2012-10-17\"\n}"}}},
+		{Address: "aws_iam_policy.write", Mode: "managed", Type: "aws_iam_policy", Name: "write",
+			Change: Change{Actions: []string{"update"},
+				Before: map[string]interface{}{"name": "write", "description": "old"},
+				After:  map[string]interface{}{"name": "write", "description": "new"}}},
+	}}
+
+	analyzed := analyzePlan(plan)
+	applyCriticalAttributes(&analyzed, []criticalAttributeRule{{Type: "aws_iam_policy", Attributes: []string{"policy"}, Impact: "High"}})
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			switch r.Address {
+			case "aws_iam_policy.read":
+				if !r.CosmeticOnly() || r.Impact != "Low" || !r.Changes[0].Cosmetic {
+					t.Errorf("read: CosmeticOnly=%v impact=%s changes=%+v", r.CosmeticOnly(), r.Impact, r.Changes)
+				}
+			case "aws_iam_policy.write":
+				if r.CosmeticOnly() || r.Impact != "High" {
+					t.Errorf("write: CosmeticOnly=%v impact=%s", r.CosmeticOnly(), r.Impact)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// criticalAttributeRule raises the impact of changes to resources matching
+// Type (a glob pattern) that touch any of Attributes, whatever the action.
+type criticalAttributeRule struct {
+	Type       string   `json:"type"`
+	Attributes []string `json:"attributes"`
+	Impact     string   `json:"impact"`
+}
+
+func validateCriticalAttributes(rules []criticalAttributeRule) error {
+	for i, rule := range rules {
+		if rule.Type == "" || len(rule.Attributes) == 0 {
+			return fmt.Errorf("critical_attributes[%d]: type and attributes are required", i)
+		}
+		if _, err := path.Match(rule.Type, ""); err != nil {
+			return fmt.Errorf("critical_attributes[%d]: invalid pattern %q", i, rule.Type)
+		}
+		if impactRank[rule.Impact] == 0 {
+			return fmt.Errorf("critical_attributes[%d]: impact must be Low, Medium or High, got %q", i, rule.Impact)
+		}
+	}
+	return nil
+}
+
+// applyCriticalAttributes escalates the impact of resources whose changed
+// attributes match a rule and records why. Impact is never lowered.
+func applyCriticalAttributes(analyzed *AnalyzedPlan, rules []criticalAttributeRule) {
+	for i := range analyzed.Modules {
+		resources := analyzed.Modules[i].Resources
+		for j := range resources {
+			r := &resources[j]
+			if r.Action == "no-op" {
+				continue
+			}
+			changed := map[string]bool{}
+			for _, c := range r.Changes {
+				if !c.Cosmetic {
+					changed[c.Field] = true
+				}
+			}
+			for _, rule := range rules {
+				if ok, _ := path.Match(rule.Type, r.Type); !ok {
+					continue
+				}
+				var hits []string
+				for _, attr := range rule.Attributes {
+					if changed[attr] {
+						hits = append(hits, attr)
+					}
+				}
+				if len(hits) == 0 || impactRank[rule.Impact] <= impactRank[r.Impact] {
+					continue
+				}
+				sort.Strings(hits)
+				r.Impact = rule.Impact
+				r.ImpactReason = "changes " + strings.Join(hits, ", ")
+			}
+		}
+	}
+}
+
+// HighRiskResources lists the changed resources with High impact, for the
+// report's high-risk section.
+func (a AnalyzedPlan) HighRiskResources() []ResourceAnalysis {
+	var result []ResourceAnalysis
+	for _, m := range a.Modules {
+		for _, r := range m.Resources {
+			if r.Action != "no-op" && r.Impact == "High" {
+				result = append(result, r)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyCriticalAttributes(t *testing.T) {
+	rules := []criticalAttributeRule{
+		{Type: "aws_db_instance", Attributes: []string{"engine_version", "allocated_storage"}, Impact: "High"},
+		{Type: "aws_*", Attributes: []string{"tags"}, Impact: "Low"},
+	}
+	if err := validateCriticalAttributes(rules); err != nil {
+		t.Fatal(err)
+	}
+
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{
+		{Address: "aws_db_instance.main", Type: "aws_db_instance", Action: "update", Impact: "Medium",
+			Changes: []ChangeDetail{{Field: "engine_version"}, {Field: "allocated_storage"}, {Field: "tags"}}},
+		{Address: "aws_db_instance.replica", Type: "aws_db_instance", Action: "update", Impact: "Medium",
+			Changes: []ChangeDetail{{Field: "tags"}}},
+		{Address: "aws_db_instance.idle", Type: "aws_db_instance", Action: "no-op", Impact: "Low",
+			Changes: []ChangeDetail{{Field: "engine_version"}}},
+	}}}}
+	applyCriticalAttributes(&analyzed, rules)
+
+	resources := analyzed.Modules[0].Resources
+	if r := resources[0]; r.Impact != "High" || r.ImpactReason != "changes allocated_storage, engine_version" {
+		t.Errorf("main: impact %q, reason %q", r.Impact, r.ImpactReason)
+	}
+	// A rule never lowers the impact
+	if r := resources[1]; r.Impact != "Medium" || r.ImpactReason != "" {
+		t.Errorf("replica: impact %q, reason %q", r.Impact, r.ImpactReason)
+	}
+	if r := resources[2]; r.Impact != "Low" {
+		t.Errorf("no-op resource escalated to %q", r.Impact)
+	}
+
+	high := analyzed.HighRiskResources()
+	if len(high) != 1 || high[0].Address != "aws_db_instance.main" {
+		t.Fatalf("HighRiskResources = %+v", high)
+	}
+	html := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+	if !strings.Contains(html, "High-risk changes") || !strings.Contains(html, "changes allocated_storage, engine_version") {
+		t.Error("report does not show the high-risk section")
+	}
+}
+
+func TestValidateCriticalAttributes(t *testing.T) {
+	bad := [][]criticalAttributeRule{
+		{{Type: "aws_db_instance", Attributes: []string{"engine_version"}, Impact: "Severe"}},
+		{{Type: "aws_db_instance", Impact: "High"}},
+		{{Type: "aws_[", Attributes: []string{"x"}, Impact: "High"}},
+	}
+	for _, rules := range bad {
+		if err := validateCriticalAttributes(rules); err == nil {
+			t.Errorf("validateCriticalAttributes(%+v) succeeded, want error", rules)
+		}
+	}
+}
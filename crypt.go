@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Files written by tfviz that may contain plan data (cached plans, saved
+// analyses) are encrypted with AES-256-GCM when TFVIZ_PASSPHRASE is set.
+// Layout: encryptedMagic | salt (16) | nonce (12) | ciphertext+tag.
+const (
+	encryptedMagic   = "TFVIZENC1\n"
+	passphraseEnv    = "TFVIZ_PASSPHRASE"
+	kdfIterations    = 600000
+	encryptionSaltSz = 16
+)
+
+var errNoPassphrase = errors.New("data is encrypted; set " + passphraseEnv + " to decrypt it")
+
+func encryptionPassphrase() string {
+	return os.Getenv(passphraseEnv)
+}
+
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptedMagic))
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, salt, kdfIterations, 32)
+}
+
+// sealData encrypts plain with a key derived from passphrase. An empty
+// passphrase leaves the data unencrypted.
+func sealData(plain []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return plain, nil
+	}
+	salt := make([]byte, encryptionSaltSz)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(encryptedMagic)+len(salt)+len(nonce)+len(plain)+gcm.Overhead())
+	out = append(out, encryptedMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	// The header is authenticated so it cannot be swapped between files.
+	return gcm.Seal(out, nonce, plain, out[:len(encryptedMagic)+len(salt)+len(nonce)]), nil
+}
+
+// openData decrypts data written by sealData. Unencrypted data is returned
+// unchanged, so callers can read files written before encryption was enabled.
+func openData(data []byte, passphrase string) ([]byte, error) {
+	if !isEncrypted(data) {
+		return data, nil
+	}
+	if passphrase == "" {
+		return nil, errNoPassphrase
+	}
+	header := len(encryptedMagic) + encryptionSaltSz
+	if len(data) < header {
+		return nil, fmt.Errorf("encrypted data is truncated")
+	}
+	salt := data[len(encryptedMagic):header]
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < header+gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted data is truncated")
+	}
+	nonce := data[header : header+gcm.NonceSize()]
+	plain, err := gcm.Open(nil, nonce, data[header+gcm.NonceSize():], data[:header+gcm.NonceSize()])
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt data: wrong passphrase or corrupted file")
+	}
+	return plain, nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
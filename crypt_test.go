@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSealOpenData(t *testing.T) {
+	plain := []byte(`{"resource_changes":[{"change":{"after":{"password":"hunter2"}}}]}`)
+
+	sealed, err := sealData(plain, "correct horse")
+	if err != nil {
+		t.Fatalf("sealData: %v", err)
+	}
+	if !isEncrypted(sealed) || bytes.Contains(sealed, []byte("hunter2")) {
+		t.Fatal("sealed data is not encrypted")
+	}
+
+	got, err := openData(sealed, "correct horse")
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("openData = %q, %v", got, err)
+	}
+	if _, err := openData(sealed, "wrong"); err == nil {
+		t.Error("expected error with wrong passphrase")
+	}
+	if _, err := openData(sealed, ""); !errors.Is(err, errNoPassphrase) {
+		t.Errorf("missing passphrase: got %v, want errNoPassphrase", err)
+	}
+
+	// Unencrypted data passes through, and no passphrase means no encryption
+	if got, err := openData(plain, "correct horse"); err != nil || !bytes.Equal(got, plain) {
+		t.Error("plain data should be returned unchanged")
+	}
+	if got, _ := sealData(plain, ""); !bytes.Equal(got, plain) {
+		t.Error("empty passphrase should leave data unencrypted")
+	}
+
+	// Tampering with the header is detected
+	sealed[len(encryptedMagic)] ^= 0xff
+	if _, err := openData(sealed, "correct horse"); err == nil {
+		t.Error("expected error for tampered data")
+	}
+}
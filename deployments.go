@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ImageChange is a container image, or a Helm chart version, that a plan
+// moves to another tag. Image changes are often the only part of a plan
+// that matters to the people deploying, so they are listed on their own.
+type ImageChange struct {
+	Address   string `json:"address"`
+	Container string `json:"container"`
+	Before    string `json:"before,omitempty"`
+	After     string `json:"after,omitempty"`
+}
+
+// splitImage splits an image reference into repository and tag or digest.
+func splitImage(ref string) (repository, tag string) {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// SameImage reports whether only the tag changes, in which case Image is
+// the repository and From and To are the tags. Otherwise they are the full
+// references.
+func (c ImageChange) SameImage() bool {
+	before, _ := splitImage(c.Before)
+	after, _ := splitImage(c.After)
+	return c.Before != "" && c.After != "" && before == after
+}
+
+func (c ImageChange) Image() string {
+	repository, _ := splitImage(c.After)
+	return repository
+}
+
+func (c ImageChange) From() string {
+	if !c.SameImage() {
+		return c.Before
+	}
+	_, tag := splitImage(c.Before)
+	return tag
+}
+
+func (c ImageChange) To() string {
+	if !c.SameImage() {
+		return c.After
+	}
+	_, tag := splitImage(c.After)
+	return tag
+}
+
+// Deployments lists the image tag changes of Kubernetes workloads and Helm
+// releases, and the chart version changes of Helm releases.
+func (a AnalyzedPlan) Deployments() []ImageChange {
+	var changes []ImageChange
+	for _, m := range a.Modules {
+		for _, r := range m.Resources {
+			if r.Action == "no-op" || r.Action == "read" {
+				continue
+			}
+			var before, after map[string]string
+			switch {
+			case r.Type == "helm_release":
+				before, after = helmImages(r.Before), helmImages(r.After)
+			case strings.HasPrefix(r.Type, "kubernetes_"):
+				before, after = map[string]string{}, map[string]string{}
+				containerImages("", r.Before, before)
+				containerImages("", r.After, after)
+			default:
+				continue
+			}
+			for _, name := range sortedKeys(mergeKeys(before, after)) {
+				if before[name] != after[name] {
+					changes = append(changes, ImageChange{Address: r.Address, Container: name, Before: before[name], After: after[name]})
+				}
+			}
+		}
+	}
+	return changes
+}
+
+func mergeKeys(a, b map[string]string) map[string]bool {
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	return keys
+}
+
+// containerImages finds the containers of a Kubernetes resource, blocks
+// with a name and an image wherever they are nested, and records their
+// images by container name.
+func containerImages(path string, v interface{}, images map[string]string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if image, ok := v["image"].(string); ok && image != "" {
+			name, _ := v["name"].(string)
+			if name == "" {
+				name = path
+			}
+			images[name] = image
+			return
+		}
+		for _, k := range sortedKeys(v) {
+			containerImages(strings.TrimPrefix(path+"."+k, "."), v[k], images)
+		}
+	case []interface{}:
+		for i, item := range v {
+			containerImages(fmt.Sprintf("%s[%d]", path, i), item, images)
+		}
+	}
+}
+
+// helmImages finds the images set by a Helm release's values and set
+// blocks: values such as image: repo:tag, or the common image.repository
+// and image.tag pair. The chart version is included as "chart <name>".
+func helmImages(values map[string]interface{}) map[string]string {
+	images := map[string]string{}
+	if values == nil {
+		return images
+	}
+	flat := map[string]string{}
+	if list, ok := values["values"].([]interface{}); ok {
+		for _, doc := range list {
+			if s, ok := doc.(string); ok {
+				flattenYAML(s, flat)
+			}
+		}
+	}
+	if sets, ok := values["set"].([]interface{}); ok {
+		for _, set := range sets {
+			if s, ok := set.(map[string]interface{}); ok {
+				name, _ := s["name"].(string)
+				flat[name] = fmt.Sprint(s["value"])
+			}
+		}
+	}
+
+	for key, value := range flat {
+		switch {
+		case key == "image" || strings.HasSuffix(key, ".image"):
+			if strings.ContainsAny(value, ":/@") {
+				images[key] = value
+			}
+		case key == "image.repository" || strings.HasSuffix(key, ".image.repository"):
+			prefix := strings.TrimSuffix(key, ".repository")
+			images[prefix] = joinImage(value, flat[prefix+".tag"], flat[prefix+".digest"])
+		case key == "image.tag" || strings.HasSuffix(key, ".image.tag"):
+			prefix := strings.TrimSuffix(key, ".tag")
+			if _, ok := flat[prefix+".repository"]; !ok {
+				images[prefix] = ":" + value
+			}
+		}
+	}
+	if chart, ok := values["chart"].(string); ok {
+		if version, ok := values["version"].(string); ok && version != "" {
+			images["chart "+chart] = version
+		}
+	}
+	return images
+}
+
+func joinImage(repository, tag, digest string) string {
+	switch {
+	case digest != "":
+		return repository + "@" + digest
+	case tag != "":
+		return repository + ":" + tag
+	}
+	return repository
+}
+
+var yamlLine = regexp.MustCompile(`^(\s*)(- )?([\w.-]+):\s*(.*?)\s*$`)
+
+// flattenYAML records the scalar values of a YAML document by their dotted
+// key path, e.g. image.tag. It only understands block mappings, which is
+// how image settings are written in Helm values; items of lists are
+// recorded under the list's key.
+func flattenYAML(doc string, flat map[string]string) {
+	type level struct {
+		indent int
+		key    string
+	}
+	var stack []level
+	for _, line := range strings.Split(doc, "\n") {
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		m := yamlLine.FindStringSubmatch(line)
+		if m == nil || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		indent := len(m[1]) + len(m[2])
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		var path []string
+		for _, l := range stack {
+			path = append(path, l.key)
+		}
+		path = append(path, m[3])
+		value := strings.Trim(m[4], `"'`)
+		if value == "" || value == "|" || value == ">" {
+			stack = append(stack, level{indent, m[3]})
+			continue
+		}
+		flat[strings.Join(path, ".")] = value
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeployments(t *testing.T) {
+	pod := func(image, sidecar string) map[string]interface{} {
+		return map[string]interface{}{"spec": []interface{}{map[string]interface{}{
+			"template": []interface{}{map[string]interface{}{"spec": []interface{}{map[string]interface{}{
+				"container": []interface{}{
+					map[string]interface{}{"name": "api", "image": image},
+					map[string]interface{}{"name": "envoy", "image": sidecar},
+				},
+			}}}},
+		}}}
+	}
+	values := func(tag, version string) map[string]interface{} {
+		return map[string]interface{}{
+			"chart":   "web",
+			"version": version,
+			"values": []interface{}{`
+replicaCount: 2
+image:
+  repository: ghcr.io/acme/web  # the app
+  tag: "` + tag + `"
+worker:
+  image: ghcr.io/acme/worker:1.0
+`},
+			"set": []interface{}{map[string]interface{}{"name": "worker.image", "value": "ghcr.io/acme/worker:" + tag}},
+		}
+	}
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{
+		{Address: "kubernetes_deployment_v1.api", Type: "kubernetes_deployment_v1", Action: "update",
+			Before: pod("ghcr.io/acme/api:1.4.0", "envoyproxy/envoy:v1.29"), After: pod("ghcr.io/acme/api:1.5.0", "envoyproxy/envoy:v1.29")},
+		{Address: "helm_release.web", Type: "helm_release", Action: "update", Before: values("2.0", "0.3.0"), After: values("2.1", "0.4.0")},
+		{Address: "kubernetes_deployment_v1.static", Type: "kubernetes_deployment_v1", Action: "no-op",
+			Before: pod("nginx:1", "x:1"), After: pod("nginx:2", "x:1")},
+	}}}}
+
+	want := []ImageChange{
+		{Address: "kubernetes_deployment_v1.api", Container: "api", Before: "ghcr.io/acme/api:1.4.0", After: "ghcr.io/acme/api:1.5.0"},
+		{Address: "helm_release.web", Container: "chart web", Before: "0.3.0", After: "0.4.0"},
+		{Address: "helm_release.web", Container: "image", Before: "ghcr.io/acme/web:2.0", After: "ghcr.io/acme/web:2.1"},
+		{Address: "helm_release.web", Container: "worker.image", Before: "ghcr.io/acme/worker:2.0", After: "ghcr.io/acme/worker:2.1"},
+	}
+	got := analyzed.Deployments()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Deployments() =\n%+v\nwant\n%+v", got, want)
+	}
+	if c := got[0]; !c.SameImage() || c.Image() != "ghcr.io/acme/api" || c.From() != "1.4.0" || c.To() != "1.5.0" {
+		t.Errorf("unexpected tag change %q %q → %q", c.Image(), c.From(), c.To())
+	}
+}
+
+func TestSplitImage(t *testing.T) {
+	for ref, want := range map[string][2]string{
+		"nginx":                          {"nginx", ""},
+		"localhost:5000/app:1.2":         {"localhost:5000/app", "1.2"},
+		"localhost:5000/app":             {"localhost:5000/app", ""},
+		"ghcr.io/acme/app@sha256:abc123": {"ghcr.io/acme/app", "sha256:abc123"},
+	} {
+		if repository, tag := splitImage(ref); repository != want[0] || tag != want[1] {
+			t.Errorf("splitImage(%q) = %q, %q, want %q, %q", ref, repository, tag, want[0], want[1])
+		}
+	}
+}
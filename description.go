@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"text/template"
+)
+
+// descriptionTemplate overrides generateDescription for resources matching
+// Action and Type (a glob pattern); empty fields match everything. Template
+// is a Go text/template executed with descriptionData, e.g.
+// "Resize {{.Name}} to {{.After.instance_type}}".
+type descriptionTemplate struct {
+	Action   string `json:"action,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Template string `json:"template"`
+
+	tmpl *template.Template
+}
+
+// descriptionData is what description templates can refer to: every field of
+// the analyzed resource (Address, Type, Name, Action, Replace, Changes,
+// After, ...) plus the address of its module.
+type descriptionData struct {
+	ResourceAnalysis
+	Module string
+}
+
+func (d *descriptionTemplate) parse() error {
+	if d.Template == "" {
+		return fmt.Errorf("template is required")
+	}
+	if _, err := path.Match(d.Type, ""); err != nil {
+		return fmt.Errorf("invalid type pattern %q", d.Type)
+	}
+	tmpl, err := template.New("description").Option("missingkey=zero").Parse(d.Template)
+	if err != nil {
+		return err
+	}
+	d.tmpl = tmpl
+	return nil
+}
+
+func (d descriptionTemplate) matches(r ResourceAnalysis) bool {
+	if d.Action != "" && d.Action != r.Action {
+		return false
+	}
+	if d.Type == "" {
+		return true
+	}
+	ok, _ := path.Match(d.Type, r.Type)
+	return ok
+}
+
+// applyDescriptions replaces each resource's description with the first
+// matching template. Resources whose template fails to execute keep the
+// default description; the first such error is returned.
+func applyDescriptions(analyzed *AnalyzedPlan, templates []descriptionTemplate) error {
+	var firstErr error
+	for i := range analyzed.Modules {
+		m := &analyzed.Modules[i]
+		for j := range m.Resources {
+			r := &m.Resources[j]
+			for _, d := range templates {
+				if !d.matches(*r) {
+					continue
+				}
+				var buf bytes.Buffer
+				if err := d.tmpl.Execute(&buf, descriptionData{ResourceAnalysis: *r, Module: m.Address}); err != nil {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("description template for %s: %v", r.Address, err)
+					}
+				} else {
+					r.Description = buf.String()
+				}
+				break
+			}
+		}
+	}
+	return firstErr
+}
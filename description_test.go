@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestApplyDescriptions(t *testing.T) {
+	templates := []descriptionTemplate{
+		{Action: "update", Type: "aws_instance", Template: "Resize {{.Name}} to {{.After.instance_type}} in {{.Module}}"},
+		{Type: "aws_*", Template: "{{.Action}} {{.Address}}{{if .Replace}} (replaced){{end}}"},
+		{Type: "google_*", Template: "{{.After.missing.field}}"},
+	}
+	for i := range templates {
+		if err := templates[i].parse(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{
+		Address: "module.app",
+		Resources: []ResourceAnalysis{
+			{Address: "module.app.aws_instance.web", Type: "aws_instance", Name: "web", Action: "update",
+				After: map[string]interface{}{"instance_type": "t3.large"}, Description: "default"},
+			{Address: "module.app.aws_instance.db", Type: "aws_instance", Name: "db", Action: "delete", Replace: true, Description: "default"},
+			{Address: "module.app.azurerm_vm.x", Type: "azurerm_vm", Name: "x", Action: "create", Description: "default"},
+			{Address: "module.app.google_thing.y", Type: "google_thing", Name: "y", Action: "create",
+				After: map[string]interface{}{"missing": "not a map"}, Description: "default"},
+		},
+	}}}
+	err := applyDescriptions(&analyzed, templates)
+	if err == nil {
+		t.Error("expected error from failing template")
+	}
+
+	want := []string{
+		"Resize web to t3.large in module.app",
+		"delete module.app.aws_instance.db (replaced)",
+		"default",
+		"default",
+	}
+	for i, r := range analyzed.Modules[0].Resources {
+		if r.Description != want[i] {
+			t.Errorf("%s: description = %q, want %q", r.Address, r.Description, want[i])
+		}
+	}
+}
+
+func TestDescriptionTemplateParse(t *testing.T) {
+	bad := []descriptionTemplate{
+		{Type: "aws_*"},
+		{Template: "{{.Name"},
+		{Type: "aws_[", Template: "x"},
+	}
+	for _, d := range bad {
+		if err := d.parse(); err == nil {
+			t.Errorf("parse(%+v) succeeded, want error", d)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DNSChange is a DNS record a plan creates, changes or deletes. DNS edits
+// reach every client of a name and are cached for the record's TTL, so
+// they are collected in one table rather than left among the other diffs.
+type DNSChange struct {
+	Address   string `json:"address"`
+	Action    string `json:"action"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Before    string `json:"before,omitempty"`
+	After     string `json:"after,omitempty"`
+	TTLBefore string `json:"ttl_before,omitempty"`
+	TTLAfter  string `json:"ttl_after,omitempty"`
+}
+
+// TTLChanged reports whether the TTL changes in an update.
+func (c DNSChange) TTLChanged() bool {
+	return c.TTLBefore != "" && c.TTLAfter != "" && c.TTLBefore != c.TTLAfter
+}
+
+// dnsRecordTypes maps DNS record resources to the attributes holding their
+// values.
+var dnsRecordTypes = map[string][]string{
+	"aws_route53_record":       {"records", "alias"},
+	"cloudflare_record":        {"content", "value", "data"},
+	"cloudflare_dns_record":    {"content", "data"},
+	"google_dns_record_set":    {"rrdatas", "routing_policy"},
+	"azurerm_dns_a_record":     {"records", "target_resource_id"},
+	"azurerm_dns_cname_record": {"record", "target_resource_id"},
+}
+
+// DNSChanges lists the DNS record changes of the plan, ordered by name.
+func (a AnalyzedPlan) DNSChanges() []DNSChange {
+	var changes []DNSChange
+	for _, m := range a.Modules {
+		for _, r := range m.Resources {
+			attrs, ok := dnsRecordTypes[r.Type]
+			if !ok || r.Action == "no-op" || r.Action == "read" {
+				continue
+			}
+			values := r.After
+			if values == nil {
+				values = r.Before
+			}
+			c := DNSChange{
+				Address:   r.Address,
+				Action:    r.Action,
+				Name:      dnsName(values),
+				Type:      dnsType(r.Type, values),
+				Before:    dnsValues(r.Before, attrs),
+				After:     dnsValues(r.After, attrs),
+				TTLBefore: dnsTTL(r.Before),
+				TTLAfter:  dnsTTL(r.After),
+			}
+			if r.Replace {
+				c.Action = "replace"
+			}
+			changes = append(changes, c)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+func dnsName(values map[string]interface{}) string {
+	name := ""
+	for _, key := range []string{"fqdn", "hostname", "name"} {
+		if s, ok := values[key].(string); ok && s != "" {
+			name = s
+			break
+		}
+	}
+	if zone, ok := values["zone_name"].(string); ok && zone != "" && !strings.Contains(name, ".") {
+		name += "." + zone
+	}
+	if id, ok := values["set_identifier"].(string); ok && id != "" {
+		name += " (" + id + ")"
+	}
+	return name
+}
+
+func dnsType(resourceType string, values map[string]interface{}) string {
+	if t, ok := values["type"].(string); ok {
+		return t
+	}
+	// azurerm has a resource per record type, e.g. azurerm_dns_a_record.
+	t := strings.TrimSuffix(strings.TrimPrefix(resourceType, "azurerm_dns_"), "_record")
+	return strings.ToUpper(t)
+}
+
+// dnsValues renders the record's values, e.g. "10.0.0.1, 10.0.0.2" or
+// "alias my-lb-123.elb.amazonaws.com".
+func dnsValues(values map[string]interface{}, attrs []string) string {
+	if values == nil {
+		return ""
+	}
+	var parts []string
+	for _, attr := range attrs {
+		switch v := values[attr].(type) {
+		case string:
+			if v != "" {
+				parts = append(parts, v)
+			}
+		case []interface{}:
+			for _, item := range v {
+				if block, ok := item.(map[string]interface{}); ok {
+					if name, ok := block["name"].(string); ok {
+						parts = append(parts, attr+" "+name)
+					} else {
+						parts = append(parts, attr+" "+formatValue(block))
+					}
+					continue
+				}
+				parts = append(parts, fmt.Sprint(item))
+			}
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func dnsTTL(values map[string]interface{}) string {
+	if ttl, ok := values["ttl"].(float64); ok {
+		return fmt.Sprintf("%gs", ttl)
+	}
+	return ""
+}
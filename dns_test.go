@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDNSChanges(t *testing.T) {
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{
+		{Address: "aws_route53_record.api", Type: "aws_route53_record", Action: "update",
+			Before: map[string]interface{}{"name": "api.example.com", "type": "A", "ttl": 300.0, "records": []interface{}{"10.0.0.1"}, "set_identifier": "blue"},
+			After:  map[string]interface{}{"name": "api.example.com", "type": "A", "ttl": 60.0, "records": []interface{}{"10.0.0.2", "10.0.0.3"}, "set_identifier": "blue"}},
+		{Address: "aws_route53_record.www", Type: "aws_route53_record", Action: "create",
+			After: map[string]interface{}{"name": "www.example.com", "type": "A", "alias": []interface{}{map[string]interface{}{"name": "lb-1.elb.amazonaws.com", "zone_id": "Z1"}}}},
+		{Address: "cloudflare_record.mx", Type: "cloudflare_record", Action: "delete",
+			Before: map[string]interface{}{"name": "mail", "zone_name": "example.org", "type": "MX", "ttl": 3600.0, "content": "mx.example.net"}},
+		{Address: "google_dns_record_set.txt", Type: "google_dns_record_set", Action: "no-op",
+			Before: map[string]interface{}{"name": "example.com.", "type": "TXT"}},
+		{Address: "aws_instance.web", Type: "aws_instance", Action: "update"},
+	}}}}
+
+	want := []DNSChange{
+		{Address: "aws_route53_record.api", Action: "update", Name: "api.example.com (blue)", Type: "A", Before: "10.0.0.1", After: "10.0.0.2, 10.0.0.3", TTLBefore: "300s", TTLAfter: "60s"},
+		{Address: "cloudflare_record.mx", Action: "delete", Name: "mail.example.org", Type: "MX", Before: "mx.example.net", TTLBefore: "3600s"},
+		{Address: "aws_route53_record.www", Action: "create", Name: "www.example.com", Type: "A", After: "alias lb-1.elb.amazonaws.com"},
+	}
+	got := analyzed.DNSChanges()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("DNSChanges() =\n%+v\nwant\n%+v", got, want)
+	}
+	if !got[0].TTLChanged() || got[1].TTLChanged() {
+		t.Error("only the first record changes its TTL")
+	}
+
+	html := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+	if !strings.Contains(html, "<code>10.0.0.1</code> → <code>10.0.0.2, 10.0.0.3</code></td><td>300s → 60s</td>") {
+		t.Error("report does not show the record's old and new values and TTL")
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DriftEntry is a resource that changed outside Terraform since the last
+// apply, as found by the refresh of terraform plan.
+type DriftEntry struct {
+	Address    string      `json:"address"`
+	Action     string      `json:"action"`
+	Attributes []string    `json:"attributes,omitempty"`
+	Actor      *DriftActor `json:"actor,omitempty"`
+}
+
+// DriftActor is tfviz's guess at who or what made an out-of-band change,
+// with the evidence for it. It is a hint for the reviewer, not an audit
+// record: only the cloud provider's audit log can tell for sure.
+type DriftActor struct {
+	Name     string `json:"name"`
+	Evidence string `json:"evidence"`
+}
+
+// modifierKeys are attributes, tags and labels that record who last changed
+// a resource, normalized to lower case without separators.
+var modifierKeys = map[string]bool{
+	"lastmodifiedby": true, "modifiedby": true, "updatedby": true, "lastupdatedby": true,
+	"changedby": true, "lastchangedby": true, "lastmodifier": true, "lastmodifieduser": true,
+}
+
+// identityKeys hold CloudTrail-style records of the caller, e.g.
+// user_identity = {arn = "..."}.
+var identityKeys = map[string]bool{"useridentity": true, "lastmodifiedidentity": true}
+
+// autoscaledAttributes are attributes that services other than Terraform
+// adjust on their own. Drift limited to them is almost always theirs.
+var autoscaledAttributes = []struct {
+	resourceType string
+	attributes   []string
+	actor        string
+}{
+	{"aws_autoscaling_group", []string{"desired_capacity"}, "an Auto Scaling policy or scheduled action"},
+	{"aws_ecs_service", []string{"desired_count"}, "Application Auto Scaling"},
+	{"aws_dynamodb_table", []string{"read_capacity", "write_capacity"}, "Application Auto Scaling"},
+	{"aws_eks_node_group", []string{"scaling_config"}, "the Kubernetes Cluster Autoscaler"},
+	{"google_container_node_pool", []string{"node_count"}, "the GKE cluster autoscaler"},
+	{"azurerm_kubernetes_cluster_node_pool", []string{"node_count"}, "the AKS cluster autoscaler"},
+}
+
+// toolTags are tags and labels that services put on the resources they
+// manage, by key or key prefix ending in "/" or "-".
+var toolTags = []struct {
+	key   string
+	actor string
+}{
+	{"aws:cloudformation:stack-name", "CloudFormation stack %s"},
+	{"aws:autoscaling:groupName", "Auto Scaling group %s"},
+	{"elasticbeanstalk:environment-name", "Elastic Beanstalk environment %s"},
+	{"karpenter.sh/", "Karpenter"},
+	{"kubernetes.io/cluster/", "a Kubernetes controller"},
+	{"eks:cluster-name", "EKS cluster %s"},
+	{"goog-", "a Google Cloud service"},
+}
+
+func normalizeKey(key string) string {
+	return strings.NewReplacer("_", "", "-", "", ":", "", ".", "", " ", "").Replace(strings.ToLower(key))
+}
+
+func findDrift(plan TerraformPlan) []DriftEntry {
+	var entries []DriftEntry
+	for _, rc := range plan.ResourceDrift {
+		if rc.Mode != "managed" || len(rc.Change.Actions) == 0 {
+			continue
+		}
+		entry := DriftEntry{Address: rc.Address, Action: strings.Join(rc.Change.Actions, "/")}
+		if rc.Change.After != nil {
+			for _, c := range analyzeChanges(rc.Change.Before, rc.Change.After) {
+				entry.Attributes = append(entry.Attributes, c.Field)
+			}
+		}
+		sort.Strings(entry.Attributes)
+		entry.Actor = driftActor(rc, entry.Attributes)
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Address < entries[j].Address })
+	return entries
+}
+
+// driftActor guesses who changed a drifted resource, from the strongest
+// evidence to the weakest: attributes or tags naming the last modifier,
+// drift limited to attributes a service scales on its own, and tags of
+// services that manage the resource.
+func driftActor(rc ResourceChange, changed []string) *DriftActor {
+	values := rc.Change.After
+	if values == nil {
+		values = rc.Change.Before
+	}
+	tags := map[string]interface{}{}
+	for _, key := range []string{"tags", "tags_all", "labels"} {
+		if m, ok := values[key].(map[string]interface{}); ok {
+			for k, v := range m {
+				tags[k] = v
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(values) {
+		if name := modifierValue(key, values[key]); name != "" {
+			return &DriftActor{Name: name, Evidence: fmt.Sprintf("attribute %s", key)}
+		}
+	}
+	for _, key := range sortedKeys(tags) {
+		if s, ok := tags[key].(string); ok && s != "" && modifierKeys[normalizeKey(key)] {
+			return &DriftActor{Name: s, Evidence: fmt.Sprintf("tag %s", key)}
+		}
+	}
+
+	for _, a := range autoscaledAttributes {
+		if a.resourceType == rc.Type && len(changed) > 0 && subset(changed, a.attributes) {
+			return &DriftActor{Name: a.actor, Evidence: "only " + strings.Join(changed, ", ") + " changed"}
+		}
+	}
+
+	for _, t := range toolTags {
+		for _, key := range sortedKeys(tags) {
+			value, _ := tags[key].(string)
+			if key == t.key || (strings.HasSuffix(t.key, "/") || strings.HasSuffix(t.key, "-")) && strings.HasPrefix(key, t.key) {
+				name := t.actor
+				if strings.Contains(name, "%s") {
+					name = fmt.Sprintf(name, value)
+				}
+				return &DriftActor{Name: name, Evidence: fmt.Sprintf("tag %s", key)}
+			}
+		}
+	}
+	for _, key := range sortedKeys(tags) {
+		if s, ok := tags[key].(string); ok && s != "" && normalizeKey(key) == "managedby" && !strings.EqualFold(s, "terraform") {
+			return &DriftActor{Name: s, Evidence: fmt.Sprintf("tag %s", key)}
+		}
+	}
+	return nil
+}
+
+// modifierValue returns the modifier an attribute records, if it is one of
+// modifierKeys or a CloudTrail-style identity.
+func modifierValue(key string, v interface{}) string {
+	norm := normalizeKey(key)
+	if s, ok := v.(string); ok && modifierKeys[norm] {
+		return s
+	}
+	if m, ok := v.(map[string]interface{}); ok && identityKeys[norm] {
+		for _, field := range []string{"arn", "user_name", "username", "principal_id", "principalId"} {
+			if s, ok := m[field].(string); ok && s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func subset(items, of []string) bool {
+	for _, item := range items {
+		if !slices.Contains(of, item) {
+			return false
+		}
+	}
+	return true
+}
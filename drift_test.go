@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindDrift(t *testing.T) {
+	drift := func(address, resourceType string, actions []string, before, after map[string]interface{}) ResourceChange {
+		return ResourceChange{Address: address, Mode: "managed", Type: resourceType, Change: Change{Actions: actions, Before: before, After: after}}
+	}
+	plan := TerraformPlan{ResourceDrift: []ResourceChange{
+		drift("aws_s3_bucket.logs", "aws_s3_bucket", []string{"update"},
+			map[string]interface{}{"versioning": false, "tags": map[string]interface{}{"team": "data"}},
+			map[string]interface{}{"versioning": true, "tags": map[string]interface{}{"team": "data", "LastModifiedBy": "alice@example.com"}}),
+		drift("aws_ecs_service.api", "aws_ecs_service", []string{"update"},
+			map[string]interface{}{"desired_count": 2.0, "tags": map[string]interface{}{"aws:cloudformation:stack-name": "legacy"}},
+			map[string]interface{}{"desired_count": 6.0, "tags": map[string]interface{}{"aws:cloudformation:stack-name": "legacy"}}),
+		drift("aws_instance.worker", "aws_instance", []string{"update"},
+			map[string]interface{}{"instance_type": "t3.small", "tags": map[string]interface{}{"aws:autoscaling:groupName": "workers"}},
+			map[string]interface{}{"instance_type": "t3.large", "tags": map[string]interface{}{"aws:autoscaling:groupName": "workers"}}),
+		drift("aws_iam_role.ci", "aws_iam_role", []string{"update"},
+			map[string]interface{}{"max_session_duration": 3600.0},
+			map[string]interface{}{"max_session_duration": 7200.0, "user_identity": map[string]interface{}{"arn": "arn:aws:iam::1:user/bob"}}),
+		drift("aws_sqs_queue.jobs", "aws_sqs_queue", []string{"delete"}, map[string]interface{}{"name": "jobs"}, nil),
+		{Address: "data.aws_ami.base", Mode: "data", Change: Change{Actions: []string{"update"}}},
+	}}
+
+	got := findDrift(plan)
+	want := []DriftEntry{
+		{Address: "aws_ecs_service.api", Action: "update", Attributes: []string{"desired_count"},
+			Actor: &DriftActor{Name: "Application Auto Scaling", Evidence: "only desired_count changed"}},
+		{Address: "aws_iam_role.ci", Action: "update", Attributes: []string{"max_session_duration", "user_identity"},
+			Actor: &DriftActor{Name: "arn:aws:iam::1:user/bob", Evidence: "attribute user_identity"}},
+		{Address: "aws_instance.worker", Action: "update", Attributes: []string{"instance_type"},
+			Actor: &DriftActor{Name: "Auto Scaling group workers", Evidence: "tag aws:autoscaling:groupName"}},
+		{Address: "aws_s3_bucket.logs", Action: "update", Attributes: []string{"tags", "versioning"},
+			Actor: &DriftActor{Name: "alice@example.com", Evidence: "tag LastModifiedBy"}},
+		{Address: "aws_sqs_queue.jobs", Action: "delete"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findDrift() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestDriftActor_ManagedBy(t *testing.T) {
+	rc := ResourceChange{Type: "aws_vpc", Change: Change{After: map[string]interface{}{"tags": map[string]interface{}{"ManagedBy": "pulumi"}}}}
+	if got := driftActor(rc, []string{"cidr_block"}); got == nil || got.Name != "pulumi" {
+		t.Errorf("driftActor() = %+v, want pulumi", got)
+	}
+	rc.Change.After["tags"] = map[string]interface{}{"managed-by": "Terraform"}
+	if got := driftActor(rc, []string{"cidr_block"}); got != nil {
+		t.Errorf("expected no actor for resources tagged as managed by Terraform, got %+v", got)
+	}
+}
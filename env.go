@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envFlag is a flag that can also be set through a TFVIZ_* environment
+// variable, which is handy in CI where adding flags is awkward.
+type envFlag struct {
+	name    string
+	boolean bool
+}
+
+// optionEnvFlags are the flags parseOptions accepts, for the commands that
+// use it. They are read from TFVIZ_<FLAG>, e.g. TFVIZ_SHARE_TTL.
+var optionEnvFlags = []envFlag{
+	{"--graph", true},
+	{"--cache", true},
+	{"--check-updates", true},
+	{"--changed-only", true},
+	{"--include-unchanged-modules", true},
+	{"--summarize", true},
+	{"--servicenow", true},
+	{"--tls-self-signed", true},
+	{"--no-browser", true},
+	{"--trust-proxy-headers", true},
+	{"--audit-log", false},
+	{"--badge", false},
+	{"--baseline", false},
+	{"--browser", false},
+	{"--cache-ttl", false},
+	{"--config", false},
+	{"--graph-depth", false},
+	{"--graph-focus", false},
+	{"--listen", false},
+	{"--owners", false},
+	{"--profile", false},
+	{"--report-url", false},
+	{"--reviews", false},
+	{"--share-ttl", false},
+	{"--shutdown-after", false},
+	{"--sign-key", false},
+	{"--sort", false},
+	{"--tls-cert", false},
+	{"--tls-key", false},
+}
+
+var optionCommands = map[string]bool{
+	"plan": true, "build": true, "ci-compare": true, "validate": true, "test": true, "show": true,
+	"lint": true, "targets": true, "imports": true, "demo": true, "cdktf": true, "baseline": true, "export": true,
+}
+
+// commandEnvFlags are the flags of individual commands. They are read from
+// TFVIZ_<COMMAND>_<FLAG>, e.g. TFVIZ_CI_COMPARE_PREVIOUS, because the same
+// flag name can mean different things to different commands.
+var commandEnvFlags = map[string][]envFlag{
+	"audit":            {{"--log", false}, {"--format", false}},
+	"baseline":         {{"--plan", false}, {"--reason", false}},
+	"build":            {{"--envs", false}, {"--out", false}},
+	"plan":             {{"--workspaces", false}},
+	"cdktf":            {{"--app-dir", false}, {"--stacks", false}, {"--skip-synth", true}},
+	"check-idempotent": {{"--apply", true}},
+	"ci-compare":       {{"--previous", false}, {"--plan", false}, {"--save", false}, {"--fail-on-stateful", true}},
+	"export":           {{"--plan", false}, {"--addresses", false}, {"--out", false}},
+	"imports":          {{"--plan", false}, {"--format", false}},
+	"lint":             {{"--plan", false}},
+	"mcp":              {{"--config", false}, {"--profile", false}},
+	"targets":          {{"--plan", false}, {"--match", false}, {"--action", false}, {"--replan", true}},
+	"version":          {{"--json", true}},
+}
+
+// envVarName turns a flag into its variable name: --share-ttl with prefix
+// TFVIZ_ becomes TFVIZ_SHARE_TTL.
+func envVarName(prefix, flag string) string {
+	return prefix + strings.ToUpper(strings.ReplaceAll(strings.TrimPrefix(flag, "--"), "-", "_"))
+}
+
+// envArgs returns args preceded by the flags set in the environment for
+// command. Flags on the command line come later and so take precedence over
+// the environment, which in turn overrides the config file.
+func envArgs(command string, args []string) ([]string, error) {
+	var fromEnv []string
+	add := func(prefix string, flags []envFlag) error {
+		for _, f := range flags {
+			variable := envVarName(prefix, f.name)
+			value, ok := os.LookupEnv(variable)
+			if !ok || value == "" {
+				continue
+			}
+			if !f.boolean {
+				fromEnv = append(fromEnv, f.name+"="+value)
+				continue
+			}
+			on, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q (use true or false)", variable, value)
+			}
+			if on {
+				fromEnv = append(fromEnv, f.name)
+			}
+		}
+		return nil
+	}
+
+	// Flags with an optional value take true for their default.
+	optional := []string{"--install-terraform"}
+	if optionCommands[command] {
+		optional = append(optional, "--share")
+	}
+	for _, name := range optional {
+		value := os.Getenv(envVarName("TFVIZ_", name))
+		if value == "" {
+			continue
+		}
+		if on, err := strconv.ParseBool(value); err == nil {
+			if on {
+				fromEnv = append(fromEnv, name)
+			}
+		} else {
+			fromEnv = append(fromEnv, name+"="+value)
+		}
+	}
+	if err := add("TFVIZ_", []envFlag{{"--init", true}, {"--retries", false}, {"--retry-backoff", false}, {"--timeout", false}}); err != nil {
+		return nil, err
+	}
+	if optionCommands[command] {
+		if err := add("TFVIZ_", optionEnvFlags); err != nil {
+			return nil, err
+		}
+	}
+	if err := add(envVarName("TFVIZ_", command)+"_", commandEnvFlags[command]); err != nil {
+		return nil, err
+	}
+	return append(fromEnv, args...), nil
+}
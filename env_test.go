@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvArgs(t *testing.T) {
+	t.Setenv("TFVIZ_LISTEN", "0.0.0.0:9000")
+	t.Setenv("TFVIZ_GRAPH", "true")
+	t.Setenv("TFVIZ_CACHE", "false")
+	t.Setenv("TFVIZ_SORT", "impact")
+	t.Setenv("TFVIZ_IMPORTS_FORMAT", "json")
+	t.Setenv("TFVIZ_INSTALL_TERRAFORM", "tofu")
+
+	args, err := envArgs("plan", []string{"--listen", "127.0.0.1:8000", "--", "-var-file=prod.tfvars"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args[0] != "--install-terraform=tofu" {
+		t.Fatalf("args = %q, want --install-terraform=tofu first", args)
+	}
+	opts, rest, err := parseOptions(args[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.serve.listen != "127.0.0.1:8000" {
+		t.Errorf("listen = %q, want the flag to override TFVIZ_LISTEN", opts.serve.listen)
+	}
+	if !opts.showGraph || opts.cache || opts.sort != "impact" {
+		t.Errorf("environment not applied: %+v", opts)
+	}
+	if !reflect.DeepEqual(rest, []string{"-var-file=prod.tfvars"}) {
+		t.Errorf("rest = %q", rest)
+	}
+
+	args, err = envArgs("imports", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(args, " "), "--format=json") {
+		t.Errorf("TFVIZ_IMPORTS_FORMAT not applied: %q", args)
+	}
+	args, _ = envArgs("audit", nil)
+	if strings.Contains(strings.Join(args, " "), "--format") || strings.Contains(strings.Join(args, " "), "--sort") {
+		t.Errorf("flags of other commands applied to audit: %q", args)
+	}
+}
+
+func TestEnvArgs_InvalidBool(t *testing.T) {
+	t.Setenv("TFVIZ_GRAPH", "yes please")
+	if _, err := envArgs("plan", nil); err == nil || !strings.Contains(err.Error(), "TFVIZ_GRAPH") {
+		t.Errorf("got %v, want an error naming TFVIZ_GRAPH", err)
+	}
+}
+
+func TestOptionEnvFlags_KnownToParseOptions(t *testing.T) {
+	for _, f := range optionEnvFlags {
+		arg := f.name
+		if !f.boolean {
+			arg += "=x"
+		}
+		if _, _, err := parseOptions([]string{arg}); err != nil && strings.Contains(err.Error(), "unknown flag") {
+			t.Errorf("%s is not a parseOptions flag", f.name)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// exportedResource is the file tfviz export writes for each resource, for
+// automation such as CMDB syncs that needs the planned values of a few
+// resources rather than the whole plan.
+type exportedResource struct {
+	Address     string                 `json:"address"`
+	Type        string                 `json:"type"`
+	Provider    string                 `json:"provider"`
+	Action      string                 `json:"action"`
+	Replace     bool                   `json:"replace,omitempty"`
+	Fingerprint string                 `json:"fingerprint,omitempty"`
+	Before      map[string]interface{} `json:"before"`
+	After       map[string]interface{} `json:"after"`
+	Unknown     []string               `json:"unknown,omitempty"`
+	Changes     []ChangeDetail         `json:"changes,omitempty"`
+}
+
+// exportIndexEntry lists an exported resource in index.json.
+type exportIndexEntry struct {
+	Address string `json:"address"`
+	Action  string `json:"action"`
+	File    string `json:"file"`
+}
+
+func handleExport(ctx context.Context, args []string) error {
+	planFile, addressFile, outDir := "", "", ""
+	rest := []string{}
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name != "--plan" && name != "--addresses" && name != "--out" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return fmt.Errorf("flag %s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "--plan":
+			planFile = value
+		case "--addresses":
+			addressFile = value
+		default:
+			outDir = value
+		}
+	}
+	if outDir == "" {
+		return fmt.Errorf("usage: tfviz export --out <dir> [--addresses <file>] [--plan <json>]")
+	}
+	if planFile == "-" && addressFile == "-" {
+		return fmt.Errorf("stdin (-) can only be read once")
+	}
+
+	opts, tfArgs, err := parseOptions(rest)
+	if err != nil {
+		return err
+	}
+	var addresses []string
+	if addressFile != "" {
+		if addresses, err = readAddressList(addressFile); err != nil {
+			return err
+		}
+		if len(addresses) == 0 {
+			return fmt.Errorf("%s lists no addresses", addressFile)
+		}
+	}
+	var data []byte
+	if planFile != "" {
+		data, err = readPlanFile(planFile)
+	} else {
+		data, err = runTerraformPlan(ctx, "", tfArgs)
+	}
+	if err != nil {
+		return err
+	}
+	plan, err := parsePlanJSON(data)
+	if err != nil {
+		return err
+	}
+
+	analyzed := analyzePlanWith(plan, analysisOptions{includeUnchanged: true})
+	resources, unmatched := selectExports(analyzed, addresses)
+	for _, a := range unmatched {
+		fmt.Printf("⚠️  No resource matches %s\n", a)
+	}
+	if len(resources) == 0 {
+		return fmt.Errorf("no resources to export")
+	}
+	written, err := writeExports(outDir, resources, encryptionPassphrase())
+	if err != nil {
+		return err
+	}
+	if err := writeChecksumManifest(outDir, written, opts.signKey); err != nil {
+		return fmt.Errorf("error writing checksums: %v", err)
+	}
+	fmt.Printf("📦 Exported %s to %s\n", plural(len(resources), "resource"), outDir)
+	return nil
+}
+
+// readAddressList reads one resource address per line. Blank lines and
+// lines starting with # are skipped.
+func readAddressList(path string) ([]string, error) {
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			return nil, fmt.Errorf("error reading addresses: %v", err)
+		}
+		defer f.Close()
+	}
+	var addresses []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			addresses = append(addresses, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading addresses: %v", err)
+	}
+	return addresses, nil
+}
+
+// selectExports picks the resources matching addresses, which select a
+// resource, every instance of it when given without an index, or every
+// resource in a module. Without addresses, every changed resource is
+// selected. It also returns the addresses that match nothing.
+func selectExports(analyzed AnalyzedPlan, addresses []string) ([]ResourceAnalysis, []string) {
+	var selected []ResourceAnalysis
+	matched := map[string]bool{}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			if len(addresses) == 0 {
+				if r.Action != "no-op" && r.Action != "read" {
+					selected = append(selected, r)
+				}
+				continue
+			}
+			for _, a := range addresses {
+				if r.Address == a || stripIndex(r.Address) == a || strings.HasPrefix(r.Address, a+".") && strings.HasPrefix(a, "module.") {
+					matched[a] = true
+					selected = append(selected, r)
+					break
+				}
+			}
+		}
+	}
+	var unmatched []string
+	for _, a := range addresses {
+		if !matched[a] {
+			unmatched = append(unmatched, a)
+		}
+	}
+	return selected, unmatched
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// exportFileName turns an address into a file name, e.g.
+// module.app.aws_sqs_queue.jobs["eu"] into module.app.aws_sqs_queue.jobs_eu_.json.
+func exportFileName(address string) string {
+	return unsafeFileChars.ReplaceAllString(address, "_") + ".json"
+}
+
+// writeExports writes a file per resource and index.json listing them, and
+// returns the names of the files written. The values of resources can be
+// secret, so the files are private to the user and their contents are
+// encrypted with passphrase, if given, like cached plans.
+func writeExports(dir string, resources []ResourceAnalysis, passphrase string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating %s: %v", dir, err)
+	}
+	var written []string
+	var index []exportIndexEntry
+	used := map[string]bool{}
+	for _, r := range resources {
+		name := exportFileName(r.Address)
+		if used[name] {
+			// Addresses that differ only in quoting or punctuation.
+			name = strings.TrimSuffix(name, ".json") + "-" + hashID("", r.Address) + ".json"
+		}
+		used[name] = true
+
+		data, err := json.MarshalIndent(exportedResource{
+			Address:     r.Address,
+			Type:        r.Type,
+			Provider:    r.Provider,
+			Action:      r.Action,
+			Replace:     r.Replace,
+			Fingerprint: r.Fingerprint,
+			Before:      r.Before,
+			After:       r.After,
+			Unknown:     r.Unknown,
+			Changes:     r.Changes,
+		}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if data, err = sealData(append(data, '\n'), passphrase); err != nil {
+			return nil, fmt.Errorf("error encrypting %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			return nil, fmt.Errorf("error writing %s: %v", name, err)
+		}
+		written = append(written, name)
+		index = append(index, exportIndexEntry{Address: r.Address, Action: r.Action, File: name})
+	}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.json"), append(data, '\n'), 0600); err != nil {
+		return nil, fmt.Errorf("error writing index.json: %v", err)
+	}
+	return append(written, "index.json"), nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectExports(t *testing.T) {
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{
+		{Resources: []ResourceAnalysis{
+			{Address: `aws_instance.web["a"]`, Action: "update"},
+			{Address: `aws_instance.web["b"]`, Action: "no-op"},
+			{Address: "aws_s3_bucket.logs", Action: "no-op"},
+		}},
+		{Address: "module.app", Resources: []ResourceAnalysis{
+			{Address: "module.app.aws_sqs_queue.jobs", Action: "create"},
+		}},
+	}}
+
+	addresses := func(rs []ResourceAnalysis) []string {
+		var result []string
+		for _, r := range rs {
+			result = append(result, r.Address)
+		}
+		return result
+	}
+	selected, unmatched := selectExports(analyzed, []string{"aws_instance.web", "module.app", "aws_vpc.main"})
+	want := []string{`aws_instance.web["a"]`, `aws_instance.web["b"]`, "module.app.aws_sqs_queue.jobs"}
+	if got := addresses(selected); !reflect.DeepEqual(got, want) {
+		t.Errorf("selected %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(unmatched, []string{"aws_vpc.main"}) {
+		t.Errorf("unmatched = %v", unmatched)
+	}
+
+	selected, _ = selectExports(analyzed, nil)
+	if got := addresses(selected); !reflect.DeepEqual(got, []string{`aws_instance.web["a"]`, "module.app.aws_sqs_queue.jobs"}) {
+		t.Errorf("without addresses, expected the changed resources, got %v", got)
+	}
+}
+
+func TestWriteExports(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	resources := []ResourceAnalysis{{
+		Address: `aws_instance.web["a"]`, Type: "aws_instance", Action: "update",
+		Before:  map[string]interface{}{"instance_type": "t3.small"},
+		After:   map[string]interface{}{"instance_type": "t3.large"},
+		Changes: []ChangeDetail{{Field: "instance_type", Before: "t3.small", After: "t3.large", Action: "update"}},
+	}}
+	written, err := writeExports(dir, resources, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(written, []string{"aws_instance.web_a_.json", "index.json"}) {
+		t.Fatalf("written = %v", written)
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, written[0])); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("export should only be readable by the user: %v %v", info.Mode(), err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, written[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported exportedResource
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatal(err)
+	}
+	if exported.Address != `aws_instance.web["a"]` || exported.After["instance_type"] != "t3.large" || len(exported.Changes) != 1 {
+		t.Errorf("unexpected export: %+v", exported)
+	}
+
+	data, _ = os.ReadFile(filepath.Join(dir, "index.json"))
+	var index []exportIndexEntry
+	if err := json.Unmarshal(data, &index); err != nil || len(index) != 1 || index[0].File != written[0] {
+		t.Errorf("unexpected index: %s", data)
+	}
+
+	sealed := filepath.Join(t.TempDir(), "sealed")
+	if _, err := writeExports(sealed, resources, "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(filepath.Join(sealed, written[0]))
+	if !isEncrypted(data) {
+		t.Fatal("export is not encrypted with a passphrase")
+	}
+	if plain, err := openData(data, "s3cret"); err != nil || !strings.Contains(string(plain), "t3.large") {
+		t.Errorf("cannot decrypt export: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Finding is a risk detected in a planned change that reviewers should look
+// at regardless of the change's impact level.
+type Finding struct {
+	Category   string         `json:"category"`
+	Severity   string         `json:"severity"`
+	Address    string         `json:"address"`
+	Title      string         `json:"title"`
+	Detail     string         `json:"detail,omitempty"`
+	Attributes []FindingValue `json:"attributes,omitempty"`
+	// Remediation is configuration that resolves the finding, if any.
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// FindingValue is an attribute surfaced with a finding because it decides
+// how bad the change is.
+type FindingValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// findingChecks are run against every resource change.
+var findingChecks = []func(rc ResourceChange) []Finding{
+	checkDataLoss,
+	checkIAMEscalation,
+	checkSecurityGroupWidening,
+	checkGuardrails,
+}
+
+// detectFindings runs the per-change checks and the checks that need the
+// whole plan.
+func detectFindings(plan TerraformPlan) []Finding {
+	var findings []Finding
+	for _, rc := range plan.ResourceChanges {
+		if rc.Mode == "data" {
+			continue
+		}
+		for _, check := range findingChecks {
+			findings = append(findings, check(rc)...)
+		}
+	}
+	findings = append(findings, detectConflicts(plan)...)
+	findings = append(findings, detectMoves(plan)...)
+	findings = append(findings, detectCertificatesInUse(plan)...)
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Category != findings[j].Category {
+			return findings[i].Category < findings[j].Category
+		}
+		return findings[i].Address < findings[j].Address
+	})
+	return findings
+}
+
+func changeDeletes(c Change) bool {
+	for _, a := range c.Actions {
+		if a == "delete" {
+			return true
+		}
+	}
+	return false
+}
+
+// statefulTypes are resource types whose destruction loses data. Projects
+// can add their own with the stateful_types config setting.
+var statefulTypes = []string{
+	"aws_db_instance", "aws_rds_cluster", "aws_docdb_cluster", "aws_neptune_cluster",
+	"aws_redshift_cluster", "aws_dynamodb_table", "aws_elasticache_*", "aws_memorydb_cluster",
+	"aws_ebs_volume", "aws_efs_file_system", "aws_fsx_*", "aws_s3_bucket",
+	"aws_sqs_queue", "aws_kinesis_stream", "aws_msk_cluster", "aws_opensearch_domain", "aws_elasticsearch_domain",
+	"google_sql_database_instance", "google_sql_database", "google_spanner_*", "google_bigtable_instance",
+	"google_bigquery_dataset", "google_bigquery_table", "google_storage_bucket", "google_compute_disk",
+	"google_filestore_instance", "google_pubsub_topic", "google_pubsub_subscription", "google_redis_instance",
+	"azurerm_storage_account", "azurerm_storage_container", "azurerm_managed_disk", "azurerm_mssql_database",
+	"azurerm_*sql*_server", "azurerm_*_flexible_server", "azurerm_cosmosdb_*", "azurerm_servicebus_queue", "azurerm_redis_cache",
+}
+
+// dataProtectionAttributes decide whether deleting a data store is
+// recoverable.
+var dataProtectionAttributes = []string{
+	"deletion_protection", "skip_final_snapshot", "final_snapshot_identifier", "force_destroy", "backup_retention_period",
+}
+
+func isStateful(resourceType string) bool {
+	return matchesAnyPattern(statefulTypes, resourceType)
+}
+
+func checkDataLoss(rc ResourceChange) []Finding {
+	if !changeDeletes(rc.Change) || !isStateful(rc.Type) {
+		return nil
+	}
+	verb := "deleted"
+	if len(rc.Change.Actions) == 2 {
+		verb = "replaced"
+	}
+	f := Finding{
+		Category: "data-loss",
+		Severity: "High",
+		Address:  rc.Address,
+		Title:    fmt.Sprintf("Possible data loss: %s will be %s", rc.Type, verb),
+		Detail:   "The data it holds is destroyed with it unless a snapshot or backup is kept.",
+	}
+	for _, name := range dataProtectionAttributes {
+		if v, ok := rc.Change.Before[name]; ok && v != nil {
+			f.Attributes = append(f.Attributes, FindingValue{Name: name, Value: formatValue(v)})
+		}
+	}
+	if rc.Change.Before["skip_final_snapshot"] == true {
+		f.Detail = "skip_final_snapshot is true, so no final snapshot will be taken."
+	} else if rc.Change.Before["force_destroy"] == true {
+		f.Detail = "force_destroy is true, so all objects are deleted with it."
+	}
+	return []Finding{f}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckDataLoss(t *testing.T) {
+	changes := []ResourceChange{
+		{Address: "aws_db_instance.main", Type: "aws_db_instance", Change: Change{
+			Actions: []string{"delete"},
+			Before:  map[string]interface{}{"skip_final_snapshot": true, "deletion_protection": false, "engine": "postgres"},
+		}},
+		{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Change: Change{
+			Actions: []string{"delete", "create"},
+			Before:  map[string]interface{}{"force_destroy": false},
+		}},
+		{Address: "aws_db_instance.updated", Type: "aws_db_instance", Change: Change{Actions: []string{"update"}}},
+		{Address: "aws_instance.web", Type: "aws_instance", Change: Change{Actions: []string{"delete"}}},
+		{Address: "data.aws_s3_bucket.x", Mode: "data", Type: "aws_s3_bucket", Change: Change{Actions: []string{"delete"}}},
+	}
+	findings := detectFindings(TerraformPlan{ResourceChanges: changes})
+	if len(findings) != 2 {
+		t.Fatalf("got %d findings, want 2: %+v", len(findings), findings)
+	}
+
+	db := findings[0]
+	if db.Address != "aws_db_instance.main" || db.Category != "data-loss" || !strings.Contains(db.Title, "deleted") {
+		t.Errorf("unexpected finding %+v", db)
+	}
+	if !strings.Contains(db.Detail, "skip_final_snapshot") {
+		t.Errorf("detail does not mention skip_final_snapshot: %q", db.Detail)
+	}
+	if len(db.Attributes) != 2 || db.Attributes[0].Name != "deletion_protection" || db.Attributes[1].Value != "true" {
+		t.Errorf("unexpected attributes %+v", db.Attributes)
+	}
+	if !strings.Contains(findings[1].Title, "replaced") {
+		t.Errorf("bucket finding title = %q", findings[1].Title)
+	}
+
+	html := generateHTML(AnalyzedPlan{Findings: findings}, false, nil, nil, nil, graphOptions{}, reportView{})
+	if !strings.Contains(html, "Possible data loss") {
+		t.Error("report does not show data loss findings")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+func fingerprint(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Fingerprint identifies the finding across runs. Details that vary with
+// the values of a plan, such as Detail and Attributes, are left out.
+func (f Finding) Fingerprint() string {
+	return fingerprint("finding", f.Category, f.Address, f.Title)
+}
+
+// changeFingerprint identifies a resource change across plans: its address,
+// its action, with replacements told apart from deletions, and the names
+// of the attributes an update or replacement changes. The values are left
+// out, so re-planning a change, for example after a rebase, gives the same
+// fingerprint, while a change that touches other attributes does not.
+// Creations and deletions involve every attribute and are identified by
+// address and action alone.
+func changeFingerprint(r ResourceAnalysis) string {
+	action := r.Action
+	if r.Replace {
+		action = "replace"
+	}
+	var attributes []string
+	if action == "update" || action == "replace" {
+		for _, c := range r.Changes {
+			attributes = append(attributes, c.Field)
+		}
+		sort.Strings(attributes)
+	}
+	return fingerprint("change", r.Address, action, strings.Join(attributes, ","))
+}
+
+// changeFingerprints maps the address of each change in analyzed to its
+// fingerprint. The fingerprints are computed rather than read from the
+// analysis, so analyses saved by older versions of tfviz compare too.
+func changeFingerprints(analyzed AnalyzedPlan) map[string]string {
+	result := map[string]string{}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			if r.Action != "no-op" {
+				result[r.Address] = changeFingerprint(r)
+			}
+		}
+	}
+	return result
+}
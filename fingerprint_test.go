@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestChangeFingerprint(t *testing.T) {
+	update := ResourceAnalysis{Address: "aws_instance.web", Action: "update", Changes: []ChangeDetail{
+		{Field: "tags", Before: "a", After: "b"},
+		{Field: "instance_type", Before: "t3.small", After: "t3.large"},
+	}}
+	replan := update
+	replan.Changes = []ChangeDetail{
+		{Field: "instance_type", Before: "t3.small", After: "t3.xlarge"},
+		{Field: "tags", Before: "a", After: "c"},
+	}
+	if changeFingerprint(update) != changeFingerprint(replan) {
+		t.Error("re-planning with other values should keep the fingerprint")
+	}
+	replan.Changes = replan.Changes[:1]
+	if changeFingerprint(update) == changeFingerprint(replan) {
+		t.Error("changing other attributes should change the fingerprint")
+	}
+	replace := update
+	replace.Replace = true
+	if changeFingerprint(update) == changeFingerprint(replace) {
+		t.Error("a replacement should not match an update")
+	}
+
+	deletion := ResourceAnalysis{Address: "aws_instance.web", Action: "delete", Changes: []ChangeDetail{{Field: "ami", Action: "remove"}}}
+	other := deletion
+	other.Changes = nil
+	if changeFingerprint(deletion) != changeFingerprint(other) {
+		t.Error("deletions should be identified by address and action alone")
+	}
+
+	analyzed := analyzePlan(TerraformPlan{ResourceChanges: []ResourceChange{
+		{Address: "aws_s3_bucket.logs", Mode: "managed", Type: "aws_s3_bucket", Change: Change{Actions: []string{"update"}, Before: map[string]interface{}{"acl": "private"}, After: map[string]interface{}{"acl": "public-read"}}},
+		{Address: "aws_s3_bucket.data", Mode: "managed", Type: "aws_s3_bucket", Change: Change{Actions: []string{"no-op"}}},
+	}})
+	fingerprints := changeFingerprints(analyzed)
+	if len(fingerprints) != 1 {
+		t.Fatalf("expected one fingerprint, got %v", fingerprints)
+	}
+	for _, r := range analyzed.Modules[0].Resources {
+		if r.Fingerprint != fingerprints[r.Address] {
+			t.Errorf("%s: fingerprint %q, want %q", r.Address, r.Fingerprint, fingerprints[r.Address])
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitContext identifies the code a plan was made from.
+type GitContext struct {
+	Repository string `json:"repository"`
+	Branch     string `json:"branch,omitempty"`
+	Commit     string `json:"commit"`
+	// Path is the Terraform directory within the repository.
+	Path string `json:"path,omitempty"`
+	// Dirty reports uncommitted changes in the Terraform directory, in
+	// which case the commit alone does not reproduce the plan.
+	Dirty bool `json:"dirty,omitempty"`
+}
+
+// ShortCommit is the abbreviated commit hash shown in the report header.
+func (g GitContext) ShortCommit() string {
+	if len(g.Commit) > 12 {
+		return g.Commit[:12]
+	}
+	return g.Commit
+}
+
+// ciBranchVariables name the branch in CI systems that check out a detached
+// HEAD.
+var ciBranchVariables = []string{"GITHUB_HEAD_REF", "GITHUB_REF_NAME", "CI_COMMIT_REF_NAME", "BUILDKITE_BRANCH", "BRANCH_NAME"}
+
+// gitContext describes the git checkout containing dir (the current
+// directory when empty), or returns nil outside a repository or without git.
+func gitContext(ctx context.Context, dir string) *GitContext {
+	if dir == "" {
+		dir = "."
+	}
+	git := func(args ...string) (string, error) {
+		out, err := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...).Output()
+		return strings.TrimSpace(string(out)), err
+	}
+
+	commit, err := git("rev-parse", "HEAD")
+	if err != nil {
+		return nil
+	}
+	g := &GitContext{Commit: commit}
+	if top, err := git("rev-parse", "--show-toplevel"); err == nil {
+		g.Repository = filepath.Base(top)
+	}
+	if prefix, err := git("rev-parse", "--show-prefix"); err == nil {
+		g.Path = strings.TrimSuffix(prefix, "/")
+	}
+	if remote, err := git("config", "--get", "remote.origin.url"); err == nil && remote != "" {
+		g.Repository = redactRemote(remote)
+	}
+	if branch, err := git("rev-parse", "--abbrev-ref", "HEAD"); err == nil && branch != "HEAD" {
+		g.Branch = branch
+	} else {
+		for _, v := range ciBranchVariables {
+			if b := os.Getenv(v); b != "" {
+				g.Branch = b
+				break
+			}
+		}
+	}
+	if status, err := git("status", "--porcelain", "--", "."); err == nil {
+		g.Dirty = status != ""
+	}
+	return g
+}
+
+// redactRemote drops credentials from a remote URL, such as the tokens CI
+// systems put into https clone URLs.
+func redactRemote(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil || u.Scheme == "" {
+		return remote
+	}
+	u.User = nil
+	return u.String()
+}
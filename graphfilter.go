@@ -0,0 +1,183 @@
+package main
+
+import "fmt"
+
+// graphElement is a Cytoscape node or edge.
+type graphElement struct {
+	Data    map[string]interface{} `json:"data"`
+	Classes string                 `json:"classes,omitempty"`
+}
+
+func (e graphElement) id() string {
+	id, _ := e.Data["id"].(string)
+	return id
+}
+
+func (e graphElement) isEdge() bool {
+	_, ok := e.Data["source"]
+	return ok
+}
+
+// graphOptions limit which resources are drawn in the graph. Full graphs of
+// large estates are unreadable, so the report can be reduced to the part
+// that matters for the change at hand.
+type graphOptions struct {
+	changedOnly bool
+	focus       string // only draw the neighbourhood of this address
+	depth       int    // hops around focus
+}
+
+const defaultGraphDepth = 2
+
+// validate reports a focus address that matches no resource in the plan.
+func (o graphOptions) validate(analyzed AnalyzedPlan) error {
+	if o.focus == "" {
+		return nil
+	}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			if matchesFocus(r.Address, o.focus) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("--graph-focus: no resource %s in the plan", o.focus)
+}
+
+// matchesFocus lets a focus without an index select every instance of a
+// resource with count or for_each.
+func matchesFocus(address, focus string) bool {
+	return address == focus || stripIndex(address) == focus
+}
+
+// filterGraph tags the changed subgraph so the report can toggle it, and
+// drops everything else when the options ask for it.
+func filterGraph(elements []graphElement, opts graphOptions) []graphElement {
+	changed := changedSubgraph(elements)
+	for i := range elements {
+		if changed[elements[i].id()] {
+			elements[i].Classes += " changed-subgraph"
+		}
+	}
+	if opts.focus == "" {
+		if !opts.changedOnly {
+			return elements
+		}
+		return keepNodes(elements, changed)
+	}
+
+	keep := neighbourhood(elements, opts.focus, opts.depth)
+	for i := range elements {
+		if !elements[i].isEdge() && matchesFocus(elements[i].id(), opts.focus) {
+			elements[i].Classes += " focus"
+		}
+	}
+	if opts.changedOnly {
+		for id := range keep {
+			if !changed[id] && !matchesFocus(id, opts.focus) {
+				delete(keep, id)
+			}
+		}
+	}
+	return keepNodes(elements, keep)
+}
+
+// neighbourhood returns the resources at most depth edges away from focus,
+// following references and depends_on in both directions.
+func neighbourhood(elements []graphElement, focus string, depth int) map[string]bool {
+	adj := map[string][]string{}
+	dist := map[string]int{}
+	var queue []string
+	for _, e := range elements {
+		if e.isEdge() {
+			src, _ := e.Data["source"].(string)
+			tgt, _ := e.Data["target"].(string)
+			adj[src] = append(adj[src], tgt)
+			adj[tgt] = append(adj[tgt], src)
+		} else if _, isResource := e.Data["action"]; isResource && matchesFocus(e.id(), focus) {
+			dist[e.id()] = 0
+			queue = append(queue, e.id())
+		}
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if dist[node] >= depth {
+			continue
+		}
+		for _, next := range adj[node] {
+			if _, seen := dist[next]; !seen {
+				dist[next] = dist[node] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	set := map[string]bool{}
+	for id := range dist {
+		set[id] = true
+	}
+	return set
+}
+
+// changedSubgraph returns the changed resources together with their direct
+// dependencies and dependents.
+func changedSubgraph(elements []graphElement) map[string]bool {
+	set := map[string]bool{}
+	for _, e := range elements {
+		if action, _ := e.Data["action"].(string); action != "" && action != "no-op" {
+			set[e.id()] = true
+		}
+	}
+	neighbours := map[string]bool{}
+	for _, e := range elements {
+		if !e.isEdge() {
+			continue
+		}
+		src, _ := e.Data["source"].(string)
+		tgt, _ := e.Data["target"].(string)
+		if set[src] {
+			neighbours[tgt] = true
+		}
+		if set[tgt] {
+			neighbours[src] = true
+		}
+	}
+	for id := range neighbours {
+		set[id] = true
+	}
+	return set
+}
+
+// keepNodes drops nodes outside keep and the edges touching them. The
+// containers and modules around a kept node stay so it is still drawn in
+// context.
+func keepNodes(elements []graphElement, keep map[string]bool) []graphElement {
+	parents := map[string]string{}
+	for _, e := range elements {
+		if p, ok := e.Data["parent"].(string); ok {
+			parents[e.id()] = p
+		}
+	}
+	visible := map[string]bool{}
+	for id := range keep {
+		for cur := id; cur != "" && !visible[cur]; cur = parents[cur] {
+			visible[cur] = true
+		}
+	}
+
+	kept := make([]graphElement, 0, len(elements))
+	for _, e := range elements {
+		if e.isEdge() {
+			src, _ := e.Data["source"].(string)
+			tgt, _ := e.Data["target"].(string)
+			if !visible[src] || !visible[tgt] {
+				continue
+			}
+		} else if !visible[e.id()] {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	return kept
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func graphNodeIDs(t *testing.T, graphJSON string) (nodes, edges []string, classes map[string]string) {
+	t.Helper()
+	var elements []graphElement
+	if err := json.Unmarshal([]byte(graphJSON), &elements); err != nil {
+		t.Fatal(err)
+	}
+	classes = map[string]string{}
+	for _, e := range elements {
+		if e.isEdge() {
+			edges = append(edges, e.id())
+		} else {
+			nodes = append(nodes, e.id())
+		}
+		classes[e.id()] = e.Classes
+	}
+	sort.Strings(nodes)
+	sort.Strings(edges)
+	return nodes, edges, classes
+}
+
+func TestBuildGraphJSON_ChangedOnly(t *testing.T) {
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Address: "root", Resources: []ResourceAnalysis{
+		{Address: "aws_vpc.main", Type: "aws_vpc", Name: "main", Action: "no-op"},
+		{Address: "aws_subnet.a", Type: "aws_subnet", Name: "a", Action: "no-op"},
+		{Address: "aws_instance.web", Type: "aws_instance", Name: "web", Action: "update"},
+		{Address: "aws_security_group.web", Type: "aws_security_group", Name: "web", Action: "no-op"},
+		{Address: "aws_security_group_rule.ssh", Type: "aws_security_group_rule", Name: "ssh", Action: "no-op"},
+		{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Name: "logs", Action: "no-op"},
+	}}}}
+	refEdges := map[string][]string{
+		"aws_instance.web":            {"aws_security_group.web"},
+		"aws_security_group_rule.ssh": {"aws_security_group.web"},
+	}
+	containment := map[string]string{
+		"aws_instance.web": "aws_subnet.a",
+		"aws_subnet.a":     "aws_vpc.main",
+	}
+
+	full, _, err := buildGraphJSON(analyzed, refEdges, containment, nil, graphOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, _, classes := graphNodeIDs(t, full)
+	if len(nodes) != 6 {
+		t.Errorf("full graph nodes = %v", nodes)
+	}
+	for id, want := range map[string]bool{"aws_instance.web": true, "aws_security_group.web": true, "aws_security_group_rule.ssh": false, "aws_s3_bucket.logs": false} {
+		if got := strings.Contains(classes[id], "changed-subgraph"); got != want {
+			t.Errorf("%s tagged changed-subgraph = %v, want %v", id, got, want)
+		}
+	}
+
+	reduced, _, err := buildGraphJSON(analyzed, refEdges, containment, nil, graphOptions{changedOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, edges, _ := graphNodeIDs(t, reduced)
+	// The subnet and VPC stay as the containers of the changed instance
+	wantNodes := []string{"aws_instance.web", "aws_security_group.web", "aws_subnet.a", "aws_vpc.main"}
+	if strings.Join(nodes, ",") != strings.Join(wantNodes, ",") {
+		t.Errorf("changed-only nodes = %v, want %v", nodes, wantNodes)
+	}
+	if len(edges) != 1 || edges[0] != refEdgeID("aws_instance.web", "aws_security_group.web") {
+		t.Errorf("changed-only edges = %v", edges)
+	}
+}
+
+func TestBuildGraphJSON_Focus(t *testing.T) {
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Address: "root", Resources: []ResourceAnalysis{
+		{Address: "aws_lb.web", Type: "aws_lb", Name: "web", Action: "update"},
+		{Address: "aws_instance.web[0]", Type: "aws_instance", Name: "web", Action: "no-op"},
+		{Address: "aws_instance.web[1]", Type: "aws_instance", Name: "web", Action: "no-op"},
+		{Address: "aws_security_group.web", Type: "aws_security_group", Name: "web", Action: "no-op"},
+		{Address: "aws_security_group_rule.ssh", Type: "aws_security_group_rule", Name: "ssh", Action: "no-op", DependsOn: []string{"aws_security_group.web"}},
+		{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Name: "logs", Action: "no-op"},
+	}}}}
+	refEdges := map[string][]string{
+		"aws_lb.web":             {"aws_security_group.web"},
+		"aws_security_group.web": {"aws_s3_bucket.logs"},
+	}
+
+	tests := []struct {
+		opts graphOptions
+		want []string
+	}{
+		{graphOptions{focus: "aws_lb.web", depth: 0}, []string{"aws_lb.web"}},
+		{graphOptions{focus: "aws_lb.web", depth: 1}, []string{"aws_lb.web", "aws_security_group.web"}},
+		{graphOptions{focus: "aws_lb.web", depth: 2}, []string{"aws_lb.web", "aws_s3_bucket.logs", "aws_security_group.web", "aws_security_group_rule.ssh"}},
+		{graphOptions{focus: "aws_lb.web", depth: 2, changedOnly: true}, []string{"aws_lb.web", "aws_security_group.web"}},
+		{graphOptions{focus: "aws_instance.web", depth: 1}, []string{"aws_instance.web[0]", "aws_instance.web[1]"}},
+	}
+	for _, tt := range tests {
+		graphJSON, _, err := buildGraphJSON(analyzed, refEdges, nil, nil, tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes, _, classes := graphNodeIDs(t, graphJSON)
+		if strings.Join(nodes, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%+v: nodes = %v, want %v", tt.opts, nodes, tt.want)
+		}
+		if !strings.Contains(classes[tt.want[0]], "focus") {
+			t.Errorf("%+v: focus node not tagged: %q", tt.opts, classes[tt.want[0]])
+		}
+	}
+
+	if err := (graphOptions{focus: "aws_instance.web"}).validate(analyzed); err != nil {
+		t.Errorf("validate: %v", err)
+	}
+	if err := (graphOptions{focus: "aws_instance.api"}).validate(analyzed); err == nil {
+		t.Error("expected an error for an unknown focus address")
+	}
+}
+
+func TestParseOptions_GraphFocus(t *testing.T) {
+	opts, _, err := parseOptions([]string{"--graph-focus", "aws_lb.web"})
+	if err != nil || !opts.showGraph || opts.graph.focus != "aws_lb.web" || opts.graph.depth != defaultGraphDepth {
+		t.Errorf("parseOptions = %+v, %v", opts, err)
+	}
+	if opts, _, err := parseOptions([]string{"--graph-focus=aws_lb.web", "--graph-depth=4"}); err != nil || opts.graph.depth != 4 {
+		t.Errorf("parseOptions depth = %+v, %v", opts.graph, err)
+	}
+	for _, args := range [][]string{{"--graph-depth", "1"}, {"--graph-focus", "x", "--graph-depth", "-1"}} {
+		if _, _, err := parseOptions(args); err == nil {
+			t.Errorf("parseOptions(%v): expected an error", args)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import "fmt"
+
+// minKMSDeletionWindow is the deletion window below which scheduling a KMS
+// key for deletion leaves too little time to notice and cancel it.
+const minKMSDeletionWindow = 30
+
+// protectionAttributes guard a resource against accidental deletion while
+// they are true.
+var protectionAttributes = []string{
+	"deletion_protection", "disable_api_termination", "enable_deletion_protection", "termination_protection",
+}
+
+func guardrail(rc ResourceChange, severity, title, detail string, attrs ...FindingValue) Finding {
+	return Finding{Category: "guardrail", Severity: severity, Address: rc.Address, Title: title, Detail: detail, Attributes: attrs}
+}
+
+// checkGuardrails flags changes that remove safety nets: KMS keys being
+// scheduled for deletion or losing rotation, and deletion protection being
+// turned off.
+func checkGuardrails(rc ResourceChange) []Finding {
+	var findings []Finding
+	before, after := rc.Change.Before, rc.Change.After
+
+	if rc.Type == "aws_kms_key" {
+		if changeDeletes(rc.Change) {
+			window := intValue(before["deletion_window_in_days"])
+			f := guardrail(rc, "High", "KMS key scheduled for deletion",
+				"Data encrypted with this key becomes unrecoverable once the deletion window ends.",
+				FindingValue{Name: "deletion_window_in_days", Value: fmt.Sprint(window)})
+			if window > 0 && window < minKMSDeletionWindow {
+				f.Detail += fmt.Sprintf(" The window is only %d days.", window)
+			}
+			findings = append(findings, f)
+		}
+		if after != nil {
+			if w, ok := after["deletion_window_in_days"].(float64); ok && int(w) < minKMSDeletionWindow && before["deletion_window_in_days"] != w {
+				findings = append(findings, guardrail(rc, "Medium", "Short KMS key deletion window",
+					fmt.Sprintf("Deleting this key would leave less than %d days to cancel.", minKMSDeletionWindow),
+					FindingValue{Name: "deletion_window_in_days", Value: fmt.Sprint(int(w))}))
+			}
+			if after["enable_key_rotation"] == false && before["enable_key_rotation"] != false {
+				findings = append(findings, guardrail(rc, "Medium", "KMS key rotation disabled",
+					"The key material will not be rotated automatically.",
+					FindingValue{Name: "enable_key_rotation", Value: "false"}))
+			}
+			if after["is_enabled"] == false && before["is_enabled"] == true {
+				findings = append(findings, guardrail(rc, "High", "KMS key disabled",
+					"Anything encrypted with this key cannot be decrypted while it is disabled.",
+					FindingValue{Name: "is_enabled", Value: "false"}))
+			}
+		}
+	}
+
+	if before != nil && after != nil {
+		for _, name := range protectionAttributes {
+			if before[name] == true && after[name] == false {
+				findings = append(findings, guardrail(rc, "High", "Deletion protection turned off",
+					fmt.Sprintf("%s no longer protects this resource from being destroyed.", name),
+					FindingValue{Name: name, Value: "false"}))
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckGuardrails(t *testing.T) {
+	titles := func(findings []Finding) []string {
+		var out []string
+		for _, f := range findings {
+			out = append(out, f.Title)
+		}
+		return out
+	}
+	tests := []struct {
+		name string
+		rc   ResourceChange
+		want []string
+	}{
+		{"kms key deleted", ResourceChange{Type: "aws_kms_key", Change: Change{
+			Actions: []string{"delete"},
+			Before:  map[string]interface{}{"deletion_window_in_days": 7.0},
+		}}, []string{"KMS key scheduled for deletion"}},
+		{"new key with short window and no rotation", ResourceChange{Type: "aws_kms_key", Change: Change{
+			Actions: []string{"create"},
+			After:   map[string]interface{}{"deletion_window_in_days": 7.0, "enable_key_rotation": false},
+		}}, []string{"Short KMS key deletion window", "KMS key rotation disabled"}},
+		{"rotation turned off and key disabled", ResourceChange{Type: "aws_kms_key", Change: Change{
+			Actions: []string{"update"},
+			Before:  map[string]interface{}{"deletion_window_in_days": 30.0, "enable_key_rotation": true, "is_enabled": true},
+			After:   map[string]interface{}{"deletion_window_in_days": 30.0, "enable_key_rotation": false, "is_enabled": false},
+		}}, []string{"KMS key rotation disabled", "KMS key disabled"}},
+		{"unchanged short window", ResourceChange{Type: "aws_kms_key", Change: Change{
+			Actions: []string{"update"},
+			Before:  map[string]interface{}{"deletion_window_in_days": 10.0, "description": "a"},
+			After:   map[string]interface{}{"deletion_window_in_days": 10.0, "description": "b"},
+		}}, nil},
+		{"deletion protection off", ResourceChange{Type: "aws_db_instance", Change: Change{
+			Actions: []string{"update"},
+			Before:  map[string]interface{}{"deletion_protection": true},
+			After:   map[string]interface{}{"deletion_protection": false},
+		}}, []string{"Deletion protection turned off"}},
+		{"deletion protection on", ResourceChange{Type: "aws_lb", Change: Change{
+			Actions: []string{"update"},
+			Before:  map[string]interface{}{"enable_deletion_protection": false},
+			After:   map[string]interface{}{"enable_deletion_protection": true},
+		}}, nil},
+	}
+	for _, tt := range tests {
+		got := titles(checkGuardrails(tt.rc))
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
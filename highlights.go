@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// topChangesCount is how many resources each highlight list names.
+const topChangesCount = 5
+
+// TopChange is one resource in a highlight list with the figure that put it
+// there, e.g. "14 changed lines".
+type TopChange struct {
+	Address string `json:"address"`
+	Detail  string `json:"detail"`
+}
+
+// TopChanges are the changes a reviewer should look at first: the largest
+// diffs, the highest impacts and the resources most others depend on.
+type TopChanges struct {
+	LargestDiffs   []TopChange `json:"largest_diffs,omitempty"`
+	HighestImpact  []TopChange `json:"highest_impact,omitempty"`
+	MostDependents []TopChange `json:"most_dependents,omitempty"`
+}
+
+func (t TopChanges) Empty() bool {
+	return len(t.LargestDiffs) == 0 && len(t.HighestImpact) == 0 && len(t.MostDependents) == 0
+}
+
+// diffSize counts the added, removed and modified lines of a resource's
+// diff.
+func diffSize(r ResourceAnalysis) int {
+	n := 0
+	for _, l := range r.DiffLines {
+		if l.Type == "added" || l.Type == "removed" || l.Type == "modified" {
+			n++
+		}
+	}
+	return n
+}
+
+// dependents counts, per resource block, the resources that reference it or
+// name it in depends_on.
+func dependents(analyzed AnalyzedPlan) map[string]int {
+	from := map[string]map[string]bool{}
+	add := func(target, source string) {
+		target = stripIndex(target)
+		if target == stripIndex(source) {
+			return
+		}
+		if from[target] == nil {
+			from[target] = map[string]bool{}
+		}
+		from[target][source] = true
+	}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			for _, ref := range r.References {
+				add(ref.Target, r.Address)
+			}
+			for _, dep := range r.DependsOn {
+				add(dep, r.Address)
+			}
+		}
+	}
+	counts := map[string]int{}
+	for target, sources := range from {
+		counts[target] = len(sources)
+	}
+	return counts
+}
+
+// TopChanges is computed on demand, like Rollup, because impacts can still
+// change after the analysis.
+func (a AnalyzedPlan) TopChanges() TopChanges {
+	var changed []ResourceAnalysis
+	for _, m := range a.Modules {
+		for _, r := range m.Resources {
+			if r.Action != "no-op" {
+				changed = append(changed, r)
+			}
+		}
+	}
+	deps := dependents(a)
+
+	top := func(score func(ResourceAnalysis) int, detail func(ResourceAnalysis) string) []TopChange {
+		ranked := slices.Clone(changed)
+		slices.SortStableFunc(ranked, func(x, y ResourceAnalysis) int {
+			if d := score(y) - score(x); d != 0 {
+				return d
+			}
+			return strings.Compare(x.Address, y.Address)
+		})
+		var list []TopChange
+		for _, r := range ranked {
+			if len(list) == topChangesCount || score(r) == 0 {
+				break
+			}
+			list = append(list, TopChange{Address: r.Address, Detail: detail(r)})
+		}
+		return list
+	}
+
+	return TopChanges{
+		LargestDiffs: top(diffSize, func(r ResourceAnalysis) string {
+			return plural(diffSize(r), "changed line")
+		}),
+		// Low impact is the default for most changes, so only Medium and
+		// High are highlighted; the diff size breaks ties.
+		HighestImpact: top(func(r ResourceAnalysis) int {
+			if impactRank[r.Impact] < impactRank["Medium"] {
+				return 0
+			}
+			return impactRank[r.Impact]*100000 + diffSize(r)
+		}, func(r ResourceAnalysis) string {
+			if r.ImpactReason != "" {
+				return r.Impact + ": " + r.ImpactReason
+			}
+			return r.Impact
+		}),
+		MostDependents: top(func(r ResourceAnalysis) int {
+			return deps[stripIndex(r.Address)]
+		}, func(r ResourceAnalysis) string {
+			return plural(deps[stripIndex(r.Address)], "dependent")
+		}),
+	}
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
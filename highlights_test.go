@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTopChanges(t *testing.T) {
+	lines := func(n int) []DiffLine {
+		var l []DiffLine
+		for i := 0; i < n; i++ {
+			l = append(l, DiffLine{Type: "modified"})
+		}
+		return append(l, DiffLine{Type: "header"})
+	}
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Address: "root", Resources: []ResourceAnalysis{
+		{Address: "aws_vpc.main", Action: "update", Impact: "Low", DiffLines: lines(1)},
+		{Address: "aws_subnet.a[0]", Action: "create", Impact: "Low", DiffLines: lines(9),
+			References: []ResourceReference{{Attribute: "vpc_id", Target: "aws_vpc.main"}}},
+		{Address: "aws_subnet.a[1]", Action: "create", Impact: "Low", DiffLines: lines(9),
+			References: []ResourceReference{{Attribute: "vpc_id", Target: "aws_vpc.main"}}},
+		{Address: "aws_instance.web", Action: "update", Impact: "High", ImpactReason: "instance type changes", DiffLines: lines(3),
+			References: []ResourceReference{{Attribute: "subnet_id", Target: "aws_subnet.a"}}},
+		{Address: "aws_iam_role.ci", Action: "no-op", Impact: "Low", DiffLines: lines(20)},
+	}}}}
+
+	top := analyzed.TopChanges()
+	got := func(list []TopChange) string {
+		var parts []string
+		for _, c := range list {
+			parts = append(parts, c.Address+" ("+c.Detail+")")
+		}
+		return strings.Join(parts, ", ")
+	}
+	if want := "aws_subnet.a[0] (9 changed lines), aws_subnet.a[1] (9 changed lines), aws_instance.web (3 changed lines), aws_vpc.main (1 changed line)"; got(top.LargestDiffs) != want {
+		t.Errorf("LargestDiffs = %s, want %s", got(top.LargestDiffs), want)
+	}
+	if want := "aws_instance.web (High: instance type changes)"; got(top.HighestImpact) != want {
+		t.Errorf("HighestImpact = %s, want %s", got(top.HighestImpact), want)
+	}
+	if want := "aws_vpc.main (2 dependents), aws_subnet.a[0] (1 dependent), aws_subnet.a[1] (1 dependent)"; got(top.MostDependents) != want {
+		t.Errorf("MostDependents = %s, want %s", got(top.MostDependents), want)
+	}
+
+	html := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+	if !strings.Contains(html, `<h2 id="topChangesTitle">Top changes</h2>`) {
+		t.Error("report does not show the top changes")
+	}
+	if empty := generateHTML(AnalyzedPlan{}, false, nil, nil, nil, graphOptions{}, reportView{}); strings.Contains(empty, "topChangesTitle") {
+		t.Error("report shows top changes for a plan without changes")
+	}
+}
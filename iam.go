@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// policyStatement is the subset of an IAM policy statement the escalation
+// checks look at. Action, Resource and Principal accept both the string and
+// the list forms.
+type policyStatement struct {
+	Effect    string          `json:"Effect"`
+	Action    stringOrList    `json:"Action"`
+	NotAction stringOrList    `json:"NotAction"`
+	Resource  stringOrList    `json:"Resource"`
+	Principal json.RawMessage `json:"Principal"`
+	Condition json.RawMessage `json:"Condition"`
+}
+
+type stringOrList []string
+
+func (s *stringOrList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*s = stringOrList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*s = many
+	return nil
+}
+
+// parsePolicyStatements parses a policy document whose Statement may be a
+// single object or a list.
+func parsePolicyStatements(document string) ([]policyStatement, error) {
+	var doc struct {
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(document), &doc); err != nil {
+		return nil, err
+	}
+	var list []policyStatement
+	if err := json.Unmarshal(doc.Statement, &list); err == nil {
+		return list, nil
+	}
+	var one policyStatement
+	if err := json.Unmarshal(doc.Statement, &one); err != nil {
+		return nil, err
+	}
+	return []policyStatement{one}, nil
+}
+
+// grants reports whether an Allow statement covers action, honouring IAM's
+// case-insensitive wildcards.
+func (s policyStatement) grants(action string) bool {
+	if s.Effect != "Allow" {
+		return false
+	}
+	action = strings.ToLower(action)
+	if len(s.NotAction) > 0 {
+		for _, p := range s.NotAction {
+			if ok, _ := path.Match(strings.ToLower(p), action); ok {
+				return false
+			}
+		}
+		return true
+	}
+	for _, p := range s.Action {
+		if ok, _ := path.Match(strings.ToLower(p), action); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s policyStatement) onAllResources() bool {
+	for _, r := range s.Resource {
+		if r == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// escalationPaths are combinations of permissions that let a principal gain
+// permissions it was not granted directly.
+var escalationPaths = []struct {
+	actions     []string
+	explanation string
+}{
+	{[]string{"iam:PassRole", "lambda:CreateFunction"}, "can create a Lambda function running as any passable role"},
+	{[]string{"iam:PassRole", "ec2:RunInstances"}, "can launch an instance with any passable role and use its credentials"},
+	{[]string{"iam:PassRole", "cloudformation:CreateStack"}, "can create a stack that runs as any passable role"},
+	{[]string{"iam:PassRole", "glue:CreateDevEndpoint"}, "can create a Glue endpoint with any passable role"},
+	{[]string{"iam:CreatePolicyVersion"}, "can replace the policies attached to itself with arbitrary permissions"},
+	{[]string{"iam:SetDefaultPolicyVersion"}, "can switch a policy to a more permissive earlier version"},
+	{[]string{"iam:AttachUserPolicy"}, "can attach any managed policy, including AdministratorAccess, to users"},
+	{[]string{"iam:AttachRolePolicy"}, "can attach any managed policy, including AdministratorAccess, to roles"},
+	{[]string{"iam:PutUserPolicy"}, "can write arbitrary inline policies for users"},
+	{[]string{"iam:PutRolePolicy"}, "can write arbitrary inline policies for roles"},
+	{[]string{"iam:UpdateAssumeRolePolicy"}, "can make any role assumable by itself"},
+	{[]string{"iam:CreateAccessKey"}, "can create access keys for other users"},
+	{[]string{"iam:UpdateLoginProfile"}, "can reset console passwords of other users"},
+}
+
+// policyRisks returns a description of each escalation pattern in document,
+// keyed by a short name.
+func policyRisks(document string, trust bool) map[string]string {
+	statements, err := parsePolicyStatements(document)
+	if err != nil {
+		return nil
+	}
+	risks := map[string]string{}
+
+	if trust {
+		for _, s := range statements {
+			if s.Effect == "Allow" && len(s.Condition) == 0 && isWildcardPrincipal(s.Principal) {
+				risks["wildcard principal"] = "Trust policy lets any AWS principal assume this role."
+			}
+		}
+		return risks
+	}
+
+	for _, s := range statements {
+		if !s.onAllResources() {
+			continue
+		}
+		if s.grants("*:*") && s.grants("iam:*") && len(s.NotAction) == 0 {
+			risks["admin grant"] = "Grants every action on every resource (*:*)."
+		} else if len(s.NotAction) > 0 && s.Effect == "Allow" {
+			risks["NotAction allow"] = "Allow with NotAction grants every action except " + strings.Join(s.NotAction, ", ") + "."
+		}
+	}
+	for _, p := range escalationPaths {
+		granted := true
+		for _, a := range p.actions {
+			ok := false
+			for _, s := range statements {
+				if s.grants(a) && s.onAllResources() {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				granted = false
+				break
+			}
+		}
+		if granted {
+			risks[strings.Join(p.actions, " + ")] = "Grants " + strings.Join(p.actions, " + ") + " on all resources: " + p.explanation + "."
+		}
+	}
+	if _, admin := risks["admin grant"]; admin {
+		// Every path is implied; one finding is enough.
+		return map[string]string{"admin grant": risks["admin grant"]}
+	}
+	return risks
+}
+
+func isWildcardPrincipal(raw json.RawMessage) bool {
+	if len(raw) == 0 {
+		return false
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s == "*"
+	}
+	var m map[string]stringOrList
+	if json.Unmarshal(raw, &m) != nil {
+		return false
+	}
+	for _, v := range m["AWS"] {
+		if v == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// policyDocuments returns the policy documents of an IAM resource, with
+// whether each is a trust policy.
+func policyDocuments(resourceType string, values map[string]interface{}) map[string]bool {
+	docs := map[string]bool{}
+	if p, ok := values["policy"].(string); ok && strings.HasPrefix(resourceType, "aws_iam_") {
+		docs[p] = false
+	}
+	if p, ok := values["assume_role_policy"].(string); ok {
+		docs[p] = true
+	}
+	if inline, ok := values["inline_policy"].([]interface{}); ok {
+		for _, item := range inline {
+			if m, ok := item.(map[string]interface{}); ok {
+				if p, ok := m["policy"].(string); ok {
+					docs[p] = false
+				}
+			}
+		}
+	}
+	return docs
+}
+
+func collectPolicyRisks(resourceType string, values map[string]interface{}) map[string]string {
+	risks := map[string]string{}
+	for doc, trust := range policyDocuments(resourceType, values) {
+		for name, explanation := range policyRisks(doc, trust) {
+			risks[name] = explanation
+		}
+	}
+	return risks
+}
+
+const administratorAccessARN = "arn:aws:iam::aws:policy/AdministratorAccess"
+
+// checkIAMEscalation flags IAM changes that introduce privilege escalation
+// patterns which the resource did not already have before the change.
+func checkIAMEscalation(rc ResourceChange) []Finding {
+	if !strings.HasPrefix(rc.Type, "aws_iam_") || rc.Change.After == nil {
+		return nil
+	}
+
+	if strings.HasSuffix(rc.Type, "_policy_attachment") {
+		if rc.Change.After["policy_arn"] == administratorAccessARN && rc.Change.Before["policy_arn"] != administratorAccessARN {
+			return []Finding{{
+				Category: "iam-escalation",
+				Severity: "High",
+				Address:  rc.Address,
+				Title:    "IAM escalation: AdministratorAccess attached",
+				Detail:   "Attaches the AWS managed AdministratorAccess policy, granting every action on every resource.",
+			}}
+		}
+		return nil
+	}
+
+	before := collectPolicyRisks(rc.Type, rc.Change.Before)
+	after := collectPolicyRisks(rc.Type, rc.Change.After)
+	var names []string
+	for name := range after {
+		if _, existed := before[name]; !existed {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var findings []Finding
+	for _, name := range names {
+		findings = append(findings, Finding{
+			Category: "iam-escalation",
+			Severity: "High",
+			Address:  rc.Address,
+			Title:    fmt.Sprintf("IAM escalation: %s", name),
+			Detail:   after[name],
+		})
+	}
+	return findings
+}
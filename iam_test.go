@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPolicyRisks(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		trust    bool
+		want     []string
+	}{
+		{"admin", `{"Statement": {"Effect": "Allow", "Action": "*", "Resource": "*"}}`, false, []string{"admin grant"}},
+		{"pass role and lambda", `{"Statement": [
+			{"Effect": "Allow", "Action": ["iam:PassRole"], "Resource": "*"},
+			{"Effect": "Allow", "Action": ["lambda:Create*"], "Resource": ["*"]}]}`, false, []string{"iam:PassRole + lambda:CreateFunction"}},
+		{"pass role on one role", `{"Statement": [
+			{"Effect": "Allow", "Action": "iam:PassRole", "Resource": "arn:aws:iam::123:role/app"},
+			{"Effect": "Allow", "Action": "lambda:CreateFunction", "Resource": "*"}]}`, false, nil},
+		{"deny", `{"Statement": {"Effect": "Deny", "Action": "*", "Resource": "*"}}`, false, nil},
+		{"read only", `{"Statement": {"Effect": "Allow", "Action": ["s3:GetObject"], "Resource": "*"}}`, false, nil},
+		{"not action", `{"Statement": {"Effect": "Allow", "NotAction": "iam:*", "Resource": "*"}}`, false, []string{"NotAction allow"}},
+		{"wildcard principal", `{"Statement": [{"Effect": "Allow", "Principal": {"AWS": "*"}, "Action": "sts:AssumeRole"}]}`, true, []string{"wildcard principal"}},
+		{"wildcard principal with condition", `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "sts:AssumeRole",
+			"Condition": {"StringEquals": {"aws:PrincipalOrgID": "o-123"}}}]}`, true, nil},
+		{"service principal", `{"Statement": [{"Effect": "Allow", "Principal": {"Service": "lambda.amazonaws.com"}, "Action": "sts:AssumeRole"}]}`, true, nil},
+	}
+	for _, tt := range tests {
+		risks := policyRisks(tt.document, tt.trust)
+		if len(risks) != len(tt.want) {
+			t.Errorf("%s: got risks %v, want %v", tt.name, risks, tt.want)
+			continue
+		}
+		for _, w := range tt.want {
+			if _, ok := risks[w]; !ok {
+				t.Errorf("%s: missing risk %q in %v", tt.name, w, risks)
+			}
+		}
+	}
+}
+
+func TestCheckIAMEscalation(t *testing.T) {
+	admin := `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "*:*", "Resource": "*"}]}`
+	readOnly := `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "*"}]}`
+
+	findings := checkIAMEscalation(ResourceChange{Address: "aws_iam_policy.ops", Type: "aws_iam_policy", Change: Change{
+		Actions: []string{"update"},
+		Before:  map[string]interface{}{"policy": readOnly},
+		After:   map[string]interface{}{"policy": admin},
+	}})
+	if len(findings) != 1 || findings[0].Category != "iam-escalation" || !strings.Contains(findings[0].Title, "admin grant") {
+		t.Errorf("unexpected findings %+v", findings)
+	}
+
+	// Patterns that were already present are not reported again
+	findings = checkIAMEscalation(ResourceChange{Address: "aws_iam_policy.ops", Type: "aws_iam_policy", Change: Change{
+		Actions: []string{"update"},
+		Before:  map[string]interface{}{"policy": admin, "description": "a"},
+		After:   map[string]interface{}{"policy": admin, "description": "b"},
+	}})
+	if len(findings) != 0 {
+		t.Errorf("unchanged admin policy reported: %+v", findings)
+	}
+
+	findings = checkIAMEscalation(ResourceChange{Address: "aws_iam_role_policy_attachment.admin", Type: "aws_iam_role_policy_attachment", Change: Change{
+		Actions: []string{"create"},
+		After:   map[string]interface{}{"policy_arn": administratorAccessARN, "role": "ci"},
+	}})
+	if len(findings) != 1 || !strings.Contains(findings[0].Title, "AdministratorAccess") {
+		t.Errorf("unexpected findings for attachment %+v", findings)
+	}
+
+	findings = checkIAMEscalation(ResourceChange{Address: "aws_iam_role.app", Type: "aws_iam_role", Change: Change{
+		Actions: []string{"create"},
+		After: map[string]interface{}{
+			"assume_role_policy": `{"Statement": [{"Effect": "Allow", "Principal": {"AWS": ["*"]}, "Action": "sts:AssumeRole"}]}`,
+			"inline_policy":      []interface{}{map[string]interface{}{"name": "x", "policy": `{"Statement": [{"Effect": "Allow", "Action": "iam:PutRolePolicy", "Resource": "*"}]}`}},
+		},
+	}})
+	if len(findings) != 2 {
+		t.Errorf("expected trust and inline policy findings, got %+v", findings)
+	}
+}
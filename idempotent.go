@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type idempotenceFinding struct {
+	Address string
+	Reason  string
+}
+
+// handleCheckIdempotent runs terraform plan twice and reports resources whose
+// planned changes are not stable between runs. A change planned the same way
+// both times, as perpetual diffs are, cannot be told apart from an intended
+// one this way. With --apply (meant for disposable test environments) the
+// configuration is applied first, and any change still planned afterwards is
+// reported as a perpetual diff.
+//
+// The report flags of parseOptions have no effect here, so only --apply is
+// accepted; other arguments are passed to terraform.
+func handleCheckIdempotent(ctx context.Context, args []string) error {
+	apply := false
+	tfArgs := []string{}
+	for i, a := range args {
+		if a == "--" {
+			tfArgs = append(tfArgs, args[i+1:]...)
+			break
+		}
+		if a == "--apply" {
+			apply = true
+			continue
+		}
+		if strings.HasPrefix(a, "--") {
+			return fmt.Errorf("unknown flag for check-idempotent: %s (pass terraform flags after --)", a)
+		}
+		tfArgs = append(tfArgs, a)
+	}
+
+	var findings []idempotenceFinding
+	if apply {
+		fmt.Println("🔄 Running terraform apply...")
+		cmd := terraformCommand(ctx, append([]string{"apply", "-auto-approve", "-input=false"}, tfArgs...)...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("error running terraform apply: %v", err)
+		}
+		after, err := planAndAnalyze(ctx, tfArgs)
+		if err != nil {
+			return err
+		}
+		findings = perpetualChanges(after)
+	} else {
+		first, err := planAndAnalyze(ctx, tfArgs)
+		if err != nil {
+			return err
+		}
+		second, err := planAndAnalyze(ctx, tfArgs)
+		if err != nil {
+			return err
+		}
+		findings = comparePlanRuns(first, second)
+	}
+
+	if len(findings) == 0 {
+		fmt.Println("✅ Plan is idempotent: no unstable or perpetual changes found")
+		return nil
+	}
+	fmt.Printf("⚠️  %d resources are not idempotent:\n", len(findings))
+	for _, f := range findings {
+		fmt.Printf("  %s: %s\n", f.Address, f.Reason)
+	}
+	return fmt.Errorf("idempotence check failed")
+}
+
+func planAndAnalyze(ctx context.Context, args []string) (AnalyzedPlan, error) {
+	out, err := runTerraformPlan(ctx, "", args)
+	if err != nil {
+		return AnalyzedPlan{}, err
+	}
+	plan, err := parsePlanJSON(out)
+	if err != nil {
+		return AnalyzedPlan{}, err
+	}
+	return analyzePlan(plan), nil
+}
+
+// perpetualChanges lists every resource that still has a planned change after
+// an apply, which usually points at a provider normalising values.
+func perpetualChanges(analyzed AnalyzedPlan) []idempotenceFinding {
+	var findings []idempotenceFinding
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			if r.Action == "no-op" {
+				continue
+			}
+			fields := []string{}
+			for _, c := range r.Changes {
+				fields = append(fields, c.Field)
+			}
+			sort.Strings(fields)
+			reason := "still plans " + r.Action + " after apply"
+			if len(fields) > 0 {
+				reason += " (" + strings.Join(fields, ", ") + ")"
+			}
+			findings = append(findings, idempotenceFinding{Address: r.Address, Reason: reason})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Address < findings[j].Address })
+	return findings
+}
+
+// comparePlanRuns reports resources whose action or rendered diff differs
+// between two consecutive plans of the same configuration.
+func comparePlanRuns(first, second AnalyzedPlan) []idempotenceFinding {
+	index := func(a AnalyzedPlan) map[string]ResourceAnalysis {
+		m := map[string]ResourceAnalysis{}
+		for _, mod := range a.Modules {
+			for _, r := range mod.Resources {
+				m[r.Address] = r
+			}
+		}
+		return m
+	}
+	firstRes, secondRes := index(first), index(second)
+
+	var findings []idempotenceFinding
+	for addr, a := range firstRes {
+		b, ok := secondRes[addr]
+		switch {
+		case !ok:
+			findings = append(findings, idempotenceFinding{Address: addr, Reason: "planned " + a.Action + " only in the first run"})
+		case a.Action != b.Action:
+			findings = append(findings, idempotenceFinding{Address: addr, Reason: fmt.Sprintf("action changed between runs: %s → %s", a.Action, b.Action)})
+		case a.DiffText() != b.DiffText():
+			findings = append(findings, idempotenceFinding{Address: addr, Reason: "planned values differ between runs"})
+		}
+	}
+	for addr, b := range secondRes {
+		if _, ok := firstRes[addr]; !ok {
+			findings = append(findings, idempotenceFinding{Address: addr, Reason: "planned " + b.Action + " only in the second run"})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Address < findings[j].Address })
+	return findings
+}
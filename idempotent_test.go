@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComparePlanRuns(t *testing.T) {
+	mk := func(resources ...ResourceAnalysis) AnalyzedPlan {
+		return AnalyzedPlan{Modules: []ModuleAnalysis{{Address: "root", Resources: resources}}}
+	}
+	stable := ResourceAnalysis{Address: "aws_s3_bucket.logs", Action: "update", DiffLines: []DiffLine{{Text: "~ acl"}}}
+	first := mk(
+		stable,
+		ResourceAnalysis{Address: "aws_instance.web", Action: "update", DiffLines: []DiffLine{{Text: `~ tags = "a"`}}},
+		ResourceAnalysis{Address: "aws_iam_policy.p", Action: "update"},
+	)
+	second := mk(
+		stable,
+		ResourceAnalysis{Address: "aws_instance.web", Action: "update", DiffLines: []DiffLine{{Text: `~ tags = "b"`}}},
+		ResourceAnalysis{Address: "aws_lambda_function.f", Action: "create"},
+	)
+
+	findings := comparePlanRuns(first, second)
+	got := map[string]bool{}
+	for _, f := range findings {
+		got[f.Address] = true
+	}
+	for _, want := range []string{"aws_instance.web", "aws_iam_policy.p", "aws_lambda_function.f"} {
+		if !got[want] {
+			t.Errorf("expected finding for %s", want)
+		}
+	}
+	if got["aws_s3_bucket.logs"] {
+		t.Error("identical changes should not be reported")
+	}
+}
+
+func TestPerpetualChanges(t *testing.T) {
+	after := AnalyzedPlan{Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{
+		{Address: "aws_instance.web", Action: "no-op"},
+		{Address: "aws_security_group.sg", Action: "update", Changes: []ChangeDetail{{Field: "ingress"}}},
+	}}}}
+	findings := perpetualChanges(after)
+	if len(findings) != 1 || findings[0].Address != "aws_security_group.sg" {
+		t.Fatalf("unexpected findings: %+v", findings)
+	}
+	if findings[0].Reason != "still plans update after apply (ingress)" {
+		t.Errorf("reason = %q", findings[0].Reason)
+	}
+}
+
+func TestHandleCheckIdempotent_RejectsReportFlags(t *testing.T) {
+	err := handleCheckIdempotent(t.Context(), []string{"--apply", "--graph"})
+	if err == nil || !strings.Contains(err.Error(), "unknown flag for check-idempotent: --graph") {
+		t.Errorf("got %v, want an error for a flag without effect", err)
+	}
+}
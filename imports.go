@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// importIDFormats lists resource types whose import ID is not their id
+// attribute, as the attributes it is joined from and the separator.
+var importIDFormats = map[string]struct {
+	attrs []string
+	sep   string
+}{
+	"aws_iam_group_policy_attachment": {[]string{"group", "policy_arn"}, "/"},
+	"aws_iam_role_policy":             {[]string{"role", "name"}, ":"},
+	"aws_iam_role_policy_attachment":  {[]string{"role", "policy_arn"}, "/"},
+	"aws_iam_user_policy_attachment":  {[]string{"user", "policy_arn"}, "/"},
+	"aws_lambda_permission":           {[]string{"function_name", "statement_id"}, "/"},
+	"aws_route":                       {[]string{"route_table_id", "destination_cidr_block"}, "_"},
+	"aws_route_table_association":     {[]string{"subnet_id", "route_table_id"}, "/"},
+}
+
+// ImportSuggestion is a resource that can likely be brought back under
+// management with terraform import instead of being destroyed or created.
+// ID is empty when tfviz cannot derive the import ID.
+type ImportSuggestion struct {
+	Address string `json:"address"`
+	ID      string `json:"id,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// importID derives the provider import ID from a resource's state.
+func importID(resourceType string, values map[string]interface{}) string {
+	if f, ok := importIDFormats[resourceType]; ok {
+		parts := make([]string, 0, len(f.attrs))
+		for _, attr := range f.attrs {
+			v, _ := values[attr].(string)
+			if v == "" {
+				return ""
+			}
+			parts = append(parts, v)
+		}
+		return strings.Join(parts, f.sep)
+	}
+	id, _ := values["id"].(string)
+	return id
+}
+
+// importSuggestions finds resources that exist outside the plan's view of
+// the configuration:
+//   - objects deleted only because their resource block was removed, which
+//     may have moved to another configuration and need importing there;
+//   - objects deleted outside Terraform that the plan creates again, which
+//     may have been recreated by hand under the same ID.
+func importSuggestions(plan TerraformPlan) []ImportSuggestion {
+	var suggestions []ImportSuggestion
+	planned := map[string]ResourceChange{}
+	for _, rc := range plan.ResourceChanges {
+		planned[rc.Address] = rc
+		if rc.Mode != "managed" || rc.ActionReason != "delete_because_no_resource_config" || !changeDeletes(rc.Change) {
+			continue
+		}
+		suggestions = append(suggestions, ImportSuggestion{
+			Address: rc.Address,
+			ID:      importID(rc.Type, rc.Change.Before),
+			Reason:  "resource block removed; if it is declared elsewhere now, import it there instead of destroying it",
+		})
+	}
+	for _, drift := range plan.ResourceDrift {
+		if drift.Mode != "managed" || len(drift.Change.Actions) != 1 || drift.Change.Actions[0] != "delete" {
+			continue
+		}
+		if rc, ok := planned[drift.Address]; !ok || len(rc.Change.Actions) == 0 || rc.Change.Actions[len(rc.Change.Actions)-1] != "create" {
+			continue
+		}
+		suggestions = append(suggestions, ImportSuggestion{
+			Address: drift.Address,
+			ID:      importID(drift.Type, drift.Change.Before),
+			Reason:  "deleted outside Terraform; if it was recreated by hand, import it instead of creating another one",
+		})
+	}
+	return suggestions
+}
+
+// hclString quotes s as an HCL string literal, escaping interpolation.
+func hclString(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "${", "$${", "%{", "%%{").Replace(s)
+	return `"` + s + `"`
+}
+
+// importBlocks renders suggestions as Terraform 1.5+ import blocks.
+func importBlocks(suggestions []ImportSuggestion) string {
+	var b strings.Builder
+	for i, s := range suggestions {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "# %s\n", s.Reason)
+		id := hclString(s.ID)
+		if s.ID == "" {
+			id = `"" # import ID not known; see the provider documentation`
+		}
+		fmt.Fprintf(&b, "import {\n  to = %s\n  id = %s\n}\n", s.Address, id)
+	}
+	return b.String()
+}
+
+// ImportBlocks renders the plan's import suggestions for the report.
+func (a AnalyzedPlan) ImportBlocks() string {
+	return importBlocks(a.Imports)
+}
+
+// importCommands renders suggestions as terraform import command lines.
+func importCommands(suggestions []ImportSuggestion) string {
+	var b strings.Builder
+	for _, s := range suggestions {
+		fmt.Fprintf(&b, "# %s\n", s.Reason)
+		id := shellQuote(s.ID)
+		if s.ID == "" {
+			id = "'<import ID>'"
+		}
+		fmt.Fprintf(&b, "terraform import %s %s\n", shellQuote(s.Address), id)
+	}
+	return b.String()
+}
+
+// handleImports prints import blocks or commands for the plan's removed and
+// externally deleted resources.
+func handleImports(ctx context.Context, args []string) error {
+	planFile, format := "", "blocks"
+	rest := []string{}
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name != "--plan" && name != "--format" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return fmt.Errorf("flag %s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		if name == "--plan" {
+			planFile = value
+		} else {
+			format = value
+		}
+	}
+	if format != "blocks" && format != "commands" {
+		return fmt.Errorf("unsupported import format %q (use blocks or commands)", format)
+	}
+
+	_, tfArgs, err := parseOptions(rest)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if planFile != "" {
+		data, err = os.ReadFile(planFile)
+		if err != nil {
+			return fmt.Errorf("error reading plan file: %v", err)
+		}
+	} else {
+		data, err = runTerraformPlan(ctx, "", tfArgs)
+		if err != nil {
+			return err
+		}
+	}
+	plan, err := parsePlanJSON(data)
+	if err != nil {
+		return err
+	}
+
+	suggestions := importSuggestions(plan)
+	if len(suggestions) == 0 {
+		fmt.Println("✅ No removed or externally deleted resources to import")
+		return nil
+	}
+	if format == "commands" {
+		fmt.Print(importCommands(suggestions))
+	} else {
+		fmt.Print(importBlocks(suggestions))
+	}
+	return nil
+}
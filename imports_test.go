@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestImportSuggestions(t *testing.T) {
+	var plan TerraformPlan
+	err := json.Unmarshal([]byte(`{
+	  "resource_changes": [
+	    {"address": "aws_s3_bucket.logs", "mode": "managed", "type": "aws_s3_bucket", "action_reason": "delete_because_no_resource_config",
+	     "change": {"actions": ["delete"], "before": {"id": "acme-logs", "bucket": "acme-logs"}}},
+	    {"address": "aws_iam_role_policy_attachment.ci", "mode": "managed", "type": "aws_iam_role_policy_attachment", "action_reason": "delete_because_no_resource_config",
+	     "change": {"actions": ["delete"], "before": {"id": "ci-2024", "role": "ci", "policy_arn": "arn:aws:iam::aws:policy/ReadOnlyAccess"}}},
+	    {"address": "aws_instance.web[1]", "mode": "managed", "type": "aws_instance", "action_reason": "delete_because_count_index",
+	     "change": {"actions": ["delete"], "before": {"id": "i-0def"}}},
+	    {"address": "aws_security_group_rule.ssh", "mode": "managed", "type": "aws_security_group_rule",
+	     "change": {"actions": ["create"], "after": {"type": "ingress"}}},
+	    {"address": "aws_sqs_queue.jobs", "mode": "managed", "type": "aws_sqs_queue",
+	     "change": {"actions": ["create"], "after": {"name": "jobs"}}},
+	    {"address": "aws_instance.api", "mode": "managed", "type": "aws_instance",
+	     "change": {"actions": ["no-op"], "before": {"id": "i-0abc"}}}
+	  ],
+	  "resource_drift": [
+	    {"address": "aws_sqs_queue.jobs", "mode": "managed", "type": "aws_sqs_queue",
+	     "change": {"actions": ["delete"], "before": {"id": "https://sqs.eu-west-1.amazonaws.com/123/jobs"}}},
+	    {"address": "aws_security_group_rule.ssh", "mode": "managed", "type": "aws_security_group_rule",
+	     "change": {"actions": ["delete"], "before": {"id": "sgrule-123"}}},
+	    {"address": "aws_instance.api", "mode": "managed", "type": "aws_instance",
+	     "change": {"actions": ["update"], "before": {"id": "i-0abc"}}}
+	  ]
+	}`), &plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := importSuggestions(plan)
+	want := map[string]string{
+		"aws_s3_bucket.logs":                "acme-logs",
+		"aws_iam_role_policy_attachment.ci": "ci/arn:aws:iam::aws:policy/ReadOnlyAccess",
+		"aws_sqs_queue.jobs":                "https://sqs.eu-west-1.amazonaws.com/123/jobs",
+		"aws_security_group_rule.ssh":       "sgrule-123",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d suggestions, want %d: %+v", len(got), len(want), got)
+	}
+	for _, s := range got {
+		if id, ok := want[s.Address]; !ok || s.ID != id {
+			t.Errorf("unexpected suggestion %+v", s)
+		}
+	}
+}
+
+func TestImportOutput(t *testing.T) {
+	suggestions := []ImportSuggestion{
+		{Address: `module.app.aws_s3_bucket.this["logs"]`, ID: "acme-${env}", Reason: "resource block removed"},
+		{Address: "aws_security_group_rule.ssh", Reason: "deleted outside Terraform"},
+	}
+
+	wantBlocks := `# resource block removed
+import {
+  to = module.app.aws_s3_bucket.this["logs"]
+  id = "acme-$${env}"
+}
+
+# deleted outside Terraform
+import {
+  to = aws_security_group_rule.ssh
+  id = "" # import ID not known; see the provider documentation
+}
+`
+	if got := importBlocks(suggestions); got != wantBlocks {
+		t.Errorf("importBlocks =\n%s\nwant\n%s", got, wantBlocks)
+	}
+
+	wantCommands := `# resource block removed
+terraform import 'module.app.aws_s3_bucket.this["logs"]' 'acme-${env}'
+# deleted outside Terraform
+terraform import aws_security_group_rule.ssh '<import ID>'
+`
+	if got := importCommands(suggestions); got != wantCommands {
+		t.Errorf("importCommands =\n%s\nwant\n%s", got, wantCommands)
+	}
+}
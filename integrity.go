@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const checksumManifest = "SHA256SUMS"
+
+// writeChecksummed writes data to path with permissions perm, together with
+// a sha256sum-compatible path.sha256 file. When signKey is set the checksum
+// file is signed as well.
+func writeChecksummed(path string, data []byte, perm os.FileMode, signKey string) error {
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return err
+	}
+	// WriteFile keeps the permissions of a file that already exists.
+	if err := os.Chmod(path, perm); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	line := hex.EncodeToString(sum[:]) + "  " + filepath.Base(path) + "\n"
+	sumFile := path + ".sha256"
+	if err := os.WriteFile(sumFile, []byte(line), 0644); err != nil {
+		return err
+	}
+	if signKey != "" {
+		return signFile(sumFile, signKey)
+	}
+	return nil
+}
+
+// writeChecksumManifest writes a SHA256SUMS file covering the named files in
+// dir, verifiable with `sha256sum -c SHA256SUMS`, and signs it when signKey
+// is set.
+func writeChecksumManifest(dir string, names []string, signKey string) error {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+
+	var sb strings.Builder
+	for _, name := range sorted {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		sb.WriteString(hex.EncodeToString(sum[:]) + "  " + name + "\n")
+	}
+
+	manifest := filepath.Join(dir, checksumManifest)
+	if err := os.WriteFile(manifest, []byte(sb.String()), 0644); err != nil {
+		return err
+	}
+	if signKey != "" {
+		return signFile(manifest, signKey)
+	}
+	return nil
+}
+
+// signFile writes a raw Ed25519 signature of path to path.sig. The key is a
+// PKCS#8 PEM private key, as created by `openssl genpkey -algorithm ed25519`,
+// and the signature verifies with
+// `openssl pkeyutl -verify -pubin -inkey pub.pem -rawin -in FILE -sigfile FILE.sig`.
+func signFile(path, keyFile string) error {
+	key, err := loadSigningKey(keyFile)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sig, err := key.Sign(rand.Reader, data, crypto.Hash(0))
+	if err != nil {
+		return fmt.Errorf("error signing %s: %v", path, err)
+	}
+	return os.WriteFile(path+".sig", sig, 0644)
+}
+
+func loadSigningKey(keyFile string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading signing key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s is not PEM encoded", keyFile)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing signing key: %v", err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key %s is not an Ed25519 key", keyFile)
+	}
+	return key, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteChecksumManifestSigned(t *testing.T) {
+	dir := t.TempDir()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "key.pem")
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html></html>"), 0644)
+	os.WriteFile(filepath.Join(dir, "dev.html"), []byte("dev"), 0644)
+
+	if err := writeChecksumManifest(dir, []string{"index.html", "dev.html"}, keyFile); err != nil {
+		t.Fatalf("writeChecksumManifest: %v", err)
+	}
+
+	manifest, _ := os.ReadFile(filepath.Join(dir, checksumManifest))
+	lines := strings.Split(strings.TrimSpace(string(manifest)), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "  dev.html") || !strings.HasSuffix(lines[1], "  index.html") {
+		t.Errorf("unexpected manifest:\n%s", manifest)
+	}
+	// sha256 of "dev"
+	if !strings.HasPrefix(lines[0], "ef260e9aa3c673af240d17a2660480361a8e081d1ffeca2a5ed0e3219fc18567") {
+		t.Errorf("wrong checksum line: %q", lines[0])
+	}
+
+	sig, err := os.ReadFile(filepath.Join(dir, checksumManifest+".sig"))
+	if err != nil {
+		t.Fatalf("signature not written: %v", err)
+	}
+	if !ed25519.Verify(pub, manifest, sig) {
+		t.Error("signature does not verify")
+	}
+}
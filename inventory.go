@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ModuleSource is one module call in the configuration, with a warning when
+// its source is not pinned to a fixed version.
+type ModuleSource struct {
+	Address string `json:"address"`
+	Source  string `json:"source"`
+	Version string `json:"version,omitempty"`
+	Kind    string `json:"kind"`
+	Warning string `json:"warning,omitempty"`
+
+	Latest    string `json:"latest,omitempty"`
+	LatestURL string `json:"latest_url,omitempty"`
+}
+
+// registrySourceRe matches <namespace>/<name>/<provider>, optionally
+// prefixed with a private registry host.
+var registrySourceRe = regexp.MustCompile(`^([a-z0-9.-]+\.[a-z]+/)?[A-Za-z0-9_-]+/[A-Za-z0-9_-]+/[A-Za-z0-9_-]+$`)
+
+var branchRefs = map[string]bool{"main": true, "master": true, "develop": true, "dev": true, "trunk": true, "HEAD": true}
+
+func classifyModuleSource(source, version string) (kind, warning string) {
+	switch {
+	case strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../"):
+		return "local", ""
+	case registrySourceRe.MatchString(source):
+		if version == "" {
+			return "registry", "no version constraint"
+		}
+		if strings.HasPrefix(strings.TrimSpace(version), ">") && !strings.Contains(version, "<") {
+			return "registry", "version has no upper bound"
+		}
+		return "registry", ""
+	case strings.HasPrefix(source, "git::") || strings.HasPrefix(source, "git@") ||
+		strings.HasPrefix(source, "github.com/") || strings.HasPrefix(source, "bitbucket.org/"):
+		_, query, _ := strings.Cut(source, "?")
+		ref := ""
+		for _, param := range strings.Split(query, "&") {
+			if v, ok := strings.CutPrefix(param, "ref="); ok {
+				ref = v
+			}
+		}
+		if ref == "" {
+			return "git", "no ref; follows the default branch"
+		}
+		if branchRefs[ref] {
+			return "git", "ref is the branch " + ref
+		}
+		return "git", ""
+	default:
+		return "other", ""
+	}
+}
+
+// moduleInventory lists every module call in the configuration, depth
+// first in address order.
+func moduleInventory(config PlanConfiguration) []ModuleSource {
+	var result []ModuleSource
+	var walk func(mod ConfigModule, prefix string)
+	walk = func(mod ConfigModule, prefix string) {
+		for _, name := range sortedKeys(mod.ModuleCalls) {
+			call := mod.ModuleCalls[name]
+			address := prefix + "module." + name
+			kind, warning := classifyModuleSource(call.Source, call.VersionConstraint)
+			result = append(result, ModuleSource{
+				Address: address,
+				Source:  call.Source,
+				Version: call.VersionConstraint,
+				Kind:    kind,
+				Warning: warning,
+			})
+			walk(call.Module, address+".")
+		}
+	}
+	walk(config.RootModule, "")
+	return result
+}
+
+// UnpinnedModuleCount is the number of module calls with a warning.
+func (a AnalyzedPlan) UnpinnedModuleCount() int {
+	n := 0
+	for _, m := range a.ModuleCalls {
+		if m.Warning != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// checkModuleUpdates looks up registry modules and records a newer release
+// when the version constraint excludes it. Lookup failures are reported
+// once and otherwise ignored.
+func checkModuleUpdates(ctx context.Context, client *registryClient, inventory []ModuleSource) {
+	reported := false
+	for i := range inventory {
+		m := &inventory[i]
+		if m.Kind != "registry" || m.Version == "" {
+			continue
+		}
+		constraint, err := parseVersionConstraint(m.Version)
+		if err != nil {
+			continue
+		}
+		host, module := splitRegistrySource(m.Source)
+		versions, err := client.moduleVersions(ctx, host, module)
+		if err != nil {
+			if !reported {
+				fmt.Printf("⚠️  Could not check module updates: %v\n", err)
+				reported = true
+			}
+			continue
+		}
+		latest, ok := latestVersion(versions, nil)
+		if !ok || constraint.allows(latest) {
+			continue
+		}
+		m.Latest = latest.String()
+		m.LatestURL = fmt.Sprintf("https://%s/modules/%s/%s", host, module, m.Latest)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassifyModuleSource(t *testing.T) {
+	tests := []struct {
+		source, version string
+		kind            string
+		unpinned        bool
+	}{
+		{"./modules/vpc", "", "local", false},
+		{"../shared", "", "local", false},
+		{"terraform-aws-modules/vpc/aws", "", "registry", true},
+		{"terraform-aws-modules/vpc/aws", "5.1.2", "registry", false},
+		{"terraform-aws-modules/vpc/aws", "~> 5.0", "registry", false},
+		{"terraform-aws-modules/vpc/aws", ">= 5.0", "registry", true},
+		{"app.terraform.io/acme/network/aws", "1.0.0", "registry", false},
+		{"git::https://example.com/network.git", "", "git", true},
+		{"git::https://example.com/network.git?ref=v1.2.0", "", "git", false},
+		{"github.com/acme/terraform-modules//network?ref=main", "", "git", true},
+		{"git@github.com:acme/modules.git?depth=1&ref=3f2a9c1", "", "git", false},
+		{"s3::https://s3.amazonaws.com/bucket/vpc.zip", "", "other", false},
+	}
+	for _, tt := range tests {
+		kind, warning := classifyModuleSource(tt.source, tt.version)
+		if kind != tt.kind || (warning != "") != tt.unpinned {
+			t.Errorf("classifyModuleSource(%q, %q) = %q, %q; want kind %q, unpinned %v", tt.source, tt.version, kind, warning, tt.kind, tt.unpinned)
+		}
+	}
+}
+
+func TestModuleInventory(t *testing.T) {
+	config := PlanConfiguration{RootModule: ConfigModule{ModuleCalls: map[string]ConfigModuleCall{
+		"vpc": {Source: "terraform-aws-modules/vpc/aws", VersionConstraint: "5.1.2"},
+		"app": {Source: "./app", Module: ConfigModule{ModuleCalls: map[string]ConfigModuleCall{
+			"db": {Source: "git::https://example.com/db.git"},
+		}}},
+	}}}
+	inventory := moduleInventory(config)
+
+	var got []string
+	for _, m := range inventory {
+		got = append(got, m.Address)
+	}
+	if strings.Join(got, ",") != "module.app,module.app.module.db,module.vpc" {
+		t.Errorf("inventory order = %v", got)
+	}
+
+	analyzed := AnalyzedPlan{ModuleCalls: inventory}
+	if n := analyzed.UnpinnedModuleCount(); n != 1 {
+		t.Errorf("UnpinnedModuleCount = %d, want 1", n)
+	}
+	html := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+	if !strings.Contains(html, "Modules (3, 1 not pinned)") {
+		t.Error("report does not show the module inventory")
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// LintIssue is a configuration hygiene problem found in the configuration
+// section of a plan.
+type LintIssue struct {
+	Kind    string `json:"kind"`
+	Module  string `json:"module"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// moduleReferences returns every reference made by the expressions of a
+// module's resources, outputs and module calls.
+func moduleReferences(mod ConfigModule) []string {
+	var refs []string
+	for _, res := range mod.Resources {
+		refs = append(refs, extractReferences(res.Expressions)...)
+		refs = append(refs, extractReferences(res.CountExpression)...)
+		refs = append(refs, extractReferences(res.ForEachExpression)...)
+	}
+	for _, out := range mod.Outputs {
+		refs = append(refs, extractReferences(out.Expression)...)
+	}
+	for _, call := range mod.ModuleCalls {
+		refs = append(refs, extractReferences(call.Expressions)...)
+		refs = append(refs, extractReferences(call.CountExpression)...)
+		refs = append(refs, extractReferences(call.ForEachExpression)...)
+	}
+	return refs
+}
+
+// lintConfiguration reports variables nobody reads, child module outputs
+// the caller never uses and configured providers no resource uses. Root
+// module outputs are the configuration's interface and always count as
+// used.
+func lintConfiguration(config PlanConfiguration) []LintIssue {
+	var issues []LintIssue
+	usedProviders := map[string]bool{}
+	lintModule(config.RootModule, "root", usedProviders, &issues)
+
+	var keys []string
+	for key := range config.ProviderConfig {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if usedProviders[key] {
+			continue
+		}
+		p := config.ProviderConfig[key]
+		module := p.ModuleAddress
+		if module == "" {
+			module = "root"
+		}
+		name := p.Name
+		if p.Alias != "" {
+			name += "." + p.Alias
+		}
+		issues = append(issues, LintIssue{Kind: "unused-provider", Module: module, Name: name,
+			Message: fmt.Sprintf("provider %q is configured but no resource uses it", name)})
+	}
+	return issues
+}
+
+func lintModule(mod ConfigModule, address string, usedProviders map[string]bool, issues *[]LintIssue) {
+	for _, res := range mod.Resources {
+		usedProviders[res.ProviderConfigKey] = true
+	}
+
+	usedVars := map[string]bool{}
+	usedOutputs := map[string]bool{} // "call.output", or "call" when the whole module object is used
+	for _, ref := range moduleReferences(mod) {
+		parts := strings.Split(ref, ".")
+		switch {
+		case parts[0] == "var" && len(parts) >= 2:
+			usedVars[parts[1]] = true
+		case parts[0] == "module" && len(parts) >= 3:
+			usedOutputs[parts[1]+"."+parts[2]] = true
+		case parts[0] == "module" && len(parts) == 2:
+			usedOutputs[parts[1]] = true
+		}
+	}
+
+	for _, name := range sortedKeys(mod.Variables) {
+		if !usedVars[name] {
+			*issues = append(*issues, LintIssue{Kind: "unused-variable", Module: address, Name: name,
+				Message: fmt.Sprintf("variable %q is declared but never used", name)})
+		}
+	}
+
+	for _, callName := range sortedKeys(mod.ModuleCalls) {
+		call := mod.ModuleCalls[callName]
+		childAddress := "module." + callName
+		if address != "root" {
+			childAddress = address + ".module." + callName
+		}
+		if !usedOutputs[callName] {
+			for _, out := range sortedKeys(call.Module.Outputs) {
+				if !usedOutputs[callName+"."+out] {
+					*issues = append(*issues, LintIssue{Kind: "unused-output", Module: childAddress, Name: out,
+						Message: fmt.Sprintf("output %q is not used by the calling module", out)})
+				}
+			}
+		}
+		lintModule(call.Module, childAddress, usedProviders, issues)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// handleLint runs the configuration lint pass on a plan and fails when it
+// finds anything.
+func handleLint(ctx context.Context, args []string) error {
+	planFile := ""
+	rest := []string{}
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name != "--plan" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return fmt.Errorf("flag %s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		planFile = value
+	}
+
+	_, tfArgs, err := parseOptions(rest)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if planFile != "" {
+		data, err = os.ReadFile(planFile)
+		if err != nil {
+			return fmt.Errorf("error reading plan file: %v", err)
+		}
+	} else {
+		data, err = runTerraformPlan(ctx, "", tfArgs)
+		if err != nil {
+			return err
+		}
+	}
+	plan, err := parsePlanJSON(data)
+	if err != nil {
+		return err
+	}
+
+	issues := lintConfiguration(plan.Configuration)
+	if len(issues) == 0 {
+		fmt.Println("✅ No configuration lint issues")
+		return nil
+	}
+	for _, issue := range issues {
+		fmt.Printf("  %s: %s\n", issue.Module, issue.Message)
+	}
+	return fmt.Errorf("%d configuration lint issues found", len(issues))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLintConfiguration(t *testing.T) {
+	configJSON := `{
+	  "provider_config": {
+	    "aws": {"name": "aws", "full_name": "registry.terraform.io/hashicorp/aws"},
+	    "aws.west": {"name": "aws", "alias": "west"},
+	    "google": {"name": "google"}
+	  },
+	  "root_module": {
+	    "variables": {"region": {}, "unused_root": {}, "instances": {}},
+	    "resources": [{
+	      "address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web",
+	      "provider_config_key": "aws",
+	      "expressions": {"ami": {"references": ["module.net.subnet_id", "module.net"]}, "region": {"references": ["var.region"]}},
+	      "count_expression": {"references": ["var.instances"]}
+	    }],
+	    "outputs": {"vpc": {"expression": {"references": ["module.vpc.id", "module.vpc"]}}},
+	    "module_calls": {
+	      "vpc": {"source": "./vpc", "module": {
+	        "variables": {"cidr": {}, "name": {}},
+	        "resources": [{"address": "aws_vpc.this", "type": "aws_vpc", "name": "this", "provider_config_key": "aws",
+	          "expressions": {"cidr_block": {"references": ["var.cidr"]}}}],
+	        "outputs": {"id": {"expression": {"references": ["aws_vpc.this.id"]}}, "arn": {"expression": {"references": ["aws_vpc.this.arn"]}}}
+	      }},
+	      "net": {"source": "./net", "module": {
+	        "outputs": {"subnet_id": {}, "route_table_id": {}}
+	      }}
+	    }
+	  }
+	}`
+	var config PlanConfiguration
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, issue := range lintConfiguration(config) {
+		got = append(got, issue.Kind+" "+issue.Module+" "+issue.Name)
+	}
+	want := []string{
+		"unused-variable root unused_root",
+		// module.net is referenced as a whole object, so all its outputs count as used
+		"unused-variable module.vpc name",
+		"unused-provider root aws.west",
+		"unused-provider root google",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lint issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLintConfiguration_UnusedOutput(t *testing.T) {
+	config := PlanConfiguration{RootModule: ConfigModule{
+		ModuleCalls: map[string]ConfigModuleCall{"db": {Module: ConfigModule{
+			Outputs: map[string]ConfigOutput{"endpoint": {}, "port": {}},
+		}}},
+		Outputs: map[string]ConfigOutput{"endpoint": {Expression: map[string]interface{}{"references": []interface{}{"module.db.endpoint"}}}},
+	}}
+	issues := lintConfiguration(config)
+	if len(issues) != 1 || issues[0].Kind != "unused-output" || issues[0].Module != "module.db" || issues[0].Name != "port" {
+		t.Errorf("unexpected issues %+v", issues)
+	}
+}
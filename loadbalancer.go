@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// LoadBalancerChange is a change to how traffic reaches a service: a load
+// balancer, listener, routing rule, target group, certificate or health
+// check. Their settings are spread over many resources and a mistake in any
+// of them takes the service down, so they are summarized in one panel.
+type LoadBalancerChange struct {
+	Address  string          `json:"address"`
+	Kind     string          `json:"kind"`
+	Action   string          `json:"action"`
+	Settings []SettingChange `json:"settings,omitempty"`
+}
+
+// SettingChange is the old and new value of a setting; one of them is
+// empty when the resource is created or deleted.
+type SettingChange struct {
+	Name   string `json:"name"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// loadBalancerSettings are the settings shown per resource type, as paths
+// into the resource's values; "health_check.path" reads path from the
+// first health_check block.
+var loadBalancerSettings = map[string]struct {
+	kind     string
+	settings []string
+}{
+	"aws_lb":                         {"load balancer", []string{"load_balancer_type", "internal", "subnets", "security_groups", "idle_timeout"}},
+	"aws_lb_listener":                {"listener", []string{"port", "protocol", "certificate_arn", "ssl_policy", "alpn_policy", "default_action.type", "default_action.target_group_arn", "default_action.redirect.protocol"}},
+	"aws_lb_listener_rule":           {"listener rule", []string{"priority", "action.type", "action.target_group_arn", "condition.path_pattern.values", "condition.host_header.values"}},
+	"aws_lb_listener_certificate":    {"certificate", []string{"certificate_arn"}},
+	"aws_lb_target_group":            {"target group", []string{"port", "protocol", "target_type", "deregistration_delay", "health_check.path", "health_check.port", "health_check.protocol", "health_check.matcher", "health_check.interval", "health_check.timeout", "health_check.healthy_threshold", "health_check.unhealthy_threshold", "stickiness.enabled"}},
+	"aws_lb_target_group_attachment": {"target", []string{"target_id", "port"}},
+
+	"google_compute_backend_service":        {"backend service", []string{"protocol", "port_name", "timeout_sec", "health_checks", "backend.group"}},
+	"google_compute_health_check":           {"health check", []string{"check_interval_sec", "timeout_sec", "healthy_threshold", "unhealthy_threshold", "http_health_check.port", "http_health_check.request_path", "https_health_check.port", "https_health_check.request_path", "tcp_health_check.port"}},
+	"google_compute_target_https_proxy":     {"https proxy", []string{"ssl_certificates", "certificate_map", "url_map", "ssl_policy"}},
+	"google_compute_global_forwarding_rule": {"forwarding rule", []string{"port_range", "ip_protocol", "target", "ip_address"}},
+	"google_compute_forwarding_rule":        {"forwarding rule", []string{"ports", "port_range", "ip_protocol", "backend_service", "target"}},
+
+	"azurerm_lb_rule":  {"load balancing rule", []string{"protocol", "frontend_port", "backend_port", "probe_id", "backend_address_pool_ids"}},
+	"azurerm_lb_probe": {"health check", []string{"protocol", "port", "request_path", "interval_in_seconds", "number_of_probes"}},
+}
+
+func init() {
+	// The aws_alb* names are aliases of the aws_lb* resources.
+	for _, t := range []string{"aws_lb", "aws_lb_listener", "aws_lb_listener_rule", "aws_lb_listener_certificate", "aws_lb_target_group", "aws_lb_target_group_attachment"} {
+		loadBalancerSettings[strings.Replace(t, "aws_lb", "aws_alb", 1)] = loadBalancerSettings[t]
+	}
+}
+
+// LoadBalancerChanges lists the changes of load balancer resources with the
+// settings that changed, or all of their settings when they are created or
+// deleted.
+func (a AnalyzedPlan) LoadBalancerChanges() []LoadBalancerChange {
+	var changes []LoadBalancerChange
+	for _, m := range a.Modules {
+		for _, r := range m.Resources {
+			spec, ok := loadBalancerSettings[r.Type]
+			if !ok || r.Action == "no-op" || r.Action == "read" {
+				continue
+			}
+			c := LoadBalancerChange{Address: r.Address, Kind: spec.kind, Action: r.Action}
+			if r.Replace {
+				c.Action = "replace"
+			}
+			c.Settings = changedSettings(r, spec.settings)
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// changedSettings compares the named settings of a resource before and
+// after the change, leaving out those that stay the same.
+func changedSettings(r ResourceAnalysis, names []string) []SettingChange {
+	var settings []SettingChange
+	update := r.Before != nil && r.After != nil
+	for _, name := range names {
+		before := settingValue(valueAt(r.Before, name))
+		after := settingValue(valueAt(r.After, name))
+		if after == "" && r.After != nil && slices.Contains(r.Unknown, strings.Split(name, ".")[0]) {
+			after = "(known after apply)"
+		}
+		if before == after || before == "" && after == "" {
+			continue
+		}
+		if update && before == "" {
+			before = "(none)"
+		} else if update && after == "" {
+			after = "(none)"
+		}
+		settings = append(settings, SettingChange{Name: name, Before: before, After: after})
+	}
+	return settings
+}
+
+// valueAt follows a dotted path into values, descending into the first
+// element of nested blocks.
+func valueAt(values map[string]interface{}, path string) interface{} {
+	var v interface{} = values
+	for _, key := range strings.Split(path, ".") {
+		if list, ok := v.([]interface{}); ok && len(list) > 0 {
+			v = list[0]
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+// settingValue renders a setting compactly for the panel: scalars as they
+// are, lists of scalars separated by commas and other values as JSON.
+func settingValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%g", v)
+	case bool:
+		return fmt.Sprint(v)
+	case []interface{}:
+		var parts []string
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				data, _ := json.Marshal(v)
+				return string(data)
+			}
+			parts = append(parts, settingValue(item))
+		}
+		return strings.Join(parts, ", ")
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
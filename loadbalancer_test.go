@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadBalancerChanges(t *testing.T) {
+	healthCheck := func(path string) []interface{} {
+		return []interface{}{map[string]interface{}{"path": path, "matcher": "200", "interval": 30.0}}
+	}
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{
+		{Address: "aws_lb_listener.https", Type: "aws_lb_listener", Action: "update",
+			Before: map[string]interface{}{"port": 443.0, "protocol": "HTTPS", "certificate_arn": "arn:aws:acm:cert/old", "ssl_policy": "ELBSecurityPolicy-2016-08"},
+			After:  map[string]interface{}{"port": 443.0, "protocol": "HTTPS", "certificate_arn": "arn:aws:acm:cert/new", "ssl_policy": "ELBSecurityPolicy-TLS13-1-2-2021-06"}},
+		{Address: "aws_alb_target_group.api", Type: "aws_alb_target_group", Action: "update",
+			Before: map[string]interface{}{"port": 8080.0, "protocol": "HTTP", "health_check": healthCheck("/")},
+			After:  map[string]interface{}{"port": 8080.0, "protocol": "HTTP", "health_check": healthCheck("/healthz"), "deregistration_delay": "30"}},
+		{Address: "aws_lb_listener.http", Type: "aws_lb_listener", Action: "create", Unknown: []string{"certificate_arn"},
+			After: map[string]interface{}{"port": 80.0, "protocol": "HTTP", "default_action": []interface{}{map[string]interface{}{"type": "redirect", "redirect": []interface{}{map[string]interface{}{"protocol": "HTTPS"}}}}}},
+		{Address: "aws_lb_target_group.old", Type: "aws_lb_target_group", Action: "delete",
+			Before: map[string]interface{}{"port": 80.0, "protocol": "HTTP"}},
+		{Address: "aws_lb.main", Type: "aws_lb", Action: "no-op"},
+	}}}}
+
+	want := []LoadBalancerChange{
+		{Address: "aws_lb_listener.https", Kind: "listener", Action: "update", Settings: []SettingChange{
+			{Name: "certificate_arn", Before: "arn:aws:acm:cert/old", After: "arn:aws:acm:cert/new"},
+			{Name: "ssl_policy", Before: "ELBSecurityPolicy-2016-08", After: "ELBSecurityPolicy-TLS13-1-2-2021-06"},
+		}},
+		{Address: "aws_alb_target_group.api", Kind: "target group", Action: "update", Settings: []SettingChange{
+			{Name: "deregistration_delay", Before: "(none)", After: "30"},
+			{Name: "health_check.path", Before: "/", After: "/healthz"},
+		}},
+		{Address: "aws_lb_listener.http", Kind: "listener", Action: "create", Settings: []SettingChange{
+			{Name: "port", After: "80"},
+			{Name: "protocol", After: "HTTP"},
+			{Name: "certificate_arn", After: "(known after apply)"},
+			{Name: "default_action.type", After: "redirect"},
+			{Name: "default_action.redirect.protocol", After: "HTTPS"},
+		}},
+		{Address: "aws_lb_target_group.old", Kind: "target group", Action: "delete", Settings: []SettingChange{
+			{Name: "port", Before: "80"},
+			{Name: "protocol", Before: "HTTP"},
+		}},
+	}
+	if got := analyzed.LoadBalancerChanges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadBalancerChanges() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestSettingValue(t *testing.T) {
+	for _, tt := range []struct {
+		v    interface{}
+		want string
+	}{
+		{nil, ""},
+		{443.0, "443"},
+		{[]interface{}{"subnet-a", "subnet-b"}, "subnet-a, subnet-b"},
+		{[]interface{}{map[string]interface{}{"a": 1.0}}, `[{"a":1}]`},
+	} {
+		if got := settingValue(tt.v); got != tt.want {
+			t.Errorf("settingValue(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
@@ -2,35 +2,59 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"html/template"
-	"net/http"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
-	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type TerraformPlan struct {
-	FormatVersion    string            `json:"format_version"`
-	TerraformVersion string            `json:"terraform_version"`
-	PlannedValues    PlannedValues     `json:"planned_values"`
-	ResourceChanges  []ResourceChange  `json:"resource_changes"`
-	Configuration    PlanConfiguration `json:"configuration"`
+	FormatVersion    string                  `json:"format_version"`
+	TerraformVersion string                  `json:"terraform_version"`
+	PlannedValues    PlannedValues           `json:"planned_values"`
+	ResourceChanges  []ResourceChange        `json:"resource_changes"`
+	ResourceDrift    []ResourceChange        `json:"resource_drift,omitempty"`
+	OutputChanges    map[string]OutputChange `json:"output_changes,omitempty"`
+	Configuration    PlanConfiguration       `json:"configuration"`
+
+	// FormatWarnings describe parts of the plan this version of tfviz may
+	// not understand, see checkPlanFormat.
+	FormatWarnings []string `json:"-"`
 }
 
 type PlanConfiguration struct {
-	RootModule ConfigModule `json:"root_module"`
+	ProviderConfig map[string]ConfigProvider `json:"provider_config,omitempty"`
+	RootModule     ConfigModule              `json:"root_module"`
+}
+
+type ConfigProvider struct {
+	Name              string                 `json:"name"`
+	FullName          string                 `json:"full_name,omitempty"`
+	Alias             string                 `json:"alias,omitempty"`
+	ModuleAddress     string                 `json:"module_address,omitempty"`
+	VersionConstraint string                 `json:"version_constraint,omitempty"`
+	Expressions       map[string]interface{} `json:"expressions,omitempty"`
 }
 
 type ConfigModule struct {
 	Resources   []ConfigResource            `json:"resources,omitempty"`
 	ModuleCalls map[string]ConfigModuleCall  `json:"module_calls,omitempty"`
 	Outputs     map[string]ConfigOutput      `json:"outputs,omitempty"`
+	Variables   map[string]ConfigVariable    `json:"variables,omitempty"`
+}
+
+type ConfigVariable struct {
+	Description string `json:"description,omitempty"`
 }
 
 type ConfigOutput struct {
@@ -38,15 +62,23 @@ type ConfigOutput struct {
 }
 
 type ConfigModuleCall struct {
-	Expressions map[string]interface{} `json:"expressions,omitempty"`
-	Module      ConfigModule           `json:"module"`
+	Source            string                 `json:"source,omitempty"`
+	VersionConstraint string                 `json:"version_constraint,omitempty"`
+	Expressions       map[string]interface{} `json:"expressions,omitempty"`
+	CountExpression   map[string]interface{} `json:"count_expression,omitempty"`
+	ForEachExpression map[string]interface{} `json:"for_each_expression,omitempty"`
+	Module            ConfigModule           `json:"module"`
 }
 
 type ConfigResource struct {
-	Address     string                 `json:"address"`
-	Type        string                 `json:"type"`
-	Name        string                 `json:"name"`
-	Expressions map[string]interface{} `json:"expressions"`
+	Address           string                 `json:"address"`
+	Mode              string                 `json:"mode,omitempty"`
+	Type              string                 `json:"type"`
+	Name              string                 `json:"name"`
+	ProviderConfigKey string                 `json:"provider_config_key,omitempty"`
+	Expressions       map[string]interface{} `json:"expressions"`
+	CountExpression   map[string]interface{} `json:"count_expression,omitempty"`
+	ForEachExpression map[string]interface{} `json:"for_each_expression,omitempty"`
 }
 
 type PlannedValues struct {
@@ -76,6 +108,7 @@ type ResourceChange struct {
 	Name          string `json:"name"`
 	ProviderName  string `json:"provider_name"`
 	Change        Change `json:"change"`
+	ActionReason  string `json:"action_reason,omitempty"`
 }
 
 type Change struct {
@@ -83,6 +116,13 @@ type Change struct {
 	Before       map[string]interface{} `json:"before"`
 	After        map[string]interface{} `json:"after"`
 	AfterUnknown map[string]interface{} `json:"after_unknown"`
+	ReplacePaths [][]interface{}        `json:"replace_paths,omitempty"`
+}
+
+// OutputChange is the planned change of a root output. Its values are left
+// out as they may be of any type.
+type OutputChange struct {
+	Actions []string `json:"actions"`
 }
 
 type AnalyzedPlan struct {
@@ -90,12 +130,40 @@ type AnalyzedPlan struct {
 	Modules          []ModuleAnalysis `json:"modules"`
 	Timestamp        string           `json:"timestamp"`
 	TerraformVersion string           `json:"terraform_version"`
+
+	ChangeWindow     *ChangeWindowStatus `json:"change_window,omitempty"`
+	Findings         []Finding           `json:"findings,omitempty"`
+	Acknowledged     []Finding           `json:"acknowledged,omitempty"`
+	LintIssues       []LintIssue         `json:"lint_issues,omitempty"`
+	ModuleCalls      []ModuleSource      `json:"module_calls,omitempty"`
+	ProviderVersions []ProviderVersion   `json:"provider_versions,omitempty"`
+	Timeline         *Timeline           `json:"timeline,omitempty"`
+	Imports          []ImportSuggestion  `json:"imports,omitempty"`
+	FormatWarnings   []string            `json:"format_warnings,omitempty"`
+	Narrative        *PlanNarrative      `json:"narrative,omitempty"`
+	RemoteStates     []RemoteState       `json:"remote_states,omitempty"`
+	StackOutputs     []StackOutput       `json:"stack_outputs,omitempty"`
+	OutputImpacts    []OutputImpact      `json:"output_impacts,omitempty"`
+	Drift            []DriftEntry        `json:"drift,omitempty"`
+	Certificates     []CertificateChange `json:"certificates,omitempty"`
+	Reviews          []ReviewEntry       `json:"reviews,omitempty"`
+	Git              *GitContext         `json:"git,omitempty"`
+	Reviewers        *Reviewers          `json:"reviewers,omitempty"`
+	Metrics          *PlanMetrics        `json:"metrics,omitempty"`
 }
 
 type PlanSummary struct {
 	TotalResources int            `json:"total_resources"`
 	Actions        map[string]int `json:"actions"`
 	Providers      []string       `json:"providers"`
+	Renames        int            `json:"renames,omitempty"`
+
+	UnknownResources  int `json:"unknown_resources,omitempty"`
+	UnknownAttributes int `json:"unknown_attributes,omitempty"`
+
+	// HiddenModules counts the modules left out because none of their
+	// resources change; see analysisOptions.includeUnchanged.
+	HiddenModules int `json:"hidden_modules,omitempty"`
 }
 
 type ModuleAnalysis struct {
@@ -123,12 +191,108 @@ type ResourceAnalysis struct {
 	Action             string                 `json:"action"`
 	Changes            []ChangeDetail         `json:"changes,omitempty"`
 	Impact             string                 `json:"impact"`
+	ImpactReason       string                 `json:"impact_reason,omitempty"`
 	Description        string                 `json:"description"`
 	DiffLines          []DiffLine             `json:"diff_lines"`
+	Replace            bool                   `json:"replace,omitempty"`
+	ActionReason       string                 `json:"action_reason,omitempty"`
+	Runbook            string                 `json:"runbook,omitempty"`
+	RiskNote           string                 `json:"risk_note,omitempty"`
+	Stateful           bool                   `json:"stateful,omitempty"`
 	PolicyDocumentJSON string                 `json:"policy_document_json,omitempty"`
-	After              map[string]interface{} `json:"after,omitempty"`
+	Before             map[string]interface{} `json:"-"`
+	After              map[string]interface{} `json:"-"`
+	ConstructPath      string                 `json:"construct_path,omitempty"`
+	Source             *SourceLocation        `json:"source,omitempty"`
+	Target             *ProviderTarget        `json:"target,omitempty"`
+	// Fingerprint identifies the change across plans, see changeFingerprint.
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	DependsOn  []string            `json:"depends_on,omitempty"`
+	References []ResourceReference `json:"references,omitempty"`
+
+	// RenamedTo and RenamedFrom link a destroyed and a created resource
+	// that are likely the same object under a new address.
+	RenamedTo   string `json:"renamed_to,omitempty"`
+	RenamedFrom string `json:"renamed_from,omitempty"`
+
+	// Unknown lists the attributes known only after apply; the ones other
+	// resources reference are repeated in UnknownReferenced.
+	Unknown           []string            `json:"unknown,omitempty"`
+	UnknownReferenced []string            `json:"unknown_referenced,omitempty"`
+	UnknownSources    []UnknownProvenance `json:"unknown_sources,omitempty"`
+}
+
+// ResourceReference records which attribute of a resource refers to another
+// resource in the configuration, e.g. subnet_id → aws_subnet.a.id.
+type ResourceReference struct {
+	Attribute  string `json:"attribute"`
+	Expression string `json:"expression"`
+	Target     string `json:"target"`
+}
+
+func (r ResourceReference) String() string {
+	return r.Attribute + " → " + r.Expression
+}
+
+// DetailsID is a stable HTML id for the resource's details panel.
+func (r ResourceAnalysis) DetailsID() string {
+	return hashID("res-", r.Address)
+}
+
+// ContentID is a stable HTML id for the list of the module's resources.
+func (m ModuleAnalysis) ContentID() string {
+	return hashID("mod-", m.Address)
+}
+
+// hashID derives an HTML id from an address. Addresses can contain quotes,
+// spaces and brackets, so the id is a hash of it.
+func hashID(prefix, address string) string {
+	h := fnv.New64a()
+	h.Write([]byte(address))
+	return prefix + strconv.FormatUint(h.Sum64(), 36)
+}
+
+// AnchorID is the resource's permalink anchor: its address, with any
+// whitespace (only possible inside for_each keys) replaced by underscores
+// because HTML ids cannot contain it.
+func (r ResourceAnalysis) AnchorID() string {
+	return strings.Join(strings.Fields(r.Address), "_")
+}
+
+// BeforeJSON and AfterJSON are the resource's complete values before and
+// after the change as indented JSON, or "" when the object does not exist
+// on that side.
+func (r ResourceAnalysis) BeforeJSON() string { return indentJSON(r.Before) }
+func (r ResourceAnalysis) AfterJSON() string  { return indentJSON(r.After) }
+
+func indentJSON(v map[string]interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// ApplyCommand is the terraform apply command limited to this resource, or
+// "" when it has no changes.
+func (r ResourceAnalysis) ApplyCommand() string {
+	if r.Action == "no-op" {
+		return ""
+	}
+	return targetCommand("apply", []string{r.Address})
+}
 
-	DependsOn []string `json:"depends_on,omitempty"`
+// DiffText is the resource's diff as plain text, for pasting into tickets.
+func (r ResourceAnalysis) DiffText() string {
+	lines := make([]string, 0, len(r.DiffLines))
+	for _, l := range r.DiffLines {
+		lines = append(lines, l.Text)
+	}
+	return strings.Join(lines, "\n")
 }
 
 type ChangeDetail struct {
@@ -136,6 +300,10 @@ type ChangeDetail struct {
 	Before interface{} `json:"before"`
 	After  interface{} `json:"after"`
 	Action string      `json:"action"`
+	// Cosmetic marks an update that only changes formatting, see isCosmetic.
+	Cosmetic bool `json:"cosmetic,omitempty"`
+	// Size counts the elements of a collection attribute, see collectionSizes.
+	Size *CollectionSize `json:"size,omitempty"`
 }
 
 func main() {
@@ -147,153 +315,654 @@ func main() {
 	command := os.Args[1]
 	args := os.Args[2:]
 
+	ctx, stop := notifySignals(context.Background())
+	defer stop()
+
+	args, err := envArgs(command, args)
+	if err == nil {
+		args, err = setupTerraform(ctx, args)
+	}
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		stop()
+		os.Exit(1)
+	}
+
 	if command == "plan" {
-		handlePlan(args)
+		err = handlePlan(ctx, args)
+	} else if command == "build" {
+		err = handleBuild(ctx, args)
+	} else if command == "ci-compare" {
+		err = handleCICompare(ctx, args)
+	} else if command == "check-idempotent" {
+		err = handleCheckIdempotent(ctx, args)
+	} else if command == "validate" {
+		err = handleValidate(ctx, args)
+	} else if command == "show" {
+		err = handleShow(ctx, args)
+	} else if command == "test" {
+		err = handleTest(ctx, args)
+	} else if command == "lint" {
+		err = handleLint(ctx, args)
+	} else if command == "targets" {
+		err = handleTargets(ctx, args)
+	} else if command == "imports" {
+		err = handleImports(ctx, args)
+	} else if command == "baseline" {
+		err = handleBaseline(ctx, args)
+	} else if command == "export" {
+		err = handleExport(ctx, args)
+	} else if command == "audit" {
+		err = handleAudit(args)
+	} else if command == "version" {
+		err = handleVersion(args)
+	} else if command == "cdktf" {
+		err = handleCDKTF(ctx, args)
+	} else if command == "mcp" {
+		err = handleMCP(ctx, args)
 	} else if command == "demo" {
-		if len(args) < 1 {
+		opts, rest, perr := parseOptions(args)
+		if perr != nil {
+			fmt.Printf("❌ %v\n", perr)
+			os.Exit(1)
+		}
+		if len(rest) < 1 {
 			fmt.Println("Usage: tfviz demo <json-file>")
 			os.Exit(1)
 		}
-		generateHTMLFromJSON(args[0], true)
+		opts.showGraph = true
+		err = generateHTMLFromJSON(ctx, rest[0], opts)
 	} else {
 		fmt.Println("❗️ Unsupported command:", command)
 		fmt.Println("Please use 'tfviz plan' to generate a plan visualization.")
 		printUsage()
 		os.Exit(1)
 	}
+
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		stop()
+		os.Exit(1)
+	}
 }
 
 func printUsage() {
-	fmt.Println(`tfviz - Terraform Plan Visualizer
+	fmt.Print(`tfviz - Terraform Plan Visualizer
 
 Usage:
-  tfviz plan [options]    Run terraform plan and generate HTML visualization
+  tfviz plan [options] [-- terraform flags]
+                          Run terraform plan and generate HTML visualization
+  tfviz plan --workspaces <list> [options] [-- terraform flags]
+                          Plan each workspace, e.g. dev,stage,prod, and compare them
+  tfviz show [options] <plan.json>...
+                          Serve the report of existing plan JSON files; several plans are
+                          shown as tabs with a summary across them. "-" reads stdin
+  tfviz build --envs <list> --out <dir>
+                          Build a static dashboard with one report per environment
+  tfviz ci-compare --previous <file|url> [--plan <json>] [--save <file>] [--fail-on-stateful]
+                          Report destructive changes not present in the previous analysis
+  tfviz check-idempotent [--apply] [-- terraform flags]
+                          Plan twice and report changes that differ between the runs; with
+                          --apply, apply first and report perpetual diffs (changes still
+                          planned afterwards), which planning twice alone does not catch
+  tfviz validate          Run terraform validate and render its diagnostics
+  tfviz test [options] [-- terraform test flags]
+                          Run terraform test and render the result and planned changes of each run
+  tfviz lint [--plan <json>]
+                          Report unused variables, module outputs and providers
+  tfviz targets [--plan <json>] [--match <pattern>]... [--action create,update,delete] [--replan]
+                          Print terraform apply -target arguments for the selected changes,
+                          or re-plan with them and serve the report
+  tfviz imports [--plan <json>] [--format blocks|commands]
+                          Print import blocks for removed or externally deleted resources
+  tfviz cdktf [--app-dir <dir>] [--stacks <list>] [--skip-synth] [options] [-- terraform flags]
+                          Run cdktf synth, plan every stack and serve one report that shows
+                          the construct behind each change
+  tfviz baseline [--plan <json>] [--reason <text>] [--baseline <file>]
+                          Acknowledge the current findings, deletions and replacements so
+                          they no longer fail CI or clutter the report
+  tfviz export --out <dir> [--addresses <file>] [--plan <json>]
+                          Write the before and after values and the diff of each listed
+                          resource (default: every change) to its own JSON file
+  tfviz audit --log <file> [--format json|csv]
+                          Export the report access log written by --audit-log
+  tfviz version [--json]  Show tfviz build information and detected terraform/tofu binaries
+  tfviz mcp [--config <file>] [--profile <name>]
+                          Serve plan analysis tools to AI assistants over MCP (stdio)
+
+Options:
+  -g, --graph             Show the resource dependency graph
+  --badge <file>          Write a shields.io endpoint badge describing the plan
+  --baseline <file>       Read acknowledged findings and changes from this file
+                          (default .tfviz-baseline.json)
+  --owners <file>         Write who needs to review the plan, from CODEOWNERS and "owners" config
+  --sign-key <file>       Sign checksums of written artifacts with an Ed25519 PEM key
+  --cache                 Reuse the last plan when the configuration has not changed
+  --cache-ttl <dur>       Maximum age of a cached plan (default 1h)
+  --check-updates         Look up newer module and provider versions in the Terraform registry
+  --summarize             Ask a language model for a summary of the plan (see "summary" config)
+  --servicenow            Open or update a ServiceNow change request for the plan
+                          (see "servicenow" config)
+  --report-url <url>      Link to the report in the change request, e.g. a CI artifact
+  --changed-only          Draw only changed resources and their direct neighbours in the graph
+  --include-unchanged-modules
+                          List modules in which nothing changes, and count their resources
+  --graph-focus <addr>    Draw only the neighbourhood of this resource (implies --graph)
+  --graph-depth <n>       Hops around --graph-focus to include (default 2)
+  --config <file>         Read project settings from this file (default .tfviz.json)
+  --profile <name>        Apply a named profile from the config file
+  --sort <order>          Order resources by address (default), impact, action or type
+  --init                  Run terraform init -input=false first if the directory is not initialised
+  --retries <n>           Run terraform plan/show again up to n times after transient failures
+                          such as API throttling or registry timeouts (default 0)
+  --retry-backoff <dur>   Wait before the first retry, doubled after each one (default 10s)
+  --timeout <dur>         Stop terraform plan/show if they take longer than this (e.g. 20m)
+  --install-terraform[=terraform|tofu]
+                          Download a release matching required_version if the binary on PATH
+                          is missing or does not match
+  --audit-log <file>      Append every report access to this JSON Lines file
+  --trust-proxy-headers   Take the user from X-Forwarded-User or X-Auth-Request-User; only
+                          use behind an authenticating proxy
+  --reviews <file>        Keep review comments and sign-offs in this file (default in the
+                          user cache directory)
+  --listen <addr:port>    Address to serve the report on (default 127.0.0.1:9876)
+  --share-ttl <dur>       Require a generated share link that expires after this long (e.g. 24h)
+  --shutdown-after <dur>  Stop the server after this long without activity (e.g. 30s)
+  --tls-cert <file>       Serve the report over HTTPS using this certificate
+  --tls-key <file>        Private key for --tls-cert
+  --tls-self-signed       Serve over HTTPS with a generated self-signed certificate
+  --browser <cmd>         Open the report with this command instead of $BROWSER or the
+                          platform default
+  --no-browser            Only print the report URL
+  --share[=cloudflared|ngrok|ssh|<name>]
+                          Make the report reachable through a temporary tunnel and print a
+                          link for reviewers (implies --share-ttl 8h unless given)
+
+Arguments after -- are passed to terraform unchanged, e.g.
+  tfviz plan --graph -- -var-file=prod.tfvars -lock-timeout=60s
+
+Flags can also be set as TFVIZ_<FLAG>, or TFVIZ_<COMMAND>_<FLAG> for those of a single
+command, e.g. TFVIZ_SHARE_TTL=24h or TFVIZ_CI_COMPARE_PREVIOUS=previous.json.
 `)
 }
 
-func handlePlan(args []string) {
-	planBinaryFile := "tfplan"
+type cliOptions struct {
+	showGraph    bool
+	badgeFile    string
+	ownersFile   string
+	baselineFile string
+	signKey      string
+	cache        bool
+	cacheTTL     time.Duration
+	configFile   string
+	profile      string
+	reviewFile   string
+
+	checkUpdates bool
+	graph        graphOptions
+	serve        serveOptions
+	sort         string
+	analysis     analysisOptions
+	summarize    bool
+	servicenow   bool
+	reportURL    string
+}
 
-	showGraph := false
-	filtered := []string{}
-	for _, a := range args {
-		if a == "--graph" || a == "-g" {
-			showGraph = true
-			continue
+// parseOptions extracts tfviz flags from args and returns the remaining
+// arguments untouched, so they can be forwarded to terraform. Everything
+// after a "--" separator is forwarded as is; before it, single-dash
+// arguments are still forwarded for compatibility, but an unknown
+// double-dash flag is reported as a mistyped tfviz flag.
+func parseOptions(args []string) (cliOptions, []string, error) {
+	opts := cliOptions{cacheTTL: defaultCacheTTL, graph: graphOptions{depth: defaultGraphDepth}}
+	rest := []string{}
+	depthSet := false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		name, value, hasValue := strings.Cut(a, "=")
+
+		switch name {
+		case "--graph", "-g":
+			opts.showGraph = true
+		case "--tls-self-signed":
+			opts.serve.tlsSelfSigned = true
+		case "--no-browser":
+			opts.serve.noBrowser = true
+		case "--trust-proxy-headers":
+			opts.serve.trustProxyHeaders = true
+		case "--share":
+			opts.serve.share = defaultTunnelProvider
+			if hasValue {
+				opts.serve.share = value
+			}
+		case "--cache":
+			opts.cache = true
+		case "--check-updates":
+			opts.checkUpdates = true
+		case "--changed-only":
+			opts.graph.changedOnly = true
+		case "--include-unchanged-modules":
+			opts.analysis.includeUnchanged = true
+		case "--summarize":
+			opts.summarize = true
+		case "--servicenow":
+			opts.servicenow = true
+		case "--":
+			rest = append(rest, args[i+1:]...)
+			i = len(args)
+		case "--audit-log", "--badge", "--baseline", "--browser", "--cache-ttl", "--config", "--graph-depth", "--graph-focus", "--listen", "--owners", "--profile", "--report-url", "--reviews", "--share-ttl", "--shutdown-after", "--sign-key", "--sort", "--tls-cert", "--tls-key":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return opts, nil, fmt.Errorf("flag %s requires a value", name)
+				}
+				i++
+				value = args[i]
+			}
+			switch name {
+			case "--audit-log":
+				opts.serve.auditLog = value
+			case "--badge":
+				opts.badgeFile = value
+			case "--baseline":
+				opts.baselineFile = value
+			case "--browser":
+				opts.serve.browser = value
+			case "--cache-ttl":
+				d, err := time.ParseDuration(value)
+				if err != nil || d <= 0 {
+					return opts, nil, fmt.Errorf("invalid --cache-ttl duration %q", value)
+				}
+				opts.cacheTTL = d
+			case "--config":
+				opts.configFile = value
+			case "--graph-depth":
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 {
+					return opts, nil, fmt.Errorf("invalid --graph-depth %q", value)
+				}
+				opts.graph.depth = n
+				depthSet = true
+			case "--graph-focus":
+				opts.graph.focus = value
+				opts.showGraph = true
+			case "--owners":
+				opts.ownersFile = value
+			case "--profile":
+				opts.profile = value
+			case "--report-url":
+				opts.reportURL = value
+			case "--reviews":
+				opts.reviewFile = value
+			case "--sign-key":
+				opts.signKey = value
+			case "--sort":
+				if err := validateSort(value); err != nil {
+					return opts, nil, err
+				}
+				opts.sort = value
+			case "--listen":
+				opts.serve.listen = value
+			case "--share-ttl":
+				d, err := time.ParseDuration(value)
+				if err != nil || d <= 0 {
+					return opts, nil, fmt.Errorf("invalid --share-ttl duration %q", value)
+				}
+				opts.serve.shareTTL = d
+			case "--shutdown-after":
+				d, err := time.ParseDuration(value)
+				if err != nil || d < 0 {
+					return opts, nil, fmt.Errorf("invalid --shutdown-after duration %q", value)
+				}
+				opts.serve.shutdownAfter = d
+			case "--tls-cert":
+				opts.serve.tlsCert = value
+			default:
+				opts.serve.tlsKey = value
+			}
+		default:
+			if strings.HasPrefix(name, "--") {
+				return opts, nil, fmt.Errorf("unknown flag %s (pass terraform flags after --)", name)
+			}
+			rest = append(rest, a)
 		}
-		filtered = append(filtered, a)
 	}
-	args = filtered
 
-	fmt.Println("🔄 Running terraform plan...")
-	planArgs := append([]string{"plan", "-out=" + planBinaryFile}, args...)
-	cmd := exec.Command("terraform", planArgs...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		fmt.Printf("❌ Error running terraform plan: %v\n", err)
-		os.Exit(1)
+	if depthSet && opts.graph.focus == "" {
+		return opts, nil, fmt.Errorf("--graph-depth requires --graph-focus")
 	}
+	if (opts.serve.tlsCert == "") != (opts.serve.tlsKey == "") {
+		return opts, nil, fmt.Errorf("--tls-cert and --tls-key must be used together")
+	}
+	if opts.serve.tlsSelfSigned && opts.serve.tlsCert != "" {
+		return opts, nil, fmt.Errorf("--tls-self-signed cannot be combined with --tls-cert")
+	}
+	if opts.serve.share != "" && opts.serve.useTLS() {
+		return opts, nil, fmt.Errorf("--share cannot be combined with TLS; the tunnel provides HTTPS")
+	}
+	return opts, rest, nil
+}
 
-	fmt.Println("📄 Extracting JSON from plan...")
-	showCmd := exec.Command("terraform", "show", "-json", planBinaryFile)
-	out, err := showCmd.Output()
+func handlePlan(ctx context.Context, args []string) error {
+	args, workspaces, err := workspacesFlag(args)
 	if err != nil {
-		fmt.Printf("❌ Error running terraform show: %v\n", err)
-		os.Exit(1)
+		return err
+	}
+	opts, args, err := parseOptions(args)
+	if err != nil {
+		return err
+	}
+	if workspaces != nil {
+		return planWorkspaces(ctx, workspaces, args, opts)
 	}
 
-	var plan TerraformPlan
-	err = json.Unmarshal(out, &plan)
+	var out []byte
+	if opts.cache {
+		out, err = cachedTerraformPlan(ctx, "", args, opts.cacheTTL)
+	} else {
+		out, err = runTerraformPlan(ctx, "", args)
+	}
 	if err != nil {
-		fmt.Printf("❌ Error parsing JSON plan: %v\n", err)
-		os.Exit(1)
+		return err
 	}
 
-	analyzed := analyzePlan(plan)
-	refEdges := buildRefEdges(plan.Configuration)
-	containment := buildContainmentMap(plan.Configuration)
-	allPlanned := collectAllPlannedResources(plan.PlannedValues.RootModule)
-	enrichContainmentFromValues(allPlanned, containment)
-	plannedValues := buildPlannedValuesMap(allPlanned)
-	html := generateHTML(analyzed, showGraph, refEdges, containment, plannedValues)
+	start := time.Now()
+	plan, err := parsePlanJSON(out)
+	if err != nil {
+		return err
+	}
+	runMetrics.since("parse JSON", start)
+	runMetrics.JSONBytes = len(out)
 
-	err = os.Remove(planBinaryFile)
+	opts.analysis.git = gitContext(ctx, "")
+	return presentPlan(ctx, plan, opts)
+}
+
+// runTerraformPlan runs terraform plan in dir (the current directory when
+// empty) into a private temporary directory and returns the JSON form of the
+// plan. The binary plan file is always removed before returning, including
+// when terraform fails or the run is interrupted.
+func runTerraformPlan(ctx context.Context, dir string, args []string) ([]byte, error) {
+	if err := checkPlanArgs(args); err != nil {
+		return nil, err
+	}
+	if err := preflight(ctx, dir); err != nil {
+		return nil, err
+	}
+	planDir, err := os.MkdirTemp("", "tfviz-")
 	if err != nil {
-		fmt.Printf("❌ Error deleting plan file %s: %v\n", planBinaryFile, err)
-	} else {
-		fmt.Println("✅ Plan file deleted successfully")
+		return nil, fmt.Errorf("error creating temporary directory: %v", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(planDir); err != nil {
+			fmt.Printf("❌ Error deleting plan directory %s: %v\n", planDir, err)
+		}
+	}()
+	planBinaryFile := filepath.Join(planDir, "tfplan")
+	ctx, cancel := withTerraformTimeout(ctx)
+	defer cancel()
+
+	fmt.Println("🔄 Running terraform plan...")
+	start := time.Now()
+	planArgs := append([]string{"plan", "-out=" + planBinaryFile}, args...)
+	err = withRetries(ctx, "terraform plan", func(stderr io.Writer) error {
+		cmd := terraformCommand(ctx, planArgs...)
+		cmd.Dir = dir
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
+		return cmd.Run()
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, stoppedError(ctx, "terraform plan")
+		}
+		return nil, fmt.Errorf("error running terraform plan: %v", err)
+	}
+	runMetrics.since("terraform plan", start)
+
+	fmt.Println("📄 Extracting JSON from plan...")
+	start = time.Now()
+	var out []byte
+	err = withRetries(ctx, "terraform show", func(stderr io.Writer) error {
+		showCmd := terraformCommand(ctx, "show", "-json", planBinaryFile)
+		showCmd.Dir = dir
+		showCmd.Stderr = io.MultiWriter(os.Stderr, stderr)
+		var err error
+		out, err = showCmd.Output()
+		return err
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, stoppedError(ctx, "terraform show")
+		}
+		return nil, fmt.Errorf("error running terraform show: %v", err)
 	}
+	runMetrics.since("terraform show -json", start)
+	return out, nil
+}
+
+// checkPlanArgs rejects terraform flags that clash with the way tfviz runs
+// terraform plan.
+func checkPlanArgs(args []string) error {
+	for _, a := range args {
+		name, _, _ := strings.Cut(a, "=")
+		switch strings.TrimPrefix(name, "-") {
+		case "-out", "out":
+			return fmt.Errorf("terraform flag %s is not supported: tfviz writes and removes its own plan file", name)
+		case "-chdir", "chdir":
+			return fmt.Errorf("terraform flag %s must come before the subcommand; run tfviz from that directory instead", name)
+		case "-help", "help", "h":
+			return fmt.Errorf("terraform flag %s is not supported; run terraform plan -help directly", name)
+		}
+	}
+	return nil
+}
 
-	serveHTMLOnce(html)
+// terraformCommand builds a terraform invocation that is interrupted, rather
+// than killed, when ctx is cancelled so terraform can release state locks.
+// Processes left in its group after terraformStopDelay, such as a stuck
+// provider, are killed.
+func terraformCommand(ctx context.Context, args ...string) *terraformCmd {
+	cmd := &terraformCmd{Cmd: exec.CommandContext(ctx, terraformBinary, args...)}
+	group := ownProcessGroup(cmd.Cmd)
+	cmd.Cancel = func() error {
+		if !group {
+			// Ctrl-C in the terminal has reached terraform already, as it
+			// shares tfviz's process group.
+			if interruptedFromTerminal(ctx) {
+				return nil
+			}
+			return cmd.Process.Signal(os.Interrupt)
+		}
+		process := cmd.Process
+		cmd.killTimer = time.AfterFunc(terraformStopDelay, func() { killProcessGroup(process) })
+		return interruptProcessGroup(process)
+	}
+	cmd.WaitDelay = terraformStopDelay
+	return cmd
 }
 
-func generateHTMLFromJSON(planFile string, showGraph bool) {
+func generateHTMLFromJSON(ctx context.Context, planFile string, opts cliOptions) error {
 	fmt.Println("📊 Analyzing terraform plan...")
 
-	data, err := os.ReadFile(planFile)
+	data, err := readPlanFile(planFile)
 	if err != nil {
-		fmt.Printf("❌ Error reading plan file: %v\n", err)
-		return
+		return err
 	}
 
-	var plan TerraformPlan
-	err = json.Unmarshal(data, &plan)
+	start := time.Now()
+	plan, err := parsePlanJSON(data)
 	if err != nil {
-		fmt.Printf("❌ Error parsing JSON plan: %v\n", err)
-		return
+		return err
 	}
+	runMetrics.since("parse JSON", start)
+	runMetrics.JSONBytes = len(data)
 
-	analyzed := analyzePlan(plan)
-	refEdges := buildRefEdges(plan.Configuration)
-	containment := buildContainmentMap(plan.Configuration)
-	allPlanned := collectAllPlannedResources(plan.PlannedValues.RootModule)
-	enrichContainmentFromValues(allPlanned, containment)
-	plannedValues := buildPlannedValuesMap(allPlanned)
-	html := generateHTML(analyzed, showGraph, refEdges, containment, plannedValues)
-	serveHTMLOnce(html)
+	return presentPlan(ctx, plan, opts)
 }
 
-func serveHTMLOnce(html string) {
-	port := "9876"
-	url := "http://localhost:" + port
+func parsePlanJSON(data []byte) (TerraformPlan, error) {
+	var plan TerraformPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return plan, describePlanError(data, err)
+	}
+	plan.FormatWarnings = checkPlanFormat(data, plan)
+	normalizePlan(&plan)
+	return plan, nil
+}
 
-	go func() {
-		time.Sleep(300 * time.Millisecond)
-		openBrowser(url)
-	}()
+// presentPlan analyzes a parsed plan, writes any requested side outputs and
+// serves the HTML report.
+func presentPlan(ctx context.Context, plan TerraformPlan, opts cliOptions) error {
+	cfg, err := loadConfig(opts.configFile, opts.profile)
+	if err != nil {
+		return err
+	}
+	opts.serve.tunnels = cfg.Tunnels
+	start := time.Now()
+	analyzed := analyzePlanWith(plan, opts.analysis)
+	if err := opts.graph.validate(analyzed); err != nil {
+		return err
+	}
+	if err := applyConfig(&analyzed, cfg, time.Now()); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	accepted, err := readBaseline(opts.baselineFile)
+	if err != nil {
+		return err
+	}
+	applyBaseline(&analyzed, accepted)
+	applyLockedVersions(analyzed.ProviderVersions, readLockedProviders("."))
+	applySourceLocations(&analyzed, plan.Configuration, ".")
+	analyzed.Reviewers = findReviewers(analyzed, cfg.Owners, ".")
+	runMetrics.since("analysis", start)
+	if opts.checkUpdates {
+		start = time.Now()
+		checkUpdates(ctx, newRegistryClient(), &analyzed, cfg)
+		runMetrics.since("registry lookups", start)
+	}
+	if opts.summarize {
+		start = time.Now()
+		addNarrative(ctx, &analyzed, cfg.Summary)
+		runMetrics.since("plan summary", start)
+	}
+	metrics := *runMetrics
+	metrics.Resources = analyzed.Summary.TotalResources
+	analyzed.Metrics = &metrics
+	fmt.Printf("📈 %s (%s)\n", metrics, metrics.Size())
+	if g := analyzed.Git; g != nil && g.Dirty {
+		fmt.Printf("⚠️  %s has uncommitted changes; commit %s alone does not reproduce this plan.\n", g.Repository, g.ShortCommit())
+	}
+	if analyzed.ChangeWindow != nil && !analyzed.ChangeWindow.Inside {
+		fmt.Println("⛔ Outside the allowed change window; do not apply this plan now.")
+	}
+	for _, w := range analyzed.FormatWarnings {
+		fmt.Printf("⚠️  %s\n", w)
+	}
+	for _, f := range analyzed.Findings {
+		fmt.Printf("⚠️  %s (%s)\n", f.Title, f.Address)
+		if f.Remediation != "" {
+			fmt.Println("   " + strings.ReplaceAll(f.Remediation, "\n", "\n   "))
+		}
+	}
+	if n := len(analyzed.Acknowledged); n > 0 {
+		fmt.Printf("📌 %s acknowledged in the baseline\n", plural(n, "finding"))
+	}
+	if opts.servicenow {
+		if err := openChangeRequest(ctx, cfg.ServiceNow, analyzed, opts.reportURL); err != nil {
+			return err
+		}
+	}
+	if analyzed.Timeline != nil {
+		t := analyzed.Timeline
+		fmt.Printf("⏱️  Estimated apply time %s with parallelism %d (%s if only dependencies limited it)\n", t.TotalText(), t.Parallelism, t.UnlimitedText())
+	}
+	sortBy := opts.sort
+	if sortBy == "" {
+		sortBy = cfg.Sort
+	}
+	render := renderPlan(plan, analyzed, opts.showGraph, opts.graph, sortBy)
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprint(w, html)
-		go func() {
-			time.Sleep(5 * time.Second)
-			os.Exit(0)
-		}()
-	})
+	badge, err := json.Marshal(buildBadge(analyzed))
+	if err != nil {
+		return err
+	}
+	if opts.badgeFile != "" {
+		if err := writeChecksummed(opts.badgeFile, badge, 0644, opts.signKey); err != nil {
+			return fmt.Errorf("error writing badge file: %v", err)
+		}
+		fmt.Printf("🏷️  Badge written to %s\n", opts.badgeFile)
+	}
+	if opts.ownersFile != "" {
+		if err := writeReviewers(opts.ownersFile, analyzed.Reviewers, opts.signKey); err != nil {
+			return err
+		}
+		fmt.Printf("👥 Reviewers written to %s\n", opts.ownersFile)
+	}
 
-	fmt.Println("🚀 Preview opened in browser. The server will shut down automatically.")
-	err := http.ListenAndServe(":"+port, nil)
+	paths := newDependencyGraph(analyzed, buildRefEdges(plan.Configuration))
+	routes := []route{
+		{"/badge.json", jsonHandler(badge)},
+		{"/paths.json", pathsHandler(paths)},
+	}
+	reviews, err := reviewStoreFor(opts.reviewFile, plan)
 	if err != nil {
-		fmt.Printf("❌ HTTP server error: %v\n", err)
+		return err
 	}
+	if reviews != nil {
+		changed := map[string]bool{}
+		for _, m := range analyzed.Modules {
+			for _, r := range m.Resources {
+				if r.Action != "no-op" {
+					changed[r.Address] = true
+				}
+			}
+		}
+		routes = append(routes,
+			route{"/reviews.json", reviewsHandler(reviews, changed, opts.serve.trustProxyHeaders)},
+			route{"/analysis.json", analysisHandler(analyzed, reviews)})
+		fmt.Printf("💬 Review comments and sign-offs are saved to %s\n", reviews.path)
+	}
+	return serveHTMLOnce(ctx, render, opts.serve, routes...)
 }
 
-func openBrowser(url string) {
-	var cmd *exec.Cmd
-
-	switch runtime.GOOS {
-	case "darwin":
-		cmd = exec.Command("open", url)
-	case "windows":
-		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
-	default:
-		cmd = exec.Command("xdg-open", url)
+// renderPlan returns a renderer of the report for a view of the resource
+// list. Views without a sort use sortBy.
+func renderPlan(plan TerraformPlan, analyzed AnalyzedPlan, showGraph bool, graph graphOptions, sortBy string) func(reportView) string {
+	refEdges := buildRefEdges(plan.Configuration)
+	containment := buildContainmentMap(plan.Configuration)
+	allPlanned := collectAllPlannedResources(plan.PlannedValues.RootModule)
+	enrichContainmentFromValues(allPlanned, containment)
+	plannedValues := buildPlannedValuesMap(allPlanned)
+	return func(view reportView) string {
+		if view.Sort == "" {
+			view.Sort = sortBy
+		}
+		start := time.Now()
+		html := generateHTML(analyzed, showGraph, refEdges, containment, plannedValues, graph, view)
+		if analyzed.Metrics != nil {
+			html = strings.Replace(html, renderTimeMarker, formatStageDuration(time.Since(start)), 1)
+		}
+		return html
 	}
+}
 
-	cmd.Start()
+// analysisOptions change what analyzePlanWith keeps in the analysis.
+type analysisOptions struct {
+	// includeUnchanged keeps modules whose resources are all no-ops, which
+	// are otherwise left out of the report and its resource count.
+	includeUnchanged bool
+	// constructPaths maps addresses to the CDK for Terraform constructs that
+	// defined them, see readConstructPaths.
+	constructPaths map[string]string
+	// git describes the checkout the plan was made from, see gitContext.
+	git *GitContext
 }
 
 func analyzePlan(plan TerraformPlan) AnalyzedPlan {
+	return analyzePlanWith(plan, analysisOptions{})
+}
+
+func analyzePlanWith(plan TerraformPlan, opts analysisOptions) AnalyzedPlan {
 	analyzed := AnalyzedPlan{
 		Summary: PlanSummary{
 			Actions:   make(map[string]int),
@@ -302,15 +971,20 @@ func analyzePlan(plan TerraformPlan) AnalyzedPlan {
 		Modules:          []ModuleAnalysis{},
 		Timestamp:        time.Now().Format("2006-01-02 15:04:05"),
 		TerraformVersion: plan.TerraformVersion,
+		Git:              opts.git,
 	}
 
 	providerSet := make(map[string]bool)
 	moduleMap := map[string]*ModuleAnalysis{}
+	references := buildResourceReferences(plan.Configuration)
 
 	for _, rc := range plan.ResourceChanges {
+		// A replacement is ["delete","create"], or ["create","delete"] with
+		// create_before_destroy.
+		isReplace := len(rc.Change.Actions) == 2 && changeDeletes(rc.Change)
 		action := "no-op"
 		if len(rc.Change.Actions) > 0 {
-    		if len(rc.Change.Actions) == 2 && rc.Change.Actions[0] == "delete" && rc.Change.Actions[1] == "create" {
+    		if isReplace {
         		action = "update"
     		} else {
         		action = rc.Change.Actions[0]
@@ -334,14 +1008,20 @@ func analyzePlan(plan TerraformPlan) AnalyzedPlan {
 		}
 
 		res := ResourceAnalysis{
-			Address:     rc.Address,
-			Type:        rc.Type,
-			Name:        rc.Name,
-			Provider:    rc.ProviderName,
-			Action:      action,
-			Impact:      determineImpact(action, rc.Type),
-			Description: generateDescription(action, rc.Type, rc.Name),
-			After:       rc.Change.After,
+			Address:      rc.Address,
+			Type:         rc.Type,
+			Name:         rc.Name,
+			Provider:     rc.ProviderName,
+			Action:       action,
+			ActionReason: rc.ActionReason,
+			Impact:       determineImpact(action, rc.Type),
+			Description:  generateDescription(action, rc.Type, rc.Name),
+			Before:       rc.Change.Before,
+			After:        rc.Change.After,
+			References:   references[stripIndex(rc.Address)],
+		}
+		if opts.constructPaths != nil {
+			res.ConstructPath = constructPath(opts.constructPaths, rc.Address)
 		}
 
 		if depVal, ok := rc.Change.After["depends_on"]; ok {
@@ -383,8 +1063,16 @@ func analyzePlan(plan TerraformPlan) AnalyzedPlan {
 		}
 
 		res.Changes = analyzeChanges(rc.Change.Before, rc.Change.After)
+		if res.CosmeticOnly() {
+			res.Impact = "Low"
+			res.ImpactReason = "formatting-only changes"
+		}
 
-		isReplace := len(rc.Change.Actions) == 2 && rc.Change.Actions[0] == "delete" && rc.Change.Actions[1] == "create"
+		res.Replace = isReplace
+		if action != "no-op" {
+			res.Fingerprint = changeFingerprint(res)
+		}
+		res.Stateful = isStateful(rc.Type)
 		res.DiffLines = generateTerraformStyleDiff(rc, isReplace)
 
 		m := moduleMap[modAddr]
@@ -409,8 +1097,10 @@ func analyzePlan(plan TerraformPlan) AnalyzedPlan {
 			return m.Resources[i].Address < m.Resources[j].Address
 		})
 
-		if hasChanges(*m) {
+		if opts.includeUnchanged || hasChanges(*m) {
 			modules = append(modules, *m)
+		} else {
+			analyzed.Summary.HiddenModules++
 		}
 	}
 
@@ -431,6 +1121,20 @@ func analyzePlan(plan TerraformPlan) AnalyzedPlan {
 	analyzed.Summary.TotalResources = total
 
 	analyzed.Modules = modules
+	markRenames(&analyzed, matchMoves(plan))
+	markUnknowns(&analyzed, plan, references)
+	markTargets(&analyzed, plan.Configuration)
+	analyzed.Findings = detectFindings(plan)
+	analyzed.LintIssues = lintConfiguration(plan.Configuration)
+	analyzed.ModuleCalls = moduleInventory(plan.Configuration)
+	analyzed.ProviderVersions = providerInventory(plan.Configuration)
+	analyzed.Imports = importSuggestions(plan)
+	analyzed.RemoteStates = findRemoteStates(plan.Configuration)
+	analyzed.StackOutputs = findStackOutputs(plan)
+	analyzed.OutputImpacts = findOutputImpacts(plan)
+	analyzed.Drift = findDrift(plan)
+	analyzed.Certificates = findCertificates(plan, time.Now())
+	analyzed.FormatWarnings = plan.FormatWarnings
 	return analyzed
 }
 
@@ -459,10 +1163,12 @@ func analyzeChanges(before, after map[string]interface{}) []ChangeDetail {
 		}
 
 		changes = append(changes, ChangeDetail{
-			Field:  key,
-			Before: beforeValue,
-			After:  afterValue,
-			Action: action,
+			Field:    key,
+			Before:   beforeValue,
+			After:    afterValue,
+			Action:   action,
+			Cosmetic: action == "update" && isCosmetic(beforeValue, afterValue),
+			Size:     collectionSizes(beforeValue, afterValue),
 		})
 	}
 
@@ -473,6 +1179,7 @@ func analyzeChanges(before, after map[string]interface{}) []ChangeDetail {
 				Before: beforeValue,
 				After:  nil,
 				Action: "remove",
+				Size:   collectionSizes(beforeValue, nil),
 			})
 		}
 	}
@@ -489,6 +1196,8 @@ func deepEqual(a, b interface{}) bool {
 	return string(ajson) == string(bjson)
 }
 
+var impactRank = map[string]int{"Low": 1, "Medium": 2, "High": 3}
+
 func determineImpact(action, resourceType string) string {
 	switch action {
 	case "create":
@@ -510,13 +1219,13 @@ func determineImpact(action, resourceType string) string {
 func generateDescription(action, resourceType, name string) string {
 	switch action {
 	case "create", "add":
-		return fmt.Sprintf("%s '%s' Create", resourceType, name)
+		return fmt.Sprintf("Create %s '%s'", resourceType, name)
 	case "update":
-		return fmt.Sprintf("%s '%s' Update ", resourceType, name)
+		return fmt.Sprintf("Update %s '%s'", resourceType, name)
 	case "delete", "destroy", "remove":
 		return fmt.Sprintf("Delete %s '%s'", resourceType, name)
 	default:
-		return fmt.Sprintf("%s '%s' Unchanged", resourceType, name)
+		return fmt.Sprintf("No changes to %s '%s'", resourceType, name)
 	}
 }
 
@@ -535,14 +1244,15 @@ func generateTerraformStyleDiff(rc ResourceChange, isReplace bool) []DiffLine {
 
 	lines = append(lines, DiffLine{Type: "header", Text: fmt.Sprintf("%s resource \"%s\" \"%s\" {", actionPrefix, rc.Type, rc.Name)})
 
-	diffAttributes(rc.Change.Before, rc.Change.After, rc.Change.AfterUnknown, isReplace, 1, &lines)
+	replace := replacement{replace: isReplace, paths: rc.Change.ReplacePaths}
+	diffAttributes(rc.Change.Before, rc.Change.After, rc.Change.AfterUnknown, replace, nil, 1, &lines)
 
 	lines = append(lines, DiffLine{Type: "header", Text: "}"})
 
 	return lines
 }
 
-func diffAttributes(before, after, afterUnknown map[string]interface{}, isReplace bool, indentLevel int, lines *[]DiffLine) {
+func diffAttributes(before, after, afterUnknown map[string]interface{}, replace replacement, path []string, indentLevel int, lines *[]DiffLine) {
 	indent := strings.Repeat("  ", indentLevel)
 	allKeys := uniqueSortedKeys(before, after, afterUnknown)
 
@@ -551,7 +1261,8 @@ func diffAttributes(before, after, afterUnknown map[string]interface{}, isReplac
 		av, aOk := after[key]
 		auv, auOk := afterUnknown[key]
 
-		comment := ifReplaceComment(isReplace)
+		keyPath := append(path[:len(path):len(path)], key)
+		comment := replace.comment(keyPath)
 
 		if auOk {
 			if bVal, isBool := auv.(bool); isBool && bVal {
@@ -572,7 +1283,7 @@ func diffAttributes(before, after, afterUnknown map[string]interface{}, isReplac
 			if bMap, bIsMap := bv.(map[string]interface{}); bIsMap {
 				if aMap, aIsMap := av.(map[string]interface{}); aIsMap {
 					*lines = append(*lines, DiffLine{Type: "modified", Text: fmt.Sprintf("%s  %s {", indent, key)})
-					diffAttributes(bMap, aMap, afterUnknown, isReplace, indentLevel+1, lines)
+					diffAttributes(bMap, aMap, afterUnknown, replace, keyPath, indentLevel+1, lines)
 					*lines = append(*lines, DiffLine{Type: "modified", Text: fmt.Sprintf("%s}", indent)})
 					continue
 				}
@@ -758,29 +1469,44 @@ func propagateParentVars(callExprs map[string]interface{}, parentVarMap map[stri
 
 func buildRefEdges(config PlanConfiguration) map[string][]string {
 	edges := map[string][]string{}
-	outputMap := buildModuleOutputMap(config)
-	collectFromModule(config.RootModule, "", nil, edges, outputMap)
+	for src, refs := range buildResourceReferences(config) {
+		seen := map[string]bool{}
+		for _, ref := range refs {
+			if !seen[ref.Target] {
+				seen[ref.Target] = true
+				edges[src] = append(edges[src], ref.Target)
+			}
+		}
+	}
 	return edges
 }
 
-func collectFromModule(mod ConfigModule, modulePrefix string, varMap map[string]string, edges map[string][]string, outputMap map[string]string) {
+// buildResourceReferences maps each configured resource address to the
+// resources its attributes reference, resolving variables passed into
+// modules and module outputs.
+func buildResourceReferences(config PlanConfiguration) map[string][]ResourceReference {
+	refs := map[string][]ResourceReference{}
+	outputMap := buildModuleOutputMap(config)
+	collectFromModule(config.RootModule, "", nil, refs, outputMap)
+	return refs
+}
+
+func collectFromModule(mod ConfigModule, modulePrefix string, varMap map[string]string, refs map[string][]ResourceReference, outputMap map[string]string) {
 	for _, res := range mod.Resources {
 		srcAddr := res.Address
 		if modulePrefix != "" {
 			srcAddr = modulePrefix + "." + res.Address
 		}
 
-		rawRefs := extractReferences(res.Expressions)
-
 		seen := map[string]bool{}
-		for _, ref := range rawRefs {
+		walkAttributeReferences("", res.Expressions, func(attr, ref string) {
 			resolved := resolveRef(ref, modulePrefix, varMap)
-			if resolved == "" || resolved == srcAddr || seen[resolved] {
-				continue
+			if resolved == "" || resolved == srcAddr || seen[attr+" "+resolved] {
+				return
 			}
-			seen[resolved] = true
-			edges[srcAddr] = append(edges[srcAddr], resolved)
-		}
+			seen[attr+" "+resolved] = true
+			refs[srcAddr] = append(refs[srcAddr], ResourceReference{Attribute: attr, Expression: ref, Target: resolved})
+		})
 	}
 
 	for callName, call := range mod.ModuleCalls {
@@ -790,7 +1516,35 @@ func collectFromModule(mod ConfigModule, modulePrefix string, varMap map[string]
 		}
 		childVarMap := buildVarMap(call.Expressions, outputMap)
 		propagateParentVars(call.Expressions, varMap, childVarMap)
-		collectFromModule(call.Module, childPrefix, childVarMap, edges, outputMap)
+		collectFromModule(call.Module, childPrefix, childVarMap, refs, outputMap)
+	}
+}
+
+// walkAttributeReferences calls fn for every reference in a resource's
+// expressions together with the attribute path it appears under. Nested
+// blocks are joined with dots, e.g. "ingress.security_groups".
+func walkAttributeReferences(path string, v interface{}, fn func(attr, ref string)) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if refList, ok := val["references"].([]interface{}); ok {
+			for _, item := range refList {
+				if s, ok := item.(string); ok {
+					fn(path, s)
+				}
+			}
+			return
+		}
+		for _, key := range sortedKeys(val) {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			walkAttributeReferences(child, val[key], fn)
+		}
+	case []interface{}:
+		for _, item := range val {
+			walkAttributeReferences(path, item, fn)
+		}
 	}
 }
 
@@ -1075,13 +1829,8 @@ func getNameTag(values map[string]interface{}) string {
 	return name
 }
 
-func buildGraphJSON(analyzed AnalyzedPlan, refEdges map[string][]string, containment map[string]string, plannedValues map[string]map[string]interface{}) (string, string, error) {
-	type elem struct {
-		Data    map[string]interface{} `json:"data"`
-		Classes string                 `json:"classes,omitempty"`
-	}
-
-	elements := make([]elem, 0)
+func buildGraphJSON(analyzed AnalyzedPlan, refEdges map[string][]string, containment map[string]string, plannedValues map[string]map[string]interface{}, opts graphOptions) (string, string, error) {
+	elements := make([]graphElement, 0)
 	resourceDetails := map[string]ResourceAnalysis{}
 	knownNodes := map[string]bool{}
 
@@ -1109,7 +1858,7 @@ func buildGraphJSON(analyzed AnalyzedPlan, refEdges map[string][]string, contain
 				nodeData["parent"] = parent
 			}
 
-			elements = append(elements, elem{Data: nodeData, Classes: classes})
+			elements = append(elements, graphElement{Data: nodeData, Classes: classes})
 			knownNodes[rID] = true
 			resourceDetails[r.Address] = r
 		}
@@ -1145,6 +1894,46 @@ func buildGraphJSON(analyzed AnalyzedPlan, refEdges map[string][]string, contain
 		}
 	}
 
+	// Child modules become compound nodes carrying their rollup; resources
+	// that are not inside a container are placed in them.
+	moduleNodes := map[string]bool{}
+	for _, m := range analyzed.Modules {
+		if m.Address != "root" {
+			moduleNodes[m.Address] = true
+		}
+	}
+	moduleParent := func(addr string) string {
+		for p := parentModule(addr); p != ""; p = parentModule(p) {
+			if moduleNodes[p] {
+				return p
+			}
+		}
+		return ""
+	}
+	for i := range elements {
+		mod, _ := elements[i].Data["module"].(string)
+		if _, hasParent := elements[i].Data["parent"]; !hasParent && moduleNodes[mod] {
+			elements[i].Data["parent"] = mod
+		}
+	}
+	for _, m := range analyzed.Modules {
+		if !moduleNodes[m.Address] {
+			continue
+		}
+		rollup := m.Rollup()
+		nodeData := map[string]interface{}{
+			"id":     m.Address,
+			"label":  rollup.graphLabel(m.Address),
+			"type":   "module",
+			"impact": rollup.Impact,
+			"rollup": rollup,
+		}
+		if p := moduleParent(m.Address); p != "" {
+			nodeData["parent"] = p
+		}
+		elements = append(elements, graphElement{Data: nodeData, Classes: "module-group"})
+	}
+
 	knownBase := map[string]bool{}
 	for id := range knownNodes {
 		knownBase[stripIndex(id)] = true
@@ -1190,7 +1979,7 @@ func buildGraphJSON(analyzed AnalyzedPlan, refEdges map[string][]string, contain
 			if pp, ok := containment[parent]; ok {
 				nodeData["parent"] = pp
 			}
-			elements = append(elements, elem{Data: nodeData, Classes: "resource container"})
+			elements = append(elements, graphElement{Data: nodeData, Classes: "resource container"})
 			knownNodes[parent] = true
 			added = true
 		}
@@ -1209,11 +1998,18 @@ func buildGraphJSON(analyzed AnalyzedPlan, refEdges map[string][]string, contain
 			if containmentPairs[stripIndex(src)+"->"+stripIndex(tgt)] {
 				continue
 			}
-			edgeID := "edge:ref:" + src + "->" + tgt
-			elements = append(elements, elem{
-				Data:    map[string]interface{}{"id": edgeID, "source": src, "target": tgt},
-				Classes: "reference",
-			})
+			edgeID := refEdgeID(src, tgt)
+			edgeData := map[string]interface{}{"id": edgeID, "source": src, "target": tgt}
+			var labels []string
+			for _, ref := range resourceDetails[src].References {
+				if ref.Target == tgt {
+					labels = append(labels, ref.String())
+				}
+			}
+			if len(labels) > 0 {
+				edgeData["label"] = strings.Join(labels, "\n")
+			}
+			elements = append(elements, graphElement{Data: edgeData, Classes: "reference"})
 		}
 	}
 
@@ -1223,8 +2019,8 @@ func buildGraphJSON(analyzed AnalyzedPlan, refEdges map[string][]string, contain
 				if !knownNodes[dep] {
 					continue
 				}
-				edgeID := "edge:dep:" + dep + "->" + r.Address
-				elements = append(elements, elem{
+				edgeID := depEdgeID(dep, r.Address)
+				elements = append(elements, graphElement{
 					Data:    map[string]interface{}{"id": edgeID, "source": dep, "target": r.Address},
 					Classes: "depends_on",
 				})
@@ -1232,6 +2028,9 @@ func buildGraphJSON(analyzed AnalyzedPlan, refEdges map[string][]string, contain
 		}
 	}
 
+	elements = append(elements, crossStackElements(analyzed, knownNodes)...)
+	elements = filterGraph(elements, opts)
+
 	elJSON, err := json.Marshal(elements)
 	if err != nil {
 		return "", "", err
@@ -1243,13 +2042,45 @@ func buildGraphJSON(analyzed AnalyzedPlan, refEdges map[string][]string, contain
 	return string(elJSON), string(rdJSON), nil
 }
 
-func ifReplaceComment(isReplace bool) string {
-	if isReplace {
+// replacement decides which attributes of a replaced resource are marked
+// "# forces replacement". Terraform lists them in replace_paths; plans
+// without the list mark every changed attribute.
+type replacement struct {
+	replace bool
+	paths   [][]interface{}
+}
+
+func (r replacement) comment(path []string) string {
+	if !r.replace {
+		return ""
+	}
+	if len(r.paths) == 0 {
 		return " # forces replacement"
 	}
+	for _, p := range r.paths {
+		if pathsOverlap(path, p) {
+			return " # forces replacement"
+		}
+	}
 	return ""
 }
 
+// pathsOverlap reports whether one attribute path contains the other. A
+// replace path into a list element, e.g. ingress[0].cidr_blocks, marks the
+// whole ingress line since lists are rendered on one line.
+func pathsOverlap(path []string, replacePath []interface{}) bool {
+	n := min(len(path), len(replacePath))
+	if n == 0 {
+		return false
+	}
+	for i := 0; i < n; i++ {
+		if fmt.Sprint(replacePath[i]) != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func uniqueSortedKeys(maps ...map[string]interface{}) []string {
 	keysMap := make(map[string]struct{})
 	for _, m := range maps {
@@ -1300,32 +2131,7 @@ func formatJSON(s string) (string, bool) {
 	return string(out), true
 }
 
-func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]string, containment map[string]string, plannedValues map[string]map[string]interface{}) string {
-	graphJSON, resourceDetailsJSON, _ := buildGraphJSON(analysis, refEdges, containment, plannedValues)
-	data := struct {
-		AnalyzedPlan
-		GraphJSON           template.JS
-		ResourceDetailsJSON template.JS
-		ShowGraph           bool
-	}{
-		AnalyzedPlan:        analysis,
-		GraphJSON:           template.JS(graphJSON),
-		ResourceDetailsJSON: template.JS(resourceDetailsJSON),
-		ShowGraph:           showGraph,
-	}
-
-	htmlTemplate := `<!DOCTYPE html>
-
-<html lang="ko">
-<head>
-  <meta charset="UTF-8" />
-  <meta name="viewport" content="width=device-width, initial-scale=1" />
-  <title>Terraform Plan Analysis</title>
-  <script src="https://cdnjs.cloudflare.com/ajax/libs/cytoscape/3.28.1/cytoscape.min.js"></script>
-  <script src="https://cdn.jsdelivr.net/npm/elkjs@0.9.3/lib/elk.bundled.js"></script>
-  <script src="https://cdn.jsdelivr.net/npm/cytoscape-elk@2.2.0/dist/cytoscape-elk.js"></script>
-  <style>
-    :root {
+const reportStyles = `    :root {
       --background-color: #f7f8fa;
       --container-bg: #ffffff;
       --sidebar-bg: #f1f3f6;
@@ -1369,6 +2175,10 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
       font-size: 12px;
       color: var(--text-secondary-color);
     }
+    .header .git-dirty {
+      color: var(--update-color);
+      font-weight: 600;
+    }
     .search-container {
       margin-top: 15px;
     }
@@ -1478,6 +2288,10 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
       font-size: 11px;
     }
     .ctrl-btn:hover { background: #f0f0f0; }
+    .path-info {
+      font-size: 11px;
+      color: var(--text-secondary-color);
+    }
     .graph-legend {
       display: flex;
       gap: 12px;
@@ -1510,8 +2324,227 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
     .resource {
       padding: 15px 20px;
       border-bottom: 1px solid var(--border-color);
+    }
+    .resource-toggle {
+      display: block;
+      width: 100%;
+      padding: 0;
+      border: none;
+      background: none;
+      font: inherit;
+      color: inherit;
+      text-align: left;
+      cursor: pointer;
+    }
+    .resource-info {
+      flex: 1;
+    }
+    .resource:target {
+      background: #fffbea;
+    }
+    .module-toggle {
+      padding: 0;
+      border: none;
+      background: none;
+      font: inherit;
+      color: inherit;
+      cursor: pointer;
+    }
+    .module-toggle::before {
+      content: "▾ ";
+    }
+    .module-toggle[aria-expanded="false"]::before {
+      content: "▸ ";
+    }
+    .module-count {
+      font-weight: normal;
+      color: var(--text-secondary-color);
+    }
+    .narrative {
+      padding: 10px 20px;
+      border-bottom: 1px solid var(--border-color);
+    }
+    .narrative h2 {
+      margin: 0 0 8px;
+      font-size: 16px;
+    }
+    .narrative-text {
+      margin: 0;
+      white-space: pre-wrap;
+    }
+    .narrative-note {
+      margin: 6px 0 0;
+      font-size: 12px;
+      color: var(--text-secondary-color);
+    }
+    .top-changes {
+      padding: 10px 20px;
+      border-bottom: 1px solid var(--border-color);
+    }
+    .top-changes h2 {
+      margin: 0 0 8px;
+      font-size: 16px;
+    }
+    .top-changes-lists {
+      display: grid;
+      grid-template-columns: repeat(auto-fit, minmax(260px, 1fr));
+      gap: 16px;
+    }
+    .top-changes h3 {
+      margin: 0 0 4px;
+      font-size: 13px;
+      color: var(--text-secondary-color);
+    }
+    .top-changes ol {
+      margin: 0;
+      padding-left: 20px;
+      font-size: 13px;
+    }
+    .top-detail {
+      color: var(--text-secondary-color);
+    }
+    .hidden-modules-note {
+      margin: 0;
+      padding: 8px 20px;
+      font-size: 13px;
+      color: var(--text-secondary-color);
+      border-bottom: 1px solid var(--border-color);
+    }
+    .layout-controls {
+      display: flex;
+      gap: 0;
+    }
+    .layout-btn[aria-pressed="true"] {
+      background: var(--accent-color);
+      color: #fff;
+      border-color: var(--accent-color);
+    }
+    .change-table-wrap {
+      overflow-x: auto;
+    }
+    .change-table {
+      width: 100%;
+      border-collapse: collapse;
+      font-size: 13px;
+    }
+    .change-table th,
+    .change-table td {
+      padding: 6px 12px;
+      border-bottom: 1px solid var(--border-color);
+      text-align: left;
+      vertical-align: top;
+    }
+    .change-table th button {
+      padding: 0;
+      border: none;
+      background: none;
+      font: inherit;
+      font-weight: 600;
+      color: inherit;
+      cursor: pointer;
+    }
+    .change-table th[aria-sort="ascending"] button::after { content: " ▲"; }
+    .change-table th[aria-sort="descending"] button::after { content: " ▼"; }
+    .change-table .action-icon {
+      display: inline-flex;
+    }
+    .expand-controls {
+      display: flex;
+      gap: 6px;
+      align-items: center;
+    }
+    .detail-tabs {
+      display: flex;
+      gap: 4px;
+      margin: 10px 0;
+      border-bottom: 1px solid var(--border-color);
+    }
+    .detail-tab {
+      padding: 4px 12px;
+      border: none;
+      border-bottom: 2px solid transparent;
+      background: none;
+      font: inherit;
+      font-size: 12px;
+      color: var(--text-secondary-color);
       cursor: pointer;
     }
+    .detail-tab[aria-selected="true"] {
+      border-bottom-color: var(--accent-color);
+      color: inherit;
+    }
+    .raw-json {
+      max-height: 480px;
+      overflow: auto;
+    }
+    .empty-panel {
+      color: var(--text-secondary-color);
+      font-style: italic;
+    }
+    .resource-meta {
+      display: grid;
+      grid-template-columns: max-content 1fr;
+      gap: 4px 16px;
+      font-size: 13px;
+    }
+    .resource-meta dt {
+      color: var(--text-secondary-color);
+    }
+    .resource-meta dd {
+      margin: 0;
+    }
+    .construct-path {
+      font-family: monospace;
+      font-size: 12px;
+    }
+    .copy-actions {
+      display: flex;
+      gap: 6px;
+      flex-shrink: 0;
+    }
+    .diff-block {
+      position: relative;
+    }
+    .diff-copy {
+      position: absolute;
+      top: 6px;
+      right: 6px;
+    }
+    :focus-visible {
+      outline: 2px solid var(--accent-color);
+      outline-offset: 2px;
+    }
+    .visually-hidden {
+      position: absolute;
+      width: 1px;
+      height: 1px;
+      overflow: hidden;
+      clip: rect(0 0 0 0);
+      white-space: nowrap;
+    }
+    .skip-link {
+      position: absolute;
+      left: -9999px;
+      top: 0;
+      z-index: 10;
+      padding: 8px 12px;
+      background: var(--container-bg);
+      color: var(--accent-color);
+    }
+    .skip-link:focus {
+      left: 10px;
+    }
+    .shortcut-hint {
+      margin-top: 4px;
+      font-size: 11px;
+      color: var(--text-secondary-color);
+    }
+    .shortcut-hint kbd {
+      padding: 0 4px;
+      border: 1px solid var(--border-color);
+      border-radius: 3px;
+      font-family: inherit;
+    }
     .resource:last-child {
       border-bottom: none;
     }
@@ -1536,6 +2569,59 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
     .resource.resource-changed-create { border-left: 4px solid var(--create-color); }
     .resource.resource-changed-update { border-left: 4px solid var(--update-color); }
     .resource.resource-changed-delete { border-left: 4px solid var(--delete-color); }
+    .resource.resource-rename { border-left-style: dashed; border-left-color: var(--accent-color); }
+    .rename-tag { color: var(--accent-color); }
+    .unknown-note {
+      margin: 10px 0;
+      font-size: 13px;
+      color: var(--text-secondary-color);
+    }
+    .risk-note {
+      margin-bottom: 10px;
+      padding: 8px 12px;
+      font-size: 13px;
+      border-left: 3px solid var(--update-color);
+      background-color: var(--background-color);
+    }
+    .review {
+      margin-top: 12px;
+      padding-top: 8px;
+      border-top: 1px solid var(--border-color);
+    }
+    .review-comments {
+      margin: 8px 0;
+      padding-left: 18px;
+      font-size: 13px;
+    }
+    .review-form {
+      display: flex;
+      gap: 6px;
+    }
+    .review-form input {
+      flex: 1;
+    }
+    .review-status {
+      font-size: 12px;
+      color: var(--create-color);
+    }
+    .cosmetic-note,
+    .size-changes {
+      margin-bottom: 10px;
+      font-size: 13px;
+      color: var(--text-secondary-color);
+    }
+    .filter-toggle {
+      display: flex;
+      align-items: center;
+      gap: 4px;
+      font-size: 13px;
+      color: var(--text-secondary-color);
+    }
+    .rename-note {
+      margin-bottom: 10px;
+      font-size: 13px;
+      color: var(--accent-color);
+    }
     .resource-info h3 {
       font-size: 16px;
     }
@@ -1546,7 +2632,6 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
     .details {
       margin-top: 15px;
       padding-left: 30px;
-      display: none;
     }
     .details pre {
       background: #f6f8fa;
@@ -1570,17 +2655,331 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
     .diff-line-unchanged {
       color: var(--text-secondary-color);
     }
-  </style>
+    .stateful-banner {
+      padding: 15px 20px;
+      border-bottom: 1px solid var(--border-color);
+      background-color: #ffeef0;
+      color: var(--delete-color);
+    }
+    .stateful-banner.confirmed {
+      background-color: var(--sidebar-bg);
+      color: var(--text-secondary-color);
+    }
+    .stateful-banner ul {
+      margin: 8px 0 8px 20px;
+    }
+    .stateful-banner label {
+      cursor: pointer;
+      font-weight: bold;
+    }
+    .orphans {
+      padding: 15px 20px;
+      border-bottom: 1px solid var(--border-color);
+    }
+    .orphans h2 {
+      font-size: 16px;
+      margin-bottom: 4px;
+    }
+    .orphans p {
+      font-size: 12px;
+      color: var(--text-secondary-color);
+    }
+    .orphans h3 {
+      font-size: 13px;
+      margin-top: 10px;
+    }
+    .orphans ul {
+      margin-left: 20px;
+      font-size: 12px;
+    }
+    .module-inventory {
+      padding: 10px 20px;
+      border-bottom: 1px solid var(--border-color);
+      font-size: 12px;
+    }
+    .module-inventory summary {
+      cursor: pointer;
+      color: var(--text-secondary-color);
+    }
+    .module-inventory table {
+      margin-top: 8px;
+      border-collapse: collapse;
+      width: 100%;
+    }
+    .module-inventory th, .module-inventory td {
+      text-align: left;
+      padding: 4px 8px;
+      border-bottom: 1px solid var(--border-color);
+    }
+    .target-bar {
+      position: sticky;
+      bottom: 0;
+      display: flex;
+      align-items: center;
+      gap: 10px;
+      padding: 10px 20px;
+      border-top: 1px solid var(--border-color);
+      background: var(--sidebar-bg);
+      font-size: 12px;
+    }
+    .target-bar[hidden] {
+      display: none;
+    }
+    .target-bar code {
+      flex: 1;
+      overflow-x: auto;
+      white-space: nowrap;
+    }
+    .target-select {
+      margin-right: 8px;
+    }
+    .timeline {
+      padding: 10px 20px;
+      border-bottom: 1px solid var(--border-color);
+      font-size: 12px;
+    }
+    .timeline summary {
+      cursor: pointer;
+      color: var(--text-secondary-color);
+    }
+    .timeline-chains {
+      margin: 8px 0 0 20px;
+    }
+    .timeline-rows {
+      margin-top: 8px;
+    }
+    .timeline-row {
+      display: flex;
+      align-items: center;
+      gap: 8px;
+      padding: 2px 0;
+    }
+    .timeline-label {
+      flex: 0 0 300px;
+      overflow: hidden;
+      text-overflow: ellipsis;
+      white-space: nowrap;
+    }
+    .timeline-track {
+      position: relative;
+      flex: 1;
+      height: 12px;
+      background: var(--sidebar-bg);
+    }
+    .timeline-bar {
+      position: absolute;
+      top: 0;
+      bottom: 0;
+      min-width: 2px;
+      border-radius: 2px;
+      background-color: var(--text-secondary-color);
+    }
+    .timeline-bar.create { background-color: var(--create-color); }
+    .timeline-bar.update { background-color: var(--update-color); }
+    .timeline-bar.delete { background-color: var(--delete-color); }
+    .timeline-row.critical .timeline-label {
+      font-weight: 600;
+    }
+    .timeline-row.critical .timeline-bar {
+      outline: 2px solid #24292e;
+    }
+    .timeline-note {
+      margin-top: 6px;
+      color: var(--text-secondary-color);
+    }
+    .module-warning {
+      color: var(--delete-color);
+    }
+    .lint {
+      padding: 10px 20px;
+      border-bottom: 1px solid var(--border-color);
+      font-size: 12px;
+    }
+    .lint summary {
+      cursor: pointer;
+      color: var(--text-secondary-color);
+    }
+    .lint ul {
+      margin: 8px 0 0 20px;
+    }
+    .findings {
+      padding: 15px 20px;
+      border-bottom: 1px solid var(--border-color);
+      background-color: #fffbea;
+    }
+    .findings h2 {
+      font-size: 16px;
+      margin-bottom: 8px;
+    }
+    .finding {
+      padding: 8px 12px;
+      margin-bottom: 8px;
+      border-left: 4px solid var(--update-color);
+      background: var(--container-bg);
+    }
+    .finding.data-loss, .finding.iam-escalation, .finding.conflict {
+      border-left-color: var(--delete-color);
+    }
+    .finding h3 {
+      font-size: 14px;
+      margin-bottom: 4px;
+    }
+    .finding p {
+      font-size: 12px;
+      color: var(--text-secondary-color);
+    }
+    .finding-attributes code {
+      margin-right: 8px;
+    }
+    .finding-remediation {
+      margin-top: 6px;
+      padding: 6px 8px;
+      font-size: 12px;
+      background: var(--background-color);
+      border: 1px solid var(--border-color);
+      border-radius: 4px;
+      user-select: all;
+    }
+    .high-risk {
+      padding: 15px 20px;
+      border-bottom: 1px solid var(--border-color);
+      background-color: #fff8f8;
+    }
+    .high-risk h2 {
+      font-size: 16px;
+      margin-bottom: 8px;
+      color: var(--delete-color);
+    }
+    .high-risk ul {
+      list-style: none;
+    }
+    .high-risk li {
+      display: flex;
+      align-items: center;
+      gap: 8px;
+      padding: 3px 0;
+    }
+    .impact-reason {
+      color: var(--text-secondary-color);
+      font-size: 12px;
+    }
+    .resource-description {
+      margin-bottom: 8px;
+      color: var(--text-secondary-color);
+    }
+    .resource-references {
+      list-style: none;
+      margin-bottom: 8px;
+      font-size: 13px;
+    }
+    .runbook-link {
+      color: var(--accent-color);
+    }
+    .module-header {
+      display: flex;
+      align-items: center;
+      justify-content: space-between;
+      gap: 12px;
+    }
+    .module-rollup {
+      display: flex;
+      align-items: center;
+      gap: 8px;
+      font-size: 12px;
+      font-weight: 600;
+    }
+    .rollup-count.create { color: var(--create-color); }
+    .rollup-count.update { color: var(--update-color); }
+    .rollup-count.delete { color: var(--delete-color); }
+    .rollup-note {
+      color: var(--text-secondary-color);
+      font-weight: normal;
+    }
+    .rollup-impact {
+      padding: 2px 8px;
+      border-radius: 10px;
+      color: #fff;
+      background-color: var(--create-color);
+    }
+    .rollup-impact.Medium { background-color: var(--update-color); }
+    .rollup-impact.High { background-color: var(--delete-color); }
+    .change-window {
+      padding: 10px 20px;
+      border-bottom: 1px solid var(--border-color);
+      font-size: 13px;
+    }
+    .change-window.inside {
+      background-color: #e6ffed;
+    }
+    .change-window.outside {
+      background-color: #ffeef0;
+      color: var(--delete-color);
+    }
+    .format-warning {
+      padding: 10px 20px;
+      border-bottom: 1px solid var(--border-color);
+      background-color: #fffbea;
+      font-size: 13px;
+    }
+    .format-warning ul {
+      margin: 4px 0 0 20px;
+    }
+`
+
+func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]string, containment map[string]string, plannedValues map[string]map[string]interface{}, graph graphOptions, view reportView) string {
+	graphJSON, resourceDetailsJSON, _ := buildGraphJSON(analysis, refEdges, containment, plannedValues, graph)
+	analysis.Modules = sortResources(groupResources(analysis.Modules, view.Group), view.Sort)
+	data := struct {
+		AnalyzedPlan
+		GraphJSON           template.JS
+		ResourceDetailsJSON template.JS
+		ShowGraph           bool
+		View                reportView
+	}{
+		AnalyzedPlan:        analysis,
+		GraphJSON:           template.JS(graphJSON),
+		ResourceDetailsJSON: template.JS(resourceDetailsJSON),
+		ShowGraph:           showGraph,
+		View:                view,
+	}
+
+	htmlTemplate := `<!DOCTYPE html>
+
+<html lang="en">
+<head>
+  <meta charset="UTF-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1" />
+  <title>Terraform Plan Analysis</title>
+  <script src="https://cdnjs.cloudflare.com/ajax/libs/cytoscape/3.28.1/cytoscape.min.js"></script>
+  <script src="https://cdn.jsdelivr.net/npm/elkjs@0.9.3/lib/elk.bundled.js"></script>
+  <script src="https://cdn.jsdelivr.net/npm/cytoscape-elk@2.2.0/dist/cytoscape-elk.js"></script>
+  <style>
+` + reportStyles + `  </style>
 </head>
 <body>
-  <div class="container">
+  <a class="skip-link" href="#resources">Skip to resources</a>
+  <main class="container">
     <div class="header">
       <h1>Terraform Plan</h1>
       <div class="subtitle">{{.Timestamp}} (v{{.TerraformVersion}})</div>
+      {{with .Git}}<div class="subtitle git-context" title="{{.Commit}}">{{.Repository}}{{with .Branch}} · {{.}}{{end}} @ <code>{{.ShortCommit}}</code>{{if .Dirty}} · <span class="git-dirty">uncommitted changes</span>{{end}}</div>{{end}}
       <div class="search-container">
-        <input type="text" id="resourceSearch" placeholder="Search resources..." onkeyup="filterResources()">
+        <input type="search" id="resourceSearch" placeholder="Search resources..." aria-label="Search resources" aria-describedby="shortcutHint" value="{{.View.Search}}" onkeyup="filterResources()">
+        <p id="shortcutHint" class="shortcut-hint">Keys: <kbd>/</kbd> search · <kbd>j</kbd>/<kbd>k</kbd> next/previous resource · <kbd>Enter</kbd> expand</p>
       </div>
     </div>
+    {{with .FormatWarnings}}
+    <div class="format-warning" role="alert">
+      <strong>⚠️ This plan may not be shown completely.</strong>
+      <ul>{{range .}}<li>{{.}}</li>{{end}}</ul>
+    </div>
+    {{end}}
+    {{with .ChangeWindow}}
+    <div class="change-window {{if .Inside}}inside{{else}}outside{{end}}">
+      {{if .Inside}}✅ Inside the allowed change window{{else}}⛔ Outside the allowed change window{{end}}
+      ({{range $i, $w := .Windows}}{{if $i}}; {{end}}{{$w}}{{end}}), checked {{.Checked}}
+    </div>
+    {{end}}
     <div class="summary">
       <div class="summary-item">
         <h2>{{.Summary.TotalResources}}</h2>
@@ -1598,14 +2997,307 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
         <h2 style="color: var(--delete-color)">{{index .Summary.Actions "delete"}}</h2>
         <p>Delete</p>
       </div>
-      <div class="filters">
-        <button class="filter-btn active" data-action="all" onclick="filterByAction('all', this)">All</button>
-        <button class="filter-btn" data-action="create" onclick="filterByAction('create', this)">Create</button>
-        <button class="filter-btn" data-action="update" onclick="filterByAction('update', this)">Update</button>
-        <button class="filter-btn" data-action="delete" onclick="filterByAction('delete', this)">Delete</button>
-        <button class="filter-btn" data-action="no-op" onclick="filterByAction('no-op', this)">No-op</button>
+      {{with .Summary.Renames}}
+      <div class="summary-item" title="Destroy and create pairs that look like the same object under a new address">
+        <h2 style="color: var(--accent-color)">{{.}}</h2>
+        <p>Likely renames</p>
+      </div>
+      {{end}}
+      {{if .Summary.UnknownAttributes}}
+      <div class="summary-item" title="Attributes whose value is only known after apply">
+        <h2 style="color: var(--text-secondary-color)">{{.Summary.UnknownAttributes}}</h2>
+        <p>Known after apply ({{.Summary.UnknownResources}} resources)</p>
+      </div>
+      {{end}}
+      <div class="filters" role="group" aria-label="Filter resources">
+        <button class="filter-btn{{if .View.IsAction "all"}} active{{end}}" data-action="all" aria-pressed="{{.View.IsAction "all"}}" onclick="filterByAction('all', this)">All</button>
+        <button class="filter-btn{{if .View.IsAction "create"}} active{{end}}" data-action="create" aria-pressed="{{.View.IsAction "create"}}" onclick="filterByAction('create', this)">Create</button>
+        <button class="filter-btn{{if .View.IsAction "update"}} active{{end}}" data-action="update" aria-pressed="{{.View.IsAction "update"}}" onclick="filterByAction('update', this)">Update</button>
+        <button class="filter-btn{{if .View.IsAction "delete"}} active{{end}}" data-action="delete" aria-pressed="{{.View.IsAction "delete"}}" onclick="filterByAction('delete', this)">Delete</button>
+        <button class="filter-btn{{if .View.IsAction "no-op"}} active{{end}}" data-action="no-op" aria-pressed="{{.View.IsAction "no-op"}}" onclick="filterByAction('no-op', this)">No-op</button>
+        <label class="filter-toggle" title="Hide updates that only change whitespace, key order or letter case"><input type="checkbox" id="hideCosmetic" onchange="filterResources()"> Hide formatting-only</label>
+        <label class="filter-toggle" title="Only show resources with values known after apply that other resources reference"><input type="checkbox" id="onlyUnknownReferenced" onchange="filterResources()"> Referenced values unknown</label>
+        <label class="filter-toggle">Sort by
+          <select id="sortBy" onchange="sortResources(this.value); syncViewURL()">
+            <option value="address"{{if .View.IsSort "address"}} selected{{end}}>Address</option>
+            <option value="impact"{{if .View.IsSort "impact"}} selected{{end}}>Impact</option>
+            <option value="action"{{if .View.IsSort "action"}} selected{{end}}>Action</option>
+            <option value="type"{{if .View.IsSort "type"}} selected{{end}}>Type</option>
+          </select>
+        </label>
+        {{if .View.Served}}<label class="filter-toggle">Group by
+          <select id="groupBy" onchange="changeGrouping()">
+            <option value="module"{{if .View.IsGroup "module"}} selected{{end}}>Module</option>
+            <option value="type"{{if .View.IsGroup "type"}} selected{{end}}>Resource type</option>
+            <option value="action"{{if .View.IsGroup "action"}} selected{{end}}>Action</option>
+          </select>
+        </label>{{end}}
+      </div>
+      <div class="layout-controls" role="group" aria-label="Layout">
+        <button type="button" class="ctrl-btn layout-btn" data-layout="cards" aria-pressed="{{not .View.IsTable}}" onclick="setLayout('cards')">Cards</button>
+        <button type="button" class="ctrl-btn layout-btn" data-layout="table" aria-pressed="{{.View.IsTable}}" onclick="setLayout('table')">Table</button>
+      </div>
+      <div class="expand-controls">
+        <button type="button" class="ctrl-btn" onclick="setAllExpanded(true)">Expand all</button>
+        <button type="button" class="ctrl-btn" onclick="setAllExpanded(false)">Collapse all</button>
       </div>
     </div>
+    {{with .Narrative}}
+    <section class="narrative" aria-labelledby="narrativeTitle">
+      <h2 id="narrativeTitle">Summary</h2>
+      <p class="narrative-text">{{.Text}}</p>
+      <p class="narrative-note">Generated by {{.Model}} from resource addresses, actions and impacts. It can be wrong; check it against the changes below.</p>
+    </section>
+    {{end}}
+    {{with .TopChanges}}{{if not .Empty}}
+    <section class="top-changes" aria-labelledby="topChangesTitle">
+      <h2 id="topChangesTitle">Top changes</h2>
+      <div class="top-changes-lists">
+        {{with .HighestImpact}}<div>
+          <h3>Highest impact</h3>
+          <ol>{{range .}}<li><a href="#{{.Address}}">{{.Address}}</a> <span class="top-detail">{{.Detail}}</span></li>{{end}}</ol>
+        </div>{{end}}
+        {{with .LargestDiffs}}<div>
+          <h3>Largest diffs</h3>
+          <ol>{{range .}}<li><a href="#{{.Address}}">{{.Address}}</a> <span class="top-detail">{{.Detail}}</span></li>{{end}}</ol>
+        </div>{{end}}
+        {{with .MostDependents}}<div>
+          <h3>Most dependents</h3>
+          <ol>{{range .}}<li><a href="#{{.Address}}">{{.Address}}</a> <span class="top-detail">{{.Detail}}</span></li>{{end}}</ol>
+        </div>{{end}}
+      </div>
+    </section>
+    {{end}}{{end}}
+    {{with .Deployments}}
+    <details class="module-inventory" open>
+      <summary>Deployments ({{len .}} image or chart changes)</summary>
+      <table>
+        <tr><th>Resource</th><th>Container</th><th>Image</th><th>Change</th></tr>
+        {{range .}}
+        <tr><td><code>{{.Address}}</code></td><td>{{.Container}}</td><td>{{if .SameImage}}<code>{{.Image}}</code>{{end}}</td><td><code>{{with .From}}{{.}}{{else}}(none){{end}}</code> → <code>{{with .To}}{{.}}{{else}}(none){{end}}</code></td></tr>
+        {{end}}
+      </table>
+    </details>
+    {{end}}
+    {{with .DNSChanges}}
+    <details class="module-inventory" open>
+      <summary>DNS changes ({{len .}})</summary>
+      <table>
+        <tr><th>Record</th><th>Type</th><th>Change</th><th>Values</th><th>TTL</th></tr>
+        {{range .}}
+        <tr><td><code>{{.Name}}</code><br><a href="#{{.Address}}">{{.Address}}</a></td><td>{{.Type}}</td><td>{{.Action}}</td><td>{{if and .Before .After}}{{if ne .Before .After}}<code>{{.Before}}</code> → <code>{{.After}}</code>{{else}}<code>{{.After}}</code>{{end}}{{else}}<code>{{.Before}}{{.After}}</code>{{end}}</td><td>{{if .TTLChanged}}{{.TTLBefore}} → {{.TTLAfter}}{{else}}{{with .TTLAfter}}{{.}}{{else}}{{.TTLBefore}}{{end}}{{end}}</td></tr>
+        {{end}}
+      </table>
+    </details>
+    {{end}}
+    {{with .LoadBalancerChanges}}
+    <details class="module-inventory" open>
+      <summary>Load balancing ({{len .}})</summary>
+      <table>
+        <tr><th>Resource</th><th>Change</th><th>Settings</th></tr>
+        {{range .}}
+        <tr><td>{{.Kind}}<br><a href="#{{.Address}}">{{.Address}}</a></td><td>{{.Action}}</td><td>{{range $i, $s := .Settings}}{{if $i}}<br>{{end}}{{.Name}}: {{if and .Before .After}}<code>{{.Before}}</code> → <code>{{.After}}</code>{{else}}<code>{{.Before}}{{.After}}</code>{{end}}{{end}}</td></tr>
+        {{end}}
+      </table>
+    </details>
+    {{end}}
+    {{with .Capacity}}
+    <details class="module-inventory" open>
+      <summary>Capacity ({{len .Changes}}){{with .String}} · {{.}}{{end}}</summary>
+      <table>
+        <tr><th>Resource</th><th>Change</th><th>Settings</th></tr>
+        {{range .Changes}}
+        <tr><td>{{.Kind}}<br><a href="#{{.Address}}">{{.Address}}</a></td><td>{{.Action}}</td><td>{{range $i, $s := .Settings}}{{if $i}}<br>{{end}}{{.Name}}: {{if and .Before .After}}<code>{{.Before}}</code> → <code>{{.After}}</code>{{else}}<code>{{.Before}}{{.After}}</code>{{end}}{{end}}</td></tr>
+        {{end}}
+      </table>
+    </details>
+    {{end}}
+    {{with .Certificates}}
+    <details class="module-inventory" open>
+      <summary>Certificates ({{len .}})</summary>
+      <table>
+        <tr><th>Resource</th><th>Change</th><th>Domains</th><th>Validation</th><th>Validity</th><th>In use by</th></tr>
+        {{range .}}
+        <tr><td><a href="#{{.Address}}">{{.Address}}</a></td><td>{{if eq .Action "no-op"}}unchanged{{else}}{{.Action}}{{end}}</td><td>{{with .DomainsBefore}}{{range $i, $d := .}}{{if $i}}, {{end}}<del>{{$d}}</del>{{end}}<br>{{end}}{{range $i, $d := .Domains}}{{if $i}}, {{end}}{{$d}}{{end}}</td><td>{{if .ValidationChanged}}<strong>{{.ValidationBefore}} → {{.ValidationAfter}}</strong>{{else}}{{with .ValidationAfter}}{{.}}{{else}}{{with .ValidationBefore}}{{.}}{{else}}-{{end}}{{end}}{{end}}</td><td>{{with .Expires}}{{.Format "2006-01-02"}}<br>{{end}}{{if .ExpiresSoon}}<strong>⚠️ {{.Remaining}}</strong>{{else}}{{with .Remaining}}{{.}}{{else}}-{{end}}{{end}}</td><td>{{range $i, $u := .InUseBy}}{{if $i}}<br>{{end}}<a href="#{{$u}}">{{$u}}</a>{{else}}-{{end}}</td></tr>
+        {{end}}
+      </table>
+    </details>
+    {{end}}
+    {{with .StatefulDestroys}}
+    <div class="stateful-banner">
+      <strong>⚠️ This plan destroys {{len .}} stateful resource(s).</strong> Make sure their data is backed up or no longer needed before applying.
+      <ul>
+        {{range .}}
+        <li><code>{{.Address}}</code> ({{if .Replace}}replace{{else}}delete{{end}})</li>
+        {{end}}
+      </ul>
+      <label><input type="checkbox" onchange="this.closest('.stateful-banner').classList.toggle('confirmed', this.checked)"> I have reviewed these deletions</label>
+    </div>
+    {{end}}
+    {{with .OrphanDeletes}}
+    <div class="orphans">
+      <h2>Removed from configuration</h2>
+      <p>These resources are destroyed only because their configuration no longer exists. Check that no file or module block was deleted by accident.</p>
+      {{range .}}
+      <h3>{{.Module}} · {{.Reason}} ({{len .Addresses}})</h3>
+      <ul>{{range .Addresses}}<li><code>{{.}}</code></li>{{end}}</ul>
+      {{end}}
+    </div>
+    {{end}}
+    {{if .Imports}}
+    <details class="module-inventory">
+      <summary>Import blocks ({{len .Imports}})</summary>
+      <p>Removed or externally deleted resources that may still exist. Paste these into the configuration that should manage them, or run <code>tfviz imports --format commands</code>.</p>
+      <pre>{{.ImportBlocks}}</pre>
+    </details>
+    {{end}}
+    {{with .Findings}}
+    <div class="findings">
+      <h2>Findings</h2>
+      {{range .}}
+      <div class="finding {{.Category}}">
+        <h3>{{.Title}}</h3>
+        <p><code>{{.Address}}</code>{{if .Detail}} · {{.Detail}}{{end}}</p>
+        {{if .Attributes}}
+        <p class="finding-attributes">{{range .Attributes}}<code>{{.Name}} = {{.Value}}</code> {{end}}</p>
+        {{end}}
+        {{with .Remediation}}<pre class="finding-remediation">{{.}}</pre>{{end}}
+      </div>
+      {{end}}
+    </div>
+    {{end}}
+    {{with .Acknowledged}}
+    <details class="module-inventory">
+      <summary>Acknowledged findings ({{len .}})</summary>
+      <table>
+        <tr><th>Finding</th><th>Resource</th></tr>
+        {{range .}}<tr><td>{{.Title}}</td><td><code>{{.Address}}</code></td></tr>
+        {{end}}
+      </table>
+    </details>
+    {{end}}
+    {{if .ModuleCalls}}
+    <details class="module-inventory">
+      <summary>Modules ({{len .ModuleCalls}}{{with .UnpinnedModuleCount}}, {{.}} not pinned{{end}})</summary>
+      <table>
+        <tr><th>Module</th><th>Source</th><th>Version</th><th></th></tr>
+        {{range .ModuleCalls}}
+        <tr><td><code>{{.Address}}</code></td><td>{{.Source}}</td><td>{{.Version}}{{if .Latest}} · <a href="{{.LatestURL}}" target="_blank" rel="noopener">{{.Latest}} available</a>{{end}}</td><td class="module-warning">{{if .Warning}}⚠️ {{.Warning}}{{end}}</td></tr>
+        {{end}}
+      </table>
+    </details>
+    {{end}}
+    {{if .ProviderVersions}}
+    <details class="module-inventory">
+      <summary>Providers ({{len .ProviderVersions}})</summary>
+      <table>
+        <tr><th>Provider</th><th>Constraint</th><th>Locked</th><th>Latest</th></tr>
+        {{range .ProviderVersions}}
+        <tr><td><code>{{.Source}}</code></td><td>{{.Constraint}}</td><td>{{.Locked}}</td><td>{{.Latest}}{{if .Warning}} <span class="module-warning">⚠️ {{.Warning}}</span>{{end}}</td></tr>
+        {{end}}
+      </table>
+    </details>
+    {{end}}
+    {{if or .RemoteStates .StackOutputs}}
+    <details class="module-inventory">
+      <summary>Cross-stack dependencies ({{len .RemoteStates}} remote state{{if ne (len .RemoteStates) 1}}s{{end}}{{with .StackOutputs}}, {{len .}} changed output{{if ne (len .) 1}}s{{end}}{{end}})</summary>
+      <table>
+        <tr><th>Dependency</th><th>Resources</th></tr>
+        {{range .RemoteStates}}
+        <tr><td><code>{{.Address}}</code>{{with .Backend}} · {{.}}{{end}}{{with .Location}} <code>{{.}}</code>{{end}}</td><td>{{range $i, $c := .Consumers}}{{if $i}}<br>{{end}}<code>{{$c.Address}}</code> {{$c.Attribute}} ← outputs.{{$c.Output}}{{else}}not referenced{{end}}</td></tr>
+        {{end}}
+        {{range .StackOutputs}}
+        <tr><td><code>output.{{.Name}}</code> · {{if eq .Action "delete"}}removed, other stacks reading it will fail{{else if .ReadBy}}changes for the plans that read it{{else}}may change for other stacks{{end}}</td><td>{{range $i, $a := .Changed}}{{if $i}}<br>{{end}}<code>{{$a}}</code>{{else}}value changes{{end}}{{range .ReadBy}}<br>→ <code>{{.}}</code>{{end}}</td></tr>
+        {{end}}
+      </table>
+    </details>
+    {{end}}
+    {{with .OutputImpacts}}
+    <details class="module-inventory">
+      <summary>Module outputs read elsewhere ({{len .}})</summary>
+      <table>
+        <tr><th>Output</th><th>Changes because of</th><th>Read by</th></tr>
+        {{range .}}
+        <tr><td><code>{{.Output}}</code></td><td>{{range $i, $a := .Changed}}{{if $i}}<br>{{end}}<code>{{$a}}</code>{{end}}</td><td>{{range $i, $c := .Consumers}}{{if $i}}<br>{{end}}<code>{{$c.Address}}</code> {{$c.Attribute}}{{end}}</td></tr>
+        {{end}}
+      </table>
+    </details>
+    {{end}}
+    {{with .Drift}}
+    <details class="module-inventory">
+      <summary>Changed outside Terraform ({{len .}})</summary>
+      <table>
+        <tr><th>Resource</th><th>Change</th><th>Attributes</th><th>Likely changed by</th></tr>
+        {{range .}}
+        <tr><td><code>{{.Address}}</code></td><td>{{.Action}}</td><td>{{range $i, $a := .Attributes}}{{if $i}}, {{end}}<code>{{$a}}</code>{{end}}</td><td>{{with .Actor}}{{.Name}} <span class="module-count">({{.Evidence}})</span>{{else}}-{{end}}</td></tr>
+        {{end}}
+      </table>
+    </details>
+    {{end}}
+    {{with .Reviewers}}
+    <details class="module-inventory" open>
+      <summary>Who needs to review ({{len .Groups}})</summary>
+      <table>
+        <tr><th>Owner</th><th>Changes</th></tr>
+        {{range .Groups}}
+        <tr><td>{{.Owner}}</td><td>{{range $i, $a := .Resources}}{{if $i}}<br>{{end}}<code>{{$a}}</code>{{end}}</td></tr>
+        {{end}}
+        {{with .Unowned}}<tr><td><em>No owner</em></td><td>{{range $i, $a := .}}{{if $i}}<br>{{end}}<code>{{$a}}</code>{{end}}</td></tr>{{end}}
+      </table>
+    </details>
+    {{end}}
+    {{with .Metrics}}
+    <details class="module-inventory">
+      <summary>Performance ({{.Size}})</summary>
+      <table>
+        <tr><th>Stage</th><th>Duration</th></tr>
+        {{range .Stages}}<tr><td>{{.Name}}</td><td>{{.Text}}</td></tr>
+        {{end}}<tr><td>render report</td><td>` + renderTimeMarker + `</td></tr>
+      </table>
+      {{with .Retries}}
+      <table>
+        <tr><th>Retried</th><th>Reason</th><th>Waited</th><th>Error</th></tr>
+        {{range .}}<tr><td>{{.Command}} ({{.Retry}})</td><td>{{.Reason}}</td><td>{{.WaitText}}</td><td><code>{{.Error}}</code></td></tr>
+        {{end}}
+      </table>
+      {{end}}
+    </details>
+    {{end}}
+    {{with .LintIssues}}
+    <details class="lint">
+      <summary>Configuration lint ({{len .}})</summary>
+      <ul>{{range .}}<li><code>{{.Module}}</code> {{.Message}}</li>{{end}}</ul>
+    </details>
+    {{end}}
+    {{with .HighRiskResources}}
+    <div class="high-risk">
+      <h2>High-risk changes</h2>
+      <ul>
+        {{range .}}
+        <li><span class="action-icon {{.Action}}">{{slice .Action 0 1}}</span> {{.Address}}{{if .Replace}} (replace){{end}}{{if .ImpactReason}} <span class="impact-reason">{{.ImpactReason}}</span>{{end}}</li>
+        {{end}}
+      </ul>
+    </div>
+    {{end}}
+    {{with .Timeline}}
+    <details class="timeline">
+      <summary>⏱️ Estimated apply time {{.TotalText}} with parallelism {{.Parallelism}} ({{.UnlimitedText}} if only dependencies limited it)</summary>
+      <ol class="timeline-chains">
+        {{range .Chains}}
+        <li>{{.DurationText}}: {{range $i, $r := .Resources}}{{if $i}} → {{end}}<code>{{$r}}</code>{{end}}</li>
+        {{end}}
+      </ol>
+      <div class="timeline-rows">
+        {{range .Bars}}
+        <div class="timeline-row{{if .Critical}} critical{{end}}">
+          <span class="timeline-label" title="{{.Address}}">{{.Address}}</span>
+          <div class="timeline-track"><div class="timeline-bar {{.Action}}" style="left: {{.Left}}%; width: {{.Width}}%" title="starts at {{.StartText}}, takes {{.DurationText}}"></div></div>
+        </div>
+        {{end}}
+      </div>
+      <p class="timeline-note">The longest dependency chains are listed above; changes on the longest one are outlined. A plan that is much slower than its longest chain is limited by parallelism and may be worth splitting.</p>
+    </details>
+    {{end}}
 
     {{if .ShowGraph}}
     <div class="graph-toolbar">
@@ -1619,6 +3311,11 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
           <button class="ctrl-btn" onclick="expandAll()">Expand All</button>
           <button class="ctrl-btn" onclick="collapseAll()">Collapse All</button>
           <button class="ctrl-btn" onclick="cy.fit(null,50)">Fit</button>
+          <button class="mod-btn" onclick="toggleChangedOnly(this)" title="Show only changed resources and their direct neighbours">Changed only</button>
+        </div>
+        <div class="toolbar-group">
+          <span class="toolbar-label">Path</span>
+          <span id="pathInfo" class="path-info">Click a resource, then Shift-click another</span>
         </div>
       </div>
       <div class="graph-legend">
@@ -1633,33 +3330,148 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
     <div id="graph"></div>
     {{end}}
 
-    <div class="resource-list">
+    <div class="resource-list" id="resources" tabindex="-1"{{if .View.IsTable}} hidden{{end}}>
+      {{with .Summary.HiddenModules}}<p class="hidden-modules-note">{{.}} module(s) without changes are not listed. Run tfviz with --include-unchanged-modules to list them.</p>{{end}}
       {{range .Modules}}
-      <div class="module">
+      <div class="module" data-sort-address="{{.SortKey "address"}}" data-sort-impact="{{.SortKey "impact"}}" data-sort-action="{{.SortKey "action"}}" data-sort-type="{{.SortKey "type"}}"{{if not ($.View.ShowsModule .)}} style="display: none"{{end}}>
         <div class="module-header">
-          <h2>{{.Address}}</h2>
+          <h2><button type="button" class="module-toggle" aria-expanded="true" aria-controls="{{.ContentID}}" onclick="toggleModule(this)">{{.Address}} <span class="module-count">({{len .Resources}})</span></button></h2>
+          {{with .Rollup}}<div class="module-rollup">
+            {{if .Create}}<span class="rollup-count create">+{{.Create}}</span>{{end}}
+            {{if .Update}}<span class="rollup-count update">~{{.Update}}</span>{{end}}
+            {{if .Delete}}<span class="rollup-count delete">-{{.Delete}}</span>{{end}}
+            {{if .Replace}}<span class="rollup-note">{{.Replace}} replaced</span>{{end}}
+            {{if .Impact}}<span class="rollup-impact {{.Impact}}">{{.Impact}} impact{{if .HighRisk}} · {{.HighRisk}} high-risk{{end}}</span>{{end}}
+          </div>{{end}}
         </div>
+        <div class="module-resources" id="{{.ContentID}}">
         {{range .Resources}}
-        <div class="resource{{if ne .Action "no-op"}} resource-changed-{{.Action}}{{end}}" onclick="toggleDetails(this)">
+        <div id="{{.AnchorID}}" data-address="{{.Address}}" data-sort-address="{{.SortKey "address"}}" data-sort-impact="{{.SortKey "impact"}}" data-sort-action="{{.SortKey "action"}}" data-sort-type="{{.SortKey "type"}}" class="resource{{if ne .Action "no-op"}} resource-changed-{{.Action}}{{end}}{{if or .RenamedTo .RenamedFrom}} resource-rename{{end}}"{{if .CosmeticOnly}} data-cosmetic="true"{{end}}{{if .UnknownReferenced}} data-unknown-referenced="true"{{end}}{{if not ($.View.Shows .)}} style="display: none"{{end}}>
           <div class="resource-header">
-            <div class="action-icon {{.Action}}">{{slice .Action 0 1}}</div>
+            {{if ne .Action "no-op"}}<input type="checkbox" class="target-select" value="{{.Address}}" title="Select for terraform apply -target" aria-label="Select {{.Address}} for terraform apply -target" onchange="updateTargets()">{{end}}
+            <div class="action-icon {{.Action}}" aria-hidden="true">{{slice .Action 0 1}}</div>
             <div class="resource-info">
-              <h3>{{.Address}}</h3>
-              <p>{{.Type}}</p>
+              <h3><button type="button" class="resource-toggle" aria-expanded="false" aria-controls="{{.DetailsID}}" onclick="toggleDetails(this)">{{.Address}}<span class="visually-hidden"> ({{.Action}})</span></button></h3>
+              <p>{{.Type}}{{with .Target}} · <span class="provider-target" title="Provider configuration, account and region">{{.}}</span>{{end}}{{with .ConstructPath}} · <span class="construct-path" title="CDK for Terraform construct">{{.}}</span>{{end}}{{with .Source}} · <span class="source-location"{{with .ModuleSource}} title="Module source: {{.}}"{{end}}>defined in {{if .URL}}<a href="{{.URL}}" target="_blank" rel="noopener">{{.File}}</a>{{else}}{{.File}}{{end}}</span>{{end}}{{if .RenamedTo}} · <span class="rename-tag">likely rename to {{.RenamedTo}}</span>{{else if .RenamedFrom}} · <span class="rename-tag">likely rename from {{.RenamedFrom}}</span>{{end}}{{if .Runbook}} · <a class="runbook-link" href="{{.Runbook}}" target="_blank" rel="noopener">Runbook</a>{{end}}</p>
+            </div>
+            <div class="copy-actions">
+              {{if ne .Action "no-op"}}<span class="review-status" hidden></span>{{end}}
+              <button type="button" class="ctrl-btn copy-btn" data-copy="{{.Address}}" onclick="copyText(this)" aria-label="Copy address of {{.Address}}">Copy address</button>
+              <button type="button" class="ctrl-btn copy-btn" onclick="copyLink(this)" aria-label="Copy link to {{.Address}}">Copy link</button>
+              {{with .ApplyCommand}}<button type="button" class="ctrl-btn copy-btn" data-copy="{{.}}" onclick="copyText(this)" title="{{.}}">Copy apply command</button>{{end}}
             </div>
           </div>
-          <div class="details">
-            <pre>{{range .DiffLines}}<div class="diff-line-{{.Type}}">{{.Text}}</div>{{end}}</pre>
+          <div class="details" id="{{.DetailsID}}" hidden>
+            <p class="resource-description">{{.Description}}</p>
+            {{with .RiskNote}}<p class="risk-note"><strong>Why this is risky:</strong> {{.}}</p>{{end}}
+            {{if .CosmeticOnly}}<p class="cosmetic-note">Only formatting changes: whitespace, key order or letter case. The provider will likely treat the values as equal.</p>{{end}}
+            {{with .SizeChanges}}<p class="size-changes">Element counts: {{range $i, $c := .}}{{if $i}}, {{end}}<code>{{$c.Field}}</code> {{$c.Size}}{{end}}</p>{{end}}
+            {{if .RenamedTo}}<p class="rename-note">This looks like a refactor rather than a destroy: <code>{{.RenamedTo}}</code> is created with nearly the same attributes. Add a moved block to keep the existing object.</p>
+            {{else if .RenamedFrom}}<p class="rename-note">This looks like a refactor rather than a new resource: <code>{{.RenamedFrom}}</code> is destroyed with nearly the same attributes. Add a moved block to keep the existing object.</p>{{end}}
+            <div class="detail-tabs" role="tablist" aria-label="Details of {{.Address}}" onkeydown="tabKey(event)">
+              <button type="button" role="tab" class="detail-tab" id="{{.DetailsID}}-diff-tab" aria-selected="true" aria-controls="{{.DetailsID}}-diff" onclick="selectTab(this)">Diff</button>
+              {{if ne .Action "no-op"}}
+              <button type="button" role="tab" class="detail-tab" id="{{.DetailsID}}-before-tab" aria-selected="false" aria-controls="{{.DetailsID}}-before" tabindex="-1" onclick="selectTab(this)">Before JSON</button>
+              <button type="button" role="tab" class="detail-tab" id="{{.DetailsID}}-after-tab" aria-selected="false" aria-controls="{{.DetailsID}}-after" tabindex="-1" onclick="selectTab(this)">After JSON</button>
+              {{end}}
+              <button type="button" role="tab" class="detail-tab" id="{{.DetailsID}}-meta-tab" aria-selected="false" aria-controls="{{.DetailsID}}-meta" tabindex="-1" onclick="selectTab(this)">Metadata</button>
+            </div>
+            <div role="tabpanel" id="{{.DetailsID}}-diff" aria-labelledby="{{.DetailsID}}-diff-tab">
+            <div class="diff-block">
+              <button type="button" class="ctrl-btn copy-btn diff-copy" data-copy="{{.DiffText}}" onclick="copyText(this)">Copy diff</button>
+              <pre>{{range .DiffLines}}<div class="diff-line-{{.Type}}">{{.Text}}</div>{{end}}</pre>
+            </div>
+            {{if .UnknownReferenced}}
+            <p class="unknown-note">Known after apply and referenced by other resources: {{range $i, $a := .UnknownReferenced}}{{if $i}}, {{end}}<code>{{$a}}</code>{{end}}</p>
+            {{end}}
+            {{if .UnknownSources}}
+            <h4>Known after apply because of:</h4>
+            <ul class="resource-references">{{range .UnknownSources}}<li><code>{{.}}</code></li>{{end}}</ul>
+            {{end}}
+            {{if .References}}
+            <h4>References:</h4>
+            <ul class="resource-references">{{range .References}}<li><code>{{.Attribute}}</code> → <code>{{.Expression}}</code></li>{{end}}</ul>
+            {{end}}
             {{if .PolicyDocumentJSON}}
             <h4>Policy Document:</h4>
             <pre>{{.PolicyDocumentJSON}}</pre>
             {{end}}
+            </div>
+            {{if ne .Action "no-op"}}
+            <div role="tabpanel" id="{{.DetailsID}}-before" aria-labelledby="{{.DetailsID}}-before-tab" hidden>
+              {{with .BeforeJSON}}<pre class="raw-json">{{.}}</pre>{{else}}<p class="empty-panel">The object does not exist before this change.</p>{{end}}
+            </div>
+            <div role="tabpanel" id="{{.DetailsID}}-after" aria-labelledby="{{.DetailsID}}-after-tab" hidden>
+              {{with .AfterJSON}}<pre class="raw-json">{{.}}</pre>{{else}}<p class="empty-panel">The object does not exist after this change.</p>{{end}}
+              {{if .Unknown}}<p class="unknown-note">Known after apply and not shown: {{range $i, $a := .Unknown}}{{if $i}}, {{end}}<code>{{$a}}</code>{{end}}</p>{{end}}
+            </div>
+            {{end}}
+            <div role="tabpanel" id="{{.DetailsID}}-meta" aria-labelledby="{{.DetailsID}}-meta-tab" hidden>
+              <dl class="resource-meta">
+                <dt>Address</dt><dd>{{.Address}}</dd>
+                <dt>Type</dt><dd>{{.Type}}</dd>
+                <dt>Name</dt><dd>{{.Name}}</dd>
+                <dt>Provider</dt><dd>{{.Provider}}</dd>
+                {{with .Target}}<dt>Target</dt><dd>{{.}}</dd>{{end}}
+                {{with .ConstructPath}}<dt>Construct</dt><dd>{{.}}</dd>{{end}}
+                {{with .Source}}<dt>Defined in</dt><dd>{{if .URL}}<a href="{{.URL}}" target="_blank" rel="noopener">{{.}}</a>{{else}}{{.}}{{end}}{{with .ModuleSource}} (module <code>{{.}}</code>){{end}}</dd>{{end}}
+                <dt>Action</dt><dd>{{.Action}}{{if .Replace}} (replace){{end}}</dd>
+                {{with .ActionReason}}<dt>Action reason</dt><dd><code>{{.}}</code></dd>{{end}}
+                <dt>Impact</dt><dd>{{.Impact}}{{with .ImpactReason}}: {{.}}{{end}}</dd>
+                {{if .Stateful}}<dt>Stateful</dt><dd>yes</dd>{{end}}
+                {{with .DependsOn}}<dt>Depends on</dt><dd>{{range $i, $d := .}}{{if $i}}, {{end}}<code>{{$d}}</code>{{end}}</dd>{{end}}
+              </dl>
+            </div>
+            {{if ne .Action "no-op"}}
+            <div class="review" data-address="{{.Address}}" hidden>
+              <h4>Review</h4>
+              <label class="filter-toggle"><input type="checkbox" class="review-signoff" onchange="signOff(this)"> Reviewed and approved</label>
+              <ul class="review-comments"></ul>
+              <form class="review-form" onsubmit="return addComment(this)">
+                <input type="text" name="comment" placeholder="Add a comment" aria-label="Comment on {{.Address}}">
+                <button type="submit" class="ctrl-btn">Comment</button>
+              </form>
+            </div>
+            {{end}}
           </div>
         </div>
         {{end}}
+        </div>
       </div>
       {{end}}
     </div>
+
+    <div class="change-table-wrap" id="changeTable"{{if not .View.IsTable}} hidden{{end}}>
+      <table class="change-table">
+        <thead>
+          <tr>
+            <th scope="col" aria-sort="none"><button type="button" onclick="sortTable(this, 'address')">Address</button></th>
+            <th scope="col" aria-sort="none"><button type="button" onclick="sortTable(this, 'type')">Type</button></th>
+            <th scope="col" aria-sort="none"><button type="button" onclick="sortTable(this, 'action')">Action</button></th>
+            <th scope="col" aria-sort="none"><button type="button" onclick="sortTable(this, 'impact')">Impact</button></th>
+            <th scope="col" aria-sort="none"><button type="button" onclick="sortTable(this, 'changes')">Changed attributes</button></th>
+          </tr>
+        </thead>
+        <tbody>
+          {{range .Modules}}{{range .Resources}}
+          <tr class="change-row" data-address="{{.Address}}" data-type="{{.Type}}" data-action="{{.Action}}" data-sort-address="{{.SortKey "address"}}" data-sort-type="{{.SortKey "type"}}" data-sort-action="{{.SortKey "action"}}" data-sort-impact="{{.SortKey "impact"}}" data-sort-changes="{{.ChangesSortKey}}"{{if .CosmeticOnly}} data-cosmetic="true"{{end}}{{if .UnknownReferenced}} data-unknown-referenced="true"{{end}}{{if not ($.View.Shows .)}} style="display: none"{{end}}>
+            <td><a href="#{{.Address}}">{{.Address}}</a></td>
+            <td>{{.Type}}</td>
+            <td><span class="action-icon {{.Action}}" aria-hidden="true">{{slice .Action 0 1}}</span> {{.Action}}{{if .Replace}} (replace){{end}}</td>
+            <td><span class="rollup-impact {{.Impact}}">{{.Impact}}</span></td>
+            <td>{{with .KeyChanges}}<code>{{.}}</code>{{end}}</td>
+          </tr>
+          {{end}}{{end}}
+        </tbody>
+      </table>
+    </div>
+  </main>
+
+  <div id="targetBar" class="target-bar" hidden>
+    <span id="targetCount"></span>
+    <code id="targetCommand"></code>
+    <button class="ctrl-btn" onclick="navigator.clipboard.writeText(document.getElementById('targetCommand').textContent)">Copy</button>
+    <button class="ctrl-btn" onclick="clearTargets()">Clear</button>
   </div>
 
   {{if .ShowGraph}}
@@ -1714,6 +3526,21 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
             'border-color': '#0366d6',
             'color': '#0550ae'
         }},
+        { selector: ':parent.module-group', style: {
+            'background-color': '#6a737d',
+            'border-color': '#6a737d',
+            'border-style': 'solid',
+            'color': '#444d56'
+        }},
+        { selector: ':parent.module-group[impact = "Medium"]', style: {
+            'border-color': '#dbab09',
+            'color': '#8a6d00'
+        }},
+        { selector: ':parent.module-group[impact = "High"]', style: {
+            'border-color': '#d73a49',
+            'border-width': 3,
+            'color': '#9e1c23'
+        }},
         { selector: '.cy-collapsed', style: {
             'background-opacity': 0.15,
             'border-style': 'solid'
@@ -1742,6 +3569,8 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
         { selector: 'node.update:childless', style: { 'background-color': '#dbab09', 'text-outline-color': '#8a6d00' }},
         { selector: 'node.delete:childless', style: { 'background-color': '#d73a49', 'text-outline-color': '#9e1c23' }},
         { selector: 'node.container:childless', style: { 'background-color': '#6a737d', 'text-outline-color': '#444d56' }},
+        { selector: 'node.remote-state:childless', style: { 'background-color': '#0366d6', 'text-outline-color': '#024c91', 'shape': 'cut-rectangle' }},
+        { selector: 'node.stack-output:childless', style: { 'background-color': '#6f42c1', 'text-outline-color': '#4c2889', 'shape': 'tag' }},
 
         { selector: 'edge', style: {
             'width': 1.5,
@@ -1756,11 +3585,28 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
             'target-arrow-color': '#6f42c1',
             'line-style': 'dashed'
         }},
+        { selector: 'edge.reference.highlighted[label]', style: {
+            'label': 'data(label)',
+            'font-size': '9px',
+            'color': '#6f42c1',
+            'text-wrap': 'wrap',
+            'text-background-color': '#fff',
+            'text-background-opacity': 0.85,
+            'text-background-padding': '2px'
+        }},
+        { selector: 'edge.cross-stack', style: {
+            'line-color': '#0366d6',
+            'target-arrow-color': '#0366d6',
+            'width': 2
+        }},
         { selector: 'edge.depends_on', style: {
             'line-color': '#e36209',
             'target-arrow-color': '#e36209',
             'line-style': 'dotted'
         }},
+        { selector: 'node.focus:childless', style: { 'border-width': 3, 'border-color': '#24292e', 'border-opacity': 1 }},
+        { selector: 'node.path:childless', style: { 'border-width': 3, 'border-color': '#0366d6', 'border-opacity': 1 }},
+        { selector: 'edge.path', style: { 'width': 3, 'line-color': '#0366d6', 'target-arrow-color': '#0366d6', 'opacity': 1 }},
         { selector: '.faded', style: { 'opacity': 0.12 }},
         { selector: '.highlighted', style: { 'opacity': 1 }}
       ]
@@ -1838,9 +3684,17 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
       mfDiv.appendChild(btn);
     });
 
+    let changedOnly = false;
+    function toggleChangedOnly(btn) {
+      changedOnly = !changedOnly;
+      btn.classList.toggle('active', changedOnly);
+      applyModuleFilter();
+    }
+
     function applyModuleFilter() {
       cy.nodes('[module]').forEach(function(node) {
-        if (hiddenModules.has(node.data('module'))) {
+        const outside = changedOnly && node.isChildless() && !node.hasClass('changed-subgraph');
+        if (hiddenModules.has(node.data('module')) || outside) {
           node.style('display', 'none');
           node.connectedEdges().style('display', 'none');
         } else {
@@ -1861,35 +3715,306 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
       });
     }
 
-    /* ── Highlight neighbors on leaf tap ── */
+    /* ── Highlight neighbors on leaf tap, paths on Shift-tap ── */
+    const pathInfo = document.getElementById('pathInfo');
+    let pathSource = null;
+
+    function showPaths(paths) {
+      cy.elements().removeClass('faded highlighted path');
+      if (paths.length === 0) {
+        pathInfo.textContent = 'No dependency path between ' + pathSource.id() + ' and the selected resource';
+        return;
+      }
+      let path = cy.collection();
+      paths.forEach(function(p) {
+        p.nodes.forEach(function(id) { path = path.union(cy.getElementById(id)); });
+        p.edges.forEach(function(id) { path = path.union(cy.getElementById(id)); });
+      });
+      cy.elements().not(path).not(':parent').addClass('faded');
+      path.addClass('highlighted path');
+      pathInfo.textContent = paths.length + (paths.length === 1 ? ' path' : ' paths') + ' of ' + (paths[0].nodes.length - 1) + ' hops';
+    }
+
+    function highlightPaths(from, to) {
+      fetch('paths.json?from=' + encodeURIComponent(from.id()) + '&to=' + encodeURIComponent(to.id()))
+        .then(function(r) { return r.ok ? r.json() : Promise.reject(r.status); })
+        .then(function(data) { showPaths(data.paths); })
+        .catch(function() {
+          // Saved reports have no server; fall back to a single shortest path.
+          const res = cy.elements().aStar({ root: from, goal: to, directed: false });
+          showPaths(res.found ? [{
+            nodes: res.path.nodes().map(function(n) { return n.id(); }),
+            edges: res.path.edges().map(function(e) { return e.id(); })
+          }] : []);
+        });
+    }
+
     cy.on('tap', 'node:childless', function(evt) {
-      cy.elements().removeClass('faded highlighted');
       const n = evt.target;
+      if (evt.originalEvent && evt.originalEvent.shiftKey && pathSource && pathSource !== n) {
+        highlightPaths(pathSource, n);
+        return;
+      }
+      pathSource = n;
+      pathInfo.textContent = 'From ' + n.id() + '; Shift-click another resource';
+      cy.elements().removeClass('faded highlighted path');
       const hood = n.neighborhood().add(n);
       cy.elements().not(hood).not(':parent').addClass('faded');
       hood.addClass('highlighted');
     });
     cy.on('tap', function(evt) {
-      if (evt.target === cy) cy.elements().removeClass('faded highlighted');
+      if (evt.target === cy) {
+        cy.elements().removeClass('faded highlighted path');
+        pathSource = null;
+        pathInfo.textContent = 'Click a resource, then Shift-click another';
+      }
     });
   </script>
   {{end}}
 
   <script>
-    function toggleDetails(el) {
-      const details = el.querySelector('.details');
-      if (details.style.display === 'block') {
-        details.style.display = 'none';
-      } else {
-        details.style.display = 'block';
+    // Shell quoting matches tfviz targets so the command can be pasted as is.
+    function shellQuote(s) {
+      if (/^[A-Za-z0-9_.=\/:-]+$/.test(s)) return s;
+      return "'" + s.replace(/'/g, "'\\''") + "'";
+    }
+
+    function updateTargets() {
+      const selected = Array.from(document.querySelectorAll('.target-select:checked')).map(function(cb) { return cb.value; });
+      const bar = document.getElementById('targetBar');
+      bar.hidden = selected.length === 0;
+      document.getElementById('targetCount').textContent = selected.length + ' selected';
+      document.getElementById('targetCommand').textContent = ['terraform', 'apply'].concat(selected.map(function(a) {
+        return shellQuote('-target=' + a);
+      })).join(' ');
+    }
+
+    function clearTargets() {
+      document.querySelectorAll('.target-select:checked').forEach(function(cb) { cb.checked = false; });
+      updateTargets();
+    }
+
+    // Copies the button's data-copy text and briefly confirms it.
+    function copyText(button) {
+      navigator.clipboard.writeText(button.dataset.copy).then(function() {
+        const label = button.dataset.label || button.textContent;
+        button.dataset.label = label;
+        button.textContent = 'Copied';
+        setTimeout(function() { button.textContent = label; }, 1200);
+      });
+    }
+
+    // Copies a permalink to the button's resource.
+    function copyLink(button) {
+      const resource = button.closest('.resource');
+      button.dataset.copy = location.href.split('#')[0] + '#' + encodeURIComponent(resource.dataset.address);
+      copyText(button);
+    }
+
+    function toggleDetails(button, open) {
+      const details = document.getElementById(button.getAttribute('aria-controls'));
+      if (open === undefined) {
+        open = button.getAttribute('aria-expanded') !== 'true';
+      }
+      button.setAttribute('aria-expanded', String(open));
+      details.hidden = !open;
+    }
+
+    function selectTab(tab) {
+      tab.parentElement.querySelectorAll('[role="tab"]').forEach(function(t) {
+        const selected = t === tab;
+        t.setAttribute('aria-selected', String(selected));
+        t.tabIndex = selected ? 0 : -1;
+        document.getElementById(t.getAttribute('aria-controls')).hidden = !selected;
+      });
+    }
+
+    // Moves between detail tabs with the arrow keys.
+    function tabKey(e) {
+      if (e.key !== 'ArrowLeft' && e.key !== 'ArrowRight') {
+        return;
+      }
+      const tabs = Array.from(e.currentTarget.querySelectorAll('[role="tab"]'));
+      const i = tabs.indexOf(document.activeElement);
+      if (i < 0) {
+        return;
+      }
+      const next = tabs[(i + (e.key === 'ArrowRight' ? 1 : tabs.length - 1)) % tabs.length];
+      selectTab(next);
+      next.focus();
+    }
+
+    function toggleModule(button, open) {
+      const resources = document.getElementById(button.getAttribute('aria-controls'));
+      if (open === undefined) {
+        open = button.getAttribute('aria-expanded') !== 'true';
       }
+      button.setAttribute('aria-expanded', String(open));
+      resources.hidden = !open;
+    }
+
+    // Expands every module and resource, or collapses them down to the
+    // module headers.
+    function setAllExpanded(open) {
+      document.querySelectorAll('.resource-toggle').forEach(function(b) { toggleDetails(b, open); });
+      document.querySelectorAll('.module-toggle').forEach(function(b) { toggleModule(b, open); });
     }
 
+    // Expands and scrolls to the resource named by the URL fragment, e.g.
+    // #module.app.aws_instance.web. Filters that hide it are overridden.
+    function openLinkedResource() {
+      let address;
+      try {
+        address = decodeURIComponent(location.hash.slice(1));
+      } catch (e) {
+        return;
+      }
+      if (!address) {
+        return;
+      }
+      const resource = Array.from(document.querySelectorAll('.resource')).find(function(r) {
+        return r.dataset.address === address || r.id === address;
+      });
+      if (!resource) {
+        return;
+      }
+      if (!document.getElementById('changeTable').hidden) {
+        setLayout('cards');
+      }
+      resource.style.display = '';
+      resource.closest('.module').style.display = '';
+      toggleModule(resource.closest('.module').querySelector('.module-toggle'), true);
+      const button = resource.querySelector('.resource-toggle');
+      toggleDetails(button, true);
+      resource.scrollIntoView({block: 'start'});
+      button.focus({preventScroll: true});
+    }
+
+    // Orders modules and their resources by the sort keys the renderer
+    // provides, the same order the server renders.
+    function sortResources(by) {
+      const attr = 'data-sort-' + by;
+      function byKey(a, b) {
+        const x = a.getAttribute(attr), y = b.getAttribute(attr);
+        return x < y ? -1 : x > y ? 1 : 0;
+      }
+      document.querySelectorAll('.module-resources').forEach(function(list) {
+        Array.from(list.children).sort(byKey).forEach(function(r) { list.appendChild(r); });
+      });
+      const modules = document.querySelector('.resource-list');
+      Array.from(modules.querySelectorAll(':scope > .module')).sort(byKey).forEach(function(m) { modules.appendChild(m); });
+    }
+
+    // Switches between the resource cards and the compact table.
+    function setLayout(layout) {
+      const table = layout === 'table';
+      document.getElementById('changeTable').hidden = !table;
+      document.getElementById('resources').hidden = table;
+      document.querySelectorAll('.layout-btn').forEach(function(b) {
+        b.setAttribute('aria-pressed', String(b.dataset.layout === layout));
+      });
+      syncViewURL();
+    }
+
+    // Sorts the table by a column, toggling between ascending and
+    // descending order on repeated clicks.
+    function sortTable(button, key) {
+      const th = button.parentElement;
+      const ascending = th.getAttribute('aria-sort') !== 'ascending';
+      th.closest('tr').querySelectorAll('th').forEach(function(h) { h.setAttribute('aria-sort', 'none'); });
+      th.setAttribute('aria-sort', ascending ? 'ascending' : 'descending');
+      const attr = 'data-sort-' + key;
+      const tbody = th.closest('table').querySelector('tbody');
+      Array.from(tbody.rows).sort(function(a, b) {
+        const x = a.getAttribute(attr), y = b.getAttribute(attr);
+        const order = x < y ? -1 : x > y ? 1 : 0;
+        return ascending ? order : -order;
+      }).forEach(function(r) { tbody.appendChild(r); });
+    }
+
+    // Served pages are rendered for the view in the URL already; static
+    // reports apply it here.
+    function applyViewFromURL() {
+      const params = new URLSearchParams(location.search);
+      const sortBy = document.getElementById('sortBy');
+      sortBy.dataset.initial = sortBy.value;
+      if (params.get('sort') && params.get('sort') !== sortBy.value && sortBy.querySelector('option[value="' + params.get('sort') + '"]')) {
+        sortBy.value = params.get('sort');
+        sortResources(sortBy.value);
+      }
+      const search = params.get('q');
+      if (search !== null) {
+        document.getElementById('resourceSearch').value = search;
+      }
+      if (params.get('layout') === 'table' && document.getElementById('changeTable').hidden) {
+        setLayout('table');
+      }
+      const button = document.querySelector('.filter-btn[data-action="' + params.get('action') + '"]');
+      if (button) {
+        filterByAction(button.dataset.action, button);
+      } else if (search !== null) {
+        filterResources();
+      }
+    }
+
+    window.addEventListener('hashchange', openLinkedResource);
+    applyViewFromURL();
+    openLinkedResource();
+
+    // Moves focus to the next or previous visible resource.
+    function moveResourceFocus(step) {
+      const toggles = Array.from(document.querySelectorAll('.resource-toggle')).filter(function(b) {
+        return b.offsetParent !== null;
+      });
+      if (toggles.length === 0) {
+        return;
+      }
+      const current = document.activeElement ? document.activeElement.closest('.resource') : null;
+      let i = current ? toggles.indexOf(current.querySelector('.resource-toggle')) : -1;
+      i = i < 0 ? (step > 0 ? 0 : toggles.length - 1) : Math.min(Math.max(i + step, 0), toggles.length - 1);
+      toggles[i].focus();
+      toggles[i].scrollIntoView({block: 'nearest'});
+    }
+
+    document.addEventListener('keydown', function(e) {
+      const t = e.target;
+      if (t.isContentEditable || t.tagName === 'INPUT' || t.tagName === 'TEXTAREA' || t.tagName === 'SELECT') {
+        if (e.key === 'Escape' && t.id === 'resourceSearch') {
+          t.blur();
+        }
+        return;
+      }
+      if (e.ctrlKey || e.metaKey || e.altKey) {
+        return;
+      }
+      if (e.key === '/') {
+        e.preventDefault();
+        document.getElementById('resourceSearch').focus();
+      } else if (e.key === 'j' || e.key === 'k') {
+        e.preventDefault();
+        moveResourceFocus(e.key === 'j' ? 1 : -1);
+      }
+    });
+
     function filterResources() {
       const input = document.getElementById('resourceSearch');
       const filterText = input.value.toLowerCase();
       const activeFilterButton = document.querySelector('.filter-btn.active');
       const filterAction = activeFilterButton ? activeFilterButton.dataset.action : 'all';
+      const hideCosmetic = document.getElementById('hideCosmetic').checked;
+      const onlyUnknownReferenced = document.getElementById('onlyUnknownReferenced').checked;
+
+      function matches(el, address, type, action) {
+        const matchesSearch = address.includes(filterText) || type.includes(filterText) || action.includes(filterText);
+        const matchesAction = filterAction === 'all' || action === filterAction;
+        const matchesCosmetic = !hideCosmetic || !el.dataset.cosmetic;
+        const matchesUnknown = !onlyUnknownReferenced || el.dataset.unknownReferenced;
+        return matchesSearch && matchesAction && matchesCosmetic && matchesUnknown;
+      }
+
+      document.querySelectorAll('.change-row').forEach(function(row) {
+        row.style.display = matches(row, row.dataset.address.toLowerCase(), row.dataset.type.toLowerCase(), row.dataset.action) ? '' : 'none';
+      });
 
       const modules = document.querySelectorAll('.module');
 
@@ -1901,10 +4026,7 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
           const type = resource.querySelector('p').textContent.toLowerCase();
           const action = resource.querySelector('.action-icon').classList[1];
 
-          const matchesSearch = address.includes(filterText) || type.includes(filterText) || action.includes(filterText);
-          const matchesAction = filterAction === 'all' || action === filterAction;
-
-          if (matchesSearch && matchesAction) {
+          if (matches(resource, address, type, action)) {
             resource.style.display = '';
             moduleHasVisibleResources = true;
           } else {
@@ -1918,12 +4040,129 @@ func generateHTML(analysis AnalyzedPlan, showGraph bool, refEdges map[string][]s
           module.style.display = 'none';
         }
       });
+      syncViewURL();
+    }
+
+    // Keeps the action filter, search text and grouping in the query
+    // string so the filtered view can be shared as a link. Other parameters,
+    // such as a share token, are kept.
+    function viewParams() {
+      const params = new URLSearchParams(location.search);
+      const activeFilterButton = document.querySelector('.filter-btn.active');
+      const action = activeFilterButton ? activeFilterButton.dataset.action : 'all';
+      const search = document.getElementById('resourceSearch').value.trim();
+      const table = !document.getElementById('changeTable').hidden;
+      const entries = [['action', action, 'all'], ['q', search, ''], ['layout', table ? 'table' : 'cards', 'cards']];
+      const sortBy = document.getElementById('sortBy');
+      if (sortBy.value !== sortBy.dataset.initial || params.has('sort')) {
+        params.set('sort', sortBy.value);
+      }
+      const groupBy = document.getElementById('groupBy');
+      if (groupBy) {
+        entries.push(['group', groupBy.value, 'module']);
+      }
+      entries.forEach(function(p) {
+        if (p[1] === p[2]) {
+          params.delete(p[0]);
+        } else {
+          params.set(p[0], p[1]);
+        }
+      });
+      return params;
+    }
+
+    function syncViewURL() {
+      const query = viewParams().toString();
+      history.replaceState(null, '', location.pathname + (query ? '?' + query : '') + location.hash);
+    }
+
+    // The grouping is rendered by the server, so changing it reloads the page.
+    function changeGrouping() {
+      const query = viewParams().toString();
+      location.search = query ? '?' + query : '';
+    }
+
+    // Reviews are kept by the report server; when the report is opened
+    // without it, the review controls stay hidden.
+    function reviewAuthor() {
+      let name = localStorage.getItem('tfviz-reviewer');
+      if (!name) {
+        name = (prompt('Your name for review comments') || '').trim();
+        if (name) localStorage.setItem('tfviz-reviewer', name);
+      }
+      return name;
+    }
+
+    function postReview(entry) {
+      entry.author = reviewAuthor();
+      return fetch('/reviews.json', {method: 'POST', headers: {'Content-Type': 'application/json'}, body: JSON.stringify(entry)})
+        .then(function(res) {
+          if (!res.ok) return res.text().then(function(text) { throw new Error(text); });
+          return res.json();
+        })
+        .then(function(data) { showReviews(data); return true; })
+        .catch(function(err) { alert('Could not save the review: ' + err.message); return false; });
+    }
+
+    function signOff(checkbox) {
+      const address = checkbox.closest('.review').dataset.address;
+      postReview({address: address, action: checkbox.checked ? 'sign-off' : 'revoke'})
+        .then(function(ok) { if (!ok) checkbox.checked = !checkbox.checked; });
+    }
+
+    function addComment(form) {
+      const input = form.elements.comment;
+      if (input.value.trim()) {
+        postReview({address: form.closest('.review').dataset.address, action: 'comment', comment: input.value})
+          .then(function(ok) { if (ok) input.value = ''; });
+      }
+      return false;
+    }
+
+    function showReviews(data) {
+      const byAddress = {};
+      (data.entries || []).forEach(function(e) {
+        (byAddress[e.address] = byAddress[e.address] || []).push(e);
+      });
+      document.querySelectorAll('.review').forEach(function(review) {
+        const entries = byAddress[review.dataset.address] || [];
+        let signedOff = null;
+        const list = review.querySelector('.review-comments');
+        list.replaceChildren();
+        entries.forEach(function(e) {
+          if (e.action === 'sign-off') signedOff = e;
+          if (e.action === 'revoke') signedOff = null;
+          if (e.action !== 'comment') return;
+          const item = document.createElement('li');
+          const who = document.createElement('strong');
+          who.textContent = (e.author || 'anonymous') + ' · ' + new Date(e.time).toLocaleString() + ': ';
+          item.append(who, e.comment);
+          list.append(item);
+        });
+        review.querySelector('.review-signoff').checked = !!signedOff;
+        review.hidden = false;
+        const status = review.closest('.resource').querySelector('.review-status');
+        const comments = entries.filter(function(e) { return e.action === 'comment'; }).length;
+        status.textContent = (signedOff ? '✓ approved by ' + (signedOff.author || 'anonymous') : '') +
+          (comments ? (signedOff ? ' · ' : '') + comments + ' comment' + (comments === 1 ? '' : 's') : '');
+        status.hidden = !status.textContent;
+      });
+    }
+
+    if (location.protocol !== 'file:') {
+      fetch('/reviews.json').then(function(res) { return res.ok ? res.json() : null; }).then(function(data) {
+        if (data) showReviews(data);
+      }).catch(function() {});
     }
 
     function filterByAction(action, clickedButton) {
       const filterButtons = document.querySelectorAll('.filter-btn');
-      filterButtons.forEach(btn => btn.classList.remove('active'));
+      filterButtons.forEach(btn => {
+        btn.classList.remove('active');
+        btn.setAttribute('aria-pressed', 'false');
+      });
       clickedButton.classList.add('active');
+      clickedButton.setAttribute('aria-pressed', 'true');
       filterResources();
     }
   </script>
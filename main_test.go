@@ -68,7 +68,7 @@ func TestBuildGraphJSON_Containment(t *testing.T) {
 	enrichContainmentFromValues(allPlanned, containment)
 	plannedValues := buildPlannedValuesMap(allPlanned)
 
-	graphJSON, _, err := buildGraphJSON(analyzed, refEdges, containment, plannedValues)
+	graphJSON, _, err := buildGraphJSON(analyzed, refEdges, containment, plannedValues, graphOptions{})
 	if err != nil {
 		t.Fatalf("buildGraphJSON error: %v", err)
 	}
@@ -449,3 +449,334 @@ func TestIsModuleOnlyPrefix(t *testing.T) {
 		}
 	}
 }
+
+func TestParseOptions(t *testing.T) {
+	opts, rest, err := parseOptions([]string{"-g", "--changed-only", "-var-file=prod.tfvars", "--tls-cert", "cert.pem", "--tls-key=key.pem", "-lock=false"})
+	if err != nil {
+		t.Fatalf("parseOptions: %v", err)
+	}
+	if !opts.showGraph || !opts.graph.changedOnly || opts.serve.tlsCert != "cert.pem" || opts.serve.tlsKey != "key.pem" {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+	if strings.Join(rest, " ") != "-var-file=prod.tfvars -lock=false" {
+		t.Errorf("terraform args = %q", rest)
+	}
+
+	// Certificate without key is rejected
+	if _, _, err := parseOptions([]string{"--tls-cert", "cert.pem"}); err == nil {
+		t.Error("expected error for --tls-cert without --tls-key")
+	}
+	// Missing value is rejected
+	if _, _, err := parseOptions([]string{"--tls-key"}); err == nil {
+		t.Error("expected error for --tls-key without a value")
+	}
+}
+
+func TestBuildResourceReferences(t *testing.T) {
+	config := PlanConfiguration{
+		RootModule: ConfigModule{
+			Resources: []ConfigResource{
+				{Address: "aws_subnet.a", Type: "aws_subnet", Expressions: map[string]interface{}{}},
+				{Address: "aws_security_group.web", Type: "aws_security_group", Expressions: map[string]interface{}{}},
+				{
+					Address: "aws_instance.web", Type: "aws_instance",
+					Expressions: map[string]interface{}{
+						"subnet_id": map[string]interface{}{"references": []interface{}{"aws_subnet.a.id", "aws_subnet.a"}},
+						"ebs_block_device": []interface{}{
+							map[string]interface{}{
+								"tags": map[string]interface{}{"references": []interface{}{"aws_subnet.a.tags", "aws_subnet.a"}},
+							},
+						},
+						"vpc_security_group_ids": map[string]interface{}{"references": []interface{}{"aws_security_group.web.id", "aws_security_group.web"}},
+					},
+				},
+			},
+			ModuleCalls: map[string]ConfigModuleCall{
+				"app": {
+					Expressions: map[string]interface{}{
+						"subnet_id": map[string]interface{}{"references": []interface{}{"aws_subnet.a.id", "aws_subnet.a"}},
+					},
+					Module: ConfigModule{
+						Resources: []ConfigResource{
+							{
+								Address: "aws_lb.this", Type: "aws_lb",
+								Expressions: map[string]interface{}{
+									"subnets": map[string]interface{}{"references": []interface{}{"var.subnet_id"}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	refs := buildResourceReferences(config)
+	var got []string
+	for _, r := range refs["aws_instance.web"] {
+		got = append(got, r.String()+" @ "+r.Target)
+	}
+	want := []string{
+		"ebs_block_device.tags → aws_subnet.a.tags @ aws_subnet.a",
+		"subnet_id → aws_subnet.a.id @ aws_subnet.a",
+		"vpc_security_group_ids → aws_security_group.web.id @ aws_security_group.web",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("aws_instance.web references:\n got %q\nwant %q", got, want)
+	}
+	if r := refs["module.app.aws_lb.this"]; len(r) != 1 || r[0].Attribute != "subnets" || r[0].Expression != "var.subnet_id" || r[0].Target != "aws_subnet.a" {
+		t.Errorf("module.app.aws_lb.this references = %+v", r)
+	}
+
+	edges := buildRefEdges(config)
+	if e := edges["aws_instance.web"]; len(e) != 2 {
+		t.Errorf("edges should be deduplicated per target, got %v", e)
+	}
+
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Address: "root", Resources: []ResourceAnalysis{
+		{Address: "aws_instance.web", Type: "aws_instance", Name: "web", Action: "create", References: refs["aws_instance.web"]},
+		{Address: "aws_subnet.a", Type: "aws_subnet", Name: "a", Action: "create"},
+	}}}}
+	graphJSON, _, err := buildGraphJSON(analyzed, edges, nil, nil, graphOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(graphJSON, `"label":"ebs_block_device.tags → aws_subnet.a.tags\nsubnet_id → aws_subnet.a.id"`) {
+		t.Errorf("reference edge label missing from graph JSON: %s", graphJSON)
+	}
+}
+
+func TestGenerateTerraformStyleDiff_ReplacePaths(t *testing.T) {
+	rc := ResourceChange{
+		Type: "aws_instance",
+		Name: "web",
+		Change: Change{
+			Actions: []string{"delete", "create"},
+			Before: map[string]interface{}{
+				"ami":           "ami-1",
+				"instance_type": "t3.small",
+				"tags":          map[string]interface{}{"Name": "web"},
+				"ebs":           []interface{}{map[string]interface{}{"size": 10.0}},
+			},
+			After: map[string]interface{}{
+				"ami":           "ami-2",
+				"instance_type": "t3.large",
+				"tags":          map[string]interface{}{"Name": "web-2"},
+				"ebs":           []interface{}{map[string]interface{}{"size": 20.0}},
+			},
+			ReplacePaths: [][]interface{}{{"ami"}, {"ebs", 0.0, "size"}},
+		},
+	}
+
+	forced := map[string]bool{}
+	for _, l := range generateTerraformStyleDiff(rc, true) {
+		for _, attr := range []string{"ami", "instance_type", "Name", "ebs"} {
+			if strings.Contains(l.Text, " "+attr+" = ") {
+				forced[attr] = strings.HasSuffix(l.Text, "# forces replacement")
+			}
+		}
+	}
+	want := map[string]bool{"ami": true, "instance_type": false, "Name": false, "ebs": true}
+	for attr, w := range want {
+		if forced[attr] != w {
+			t.Errorf("%s forces replacement = %v, want %v", attr, forced[attr], w)
+		}
+	}
+
+	rc.Change.ReplacePaths = nil
+	for _, l := range generateTerraformStyleDiff(rc, true) {
+		if l.Type == "modified" && strings.Contains(l.Text, " = ") && !strings.HasSuffix(l.Text, "# forces replacement") {
+			t.Errorf("without replace_paths every change should be marked: %q", l.Text)
+		}
+	}
+}
+
+func TestParseOptions_Separator(t *testing.T) {
+	opts, rest, err := parseOptions([]string{"--graph", "-lock=false", "--", "-var-file=prod.tfvars", "--graph"})
+	if err != nil {
+		t.Fatalf("parseOptions: %v", err)
+	}
+	if !opts.showGraph {
+		t.Error("--graph before the separator should be parsed")
+	}
+	if strings.Join(rest, " ") != "-lock=false -var-file=prod.tfvars --graph" {
+		t.Errorf("terraform args = %q", rest)
+	}
+
+	if _, _, err := parseOptions([]string{"--grpah"}); err == nil || !strings.Contains(err.Error(), "--grpah") {
+		t.Errorf("expected an unknown flag error, got %v", err)
+	}
+}
+
+func TestCheckPlanArgs(t *testing.T) {
+	for _, args := range [][]string{{"-out=plan.bin"}, {"--out", "plan.bin"}, {"-chdir=infra"}, {"-help"}} {
+		if err := checkPlanArgs(args); err == nil {
+			t.Errorf("checkPlanArgs(%q): expected an error", args)
+		}
+	}
+	if err := checkPlanArgs([]string{"-var", "region=eu-west-1", "-outline=x", "-refresh=false"}); err != nil {
+		t.Errorf("checkPlanArgs: unexpected error %v", err)
+	}
+}
+
+func TestGenerateHTML_AccessibleResourceToggle(t *testing.T) {
+	r := ResourceAnalysis{Address: `aws_iam_role.this["ci role"]`, Type: "aws_iam_role", Action: "create"}
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Address: "root", Resources: []ResourceAnalysis{r}}}}
+	html := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+
+	id := r.DetailsID()
+	if strings.ContainsAny(id, ` "[]`) {
+		t.Errorf("DetailsID %q is not a usable HTML id", id)
+	}
+	for _, want := range []string{
+		`<button type="button" class="resource-toggle" aria-expanded="false" aria-controls="` + id + `"`,
+		`<div class="details" id="` + id + `" hidden>`,
+		`<a class="skip-link" href="#resources">`,
+		`id="resources"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report is missing %s", want)
+		}
+	}
+}
+
+func TestResourceAnalysis_CopyData(t *testing.T) {
+	r := ResourceAnalysis{
+		Address: `aws_iam_role.this["ci role"]`,
+		Action:  "update",
+		DiffLines: []DiffLine{
+			{Type: "header", Text: `~ resource "aws_iam_role" "this" {`},
+			{Type: "modified", Text: `  ~ name = "a" => "b"`},
+			{Type: "header", Text: "}"},
+		},
+	}
+	if got, want := r.ApplyCommand(), `terraform apply '-target=aws_iam_role.this["ci role"]'`; got != want {
+		t.Errorf("ApplyCommand() = %s, want %s", got, want)
+	}
+	if got, want := r.DiffText(), "~ resource \"aws_iam_role\" \"this\" {\n  ~ name = \"a\" => \"b\"\n}"; got != want {
+		t.Errorf("DiffText() = %q, want %q", got, want)
+	}
+	r.Action = "no-op"
+	if got := r.ApplyCommand(); got != "" {
+		t.Errorf("ApplyCommand() for no-op = %q, want empty", got)
+	}
+}
+
+func TestResourceAnalysis_AnchorID(t *testing.T) {
+	tests := map[string]string{
+		"module.app.aws_instance.web":  "module.app.aws_instance.web",
+		`aws_iam_role.this["ci"]`:      `aws_iam_role.this["ci"]`,
+		`aws_iam_role.this["ci role"]`: `aws_iam_role.this["ci_role"]`,
+	}
+	for address, want := range tests {
+		if got := (ResourceAnalysis{Address: address}).AnchorID(); got != want {
+			t.Errorf("AnchorID(%s) = %s, want %s", address, got, want)
+		}
+	}
+
+	r := ResourceAnalysis{Address: "module.app.aws_instance.web", Type: "aws_instance", Action: "update"}
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Address: "module.app", Resources: []ResourceAnalysis{r}}}}
+	html := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+	if want := `<div id="module.app.aws_instance.web" data-address="module.app.aws_instance.web"`; !strings.Contains(html, want) {
+		t.Errorf("report is missing the resource anchor %s", want)
+	}
+}
+
+func TestGenerateHTML_ModuleCollapse(t *testing.T) {
+	m := ModuleAnalysis{Address: "module.app", Resources: []ResourceAnalysis{
+		{Address: "module.app.aws_instance.web", Type: "aws_instance", Action: "create"},
+	}}
+	html := generateHTML(AnalyzedPlan{Modules: []ModuleAnalysis{m}}, false, nil, nil, nil, graphOptions{}, reportView{})
+
+	for _, want := range []string{
+		`<button type="button" class="module-toggle" aria-expanded="true" aria-controls="` + m.ContentID() + `" onclick="toggleModule(this)">module.app <span class="module-count">(1)</span></button>`,
+		`<div class="module-resources" id="` + m.ContentID() + `">`,
+		`onclick="setAllExpanded(true)"`,
+		`onclick="setAllExpanded(false)"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report is missing %s", want)
+		}
+	}
+	if m.ContentID() == (ResourceAnalysis{Address: m.Address}).DetailsID() {
+		t.Error("module and resource ids collide")
+	}
+}
+
+func TestGenerateHTML_DetailTabs(t *testing.T) {
+	plan := TerraformPlan{ResourceChanges: []ResourceChange{{
+		Address: "aws_instance.web", Mode: "managed", Type: "aws_instance", Name: "web",
+		ProviderName: "registry.terraform.io/hashicorp/aws",
+		Change: Change{
+			Actions: []string{"update"},
+			Before:  map[string]interface{}{"instance_type": "t3.micro"},
+			After:   map[string]interface{}{"instance_type": "t3.large"},
+		},
+	}, {
+		Address: "aws_iam_role.ci", Mode: "managed", Type: "aws_iam_role", Name: "ci",
+		Change: Change{
+			Actions: []string{"no-op"},
+			Before:  map[string]interface{}{"name": "ci-unchanged"},
+			After:   map[string]interface{}{"name": "ci-unchanged"},
+		},
+	}}}
+	analyzed := analyzePlanWith(plan, analysisOptions{includeUnchanged: true})
+	r := analyzed.Modules[0].Resources[0]
+	if got, want := r.BeforeJSON(), "{\n  \"instance_type\": \"t3.micro\"\n}"; got != want {
+		t.Errorf("BeforeJSON() = %q, want %q", got, want)
+	}
+	if got := (ResourceAnalysis{}).AfterJSON(); got != "" {
+		t.Errorf("AfterJSON() without values = %q, want empty", got)
+	}
+
+	html := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+	id := r.DetailsID()
+	for _, want := range []string{
+		`aria-controls="` + id + `-before"`,
+		`<div role="tabpanel" id="` + id + `-after" aria-labelledby="` + id + `-after-tab" hidden>`,
+		`&#34;instance_type&#34;: &#34;t3.large&#34;`,
+		`<dt>Provider</dt><dd>registry.terraform.io/hashicorp/aws</dd>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report is missing %s", want)
+		}
+	}
+	// Unchanged resources get no value tabs, and the analysis JSON never
+	// carries complete values, which can hold secrets.
+	unchanged := analyzed.Modules[0].Resources[1]
+	if unchanged.Action != "no-op" || strings.Contains(html, unchanged.DetailsID()+"-before") {
+		t.Error("report has value tabs for an unchanged resource")
+	}
+	data, _ := json.Marshal(r)
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+	if _, ok := fields["before"]; ok || fields["after"] != nil {
+		t.Errorf("analysis JSON contains the resource's values: %s", data)
+	}
+}
+
+func TestAnalyzePlanWith_IncludeUnchanged(t *testing.T) {
+	plan := TerraformPlan{ResourceChanges: []ResourceChange{
+		{Address: "aws_instance.web", Mode: "managed", Type: "aws_instance", Name: "web", Change: Change{Actions: []string{"update"}}},
+		{Address: "aws_iam_role.ci", Mode: "managed", Type: "aws_iam_role", Name: "ci", Change: Change{Actions: []string{"no-op"}}},
+		{Address: "module.dns.aws_route53_record.a", ModuleAddress: "module.dns", Mode: "managed", Type: "aws_route53_record", Name: "a", Change: Change{Actions: []string{"no-op"}}},
+		{Address: "module.dns.aws_route53_record.b", ModuleAddress: "module.dns", Mode: "managed", Type: "aws_route53_record", Name: "b", Change: Change{Actions: []string{"no-op"}}},
+	}}
+
+	analyzed := analyzePlan(plan)
+	if len(analyzed.Modules) != 1 || analyzed.Summary.TotalResources != 2 || analyzed.Summary.HiddenModules != 1 {
+		t.Errorf("default analysis: %d modules, %d resources, %d hidden; want 1, 2, 1",
+			len(analyzed.Modules), analyzed.Summary.TotalResources, analyzed.Summary.HiddenModules)
+	}
+
+	full := analyzePlanWith(plan, analysisOptions{includeUnchanged: true})
+	if len(full.Modules) != 2 || full.Modules[1].Address != "module.dns" || full.Summary.TotalResources != 4 || full.Summary.HiddenModules != 0 {
+		t.Errorf("with unchanged modules: %d modules, %d resources, %d hidden; want 2, 4, 0",
+			len(full.Modules), full.Summary.TotalResources, full.Summary.HiddenModules)
+	}
+
+	opts, _, err := parseOptions([]string{"--include-unchanged-modules"})
+	if err != nil || !opts.analysis.includeUnchanged {
+		t.Errorf("--include-unchanged-modules not parsed: %+v, %v", opts.analysis, err)
+	}
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// mcpProtocolVersion is the Model Context Protocol revision tfviz speaks.
+const mcpProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes used by the MCP server.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type mcpTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+func toolSchema(required []string, props map[string]string) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for name, desc := range props {
+		properties[name] = map[string]string{"type": "string", "description": desc}
+	}
+	return map[string]interface{}{"type": "object", "properties": properties, "required": required}
+}
+
+var mcpTools = []mcpTool{
+	{
+		Name:        "analyze_plan",
+		Description: "Summarize a Terraform plan: action counts, findings, top changes and every changed resource with its impact.",
+		InputSchema: toolSchema([]string{"plan_file"}, map[string]string{
+			"plan_file": "Path to the output of terraform show -json",
+		}),
+	},
+	{
+		Name:        "get_resource_diff",
+		Description: "Show the Terraform-style diff and changed attributes of one resource in a plan.",
+		InputSchema: toolSchema([]string{"plan_file", "address"}, map[string]string{
+			"plan_file": "Path to the output of terraform show -json",
+			"address":   "Resource address, e.g. module.app.aws_instance.web",
+		}),
+	},
+	{
+		Name:        "get_blast_radius",
+		Description: "List the resources that depend on a resource, directly or transitively, through references and depends_on, with what the plan does to each.",
+		InputSchema: toolSchema([]string{"plan_file", "address"}, map[string]string{
+			"plan_file": "Path to the output of terraform show -json",
+			"address":   "Resource address, e.g. aws_vpc.main",
+		}),
+	},
+}
+
+// mcpServer answers Model Context Protocol requests about plan files.
+// Analyses are cached per file until it changes.
+type mcpServer struct {
+	cfg   tfvizConfig
+	plans map[string]cachedAnalysis
+}
+
+type cachedAnalysis struct {
+	modTime  time.Time
+	analyzed AnalyzedPlan
+}
+
+// handleMCP serves MCP over stdin and stdout until stdin is closed. Nothing
+// else may be written to stdout, so the plan must already exist as JSON.
+func handleMCP(ctx context.Context, args []string) error {
+	configFile, profile := "", ""
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name != "--config" && name != "--profile" {
+			return fmt.Errorf("unknown flag for mcp: %s", args[i])
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return fmt.Errorf("flag %s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		if name == "--config" {
+			configFile = value
+		} else {
+			profile = value
+		}
+	}
+	cfg, err := loadConfig(configFile, profile)
+	if err != nil {
+		return err
+	}
+	s := &mcpServer{cfg: cfg, plans: map[string]cachedAnalysis{}}
+	return s.serve(ctx, os.Stdin, os.Stdout)
+}
+
+// serve reads newline-delimited JSON-RPC messages from r and writes the
+// responses to w.
+func (s *mcpServer) serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return nil
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req rpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			if err := enc.Encode(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcParseError, err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		resp := s.handle(req)
+		if resp == nil {
+			continue
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// handle answers one request. Notifications, which have no ID, get no
+// response.
+func (s *mcpServer) handle(req rpcRequest) *rpcResponse {
+	var result interface{}
+	var rerr *rpcError
+	switch req.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"protocolVersion": mcpProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": "tfviz", "version": version},
+		}
+	case "ping":
+		result = map[string]interface{}{}
+	case "tools/list":
+		result = map[string]interface{}{"tools": mcpTools}
+	case "tools/call":
+		var params struct {
+			Name      string            `json:"name"`
+			Arguments map[string]string `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			rerr = &rpcError{rpcInvalidParams, err.Error()}
+			break
+		}
+		result = s.callTool(params.Name, params.Arguments)
+	default:
+		rerr = &rpcError{rpcMethodNotFound, "method not found: " + req.Method}
+	}
+	if len(req.ID) == 0 {
+		return nil
+	}
+	if rerr != nil {
+		return &rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: rerr}
+	}
+	return &rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+type toolResult struct {
+	Content []toolContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+type toolContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// callTool runs a tool. Failures are reported in the result, as MCP
+// expects, so the assistant can see and correct them.
+func (s *mcpServer) callTool(name string, args map[string]string) toolResult {
+	out, err := s.runTool(name, args)
+	if err != nil {
+		return toolResult{Content: []toolContent{{Type: "text", Text: err.Error()}}, IsError: true}
+	}
+	if text, ok := out.(string); ok {
+		return toolResult{Content: []toolContent{{Type: "text", Text: text}}}
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return toolResult{Content: []toolContent{{Type: "text", Text: err.Error()}}, IsError: true}
+	}
+	return toolResult{Content: []toolContent{{Type: "text", Text: string(data)}}}
+}
+
+func (s *mcpServer) runTool(name string, args map[string]string) (interface{}, error) {
+	if name != "analyze_plan" && name != "get_resource_diff" && name != "get_blast_radius" {
+		return nil, fmt.Errorf("unknown tool %q", name)
+	}
+	if args["plan_file"] == "" {
+		return nil, fmt.Errorf("plan_file is required")
+	}
+	analyzed, err := s.analysis(args["plan_file"])
+	if err != nil {
+		return nil, err
+	}
+	if name == "analyze_plan" {
+		return planOverview(analyzed), nil
+	}
+
+	address := args["address"]
+	if address == "" {
+		return nil, fmt.Errorf("address is required")
+	}
+	r, ok := findResource(analyzed, address)
+	if !ok {
+		return nil, fmt.Errorf("%s is not in the plan", address)
+	}
+	if name == "get_resource_diff" {
+		diff := r.DiffText()
+		if keys := r.KeyChanges(); keys != "" {
+			diff += "\n\nChanged attributes: " + keys
+		}
+		return diff, nil
+	}
+	return blastRadius(analyzed, address), nil
+}
+
+// analysis parses and analyzes a plan file, reusing the previous analysis
+// while the file is unchanged.
+func (s *mcpServer) analysis(path string) (AnalyzedPlan, error) {
+	// Stdin carries the MCP messages, so a plan cannot be read from it.
+	if path == "-" {
+		return AnalyzedPlan{}, fmt.Errorf("plan must be a file, not stdin")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return AnalyzedPlan{}, fmt.Errorf("error reading plan file: %w", err)
+	}
+	if c, ok := s.plans[path]; ok && c.modTime.Equal(info.ModTime()) {
+		return c.analyzed, nil
+	}
+	data, err := readPlanFile(path)
+	if err != nil {
+		return AnalyzedPlan{}, err
+	}
+	plan, err := parsePlanJSON(data)
+	if err != nil {
+		return AnalyzedPlan{}, err
+	}
+	analyzed := analyzePlan(plan)
+	// Config problems are reported by the other commands; the analysis is
+	// still useful without them.
+	_ = applyConfig(&analyzed, s.cfg, time.Now())
+	s.plans[path] = cachedAnalysis{modTime: info.ModTime(), analyzed: analyzed}
+	return analyzed, nil
+}
+
+func findResource(analyzed AnalyzedPlan, address string) (ResourceAnalysis, bool) {
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			if r.Address == address {
+				return r, true
+			}
+		}
+	}
+	return ResourceAnalysis{}, false
+}
+
+type overviewResource struct {
+	Address      string `json:"address"`
+	Action       string `json:"action"`
+	Replace      bool   `json:"replace,omitempty"`
+	Impact       string `json:"impact"`
+	ImpactReason string `json:"impact_reason,omitempty"`
+	Changed      string `json:"changed_attributes,omitempty"`
+}
+
+type planOverviewResult struct {
+	Summary    PlanSummary        `json:"summary"`
+	Findings   []Finding          `json:"findings,omitempty"`
+	TopChanges TopChanges         `json:"top_changes"`
+	Changes    []overviewResource `json:"changes"`
+}
+
+func planOverview(analyzed AnalyzedPlan) planOverviewResult {
+	out := planOverviewResult{Summary: analyzed.Summary, Findings: analyzed.Findings, TopChanges: analyzed.TopChanges()}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			if r.Action == "no-op" {
+				continue
+			}
+			out.Changes = append(out.Changes, overviewResource{
+				Address: r.Address, Action: r.Action, Replace: r.Replace,
+				Impact: r.Impact, ImpactReason: r.ImpactReason, Changed: r.KeyChanges(),
+			})
+		}
+	}
+	return out
+}
+
+// BlastEntry is a resource affected by a change to another one: Distance
+// is the number of references or depends_on hops from it, and Via names the
+// attribute of the first hop.
+type BlastEntry struct {
+	Address  string `json:"address"`
+	Action   string `json:"action"`
+	Distance int    `json:"distance"`
+	Via      string `json:"via"`
+}
+
+// blastRadius walks references and depends_on backwards from address and
+// returns every resource that depends on it, nearest first.
+func blastRadius(analyzed AnalyzedPlan, address string) []BlastEntry {
+	type edge struct{ from, via string }
+	reverse := map[string][]edge{}
+	action := map[string]string{}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			action[r.Address] = r.Action
+			for _, ref := range r.References {
+				reverse[stripIndex(ref.Target)] = append(reverse[stripIndex(ref.Target)], edge{r.Address, ref.Attribute})
+			}
+			for _, dep := range r.DependsOn {
+				reverse[stripIndex(dep)] = append(reverse[stripIndex(dep)], edge{r.Address, "depends_on"})
+			}
+		}
+	}
+
+	seen := map[string]bool{address: true}
+	var result []BlastEntry
+	frontier := []string{address}
+	for distance := 1; len(frontier) > 0; distance++ {
+		var next []string
+		for _, addr := range frontier {
+			for _, e := range reverse[stripIndex(addr)] {
+				if seen[e.from] {
+					continue
+				}
+				seen[e.from] = true
+				result = append(result, BlastEntry{Address: e.from, Action: action[e.from], Distance: distance, Via: e.via})
+				next = append(next, e.from)
+			}
+		}
+		sort.Strings(next)
+		frontier = next
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Distance != result[j].Distance {
+			return result[i].Distance < result[j].Distance
+		}
+		return result[i].Address < result[j].Address
+	})
+	return result
+}
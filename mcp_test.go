@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const mcpTestPlan = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main",
+     "change": {"actions": ["update"], "before": {"cidr_block": "10.0.0.0/16"}, "after": {"cidr_block": "10.1.0.0/16"}}},
+    {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a",
+     "change": {"actions": ["no-op"], "before": {}, "after": {}}},
+    {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web",
+     "change": {"actions": ["no-op"], "before": {}, "after": {}}}
+  ],
+  "configuration": {"root_module": {"resources": [
+    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main", "expressions": {}},
+    {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a",
+     "expressions": {"vpc_id": {"references": ["aws_vpc.main.id", "aws_vpc.main"]}}},
+    {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web",
+     "expressions": {"subnet_id": {"references": ["aws_subnet.a.id", "aws_subnet.a"]}}}
+  ]}}
+}`
+
+func runMCP(t *testing.T, requests ...string) []rpcResponse {
+	t.Helper()
+	s := &mcpServer{plans: map[string]cachedAnalysis{}}
+	var out bytes.Buffer
+	if err := s.serve(context.Background(), strings.NewReader(strings.Join(requests, "\n")+"\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	var responses []rpcResponse
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var r rpcResponse
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, r)
+	}
+	return responses
+}
+
+func toolText(t *testing.T, r rpcResponse) (string, bool) {
+	t.Helper()
+	data, _ := json.Marshal(r.Result)
+	var res toolResult
+	if err := json.Unmarshal(data, &res); err != nil || len(res.Content) != 1 {
+		t.Fatalf("unexpected tool result %s", data)
+	}
+	return res.Content[0].Text, res.IsError
+}
+
+func TestMCPServer(t *testing.T) {
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	os.WriteFile(planFile, []byte(mcpTestPlan), 0644)
+	call := func(id int, tool string, args map[string]string) string {
+		args["plan_file"] = planFile
+		params, _ := json.Marshal(map[string]interface{}{"name": tool, "arguments": args})
+		req, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": "tools/call", "params": json.RawMessage(params)})
+		return string(req)
+	}
+
+	responses := runMCP(t,
+		`{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {"protocolVersion": "2024-11-05"}}`,
+		`{"jsonrpc": "2.0", "method": "notifications/initialized"}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "tools/list"}`,
+		call(3, "analyze_plan", map[string]string{}),
+		call(4, "get_resource_diff", map[string]string{"address": "aws_vpc.main"}),
+		call(5, "get_blast_radius", map[string]string{"address": "aws_vpc.main"}),
+		call(6, "get_resource_diff", map[string]string{"address": "aws_vpc.other"}),
+		`{"jsonrpc": "2.0", "id": 7, "method": "resources/list"}`,
+		`not json`,
+	)
+	if len(responses) != 8 {
+		t.Fatalf("got %d responses, want 8 (no response to the notification)", len(responses))
+	}
+	if string(responses[0].ID) != "1" || !strings.Contains(mustJSON(responses[0].Result), `"protocolVersion":"2024-11-05"`) {
+		t.Errorf("unexpected initialize response %+v", responses[0])
+	}
+	if list := mustJSON(responses[1].Result); !strings.Contains(list, `"get_blast_radius"`) {
+		t.Errorf("tools/list does not list get_blast_radius: %s", list)
+	}
+	if text, _ := toolText(t, responses[2]); !strings.Contains(text, `"address": "aws_vpc.main"`) || strings.Contains(text, `"address": "aws_subnet.a"`) {
+		t.Errorf("analyze_plan does not list exactly the changed resources: %s", text)
+	}
+	if text, _ := toolText(t, responses[3]); !strings.Contains(text, `cidr_block = "10.0.0.0/16" => "10.1.0.0/16"`) {
+		t.Errorf("get_resource_diff does not show the diff: %s", text)
+	}
+	var blast []BlastEntry
+	text, _ := toolText(t, responses[4])
+	if err := json.Unmarshal([]byte(text), &blast); err != nil || len(blast) != 2 ||
+		blast[0] != (BlastEntry{Address: "aws_subnet.a", Action: "no-op", Distance: 1, Via: "vpc_id"}) ||
+		blast[1] != (BlastEntry{Address: "aws_instance.web", Action: "no-op", Distance: 2, Via: "subnet_id"}) {
+		t.Errorf("unexpected blast radius %s", text)
+	}
+	if text, isErr := toolText(t, responses[5]); !isErr || !strings.Contains(text, "not in the plan") {
+		t.Errorf("unknown address not reported as a tool error: %s", text)
+	}
+	if responses[6].Error == nil || responses[6].Error.Code != rpcMethodNotFound {
+		t.Errorf("unknown method: %+v", responses[6])
+	}
+	if responses[7].Error == nil || responses[7].Error.Code != rpcParseError {
+		t.Errorf("invalid JSON: %+v", responses[7])
+	}
+}
+
+func mustJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// renderTimeMarker stands in for the rendering time in the performance
+// panel, which is only known once the template has been executed.
+const renderTimeMarker = "__TFVIZ_RENDER_TIME__"
+
+// PlanMetrics records how long each stage of producing a report took and
+// how big the plan was, to diagnose slow pipelines.
+type PlanMetrics struct {
+	Stages    []StageTiming  `json:"stages"`
+	JSONBytes int            `json:"json_bytes"`
+	Resources int            `json:"resources"`
+	Retries   []RetryAttempt `json:"retries,omitempty"`
+}
+
+type StageTiming struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+func (s StageTiming) Text() string {
+	return formatStageDuration(s.Duration)
+}
+
+func formatStageDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// runMetrics collects the timings of the current command. tfviz produces
+// one report per run, so the stages are recorded where they happen rather
+// than passed through every function.
+var runMetrics = &PlanMetrics{}
+
+// since records a stage that started at start.
+func (m *PlanMetrics) since(name string, start time.Time) {
+	m.Stages = append(m.Stages, StageTiming{Name: name, Duration: time.Since(start)})
+}
+
+// Size describes the plan, e.g. "1.2 MB of JSON, 340 resources".
+func (m PlanMetrics) Size() string {
+	size := fmt.Sprintf("%d bytes", m.JSONBytes)
+	switch {
+	case m.JSONBytes >= 1<<20:
+		size = fmt.Sprintf("%.1f MB", float64(m.JSONBytes)/(1<<20))
+	case m.JSONBytes >= 1<<10:
+		size = fmt.Sprintf("%.1f KB", float64(m.JSONBytes)/(1<<10))
+	}
+	return size + " of JSON, " + plural(m.Resources, "resource")
+}
+
+func (m PlanMetrics) String() string {
+	var parts []string
+	for _, s := range m.Stages {
+		parts = append(parts, s.Name+" "+s.Text())
+	}
+	return strings.Join(parts, ", ")
+}
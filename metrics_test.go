@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanMetrics_Report(t *testing.T) {
+	analyzed := analyzePlan(TerraformPlan{})
+	analyzed.Metrics = &PlanMetrics{
+		Stages:    []StageTiming{{Name: "terraform plan", Duration: 12345 * time.Millisecond}, {Name: "analysis", Duration: 250 * time.Microsecond}},
+		JSONBytes: 3 << 20,
+		Resources: 1,
+	}
+	if got := analyzed.Metrics.String(); got != "terraform plan 12.345s, analysis 250µs" {
+		t.Errorf("String() = %q", got)
+	}
+
+	html := renderPlan(TerraformPlan{}, analyzed, false, graphOptions{}, "")(reportView{})
+	if !strings.Contains(html, "Performance (3.0 MB of JSON, 1 resource)") || !strings.Contains(html, "<td>terraform plan</td><td>12.345s</td>") {
+		t.Error("performance panel missing")
+	}
+	if strings.Contains(html, renderTimeMarker) || !strings.Contains(html, "<td>render report</td><td>") {
+		t.Error("render time not filled in")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// minMoveSimilarity is the share of configured attributes a destroyed and a
+// created resource of the same type must agree on to be reported as a
+// likely rename.
+const minMoveSimilarity = 0.8
+
+// moveIgnoredAttributes differ between two objects even when one is a copy
+// of the other.
+var moveIgnoredAttributes = map[string]bool{"id": true, "arn": true, "tags_all": true}
+
+// attributeSimilarity compares the values a new object is configured with
+// against an old object's state. Attributes that are unknown until apply or
+// left empty say nothing about whether the objects are the same and are
+// skipped.
+func attributeSimilarity(before, after, afterUnknown map[string]interface{}) (equal, compared int) {
+	for k, v := range after {
+		if moveIgnoredAttributes[k] || afterUnknown[k] == true || isEmptyValue(v) {
+			continue
+		}
+		compared++
+		if reflect.DeepEqual(before[k], v) {
+			equal++
+		}
+	}
+	return equal, compared
+}
+
+func isEmptyValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func onlyAction(rc ResourceChange, action string) bool {
+	return rc.Mode == "managed" && len(rc.Change.Actions) == 1 && rc.Change.Actions[0] == action
+}
+
+// movedBlock renders the moved block that turns a destroy and create into
+// an address change.
+func movedBlock(from, to string) string {
+	return fmt.Sprintf("moved {\n  from = %s\n  to   = %s\n}", from, to)
+}
+
+// resourceMove is a destroyed resource paired with the created resource
+// that looks like the same object under a new address.
+type resourceMove struct {
+	from, to        ResourceChange
+	equal, compared int
+}
+
+// matchMoves pairs destroyed resources with created resources of the same
+// type whose attributes are nearly identical. That is what renaming a
+// resource or moving it into a module looks like without a moved block, and
+// the existing object is destroyed for nothing.
+func matchMoves(plan TerraformPlan) []resourceMove {
+	var deletes, creates []ResourceChange
+	for _, rc := range plan.ResourceChanges {
+		if onlyAction(rc, "delete") {
+			deletes = append(deletes, rc)
+		} else if onlyAction(rc, "create") {
+			creates = append(creates, rc)
+		}
+	}
+
+	var candidates []resourceMove
+	for _, d := range deletes {
+		for _, c := range creates {
+			if d.Type != c.Type {
+				continue
+			}
+			equal, compared := attributeSimilarity(d.Change.Before, c.Change.After, c.Change.AfterUnknown)
+			if compared > 0 && float64(equal) >= minMoveSimilarity*float64(compared) {
+				candidates = append(candidates, resourceMove{d, c, equal, compared})
+			}
+		}
+	}
+	// Pair the closest matches first, so each resource is suggested once.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.equal*b.compared != b.equal*a.compared {
+			return a.equal*b.compared > b.equal*a.compared
+		}
+		if a.compared != b.compared {
+			return a.compared > b.compared
+		}
+		if a.from.Address != b.from.Address {
+			return a.from.Address < b.from.Address
+		}
+		return a.to.Address < b.to.Address
+	})
+
+	var moves []resourceMove
+	paired := map[string]bool{}
+	for _, c := range candidates {
+		if paired[c.from.Address] || paired[c.to.Address] {
+			continue
+		}
+		paired[c.from.Address], paired[c.to.Address] = true, true
+		moves = append(moves, c)
+	}
+	return moves
+}
+
+// detectMoves suggests a moved block for every likely rename.
+func detectMoves(plan TerraformPlan) []Finding {
+	var findings []Finding
+	for _, c := range matchMoves(plan) {
+		findings = append(findings, Finding{
+			Category:    "rename",
+			Severity:    "Medium",
+			Address:     c.from.Address,
+			Title:       fmt.Sprintf("Possible rename: %s is destroyed and %s created", c.from.Address, c.to.Address),
+			Detail:      fmt.Sprintf("%d of %d configured attributes are identical. If this is the same object, a moved block keeps it instead of replacing it.", c.equal, c.compared),
+			Remediation: movedBlock(c.from.Address, c.to.Address),
+		})
+	}
+	return findings
+}
+
+// markRenames labels both halves of every likely rename, so the summary and
+// the resource list can tell refactors apart from real destroys.
+func markRenames(analyzed *AnalyzedPlan, moves []resourceMove) {
+	from, to := map[string]string{}, map[string]string{}
+	for _, m := range moves {
+		to[m.from.Address] = m.to.Address
+		from[m.to.Address] = m.from.Address
+	}
+	for i := range analyzed.Modules {
+		for j := range analyzed.Modules[i].Resources {
+			r := &analyzed.Modules[i].Resources[j]
+			r.RenamedTo, r.RenamedFrom = to[r.Address], from[r.Address]
+		}
+	}
+	analyzed.Summary.Renames = len(moves)
+}
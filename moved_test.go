@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDetectMoves(t *testing.T) {
+	var plan TerraformPlan
+	err := json.Unmarshal([]byte(`{"resource_changes": [
+	  {"address": "aws_s3_bucket.logs", "mode": "managed", "type": "aws_s3_bucket",
+	   "change": {"actions": ["delete"], "before": {"id": "acme-logs", "arn": "arn:aws:s3:::acme-logs", "bucket": "acme-logs", "force_destroy": false, "tags": {"team": "ops"}}}},
+	  {"address": "module.storage.aws_s3_bucket.logs", "mode": "managed", "type": "aws_s3_bucket",
+	   "change": {"actions": ["create"], "after": {"bucket": "acme-logs", "force_destroy": false, "tags": {"team": "ops"}}, "after_unknown": {"id": true, "arn": true}}},
+	  {"address": "aws_s3_bucket.assets", "mode": "managed", "type": "aws_s3_bucket",
+	   "change": {"actions": ["create"], "after": {"bucket": "acme-assets", "force_destroy": false, "tags": {"team": "web"}}, "after_unknown": {"id": true}}},
+	  {"address": "aws_sqs_queue.jobs", "mode": "managed", "type": "aws_sqs_queue",
+	   "change": {"actions": ["delete"], "before": {"id": "q", "name": "jobs"}}},
+	  {"address": "aws_sns_topic.jobs", "mode": "managed", "type": "aws_sns_topic",
+	   "change": {"actions": ["create"], "after": {"name": "jobs"}}},
+	  {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance",
+	   "change": {"actions": ["delete", "create"], "before": {"ami": "ami-1"}, "after": {"ami": "ami-1"}}}
+	]}`), &plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := detectMoves(plan)
+	if len(findings) != 1 {
+		t.Fatalf("got %d findings, want 1: %+v", len(findings), findings)
+	}
+	f := findings[0]
+	if f.Address != "aws_s3_bucket.logs" || f.Category != "rename" {
+		t.Errorf("unexpected finding %+v", f)
+	}
+	want := "moved {\n  from = aws_s3_bucket.logs\n  to   = module.storage.aws_s3_bucket.logs\n}"
+	if f.Remediation != want {
+		t.Errorf("remediation =\n%s\nwant\n%s", f.Remediation, want)
+	}
+}
+
+func TestDetectMoves_PairsClosestMatch(t *testing.T) {
+	before := map[string]interface{}{"ami": "ami-1", "instance_type": "t3.small", "subnet_id": "subnet-a", "monitoring": true, "key_name": "ops"}
+	plan := TerraformPlan{ResourceChanges: []ResourceChange{
+		{Address: "aws_instance.old", Mode: "managed", Type: "aws_instance", Change: Change{Actions: []string{"delete"}, Before: before}},
+		{Address: "aws_instance.near", Mode: "managed", Type: "aws_instance", Change: Change{Actions: []string{"create"}, After: map[string]interface{}{
+			"ami": "ami-1", "instance_type": "t3.small", "subnet_id": "subnet-a", "monitoring": true, "key_name": "dev"}}},
+		{Address: "aws_instance.same", Mode: "managed", Type: "aws_instance", Change: Change{Actions: []string{"create"}, After: before}},
+	}}
+
+	findings := detectMoves(plan)
+	if len(findings) != 1 || findings[0].Remediation != movedBlock("aws_instance.old", "aws_instance.same") {
+		t.Errorf("got %+v, want a single move to aws_instance.same", findings)
+	}
+}
+
+func TestAnalyzePlan_MarksRenames(t *testing.T) {
+	plan := TerraformPlan{ResourceChanges: []ResourceChange{
+		{Address: "aws_sqs_queue.jobs", Mode: "managed", Type: "aws_sqs_queue", Name: "jobs",
+			Change: Change{Actions: []string{"delete"}, Before: map[string]interface{}{"name": "jobs", "delay_seconds": 5.0}}},
+		{Address: "aws_sqs_queue.work", Mode: "managed", Type: "aws_sqs_queue", Name: "work",
+			Change: Change{Actions: []string{"create"}, After: map[string]interface{}{"name": "jobs", "delay_seconds": 5.0}}},
+		{Address: "aws_sqs_queue.dead", Mode: "managed", Type: "aws_sqs_queue", Name: "dead",
+			Change: Change{Actions: []string{"delete"}, Before: map[string]interface{}{"name": "dead", "delay_seconds": 0.0}}},
+	}}
+
+	analyzed := analyzePlan(plan)
+	if analyzed.Summary.Renames != 1 {
+		t.Errorf("Renames = %d, want 1", analyzed.Summary.Renames)
+	}
+	got := map[string][2]string{}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			got[r.Address] = [2]string{r.RenamedFrom, r.RenamedTo}
+		}
+	}
+	want := map[string][2]string{
+		"aws_sqs_queue.jobs": {"", "aws_sqs_queue.work"},
+		"aws_sqs_queue.work": {"aws_sqs_queue.jobs", ""},
+		"aws_sqs_queue.dead": {"", ""},
+	}
+	for addr, w := range want {
+		if got[addr] != w {
+			t.Errorf("%s: (from, to) = %v, want %v", addr, got[addr], w)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import "sort"
+
+// orphanReasons describes the action_reason values Terraform uses for
+// deletes caused by configuration that no longer declares the object.
+var orphanReasons = map[string]string{
+	"delete_because_no_resource_config": "resource block removed",
+	"delete_because_no_module":          "module call removed",
+	"delete_because_count_index":        "count no longer includes this index",
+	"delete_because_each_key":           "for_each no longer includes this key",
+	"delete_because_wrong_repetition":   "switched between count and for_each",
+	"delete_because_no_move_target":     "moved block target does not exist",
+}
+
+// OrphanGroup is a set of deletes in one module that happen only because
+// their configuration is gone, not because anything asked to destroy them.
+type OrphanGroup struct {
+	Module    string
+	Reason    string
+	Addresses []string
+}
+
+// OrphanDeletes groups deletes caused by configuration removal by module
+// and reason, so an accidentally deleted file or module block stands out.
+func (a AnalyzedPlan) OrphanDeletes() []OrphanGroup {
+	index := map[[2]string]*OrphanGroup{}
+	var groups []*OrphanGroup
+	for _, m := range a.Modules {
+		for _, r := range m.Resources {
+			reason, ok := orphanReasons[r.ActionReason]
+			if !ok || r.Action != "delete" {
+				continue
+			}
+			key := [2]string{m.Address, reason}
+			g := index[key]
+			if g == nil {
+				g = &OrphanGroup{Module: m.Address, Reason: reason}
+				index[key] = g
+				groups = append(groups, g)
+			}
+			g.Addresses = append(g.Addresses, r.Address)
+		}
+	}
+
+	result := make([]OrphanGroup, 0, len(groups))
+	for _, g := range groups {
+		sort.Strings(g.Addresses)
+		result = append(result, *g)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Module != result[j].Module {
+			return result[i].Module < result[j].Module
+		}
+		return result[i].Reason < result[j].Reason
+	})
+	return result
+}
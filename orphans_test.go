@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOrphanDeletes(t *testing.T) {
+	plan := TerraformPlan{ResourceChanges: []ResourceChange{
+		{Address: "aws_s3_bucket.b", Mode: "managed", Type: "aws_s3_bucket", Name: "b",
+			Change: Change{Actions: []string{"delete"}}, ActionReason: "delete_because_no_resource_config"},
+		{Address: "aws_s3_bucket.a", Mode: "managed", Type: "aws_s3_bucket", Name: "a",
+			Change: Change{Actions: []string{"delete"}}, ActionReason: "delete_because_no_resource_config"},
+		{Address: "module.cache.aws_elasticache_cluster.main", ModuleAddress: "module.cache", Mode: "managed", Type: "aws_elasticache_cluster", Name: "main",
+			Change: Change{Actions: []string{"delete"}}, ActionReason: "delete_because_no_module"},
+		{Address: "aws_instance.web[2]", Mode: "managed", Type: "aws_instance", Name: "web",
+			Change: Change{Actions: []string{"delete"}}, ActionReason: "delete_because_count_index"},
+		{Address: "aws_instance.old", Mode: "managed", Type: "aws_instance", Name: "old",
+			Change: Change{Actions: []string{"delete"}}},
+	}}
+	analyzed := analyzePlan(plan)
+
+	groups := analyzed.OrphanDeletes()
+	if len(groups) != 3 {
+		t.Fatalf("got %d groups, want 3: %+v", len(groups), groups)
+	}
+	if g := groups[2]; g.Module != "root" || g.Reason != "resource block removed" || strings.Join(g.Addresses, ",") != "aws_s3_bucket.a,aws_s3_bucket.b" {
+		t.Errorf("unexpected root group %+v", g)
+	}
+	if g := groups[0]; g.Module != "module.cache" || g.Reason != "module call removed" {
+		t.Errorf("unexpected module group %+v", g)
+	}
+
+	html := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+	if !strings.Contains(html, "Removed from configuration") || strings.Contains(html, "<code>aws_instance.old</code>") {
+		t.Error("report does not list only the orphan deletes")
+	}
+}
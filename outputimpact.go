@@ -0,0 +1,135 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// OutputImpact is a module output whose value may change because the plan
+// changes resources behind it, with what reads it in the calling module.
+// Those consumers change along with it even though their own configuration
+// does not.
+type OutputImpact struct {
+	Output    string           `json:"output"`
+	Changed   []string         `json:"changed"`
+	Consumers []OutputConsumer `json:"consumers"`
+}
+
+// OutputConsumer is a resource attribute or module input that reads an
+// output.
+type OutputConsumer struct {
+	Address   string `json:"address"`
+	Attribute string `json:"attribute"`
+}
+
+// changedAddresses returns the resources the plan changes, without instance
+// keys.
+func changedAddresses(plan TerraformPlan) map[string]bool {
+	changed := map[string]bool{}
+	for _, rc := range plan.ResourceChanges {
+		if len(rc.Change.Actions) > 0 && rc.Change.Actions[0] != "no-op" && rc.Change.Actions[0] != "read" {
+			changed[stripIndex(rc.Address)] = true
+		}
+	}
+	return changed
+}
+
+// moduleOutputRef turns a reference such as module.net[0].subnet_ids made in
+// the module at prefix into the key of outputSources, e.g.
+// module.app.module.net.subnet_ids.
+func moduleOutputRef(prefix, ref string) (string, bool) {
+	parts := strings.Split(ref, ".")
+	if len(parts) < 3 || parts[0] != "module" {
+		return "", false
+	}
+	return prefix + "module." + stripIndex(parts[1]) + "." + stripIndex(parts[2]), true
+}
+
+// changedSources returns the changed resources an expression in the module
+// at prefix depends on, directly or through the outputs of called modules.
+func changedSources(expr interface{}, prefix string, changed map[string]bool, sources map[string][]string) []string {
+	seen := map[string]bool{}
+	var result []string
+	add := func(addr string) {
+		if !seen[addr] {
+			seen[addr] = true
+			result = append(result, addr)
+		}
+	}
+	for _, ref := range extractReferences(expr) {
+		if key, ok := moduleOutputRef(prefix, ref); ok {
+			for _, addr := range sources[key] {
+				add(addr)
+			}
+			continue
+		}
+		if target := resolveRef(ref, strings.TrimSuffix(prefix, "."), nil); changed[target] {
+			add(target)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// moduleOutputSources maps every module output, e.g. module.net.subnet_ids,
+// to the changed resources its value depends on.
+func moduleOutputSources(config PlanConfiguration, changed map[string]bool) map[string][]string {
+	sources := map[string][]string{}
+	walkModuleOutputs(config.RootModule, "", changed, sources, nil)
+	return sources
+}
+
+// findOutputImpacts lists the module outputs that depend on changed
+// resources and are read elsewhere in the configuration.
+func findOutputImpacts(plan TerraformPlan) []OutputImpact {
+	var impacts []OutputImpact
+	walkModuleOutputs(plan.Configuration.RootModule, "", changedAddresses(plan), map[string][]string{}, &impacts)
+	return impacts
+}
+
+// walkModuleOutputs fills sources for the modules called from mod, innermost
+// first so outputs passed up through several modules are followed, and, if
+// impacts is not nil, adds the outputs read in mod.
+func walkModuleOutputs(mod ConfigModule, prefix string, changed map[string]bool, sources map[string][]string, impacts *[]OutputImpact) {
+	for _, name := range sortedKeys(mod.ModuleCalls) {
+		call := mod.ModuleCalls[name]
+		child := prefix + "module." + name + "."
+		walkModuleOutputs(call.Module, child, changed, sources, impacts)
+		for _, output := range sortedKeys(call.Module.Outputs) {
+			if changedBy := changedSources(call.Module.Outputs[output].Expression, child, changed, sources); len(changedBy) > 0 {
+				sources[child+output] = changedBy
+			}
+		}
+	}
+	if impacts == nil {
+		return
+	}
+
+	consumers := map[string][]OutputConsumer{}
+	add := func(address, attr, ref string) {
+		key, ok := moduleOutputRef(prefix, ref)
+		if !ok || len(sources[key]) == 0 {
+			return
+		}
+		c := OutputConsumer{Address: address, Attribute: attr}
+		for _, existing := range consumers[key] {
+			if existing == c {
+				return
+			}
+		}
+		consumers[key] = append(consumers[key], c)
+	}
+	for _, res := range mod.Resources {
+		walkAttributeReferences("", res.Expressions, func(attr, ref string) {
+			add(prefix+res.Address, attr, ref)
+		})
+	}
+	for _, name := range sortedKeys(mod.ModuleCalls) {
+		walkAttributeReferences("", mod.ModuleCalls[name].Expressions, func(attr, ref string) {
+			add(prefix+"module."+name, attr, ref)
+		})
+	}
+	for _, key := range sortedKeys(consumers) {
+		*impacts = append(*impacts, OutputImpact{Output: key, Changed: sources[key], Consumers: consumers[key]})
+	}
+}
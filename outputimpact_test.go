@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const outputImpactPlan = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "module.net.aws_subnet.a", "module_address": "module.net", "mode": "managed", "type": "aws_subnet", "name": "a",
+     "change": {"actions": ["delete", "create"], "before": {}, "after": {}}},
+    {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web",
+     "change": {"actions": ["update"], "before": {}, "after": {}}}
+  ],
+  "output_changes": {
+    "legacy_ip": {"actions": ["delete"], "before": "10.0.0.1", "after": null},
+    "subnet": {"actions": ["update"], "before": "subnet-1", "after": null}
+  },
+  "configuration": {"root_module": {
+    "outputs": {
+      "subnet": {"expression": {"references": ["module.net.subnet_id", "module.net"]}},
+      "legacy_ip": {"expression": {"constant_value": "10.0.0.1"}}
+    },
+    "resources": [
+      {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web",
+       "expressions": {"subnet_id": {"references": ["module.net.subnet_id", "module.net"]}}}
+    ],
+    "module_calls": {
+      "app": {"expressions": {"subnet_id": {"references": ["module.net.subnet_id", "module.net"]}}, "module": {}},
+      "net": {"module": {
+        "outputs": {"subnet_id": {"expression": {"references": ["aws_subnet.a.id", "aws_subnet.a"]}}},
+        "resources": [{"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a", "expressions": {}}]
+      }}
+    }
+  }}
+}`
+
+func TestOutputImpacts(t *testing.T) {
+	plan, err := parsePlanJSON([]byte(outputImpactPlan))
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyzed := analyzePlan(plan)
+
+	if len(analyzed.OutputImpacts) != 1 {
+		t.Fatalf("unexpected output impacts %+v", analyzed.OutputImpacts)
+	}
+	impact := analyzed.OutputImpacts[0]
+	want := []OutputConsumer{{Address: "aws_instance.web", Attribute: "subnet_id"}, {Address: "module.app", Attribute: "subnet_id"}}
+	if impact.Output != "module.net.subnet_id" || strings.Join(impact.Changed, ",") != "module.net.aws_subnet.a" ||
+		len(impact.Consumers) != 2 || impact.Consumers[0] != want[0] || impact.Consumers[1] != want[1] {
+		t.Errorf("unexpected output impact %+v", impact)
+	}
+
+	// Root outputs follow module outputs and the plan's own output changes
+	outputs := map[string]StackOutput{}
+	for _, o := range analyzed.StackOutputs {
+		outputs[o.Name] = o
+	}
+	if o := outputs["subnet"]; strings.Join(o.Changed, ",") != "module.net.aws_subnet.a" || o.Action != "update" {
+		t.Errorf("unexpected stack output %+v", o)
+	}
+	if o := outputs["legacy_ip"]; len(o.Changed) != 0 || o.Action != "delete" {
+		t.Errorf("unexpected stack output %+v", o)
+	}
+
+	html := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+	if !strings.Contains(html, "Module outputs read elsewhere (1)") || !strings.Contains(html, "removed, other stacks reading it will fail") {
+		t.Error("report does not show the output consumers")
+	}
+}
+
+func TestLinkStackOutputs(t *testing.T) {
+	network := &AnalyzedPlan{StackOutputs: []StackOutput{{Name: "subnet_id", Changed: []string{"aws_subnet.a"}}}}
+	app := &AnalyzedPlan{RemoteStates: []RemoteState{
+		{Address: "data.terraform_remote_state.network", Location: "tf-state/network.tfstate",
+			Consumers: []RemoteStateConsumer{{Address: "aws_instance.web", Attribute: "subnet_id", Output: "subnet_id"}}},
+		{Address: "data.terraform_remote_state.shared", Location: "tf-state/shared.tfstate",
+			Consumers: []RemoteStateConsumer{{Address: "aws_instance.db", Attribute: "subnet_id", Output: "subnet_id"}}},
+	}}
+	linkStackOutputs([]string{"network", "app"}, []*AnalyzedPlan{network, app})
+
+	got := network.StackOutputs[0].ReadBy
+	if len(got) != 1 || got[0] != "app: aws_instance.web (subnet_id)" {
+		t.Errorf("ReadBy = %v, want only the consumer of the network state", got)
+	}
+}
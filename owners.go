@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ownerRule assigns owners to resources by module address ("root" for the
+// root module) and type, both glob patterns, for code that CODEOWNERS does
+// not describe well, e.g. IAM resources spread over many modules.
+type ownerRule struct {
+	Module string   `json:"module,omitempty"`
+	Type   string   `json:"type,omitempty"`
+	Owners []string `json:"owners"`
+}
+
+// ReviewerGroup lists the changes an owner, a team or person as written in
+// CODEOWNERS or the config, is asked to review.
+type ReviewerGroup struct {
+	Owner     string   `json:"owner"`
+	Resources []string `json:"resources"`
+}
+
+// Reviewers tells who needs to review a plan. Unowned changes have no
+// matching CODEOWNERS entry or owner rule.
+type Reviewers struct {
+	Groups  []ReviewerGroup `json:"groups"`
+	Unowned []string        `json:"unowned,omitempty"`
+}
+
+func validateOwnerRules(rules []ownerRule) error {
+	for i, r := range rules {
+		if r.Module == "" && r.Type == "" || len(r.Owners) == 0 {
+			return fmt.Errorf("owners[%d]: owners and a module or type pattern are required", i)
+		}
+		for _, p := range []string{r.Module, r.Type} {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("owners[%d]: invalid pattern %q", i, p)
+			}
+		}
+	}
+	return nil
+}
+
+type codeownersEntry struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// codeownersFiles are the places GitHub and GitLab look for CODEOWNERS.
+var codeownersFiles = []string{"CODEOWNERS", ".github/CODEOWNERS", ".gitlab/CODEOWNERS", "docs/CODEOWNERS"}
+
+// readCodeowners reads the first CODEOWNERS file found in root. GitLab
+// sections ("[Section]") are ignored, their entries are used as usual.
+func readCodeowners(root string) []codeownersEntry {
+	for _, name := range codeownersFiles {
+		f, err := os.Open(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		var entries []codeownersEntry
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line, _, _ := strings.Cut(scanner.Text(), "#")
+			fields := strings.Fields(line)
+			if len(fields) == 0 || strings.HasPrefix(fields[0], "[") || strings.HasPrefix(fields[0], "^[") {
+				continue
+			}
+			entries = append(entries, codeownersEntry{pattern: codeownersPattern(fields[0]), owners: fields[1:]})
+		}
+		return entries
+	}
+	return nil
+}
+
+// codeownersPattern converts a CODEOWNERS path pattern, which follows
+// gitignore rules, to a regular expression over slash-separated paths
+// relative to the repository root.
+func codeownersPattern(p string) *regexp.Regexp {
+	dirOnly := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case p[i] == '*':
+			re.WriteString("[^/]*")
+		case p[i] == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	if dirOnly {
+		re.WriteString("/.*$")
+	} else {
+		re.WriteString("(?:/.*)?$")
+	}
+	return regexp.MustCompile(re.String())
+}
+
+// codeownersFor returns the owners of the last matching entry, as GitHub
+// and GitLab do.
+func codeownersFor(entries []codeownersEntry, file string) []string {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].pattern.MatchString(file) {
+			return entries[i].owners
+		}
+	}
+	return nil
+}
+
+// findReviewers groups the changed resources by owner, combining the owner
+// rules of the config with CODEOWNERS for the files that declare them (see
+// applySourceLocations). The CODEOWNERS file is looked up at the repository
+// root when the plan has git context, and in dir otherwise.
+func findReviewers(analyzed AnalyzedPlan, rules []ownerRule, dir string) *Reviewers {
+	root, prefix := dir, ""
+	if g := analyzed.Git; g != nil && g.Path != "" {
+		prefix = g.Path
+		for range strings.Split(g.Path, "/") {
+			root = filepath.Join(root, "..")
+		}
+	}
+	codeowners := readCodeowners(root)
+	if len(codeowners) == 0 && len(rules) == 0 {
+		return nil
+	}
+
+	byOwner := map[string][]string{}
+	reviewers := &Reviewers{}
+	for _, m := range analyzed.Modules {
+		module := stripIndex(m.Address)
+		for _, r := range m.Resources {
+			if r.Action == "no-op" || r.Action == "read" {
+				continue
+			}
+			owners := map[string]bool{}
+			for _, rule := range rules {
+				moduleOK, _ := path.Match(rule.Module, module)
+				typeOK, _ := path.Match(rule.Type, r.Type)
+				if (rule.Module == "" || moduleOK) && (rule.Type == "" || typeOK) {
+					for _, o := range rule.Owners {
+						owners[o] = true
+					}
+				}
+			}
+			if r.Source != nil {
+				for _, o := range codeownersFor(codeowners, path.Join(prefix, r.Source.File)) {
+					owners[o] = true
+				}
+			}
+			if len(owners) == 0 {
+				reviewers.Unowned = append(reviewers.Unowned, r.Address)
+			}
+			for o := range owners {
+				byOwner[o] = append(byOwner[o], r.Address)
+			}
+		}
+	}
+	for _, owner := range sortedKeys(byOwner) {
+		resources := byOwner[owner]
+		sort.Strings(resources)
+		reviewers.Groups = append(reviewers.Groups, ReviewerGroup{Owner: owner, Resources: resources})
+	}
+	sort.Strings(reviewers.Unowned)
+	return reviewers
+}
+
+// writeReviewers writes the reviewers for --owners, for bots that request
+// reviews on the pull request. Without any ownership information the file
+// lists no groups rather than being left out, so the bot can tell.
+func writeReviewers(file string, reviewers *Reviewers, signKey string) error {
+	if reviewers == nil {
+		reviewers = &Reviewers{Groups: []ReviewerGroup{}}
+	}
+	data, err := json.MarshalIndent(reviewers, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeChecksummed(file, append(data, '\n'), 0644, signKey); err != nil {
+		return fmt.Errorf("error writing owners file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCodeownersPattern(t *testing.T) {
+	tests := []struct {
+		pattern, file string
+		want          bool
+	}{
+		{"*.tf", "infra/prod/main.tf", true},
+		{"*.tf", "infra/prod/main.go", false},
+		{"/infra/", "infra/prod/main.tf", true},
+		{"/infra/", "other/infra/main.tf", false},
+		{"infra/prod", "infra/prod/main.tf", true},
+		{"infra/prod", "x/infra/prod/main.tf", false},
+		{"network", "modules/network/main.tf", true},
+		{"modules/*/main.tf", "modules/vpc/main.tf", true},
+		{"modules/*/main.tf", "modules/vpc/sub/main.tf", false},
+		{"**/iam/*.tf", "infra/iam/roles.tf", true},
+		{"/modules/**/outputs.tf", "modules/a/b/outputs.tf", true},
+	}
+	for _, tt := range tests {
+		if got := codeownersPattern(tt.pattern).MatchString(tt.file); got != tt.want {
+			t.Errorf("%q matching %q = %v, want %v", tt.pattern, tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestFindReviewers(t *testing.T) {
+	repo := t.TempDir()
+	dir := filepath.Join(repo, "infra")
+	os.MkdirAll(filepath.Join(repo, ".github"), 0o755)
+	os.WriteFile(filepath.Join(repo, ".github", "CODEOWNERS"), []byte(`# owners
+*            @acme/platform
+/infra/modules/db/  @acme/data  # the database team
+`), 0o644)
+
+	analyzed := AnalyzedPlan{
+		Git: &GitContext{Path: "infra"},
+		Modules: []ModuleAnalysis{
+			{Address: "root", Resources: []ResourceAnalysis{
+				{Address: "aws_iam_role.app", Type: "aws_iam_role", Action: "update", Source: &SourceLocation{File: "iam.tf"}},
+				{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Action: "no-op", Source: &SourceLocation{File: "s3.tf"}},
+			}},
+			{Address: "module.db[0]", Resources: []ResourceAnalysis{
+				{Address: "module.db[0].aws_db_instance.main", Type: "aws_db_instance", Action: "create", Source: &SourceLocation{File: "modules/db/main.tf"}},
+			}},
+		},
+	}
+	rules := []ownerRule{{Type: "aws_iam_*", Owners: []string{"@acme/security"}}, {Module: "module.db", Owners: []string{"@dba"}}}
+	got := findReviewers(analyzed, rules, dir)
+	want := &Reviewers{Groups: []ReviewerGroup{
+		{Owner: "@acme/data", Resources: []string{"module.db[0].aws_db_instance.main"}},
+		{Owner: "@acme/platform", Resources: []string{"aws_iam_role.app"}},
+		{Owner: "@acme/security", Resources: []string{"aws_iam_role.app"}},
+		{Owner: "@dba", Resources: []string{"module.db[0].aws_db_instance.main"}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findReviewers() = %+v, want %+v", got, want)
+	}
+
+	analyzed.Git = nil
+	got = findReviewers(analyzed, nil, dir)
+	if got != nil {
+		t.Errorf("expected no reviewers without CODEOWNERS or rules, got %+v", got)
+	}
+	got = findReviewers(analyzed, rules[:1], dir)
+	if !reflect.DeepEqual(got.Unowned, []string{"module.db[0].aws_db_instance.main"}) {
+		t.Errorf("unowned = %v", got.Unowned)
+	}
+}
+
+func TestValidateOwnerRules(t *testing.T) {
+	if err := validateOwnerRules([]ownerRule{{Owners: []string{"@a"}}}); err == nil {
+		t.Error("expected an error for a rule without patterns")
+	}
+	if err := validateOwnerRules([]ownerRule{{Type: "aws_*"}}); err == nil {
+		t.Error("expected an error for a rule without owners")
+	}
+	if err := validateOwnerRules([]ownerRule{{Module: "module.[", Owners: []string{"@a"}}}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
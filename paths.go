@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// maxDependencyPaths caps how many equally short paths are returned; dense
+// graphs can have a combinatorial number of them.
+const maxDependencyPaths = 10
+
+// dependencyGraph is an undirected view of the reference and depends_on
+// edges drawn in the graph. Direction is ignored when looking for paths:
+// "how is the load balancer related to this security group" does not care
+// which of them refers to the other.
+type dependencyGraph struct {
+	nodes map[string]bool
+	adj   map[string][]graphLink
+}
+
+type graphLink struct {
+	to   string
+	edge string // element ID of the graph edge
+}
+
+// DependencyPath is one shortest path between two resources, listed as the
+// nodes and the graph edge IDs along it.
+type DependencyPath struct {
+	Nodes []string `json:"nodes"`
+	Edges []string `json:"edges"`
+}
+
+func refEdgeID(src, tgt string) string { return "edge:ref:" + src + "->" + tgt }
+func depEdgeID(dep, res string) string { return "edge:dep:" + dep + "->" + res }
+
+func newDependencyGraph(analyzed AnalyzedPlan, refEdges map[string][]string) *dependencyGraph {
+	g := &dependencyGraph{nodes: map[string]bool{}, adj: map[string][]graphLink{}}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			g.nodes[r.Address] = true
+		}
+	}
+	link := func(a, b, edge string) {
+		if !g.nodes[a] || !g.nodes[b] {
+			return
+		}
+		g.adj[a] = append(g.adj[a], graphLink{to: b, edge: edge})
+		g.adj[b] = append(g.adj[b], graphLink{to: a, edge: edge})
+	}
+	for src, targets := range refEdges {
+		for _, tgt := range targets {
+			link(src, tgt, refEdgeID(src, tgt))
+		}
+	}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			for _, dep := range r.DependsOn {
+				link(dep, r.Address, depEdgeID(dep, r.Address))
+			}
+		}
+	}
+	for node := range g.adj {
+		links := g.adj[node]
+		sort.Slice(links, func(i, j int) bool {
+			if links[i].to != links[j].to {
+				return links[i].to < links[j].to
+			}
+			return links[i].edge < links[j].edge
+		})
+	}
+	return g
+}
+
+// shortestPaths returns up to maxDependencyPaths shortest paths from one
+// resource to another, or nil when they are not connected.
+func (g *dependencyGraph) shortestPaths(from, to string) []DependencyPath {
+	if !g.nodes[from] || !g.nodes[to] {
+		return nil
+	}
+	if from == to {
+		return []DependencyPath{{Nodes: []string{from}, Edges: []string{}}}
+	}
+
+	// Breadth-first search recording every predecessor on a shortest path.
+	dist := map[string]int{from: 0}
+	preds := map[string][]graphLink{}
+	queue := []string{from}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if d, ok := dist[to]; ok && dist[node] >= d {
+			break
+		}
+		for _, l := range g.adj[node] {
+			d, seen := dist[l.to]
+			if !seen {
+				dist[l.to] = dist[node] + 1
+				queue = append(queue, l.to)
+			} else if d != dist[node]+1 {
+				continue
+			}
+			preds[l.to] = append(preds[l.to], graphLink{to: node, edge: l.edge})
+		}
+	}
+	if _, ok := dist[to]; !ok {
+		return nil
+	}
+
+	var paths []DependencyPath
+	var walk func(node string, nodes, edges []string)
+	walk = func(node string, nodes, edges []string) {
+		if len(paths) >= maxDependencyPaths {
+			return
+		}
+		if node == from {
+			p := DependencyPath{Nodes: make([]string, 0, len(nodes)+1), Edges: make([]string, 0, len(edges))}
+			p.Nodes = append(p.Nodes, from)
+			for i := len(nodes) - 1; i >= 0; i-- {
+				p.Nodes = append(p.Nodes, nodes[i])
+			}
+			for i := len(edges) - 1; i >= 0; i-- {
+				p.Edges = append(p.Edges, edges[i])
+			}
+			paths = append(paths, p)
+			return
+		}
+		for _, l := range preds[node] {
+			walk(l.to, append(nodes, node), append(edges, l.edge))
+		}
+	}
+	walk(to, nil, nil)
+	return paths
+}
+
+// pathsHandler answers /paths.json?from=<address>&to=<address>.
+func pathsHandler(g *dependencyGraph) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+		if from == "" || to == "" {
+			http.Error(w, "from and to are required", http.StatusBadRequest)
+			return
+		}
+		for _, addr := range []string{from, to} {
+			if !g.nodes[addr] {
+				http.Error(w, "unknown resource "+addr, http.StatusNotFound)
+				return
+			}
+		}
+		paths := g.shortestPaths(from, to)
+		if paths == nil {
+			paths = []DependencyPath{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		json.NewEncoder(w).Encode(map[string]interface{}{"from": from, "to": to, "paths": paths})
+	})
+}
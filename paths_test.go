@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func testDependencyGraph() *dependencyGraph {
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Address: "root", Resources: []ResourceAnalysis{
+		{Address: "aws_lb.web"},
+		{Address: "aws_subnet.a"},
+		{Address: "aws_subnet.b"},
+		{Address: "aws_security_group.lb"},
+		{Address: "aws_instance.app", DependsOn: []string{"aws_lb.web"}},
+		{Address: "aws_s3_bucket.logs"},
+	}}}}
+	refEdges := map[string][]string{
+		"aws_lb.web":   {"aws_subnet.a", "aws_subnet.b"},
+		"aws_subnet.a": {"aws_security_group.lb"},
+		// Target outside the plan is ignored
+		"aws_subnet.b": {"aws_security_group.lb", "aws_vpc.main"},
+	}
+	return newDependencyGraph(analyzed, refEdges)
+}
+
+func TestShortestPaths(t *testing.T) {
+	g := testDependencyGraph()
+
+	paths := g.shortestPaths("aws_security_group.lb", "aws_lb.web")
+	want := []DependencyPath{
+		{
+			Nodes: []string{"aws_security_group.lb", "aws_subnet.a", "aws_lb.web"},
+			Edges: []string{"edge:ref:aws_subnet.a->aws_security_group.lb", "edge:ref:aws_lb.web->aws_subnet.a"},
+		},
+		{
+			Nodes: []string{"aws_security_group.lb", "aws_subnet.b", "aws_lb.web"},
+			Edges: []string{"edge:ref:aws_subnet.b->aws_security_group.lb", "edge:ref:aws_lb.web->aws_subnet.b"},
+		},
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("shortestPaths = %+v, want %+v", paths, want)
+	}
+
+	paths = g.shortestPaths("aws_instance.app", "aws_subnet.a")
+	if len(paths) != 1 || paths[0].Edges[0] != "edge:dep:aws_lb.web->aws_instance.app" {
+		t.Errorf("path through depends_on = %+v", paths)
+	}
+	if paths := g.shortestPaths("aws_s3_bucket.logs", "aws_lb.web"); paths != nil {
+		t.Errorf("unconnected resources should have no path, got %+v", paths)
+	}
+}
+
+func TestPathsHandler(t *testing.T) {
+	handler := pathsHandler(testDependencyGraph())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/paths.json?from=aws_s3_bucket.logs&to=aws_lb.web", nil))
+	var resp struct {
+		Paths []DependencyPath `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK || resp.Paths == nil || len(resp.Paths) != 0 {
+		t.Errorf("unconnected: status %d body %s", rec.Code, rec.Body)
+	}
+
+	for query, status := range map[string]int{
+		"?from=aws_lb.web":                 http.StatusBadRequest,
+		"?from=aws_lb.web&to=aws_vpc.main": http.StatusNotFound,
+		"?from=aws_lb.web&to=aws_subnet.a": http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/paths.json"+query, nil))
+		if rec.Code != status {
+			t.Errorf("%s: status %d, want %d", query, rec.Code, status)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// knownPlanSections are the top-level keys of terraform show -json output
+// in the supported formats, whether tfviz uses them or not.
+var knownPlanSections = map[string]bool{
+	"format_version": true, "terraform_version": true, "variables": true,
+	"planned_values": true, "resource_drift": true, "resource_changes": true,
+	"output_changes": true, "prior_state": true, "configuration": true,
+	"relevant_attributes": true, "checks": true, "timestamp": true,
+	"applyable": true, "complete": true, "errored": true,
+	"deferred_changes": true, "deferred_actions": true,
+}
+
+// checkPlanFormat compares a plan against the formats tfviz was tested
+// with and describes anything it may not understand. The plan is still
+// used; the warnings are shown so missing data is not mistaken for none.
+func checkPlanFormat(data []byte, plan TerraformPlan) []string {
+	var warnings []string
+	newest, _ := parseSemVersion(supportedPlanFormats[len(supportedPlanFormats)-1])
+	if plan.FormatVersion == "" {
+		warnings = append(warnings, "the plan has no format_version; is it terraform show -json output?")
+	} else if v, err := parseSemVersion(plan.FormatVersion); err != nil {
+		warnings = append(warnings, fmt.Sprintf("unrecognised plan format_version %q", plan.FormatVersion))
+	} else if v.major > newest.major {
+		warnings = append(warnings, fmt.Sprintf("plan format %s (terraform %s) is not supported by this tfviz, which reads formats up to %s; the report may be incomplete or wrong", plan.FormatVersion, plan.TerraformVersion, newest))
+	} else if v.major == newest.major && v.minor > newest.minor {
+		warnings = append(warnings, fmt.Sprintf("plan format %s (terraform %s) is newer than the formats this tfviz was tested with (up to %d.%d); information added since is not shown", plan.FormatVersion, plan.TerraformVersion, newest.major, newest.minor))
+	}
+
+	var sections map[string]json.RawMessage
+	if json.Unmarshal(data, &sections) == nil {
+		var unknown []string
+		for key := range sections {
+			if !knownPlanSections[key] {
+				unknown = append(unknown, key)
+			}
+		}
+		sort.Strings(unknown)
+		if len(unknown) > 0 {
+			warnings = append(warnings, "unrecognised plan sections were ignored: "+strings.Join(unknown, ", "))
+		}
+	}
+	return warnings
+}
+
+// normalizePlan fills in what older formats leave out or name differently,
+// so the analysis can rely on the current shape.
+func normalizePlan(plan *TerraformPlan) {
+	// Terraform 0.12 (format 0.1) used bare provider names such as "aws"
+	// where later versions use the full source address.
+	qualify := func(name string) string {
+		if name == "" || strings.Contains(name, "/") {
+			return name
+		}
+		return defaultRegistryHost + "/hashicorp/" + name
+	}
+	for i := range plan.ResourceChanges {
+		rc := &plan.ResourceChanges[i]
+		rc.ProviderName = qualify(rc.ProviderName)
+		if rc.ModuleAddress == "" {
+			rc.ModuleAddress = moduleOfAddress(rc.Address)
+		}
+	}
+	for i := range plan.ResourceDrift {
+		plan.ResourceDrift[i].ProviderName = qualify(plan.ResourceDrift[i].ProviderName)
+	}
+}
+
+// moduleOfAddress returns the module part of a resource address, e.g.
+// module.app.module.db for module.app.module.db.aws_db_instance.main.
+func moduleOfAddress(address string) string {
+	var module []string
+	parts := strings.Split(address, ".")
+	for i := 0; i+1 < len(parts); i += 2 {
+		if parts[i] != "module" {
+			break
+		}
+		module = append(module, parts[i], parts[i+1])
+	}
+	return strings.Join(module, ".")
+}
+
+// planSections maps the plan sections tfviz reads to the types they are
+// decoded into, so a parse failure can be traced to its section.
+var planSections = map[string]func() interface{}{
+	"format_version":    func() interface{} { return new(string) },
+	"terraform_version": func() interface{} { return new(string) },
+	"planned_values":    func() interface{} { return new(PlannedValues) },
+	"resource_changes":  func() interface{} { return new([]ResourceChange) },
+	"resource_drift":    func() interface{} { return new([]ResourceChange) },
+	"configuration":     func() interface{} { return new(PlanConfiguration) },
+}
+
+// describePlanError explains why data could not be parsed as a plan: which
+// sections and fields did not have the expected shape, and which terraform
+// version and plan format produced them.
+func describePlanError(data []byte, err error) error {
+	trimmed := strings.TrimSpace(string(data))
+	if trimmed == "" {
+		return fmt.Errorf("error parsing JSON plan: the input is empty")
+	}
+	if strings.HasPrefix(trimmed, "PK") {
+		return fmt.Errorf("error parsing JSON plan: this is a binary plan file; convert it with terraform show -json")
+	}
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		line := 1 + strings.Count(string(data[:min(int(syntax.Offset), len(data))]), "\n")
+		return fmt.Errorf("error parsing JSON plan: invalid JSON on line %d: %v", line, syntax)
+	}
+
+	var sections map[string]json.RawMessage
+	if json.Unmarshal(data, &sections) != nil {
+		return fmt.Errorf("error parsing JSON plan: the top level is not a JSON object")
+	}
+	var formatVersion, terraformVersion string
+	json.Unmarshal(sections["format_version"], &formatVersion)
+	json.Unmarshal(sections["terraform_version"], &terraformVersion)
+
+	var problems []string
+	for _, name := range sortedKeys(planSections) {
+		raw, ok := sections[name]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(raw, planSections[name]()); err != nil {
+			problems = append(problems, describeDecodeError(name, err))
+		}
+	}
+	if len(problems) == 0 {
+		problems = append(problems, err.Error())
+	}
+	if formatVersion == "" {
+		formatVersion = "unknown"
+	}
+	if terraformVersion == "" {
+		terraformVersion = "unknown"
+	}
+	return fmt.Errorf("error parsing JSON plan from terraform %s (format %s), which tfviz could not understand:\n  %s",
+		terraformVersion, formatVersion, strings.Join(problems, "\n  "))
+}
+
+// describeDecodeError names the field of a section that had the wrong type,
+// e.g. resource_changes[3].change.actions.
+func describeDecodeError(section string, err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		return section + ": " + err.Error()
+	}
+	path := section
+	if typeErr.Field != "" {
+		for _, part := range strings.Split(typeErr.Field, ".") {
+			if _, err := strconv.Atoi(part); err == nil {
+				path += "[" + part + "]"
+			} else {
+				path += "." + part
+			}
+		}
+	}
+	return fmt.Sprintf("%s is a JSON %s, expected %s", path, typeErr.Value, jsonKind(typeErr.Type))
+}
+
+// jsonKind names the JSON value a Go type is decoded from.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Interface:
+		return "any value"
+	default:
+		return "a number"
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePlanJSON_FormatWarnings(t *testing.T) {
+	tests := []struct {
+		name, json string
+		want       []string
+	}{
+		{"supported", `{"format_version": "1.2", "terraform_version": "1.9.0", "checks": [], "timestamp": "x"}`, nil},
+		{"newer minor", `{"format_version": "1.3", "terraform_version": "1.12.0"}`, []string{"newer than the formats this tfviz was tested with (up to 1.2)"}},
+		{"newer major", `{"format_version": "2.0", "terraform_version": "2.0.0"}`, []string{"plan format 2.0 (terraform 2.0.0) is not supported"}},
+		{"missing", `{"resource_changes": []}`, []string{"no format_version"}},
+		{"unknown sections", `{"format_version": "1.2", "ephemeral": {}, "actions": []}`, []string{"ignored: actions, ephemeral"}},
+	}
+	for _, tt := range tests {
+		plan, err := parsePlanJSON([]byte(tt.json))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(plan.FormatWarnings) != len(tt.want) {
+			t.Errorf("%s: warnings = %q, want %d", tt.name, plan.FormatWarnings, len(tt.want))
+			continue
+		}
+		for i, w := range tt.want {
+			if !strings.Contains(plan.FormatWarnings[i], w) {
+				t.Errorf("%s: warning %q does not mention %q", tt.name, plan.FormatWarnings[i], w)
+			}
+		}
+	}
+}
+
+func TestParsePlanJSON_NormalizesLegacyFormat(t *testing.T) {
+	plan, err := parsePlanJSON([]byte(`{
+	  "format_version": "0.1",
+	  "terraform_version": "0.12.31",
+	  "resource_changes": [
+	    {"address": "module.app.module.db.aws_db_instance.main", "mode": "managed", "type": "aws_db_instance", "name": "main", "provider_name": "aws",
+	     "change": {"actions": ["create"]}}
+	  ]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc := plan.ResourceChanges[0]
+	if rc.ProviderName != "registry.terraform.io/hashicorp/aws" || rc.ModuleAddress != "module.app.module.db" {
+		t.Errorf("provider %q, module %q", rc.ProviderName, rc.ModuleAddress)
+	}
+	if len(plan.FormatWarnings) != 0 {
+		t.Errorf("unexpected warnings %q", plan.FormatWarnings)
+	}
+}
+
+func TestParsePlanJSON_Diagnostics(t *testing.T) {
+	tests := []struct {
+		name, json string
+		want       []string
+	}{
+		{"binary plan", "PK\x03\x04...", []string{"binary plan file", "terraform show -json"}},
+		{"syntax", "{\n  \"format_version\": \"1.2\",\n  \"resource_changes\": [,]\n}", []string{"invalid JSON on line 3"}},
+		{"shape", `{
+		  "format_version": "9.0", "terraform_version": "9.1.0",
+		  "resource_changes": [{"address": "a.b", "change": {"actions": "create"}}],
+		  "configuration": {"root_module": []}
+		}`, []string{
+			"from terraform 9.1.0 (format 9.0)",
+			"configuration.root_module is a JSON array, expected an object",
+			"resource_changes[0].change.actions is a JSON string, expected an array",
+		}},
+	}
+	for _, tt := range tests {
+		_, err := parsePlanJSON([]byte(tt.json))
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+			continue
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("%s: error %q does not mention %q", tt.name, err, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// autoInit is set by --init: preflight runs terraform init in directories
+// that have not been initialised instead of failing.
+var autoInit = false
+
+// preflight checks a configuration directory before terraform runs in it,
+// so a missing binary, a version outside required_version or a directory
+// that was never initialised is reported plainly rather than as a raw
+// terraform error halfway through.
+func preflight(ctx context.Context, dir string) error {
+	if dir == "" {
+		dir = "."
+	}
+	if tf, _ := filepath.Glob(filepath.Join(dir, "*.tf")); len(tf) == 0 {
+		if tfJSON, _ := filepath.Glob(filepath.Join(dir, "*.tf.json")); len(tfJSON) == 0 {
+			return fmt.Errorf("no Terraform configuration files in %s", dir)
+		}
+	}
+
+	path, err := exec.LookPath(terraformBinary)
+	if err != nil {
+		return fmt.Errorf("%s not found on PATH (use --install-terraform to download it)", terraformBinary)
+	}
+	required, err := requiredVersion(dir)
+	if err != nil {
+		return err
+	}
+	if required != "" {
+		constraint, err := parseVersionConstraint(required)
+		if err != nil {
+			return fmt.Errorf("invalid required_version %q: %v", required, err)
+		}
+		v, err := binaryVersion(path)
+		if err != nil {
+			return fmt.Errorf("error checking %s version: %v", path, err)
+		}
+		if !versionSatisfies(v, "", constraint) {
+			return fmt.Errorf("%s %s does not satisfy required_version %q (use --install-terraform to download a matching release)", path, v, required)
+		}
+	}
+
+	if initialized(dir) {
+		return nil
+	}
+	if !autoInit {
+		return fmt.Errorf("%s has not been initialised; run terraform init or pass --init", dir)
+	}
+	fmt.Println("🔧 Running terraform init...")
+	cmd := terraformCommand(ctx, "init", "-input=false")
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("terraform init interrupted")
+		}
+		return fmt.Errorf("error running terraform init: %v", err)
+	}
+	return nil
+}
+
+// initialized reports whether terraform init has run in dir, i.e. whether
+// its data directory (.terraform, or TF_DATA_DIR) exists.
+func initialized(dir string) bool {
+	dataDir := os.Getenv("TF_DATA_DIR")
+	if dataDir == "" {
+		dataDir = ".terraform"
+	}
+	if !filepath.IsAbs(dataDir) {
+		dataDir = filepath.Join(dir, dataDir)
+	}
+	info, err := os.Stat(dataDir)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeTerraform puts a terraform script on PATH that reports version 1.7.5
+// and creates .terraform when initialised.
+func fakeTerraform(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as terraform")
+	}
+	bin := t.TempDir()
+	script := "#!/bin/sh\ncase \"$1\" in\nversion) echo '{\"terraform_version\": \"1.7.5\"}' ;;\ninit) mkdir .terraform ;;\nesac\n"
+	if err := os.WriteFile(filepath.Join(bin, "terraform"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("TF_DATA_DIR", "")
+}
+
+func TestPreflight(t *testing.T) {
+	fakeTerraform(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	if err := preflight(ctx, dir); err == nil || !strings.Contains(err.Error(), "no Terraform configuration files") {
+		t.Errorf("empty directory: got %v", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "versions.tf"), []byte(`  required_version = ">= 1.8"`), 0644)
+	if err := preflight(ctx, dir); err == nil || !strings.Contains(err.Error(), "does not satisfy required_version") {
+		t.Errorf("old terraform: got %v", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "versions.tf"), []byte(`  required_version = "~> 1.7"`), 0644)
+	if err := preflight(ctx, dir); err == nil || !strings.Contains(err.Error(), "--init") {
+		t.Errorf("uninitialised directory: got %v", err)
+	}
+
+	autoInit = true
+	defer func() { autoInit = false }()
+	if err := preflight(ctx, dir); err != nil {
+		t.Fatalf("preflight with --init: %v", err)
+	}
+	if !initialized(dir) {
+		t.Error("terraform init did not run in the directory")
+	}
+}
+
+func TestPreflight_MissingBinary(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.tf"), nil, 0644)
+	if err := preflight(context.Background(), dir); err == nil || !strings.Contains(err.Error(), "--install-terraform") {
+		t.Errorf("got %v", err)
+	}
+}
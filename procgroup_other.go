@@ -0,0 +1,22 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// ownProcessGroup is only supported on Unix; elsewhere terraform is
+// signalled on its own and stops its provider plugins itself.
+func ownProcessGroup(cmd *exec.Cmd) bool {
+	return false
+}
+
+func interruptProcessGroup(p *os.Process) error {
+	return p.Signal(os.Interrupt)
+}
+
+func killProcessGroup(p *os.Process) error {
+	return p.Kill()
+}
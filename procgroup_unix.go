@@ -0,0 +1,34 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// ownProcessGroup starts cmd in a process group of its own, so the provider
+// plugins terraform starts can be stopped with it. In a terminal terraform
+// stays in tfviz's group: it may prompt for input, which a background group
+// cannot, and Ctrl-C already reaches all of its processes there.
+func ownProcessGroup(cmd *exec.Cmd) bool {
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		tty.Close()
+		return false
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return true
+}
+
+func signalProcessGroup(p *os.Process, sig syscall.Signal) error {
+	return syscall.Kill(-p.Pid, sig)
+}
+
+func interruptProcessGroup(p *os.Process) error {
+	return signalProcessGroup(p, syscall.SIGINT)
+}
+
+func killProcessGroup(p *os.Process) error {
+	return signalProcessGroup(p, syscall.SIGKILL)
+}
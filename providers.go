@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ProviderVersion is a provider used by the configuration with its version
+// constraint, the version locked in .terraform.lock.hcl and, when update
+// checks are enabled, the latest release.
+type ProviderVersion struct {
+	Source     string `json:"source"`
+	Constraint string `json:"constraint,omitempty"`
+	Locked     string `json:"locked,omitempty"`
+	Latest     string `json:"latest,omitempty"`
+	Warning    string `json:"warning,omitempty"`
+}
+
+// providerLag sets how far behind the latest release a locked provider may
+// be before it is flagged.
+type providerLag struct {
+	MaxMajorBehind int `json:"max_major_behind"`
+	MaxMinorBehind int `json:"max_minor_behind"`
+}
+
+var defaultProviderLag = providerLag{MaxMajorBehind: 0, MaxMinorBehind: 5}
+
+// providerSource normalizes a provider_config entry to its full source
+// address, e.g. registry.terraform.io/hashicorp/aws.
+func providerSource(p ConfigProvider) string {
+	if p.FullName != "" {
+		return p.FullName
+	}
+	return defaultRegistryHost + "/hashicorp/" + p.Name
+}
+
+// providerInventory lists the providers configured in the plan, one entry
+// per source address.
+func providerInventory(config PlanConfiguration) []ProviderVersion {
+	bySource := map[string]*ProviderVersion{}
+	for _, key := range sortedKeys(config.ProviderConfig) {
+		p := config.ProviderConfig[key]
+		source := providerSource(p)
+		entry := bySource[source]
+		if entry == nil {
+			entry = &ProviderVersion{Source: source}
+			bySource[source] = entry
+		}
+		if entry.Constraint == "" {
+			entry.Constraint = p.VersionConstraint
+		}
+	}
+	var result []ProviderVersion
+	for _, source := range sortedKeys(bySource) {
+		result = append(result, *bySource[source])
+	}
+	return result
+}
+
+var lockProviderRe = regexp.MustCompile(`(?m)^provider\s+"([^"]+)"\s*\{[^}]*?^\s*version\s*=\s*"([^"]+)"`)
+
+// readLockedProviders returns the provider versions recorded in the
+// dependency lock file in dir, keyed by source address.
+func readLockedProviders(dir string) map[string]string {
+	data, err := os.ReadFile(filepath.Join(dir, ".terraform.lock.hcl"))
+	if err != nil {
+		return nil
+	}
+	locked := map[string]string{}
+	for _, m := range lockProviderRe.FindAllStringSubmatch(string(data), -1) {
+		locked[m[1]] = m[2]
+	}
+	return locked
+}
+
+func applyLockedVersions(providers []ProviderVersion, locked map[string]string) {
+	for i := range providers {
+		providers[i].Locked = locked[providers[i].Source]
+	}
+}
+
+// providerVersions lists the published versions of a registry provider.
+func (c *registryClient) providerVersions(ctx context.Context, host, provider string) ([]string, error) {
+	base, err := c.serviceURL(ctx, host, "providers.v1")
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Versions []struct {
+			Version string `json:"version"`
+		} `json:"versions"`
+	}
+	if err := c.getJSON(ctx, base+provider+"/versions", &resp); err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, v := range resp.Versions {
+		versions = append(versions, v.Version)
+	}
+	return versions, nil
+}
+
+// providerLagWarning describes how far current is behind latest when that
+// exceeds the allowed lag.
+func providerLagWarning(current, latest semVersion, lag providerLag) string {
+	if majors := latest.major - current.major; majors > lag.MaxMajorBehind {
+		return versionsBehind(majors, "major")
+	}
+	if latest.major == current.major {
+		if minors := latest.minor - current.minor; minors > lag.MaxMinorBehind {
+			return versionsBehind(minors, "minor")
+		}
+	}
+	return ""
+}
+
+func versionsBehind(n int, kind string) string {
+	s := fmt.Sprintf("%d %s version", n, kind)
+	if n != 1 {
+		s += "s"
+	}
+	return s + " behind"
+}
+
+// checkProviderUpdates records the latest release of each provider and
+// flags those too far behind it. The locked version is compared when known,
+// otherwise the newest version the constraint allows.
+func checkProviderUpdates(ctx context.Context, client *registryClient, providers []ProviderVersion, lag providerLag) {
+	reported := false
+	for i := range providers {
+		p := &providers[i]
+		parts := strings.Split(p.Source, "/")
+		if len(parts) != 3 {
+			continue
+		}
+		versions, err := client.providerVersions(ctx, parts[0], parts[1]+"/"+parts[2])
+		if err != nil {
+			if !reported {
+				fmt.Printf("⚠️  Could not check provider updates: %v\n", err)
+				reported = true
+			}
+			continue
+		}
+		latest, ok := latestVersion(versions, nil)
+		if !ok {
+			continue
+		}
+
+		var current semVersion
+		if p.Locked != "" {
+			if current, err = parseSemVersion(p.Locked); err != nil {
+				continue
+			}
+		} else {
+			constraint, err := parseVersionConstraint(p.Constraint)
+			if err != nil {
+				continue
+			}
+			if current, ok = latestVersion(versions, constraint); !ok {
+				continue
+			}
+		}
+		if current.compare(latest) >= 0 {
+			continue
+		}
+		p.Latest = latest.String()
+		p.Warning = providerLagWarning(current, latest, lag)
+	}
+}
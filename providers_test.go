@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProviderInventory(t *testing.T) {
+	config := PlanConfiguration{ProviderConfig: map[string]ConfigProvider{
+		"aws":      {Name: "aws", FullName: "registry.terraform.io/hashicorp/aws", VersionConstraint: "~> 5.0"},
+		"aws.west": {Name: "aws", FullName: "registry.terraform.io/hashicorp/aws", Alias: "west"},
+		"random":   {Name: "random"},
+	}}
+	providers := providerInventory(config)
+	if len(providers) != 2 {
+		t.Fatalf("got %d providers, want 2: %+v", len(providers), providers)
+	}
+	if providers[0].Source != "registry.terraform.io/hashicorp/aws" || providers[0].Constraint != "~> 5.0" {
+		t.Errorf("unexpected aws entry %+v", providers[0])
+	}
+	if providers[1].Source != "registry.terraform.io/hashicorp/random" {
+		t.Errorf("unexpected random entry %+v", providers[1])
+	}
+}
+
+func TestReadLockedProviders(t *testing.T) {
+	dir := t.TempDir()
+	lock := `# This file is maintained automatically by "terraform init".
+
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.2.0"
+  constraints = "~> 5.0"
+  hashes = [
+    "h1:abc=",
+  ]
+}
+
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.5.1"
+  hashes  = ["h1:def="]
+}
+`
+	os.WriteFile(filepath.Join(dir, ".terraform.lock.hcl"), []byte(lock), 0644)
+	locked := readLockedProviders(dir)
+	if locked["registry.terraform.io/hashicorp/aws"] != "5.2.0" || locked["registry.terraform.io/hashicorp/random"] != "3.5.1" {
+		t.Errorf("unexpected locked versions %v", locked)
+	}
+	if readLockedProviders(t.TempDir()) != nil {
+		t.Error("expected nil without a lock file")
+	}
+}
+
+func TestCheckProviderUpdates(t *testing.T) {
+	client, host, _ := newTestRegistry(t)
+	source := host + "/hashicorp/aws"
+	providers := []ProviderVersion{
+		{Source: source, Locked: "5.2.0"},
+		{Source: source, Locked: "4.67.0"},
+		{Source: source, Constraint: "~> 5.0"},
+	}
+	checkProviderUpdates(t.Context(), client, providers, providerLag{MaxMajorBehind: 0, MaxMinorBehind: 5})
+
+	if p := providers[0]; p.Latest != "5.31.0" || p.Warning != "29 minor versions behind" {
+		t.Errorf("locked 5.2.0: %+v", p)
+	}
+	if p := providers[1]; p.Warning != "1 major version behind" {
+		t.Errorf("locked 4.67.0: %+v", p)
+	}
+	// Without a lock file the newest allowed version is already the latest
+	if p := providers[2]; p.Latest != "" || p.Warning != "" {
+		t.Errorf("constraint only: %+v", p)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// quotaNearFraction is the share of a quota at which tfviz starts warning.
+const quotaNearFraction = 0.8
+
+// serviceQuota is a limit on the number of resources of some types, such as
+// "VPCs per region". Used is the current account-wide usage; when it is
+// omitted, the instances already in this configuration's state are counted.
+type serviceQuota struct {
+	Name  string   `json:"name"`
+	Types []string `json:"types"`
+	Limit int      `json:"limit"`
+	Used  *int     `json:"used,omitempty"`
+}
+
+func validateQuotas(quotas []serviceQuota) error {
+	for i, q := range quotas {
+		if q.Name == "" || len(q.Types) == 0 || q.Limit <= 0 {
+			return fmt.Errorf("quotas[%d]: name, types and a positive limit are required", i)
+		}
+		if err := validatePatterns(fmt.Sprintf("quotas[%d].types", i), q.Types); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkQuotas projects each quota's usage after the plan is applied and
+// reports those that end up near or over their limit because of it.
+func checkQuotas(analyzed AnalyzedPlan, quotas []serviceQuota) []Finding {
+	var findings []Finding
+	for _, q := range quotas {
+		existing, created, deleted := 0, 0, 0
+		for _, m := range analyzed.Modules {
+			for _, r := range m.Resources {
+				if !matchesAnyPattern(q.Types, r.Type) {
+					continue
+				}
+				switch r.Action {
+				case "create":
+					created++
+				case "delete":
+					existing++
+					deleted++
+				default:
+					existing++
+				}
+			}
+		}
+		if created == 0 {
+			continue
+		}
+
+		used := existing
+		if q.Used != nil {
+			used = *q.Used
+		}
+		projected := used + created - deleted
+		if float64(projected) < quotaNearFraction*float64(q.Limit) {
+			continue
+		}
+
+		f := Finding{
+			Category: "quota",
+			Severity: "Medium",
+			Address:  strings.Join(q.Types, ", "),
+			Title:    fmt.Sprintf("%s quota nearly reached", q.Name),
+			Detail:   fmt.Sprintf("The plan creates %d and deletes %d, bringing usage to %d of %d.", created, deleted, projected, q.Limit),
+			Attributes: []FindingValue{
+				{Name: "used", Value: fmt.Sprint(used)},
+				{Name: "limit", Value: fmt.Sprint(q.Limit)},
+			},
+		}
+		if projected > q.Limit {
+			f.Severity = "High"
+			f.Title = fmt.Sprintf("%s quota exceeded", q.Name)
+			f.Detail += " The apply will fail unless the quota is raised."
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
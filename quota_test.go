@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckQuotas(t *testing.T) {
+	resources := []ResourceAnalysis{
+		{Address: "aws_vpc.a", Type: "aws_vpc", Action: "no-op"},
+		{Address: "aws_vpc.b", Type: "aws_vpc", Action: "update"},
+		{Address: "aws_vpc.c", Type: "aws_vpc", Action: "create"},
+		{Address: "aws_vpc.d", Type: "aws_vpc", Action: "create"},
+		{Address: "aws_eip.a", Type: "aws_eip", Action: "delete"},
+		{Address: "aws_eip.b", Type: "aws_eip", Action: "create"},
+		{Address: "aws_instance.a", Type: "aws_instance", Action: "update"},
+	}
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Resources: resources}}}
+	used := 9
+	quotas := []serviceQuota{
+		{Name: "VPCs per region", Types: []string{"aws_vpc"}, Limit: 5},              // 2 existing + 2 = 4 of 5: near
+		{Name: "Elastic IPs", Types: []string{"aws_eip"}, Limit: 5},                  // 1 - 1 + 1 = 1 of 5: fine
+		{Name: "Instances", Types: []string{"aws_instance"}, Limit: 1},               // nothing created
+		{Name: "VPCs (account)", Types: []string{"aws_vpc"}, Limit: 10, Used: &used}, // 9 + 2 = 11 of 10: over
+	}
+	if err := validateQuotas(quotas); err != nil {
+		t.Fatal(err)
+	}
+
+	findings := checkQuotas(analyzed, quotas)
+	if len(findings) != 2 {
+		t.Fatalf("got %d findings, want 2: %+v", len(findings), findings)
+	}
+	if f := findings[0]; f.Severity != "Medium" || !strings.Contains(f.Title, "nearly reached") || !strings.Contains(f.Detail, "4 of 5") {
+		t.Errorf("unexpected near finding %+v", f)
+	}
+	if f := findings[1]; f.Severity != "High" || !strings.Contains(f.Title, "exceeded") || !strings.Contains(f.Detail, "11 of 10") {
+		t.Errorf("unexpected over finding %+v", f)
+	}
+
+	if err := validateQuotas([]serviceQuota{{Name: "x", Types: []string{"aws_vpc"}}}); err == nil {
+		t.Error("expected error for missing limit")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRegistryHost = "registry.terraform.io"
+	registryCacheTTL    = 24 * time.Hour
+)
+
+// registryClient queries Terraform registries through their service
+// discovery document. Responses are cached on disk, so repeated plan
+// reviews do not hit the registry every time.
+type registryClient struct {
+	client   *http.Client
+	scheme   string
+	cacheDir string
+	ttl      time.Duration
+	services map[string]map[string]string
+}
+
+func newRegistryClient() *registryClient {
+	c := &registryClient{client: &http.Client{Timeout: 15 * time.Second}, scheme: "https", ttl: registryCacheTTL}
+	if base, err := os.UserCacheDir(); err == nil {
+		c.cacheDir = filepath.Join(base, "tfviz", "registry")
+	}
+	return c
+}
+
+func (c *registryClient) getJSON(ctx context.Context, url string, out interface{}) error {
+	var cacheFile string
+	if c.cacheDir != "" {
+		sum := sha256.Sum256([]byte(url))
+		cacheFile = filepath.Join(c.cacheDir, hex.EncodeToString(sum[:])+".json")
+		if info, err := os.Stat(cacheFile); err == nil && time.Since(info.ModTime()) < c.ttl {
+			if data, err := os.ReadFile(cacheFile); err == nil && json.Unmarshal(data, out) == nil {
+				return nil
+			}
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s: %v", url, err)
+	}
+
+	if cacheFile != "" && os.MkdirAll(c.cacheDir, 0700) == nil {
+		os.WriteFile(cacheFile, data, 0600)
+	}
+	return nil
+}
+
+// serviceURL resolves a service such as "modules.v1" on host.
+func (c *registryClient) serviceURL(ctx context.Context, host, service string) (string, error) {
+	services, ok := c.services[host]
+	if !ok {
+		services = map[string]string{}
+		if err := c.getJSON(ctx, c.scheme+"://"+host+"/.well-known/terraform.json", &services); err != nil {
+			return "", fmt.Errorf("registry discovery for %s failed: %v", host, err)
+		}
+		if c.services == nil {
+			c.services = map[string]map[string]string{}
+		}
+		c.services[host] = services
+	}
+	base, ok := services[service]
+	if !ok {
+		return "", fmt.Errorf("registry %s does not offer %s", host, service)
+	}
+	if strings.HasPrefix(base, "/") {
+		base = c.scheme + "://" + host + base
+	}
+	return strings.TrimSuffix(base, "/") + "/", nil
+}
+
+// moduleVersions lists the published versions of a registry module.
+func (c *registryClient) moduleVersions(ctx context.Context, host, module string) ([]string, error) {
+	base, err := c.serviceURL(ctx, host, "modules.v1")
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Modules []struct {
+			Versions []struct {
+				Version string `json:"version"`
+			} `json:"versions"`
+		} `json:"modules"`
+	}
+	if err := c.getJSON(ctx, base+module+"/versions", &resp); err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, m := range resp.Modules {
+		for _, v := range m.Versions {
+			versions = append(versions, v.Version)
+		}
+	}
+	return versions, nil
+}
+
+// splitRegistrySource splits "[host/]namespace/name/provider" into the
+// registry host and the module path.
+func splitRegistrySource(source string) (host, module string) {
+	parts := strings.Split(source, "/")
+	if len(parts) == 4 {
+		return parts[0], strings.Join(parts[1:], "/")
+	}
+	return defaultRegistryHost, source
+}
+
+// checkUpdates runs the opt-in module and provider update checks.
+func checkUpdates(ctx context.Context, client *registryClient, analyzed *AnalyzedPlan, cfg tfvizConfig) {
+	lag := defaultProviderLag
+	if cfg.ProviderLag != nil {
+		lag = *cfg.ProviderLag
+	}
+	checkModuleUpdates(ctx, client, analyzed.ModuleCalls)
+	checkProviderUpdates(ctx, client, analyzed.ProviderVersions, lag)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRegistry(t *testing.T) (*registryClient, string, *int) {
+	requests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"modules.v1": "/v1/modules/", "providers.v1": "/v1/providers/"}`))
+	})
+	mux.HandleFunc("/v1/modules/terraform-aws-modules/vpc/aws/versions", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"modules": [{"versions": [{"version": "5.1.2"}, {"version": "6.0.1"}, {"version": "5.8.0"}]}]}`))
+	})
+	mux.HandleFunc("/v1/providers/hashicorp/aws/versions", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"versions": [{"version": "4.67.0"}, {"version": "5.2.0"}, {"version": "5.31.0"}, {"version": "6.0.0-beta1"}]}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	client := &registryClient{client: srv.Client(), scheme: "http", cacheDir: t.TempDir(), ttl: time.Hour}
+	return client, strings.TrimPrefix(srv.URL, "http://"), &requests
+}
+
+func TestCheckModuleUpdates(t *testing.T) {
+	client, host, requests := newTestRegistry(t)
+	inventory := []ModuleSource{
+		{Address: "module.vpc", Source: host + "/terraform-aws-modules/vpc/aws", Version: "~> 5.0", Kind: "registry"},
+		{Address: "module.vpc_new", Source: host + "/terraform-aws-modules/vpc/aws", Version: ">= 6.0", Kind: "registry"},
+		{Address: "module.app", Source: "./app", Kind: "local"},
+	}
+	checkModuleUpdates(t.Context(), client, inventory)
+
+	if inventory[0].Latest != "6.0.1" || !strings.HasSuffix(inventory[0].LatestURL, "/modules/terraform-aws-modules/vpc/aws/6.0.1") {
+		t.Errorf("module.vpc: latest %q, url %q", inventory[0].Latest, inventory[0].LatestURL)
+	}
+	if inventory[1].Latest != "" {
+		t.Errorf("module.vpc_new allows the latest version but got %q", inventory[1].Latest)
+	}
+	// The second lookup of the same module is served from the cache
+	if *requests != 1 {
+		t.Errorf("registry was queried %d times, want 1", *requests)
+	}
+}
+
+func TestSplitRegistrySource(t *testing.T) {
+	if host, module := splitRegistrySource("terraform-aws-modules/vpc/aws"); host != defaultRegistryHost || module != "terraform-aws-modules/vpc/aws" {
+		t.Errorf("public source split into %q, %q", host, module)
+	}
+	if host, module := splitRegistrySource("app.terraform.io/acme/network/aws"); host != "app.terraform.io" || module != "acme/network/aws" {
+		t.Errorf("private source split into %q, %q", host, module)
+	}
+}
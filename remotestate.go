@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RemoteState is a terraform_remote_state data source: another stack whose
+// outputs this configuration reads.
+type RemoteState struct {
+	Address string `json:"address"`
+	Backend string `json:"backend,omitempty"`
+	// Location identifies the other stack's state, e.g. bucket/key for s3,
+	// when the backend config is a constant.
+	Location  string                `json:"location,omitempty"`
+	Consumers []RemoteStateConsumer `json:"consumers,omitempty"`
+}
+
+// RemoteStateConsumer is a resource attribute that reads an output of
+// another stack.
+type RemoteStateConsumer struct {
+	Address   string `json:"address"`
+	Attribute string `json:"attribute"`
+	Output    string `json:"output"`
+}
+
+// StackOutput is a root output of this stack that the plan changes or that
+// refers to resources the plan changes. Other stacks may read it through
+// terraform_remote_state.
+type StackOutput struct {
+	Name    string   `json:"name"`
+	Changed []string `json:"changed"`
+	// Action is the output's own planned change, update or delete, when
+	// the plan reports one.
+	Action string `json:"action,omitempty"`
+	// ReadBy are the resources of other plans shown alongside this one that
+	// read the output, see linkStackOutputs.
+	ReadBy []string `json:"read_by,omitempty"`
+}
+
+// NodeID is the output's node in the dependency graph.
+func (o StackOutput) NodeID() string {
+	return "output." + o.Name
+}
+
+// remoteStateLocationKeys are the backend config settings that identify a
+// state, in the order they are joined into RemoteState.Location.
+var remoteStateLocationKeys = []string{"organization", "bucket", "storage_account_name", "container_name", "prefix", "key", "path"}
+
+// findRemoteStates lists the terraform_remote_state data sources of every
+// module and the resource attributes in the same module that read them.
+func findRemoteStates(config PlanConfiguration) []RemoteState {
+	var result []RemoteState
+	var walk func(mod ConfigModule, prefix string)
+	walk = func(mod ConfigModule, prefix string) {
+		index := map[string]int{}
+		for _, res := range mod.Resources {
+			if res.Mode != "data" || res.Type != "terraform_remote_state" {
+				continue
+			}
+			rs := RemoteState{Address: prefix + "data.terraform_remote_state." + res.Name}
+			if backend, ok := res.Expressions["backend"].(map[string]interface{}); ok {
+				rs.Backend, _ = backend["constant_value"].(string)
+			}
+			if cfg, ok := res.Expressions["config"].(map[string]interface{}); ok {
+				rs.Location = remoteStateLocation(cfg["constant_value"])
+			}
+			index[res.Name] = len(result)
+			result = append(result, rs)
+		}
+		if len(index) > 0 {
+			for _, res := range mod.Resources {
+				seen := map[string]bool{}
+				walkAttributeReferences("", res.Expressions, func(attr, ref string) {
+					parts := strings.Split(ref, ".")
+					if len(parts) < 5 || parts[0] != "data" || parts[1] != "terraform_remote_state" || parts[3] != "outputs" {
+						return
+					}
+					i, ok := index[stripIndex(parts[2])]
+					output := stripIndex(parts[4])
+					if !ok || seen[attr+" "+parts[2]+" "+output] {
+						return
+					}
+					seen[attr+" "+parts[2]+" "+output] = true
+					result[i].Consumers = append(result[i].Consumers, RemoteStateConsumer{Address: prefix + res.Address, Attribute: attr, Output: output})
+				})
+			}
+		}
+		for _, name := range sortedKeys(mod.ModuleCalls) {
+			walk(mod.ModuleCalls[name].Module, prefix+"module."+name+".")
+		}
+	}
+	walk(config.RootModule, "")
+	return result
+}
+
+func remoteStateLocation(v interface{}) string {
+	cfg, ok := v.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	var parts []string
+	for _, key := range remoteStateLocationKeys {
+		if s, ok := cfg[key].(string); ok && s != "" {
+			parts = append(parts, s)
+		}
+	}
+	if ws, ok := cfg["workspaces"].(map[string]interface{}); ok {
+		if name, ok := ws["name"].(string); ok && name != "" {
+			parts = append(parts, name)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// findStackOutputs lists the root outputs whose value changes or depends on
+// a resource the plan changes, directly or through module outputs, since
+// another stack reading them may see new values.
+func findStackOutputs(plan TerraformPlan) []StackOutput {
+	changed := changedAddresses(plan)
+	sources := moduleOutputSources(plan.Configuration, changed)
+	var result []StackOutput
+	outputs := plan.Configuration.RootModule.Outputs
+	for _, name := range sortedKeys(outputs) {
+		o := StackOutput{Name: name, Changed: changedSources(outputs[name].Expression, "", changed, sources)}
+		if oc, ok := plan.OutputChanges[name]; ok && len(oc.Actions) == 1 && (oc.Actions[0] == "update" || oc.Actions[0] == "delete") {
+			o.Action = oc.Actions[0]
+		}
+		if len(o.Changed) > 0 || o.Action != "" {
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+// linkStackOutputs records which resources of the other plans read each
+// changing output through terraform_remote_state. Outputs are matched by
+// name; when a remote state's location is known it must also mention the
+// plan's name, so naming plan files after their state keys avoids mixing up
+// stacks with outputs of the same name.
+func linkStackOutputs(names []string, plans []*AnalyzedPlan) {
+	for i, producer := range plans {
+		for k := range producer.StackOutputs {
+			o := &producer.StackOutputs[k]
+			for j, consumer := range plans {
+				if i == j {
+					continue
+				}
+				for _, rs := range consumer.RemoteStates {
+					if rs.Location != "" && !strings.Contains(rs.Location, names[i]) {
+						continue
+					}
+					for _, c := range rs.Consumers {
+						if c.Output == o.Name {
+							o.ReadBy = append(o.ReadBy, fmt.Sprintf("%s: %s (%s)", names[j], c.Address, c.Attribute))
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// crossStackElements adds the other stacks read through remote state and the
+// changed outputs of this one to the graph, linked to the resources in it.
+func crossStackElements(analyzed AnalyzedPlan, knownNodes map[string]bool) []graphElement {
+	var elements []graphElement
+	for _, rs := range analyzed.RemoteStates {
+		labels := map[string][]string{}
+		for _, c := range rs.Consumers {
+			if source := knownResource(knownNodes, c.Address); source != "" {
+				labels[source] = append(labels[source], fmt.Sprintf("%s → outputs.%s", c.Attribute, c.Output))
+			}
+		}
+		if len(labels) == 0 {
+			continue
+		}
+		var edges []graphElement
+		for _, source := range sortedKeys(labels) {
+			edges = append(edges, graphElement{
+				Data: map[string]interface{}{
+					"id":     refEdgeID(source, rs.Address),
+					"source": source,
+					"target": rs.Address,
+					"label":  strings.Join(labels[source], "\n"),
+				},
+				Classes: "reference cross-stack",
+			})
+		}
+		label := rs.Address[strings.LastIndex(rs.Address, ".")+1:] + "\n(remote state)"
+		if rs.Location != "" {
+			label += "\n" + rs.Location
+		}
+		elements = append(elements, graphElement{
+			Data:    map[string]interface{}{"id": rs.Address, "label": label, "type": "terraform_remote_state"},
+			Classes: "resource remote-state",
+		})
+		elements = append(elements, edges...)
+	}
+	for _, o := range analyzed.StackOutputs {
+		var edges []graphElement
+		for _, src := range o.Changed {
+			target := knownResource(knownNodes, src)
+			if target == "" {
+				continue
+			}
+			edges = append(edges, graphElement{
+				Data:    map[string]interface{}{"id": refEdgeID(o.NodeID(), target), "source": o.NodeID(), "target": target},
+				Classes: "reference cross-stack",
+			})
+		}
+		if len(edges) == 0 {
+			continue
+		}
+		elements = append(elements, graphElement{
+			Data:    map[string]interface{}{"id": o.NodeID(), "label": o.Name + "\n(output)", "type": "output"},
+			Classes: "resource stack-output",
+		})
+		elements = append(elements, edges...)
+	}
+	return elements
+}
+
+// knownResource returns the graph node for address, or for its first
+// instance when the resource uses count or for_each.
+func knownResource(knownNodes map[string]bool, address string) string {
+	if knownNodes[address] {
+		return address
+	}
+	var instances []string
+	for id := range knownNodes {
+		if stripIndex(id) == address {
+			instances = append(instances, id)
+		}
+	}
+	if len(instances) == 0 {
+		return ""
+	}
+	sort.Strings(instances)
+	return instances[0]
+}
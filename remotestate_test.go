@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const remoteStatePlan = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web",
+     "change": {"actions": ["update"], "before": {}, "after": {}}},
+    {"address": "aws_lb.app", "mode": "managed", "type": "aws_lb", "name": "app",
+     "change": {"actions": ["no-op"], "before": {}, "after": {}}}
+  ],
+  "configuration": {"root_module": {
+    "outputs": {
+      "web_ip": {"expression": {"references": ["aws_instance.web.private_ip", "aws_instance.web"]}},
+      "lb_dns": {"expression": {"references": ["aws_lb.app.dns_name", "aws_lb.app"]}}
+    },
+    "resources": [
+      {"address": "data.terraform_remote_state.network", "mode": "data", "type": "terraform_remote_state", "name": "network",
+       "expressions": {"backend": {"constant_value": "s3"}, "config": {"constant_value": {"bucket": "tf-state", "key": "network.tfstate", "region": "eu-west-1"}}}},
+      {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web",
+       "expressions": {"subnet_id": {"references": ["data.terraform_remote_state.network.outputs.subnet_id", "data.terraform_remote_state.network.outputs", "data.terraform_remote_state.network"]}}},
+      {"address": "aws_lb.app", "mode": "managed", "type": "aws_lb", "name": "app", "expressions": {}}
+    ]
+  }}
+}`
+
+func TestRemoteStateDependencies(t *testing.T) {
+	plan, err := parsePlanJSON([]byte(remoteStatePlan))
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyzed := analyzePlan(plan)
+	if len(analyzed.RemoteStates) != 1 {
+		t.Fatalf("unexpected remote states %+v", analyzed.RemoteStates)
+	}
+	rs := analyzed.RemoteStates[0]
+	if rs.Backend != "s3" || rs.Location != "tf-state/network.tfstate" || len(rs.Consumers) != 1 ||
+		rs.Consumers[0] != (RemoteStateConsumer{Address: "aws_instance.web", Attribute: "subnet_id", Output: "subnet_id"}) {
+		t.Errorf("unexpected remote state %+v", rs)
+	}
+	if len(analyzed.StackOutputs) != 1 || analyzed.StackOutputs[0].Name != "web_ip" {
+		t.Errorf("unexpected stack outputs %+v", analyzed.StackOutputs)
+	}
+
+	elementsJSON, _, err := buildGraphJSON(analyzed, buildRefEdges(plan.Configuration), nil, nil, graphOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var elements []graphElement
+	json.Unmarshal([]byte(elementsJSON), &elements)
+	edges := map[string]string{}
+	for _, e := range elements {
+		if strings.Contains(e.Classes, "cross-stack") {
+			edges[e.Data["source"].(string)+" -> "+e.Data["target"].(string)] = e.Classes
+		}
+	}
+	for _, want := range []string{"aws_instance.web -> data.terraform_remote_state.network", "output.web_ip -> aws_instance.web"} {
+		if edges[want] == "" {
+			t.Errorf("missing cross-stack edge %s, got %v", want, edges)
+		}
+	}
+
+	html := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+	if !strings.Contains(html, "Cross-stack dependencies (1 remote state, 1 changed output)") {
+		t.Error("report does not list the cross-stack dependencies")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// reportView is the filter state of the resource list. It is kept in the
+// page's query string (?action=delete&q=bucket&group=type) so a filtered
+// view can be shared as a link; the server renders it and the page keeps
+// the URL in sync as the filters change. Static reports apply the action
+// and search from the URL in the browser but cannot be regrouped.
+type reportView struct {
+	Action string // all, create, update, delete or no-op
+	Search string
+	Group  string // module, type or action
+	// Sort is one of resourceSorts, or "" for the configured default.
+	Sort   string
+	Layout string // cards or table
+	// Served is set for pages rendered per request, which can be regrouped.
+	Served bool
+}
+
+var (
+	viewActions   = []string{"all", "create", "update", "delete", "no-op"}
+	viewGroupings = []string{"module", "type", "action"}
+)
+
+// parseReportView reads the view from a query string. Unknown values fall
+// back to the defaults so a stale link still opens the report.
+func parseReportView(q url.Values) reportView {
+	v := reportView{Action: "all", Search: strings.TrimSpace(q.Get("q")), Group: "module", Served: true}
+	if a := q.Get("action"); slices.Contains(viewActions, a) {
+		v.Action = a
+	}
+	if g := q.Get("group"); slices.Contains(viewGroupings, g) {
+		v.Group = g
+	}
+	if s := q.Get("sort"); slices.Contains(resourceSorts, s) {
+		v.Sort = s
+	}
+	if q.Get("layout") == "table" {
+		v.Layout = "table"
+	}
+	return v
+}
+
+// Shows reports whether the resource passes the view's filters, using the
+// same rules as the search box and filter buttons in the page.
+func (v reportView) Shows(r ResourceAnalysis) bool {
+	if v.Action != "" && v.Action != "all" && r.Action != v.Action {
+		return false
+	}
+	search := strings.ToLower(v.Search)
+	return strings.Contains(strings.ToLower(r.Address), search) ||
+		strings.Contains(strings.ToLower(r.Type), search) ||
+		strings.Contains(r.Action, search)
+}
+
+// ShowsModule reports whether any of the module's resources is shown.
+func (v reportView) ShowsModule(m ModuleAnalysis) bool {
+	return slices.ContainsFunc(m.Resources, v.Shows)
+}
+
+// IsAction and IsGroup select the initial state of the page's controls.
+func (v reportView) IsAction(action string) bool {
+	return v.Action == action || action == "all" && v.Action == ""
+}
+
+func (v reportView) IsGroup(group string) bool {
+	return v.Group == group || group == "module" && v.Group == ""
+}
+
+func (v reportView) IsTable() bool {
+	return v.Layout == "table"
+}
+
+func (v reportView) IsSort(by string) bool {
+	return v.Sort == by || by == "address" && v.Sort == ""
+}
+
+// groupResources regroups the resource list by resource type or action.
+// The default module grouping is the analyzer's own.
+func groupResources(modules []ModuleAnalysis, group string) []ModuleAnalysis {
+	if group != "type" && group != "action" {
+		return modules
+	}
+	groups := map[string]*ModuleAnalysis{}
+	for _, m := range modules {
+		for _, r := range m.Resources {
+			key := r.Type
+			if group == "action" {
+				key = r.Action
+			}
+			g, ok := groups[key]
+			if !ok {
+				g = &ModuleAnalysis{Address: key}
+				groups[key] = g
+			}
+			g.Resources = append(g.Resources, r)
+		}
+	}
+	regrouped := make([]ModuleAnalysis, 0, len(groups))
+	for _, key := range sortedKeys(groups) {
+		regrouped = append(regrouped, *groups[key])
+	}
+	return regrouped
+}
+
+// maxCachedViews bounds the rendered views kept in memory; search text is
+// free-form, so views beyond the bound are rendered for each request.
+const maxCachedViews = 32
+
+// viewPages serves the report rendered for the view in the request's query
+// string.
+type viewPages struct {
+	render func(reportView) string
+
+	mu    sync.Mutex
+	pages map[reportView]*reportPage
+}
+
+func newViewPages(render func(reportView) string) *viewPages {
+	return &viewPages{render: render, pages: map[reportView]*reportPage{}}
+}
+
+func (p *viewPages) page(v reportView) *reportPage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if page, ok := p.pages[v]; ok {
+		return page
+	}
+	page := newReportPage(p.render(v))
+	if len(p.pages) < maxCachedViews {
+		p.pages[v] = page
+	}
+	return page
+}
+
+func (p *viewPages) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.page(parseReportView(r.URL.Query())).ServeHTTP(w, r)
+}
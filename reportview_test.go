@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseReportView(t *testing.T) {
+	q, _ := url.ParseQuery("action=delete&q=+bucket+&group=type&token=abc")
+	if got, want := parseReportView(q), (reportView{Action: "delete", Search: "bucket", Group: "type", Served: true}); got != want {
+		t.Errorf("parseReportView() = %+v, want %+v", got, want)
+	}
+	q, _ = url.ParseQuery("action=destroy&group=provider")
+	if got, want := parseReportView(q), (reportView{Action: "all", Group: "module", Served: true}); got != want {
+		t.Errorf("parseReportView() with unknown values = %+v, want %+v", got, want)
+	}
+}
+
+func TestGroupResources(t *testing.T) {
+	modules := []ModuleAnalysis{
+		{Address: "root", Resources: []ResourceAnalysis{
+			{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Action: "create"},
+			{Address: "aws_instance.web", Type: "aws_instance", Action: "update"},
+		}},
+		{Address: "module.app", Resources: []ResourceAnalysis{
+			{Address: "module.app.aws_s3_bucket.data", Type: "aws_s3_bucket", Action: "delete"},
+		}},
+	}
+	byType := groupResources(modules, "type")
+	if len(byType) != 2 || byType[0].Address != "aws_instance" || byType[1].Address != "aws_s3_bucket" || len(byType[1].Resources) != 2 {
+		t.Errorf("unexpected grouping by type: %+v", byType)
+	}
+	if byAction := groupResources(modules, "action"); len(byAction) != 3 || byAction[0].Address != "create" {
+		t.Errorf("unexpected grouping by action: %+v", byAction)
+	}
+	if byModule := groupResources(modules, "module"); len(byModule) != 2 || byModule[1].Address != "module.app" {
+		t.Errorf("module grouping changed the modules: %+v", byModule)
+	}
+}
+
+func TestViewPages(t *testing.T) {
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Address: "root", Resources: []ResourceAnalysis{
+		{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Action: "create"},
+		{Address: "aws_instance.web", Type: "aws_instance", Action: "delete"},
+	}}}}
+	renders := 0
+	pages := newViewPages(func(v reportView) string {
+		renders++
+		return generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, v)
+	})
+
+	rec := httptest.NewRecorder()
+	pages.ServeHTTP(rec, httptest.NewRequest("GET", "/?action=delete&q=web", nil))
+	html := rec.Body.String()
+	if !strings.Contains(html, `data-action="delete" aria-pressed="true"`) || !strings.Contains(html, `value="web"`) {
+		t.Error("served page does not select the filters from the URL")
+	}
+	if !strings.Contains(html, `class="resource resource-changed-create" style="display: none"`) {
+		t.Error("served page shows a resource the view filters out")
+	}
+	if strings.Contains(html, `class="resource resource-changed-delete" style="display: none"`) {
+		t.Error("served page hides a resource the view shows")
+	}
+
+	pages.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/?q=web&action=delete", nil))
+	if renders != 1 {
+		t.Errorf("rendered %d times for the same view, want 1", renders)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// terraformRetries is how often a terraform command that failed with a
+// transient error is run again, set by --retries. Waits start at
+// terraformRetryBackoff (--retry-backoff) and double after every attempt,
+// up to maxRetryBackoff.
+var (
+	terraformRetries      = 0
+	terraformRetryBackoff = 10 * time.Second
+)
+
+const maxRetryBackoff = 5 * time.Minute
+
+// transientErrors recognise terraform failures that are likely to pass when
+// tried again, such as cloud API throttling and registry timeouts. Errors
+// in the configuration or permissions are never retried.
+var transientErrors = []struct {
+	pattern *regexp.Regexp
+	reason  string
+}{
+	{regexp.MustCompile(`(?i)throttl|rate exceeded|requestlimitexceeded|too many requests|\b429\b`), "rate limited"},
+	{regexp.MustCompile(`(?i)could not (connect to|query) .*registry|registry service unreachable`), "registry unreachable"},
+	{regexp.MustCompile(`(?i)i/o timeout|tls handshake timeout|context deadline exceeded|client\.timeout exceeded|timeout awaiting`), "timeout"},
+	{regexp.MustCompile(`(?i)connection reset by peer|connection refused|unexpected eof|no such host`), "network error"},
+	{regexp.MustCompile(`(?i)\b50[234]\b|bad gateway|service unavailable|gateway timeout`), "service unavailable"},
+}
+
+// transientReason returns why output of a failed terraform command looks
+// transient, or "" when it does not.
+func transientReason(output string) string {
+	for _, t := range transientErrors {
+		if t.pattern.MatchString(output) {
+			return t.reason
+		}
+	}
+	return ""
+}
+
+// retryDelay is the wait before the given retry, counting from 1.
+func retryDelay(base time.Duration, retry int) time.Duration {
+	d := base
+	for i := 1; i < retry && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryBackoff)
+}
+
+// RetryAttempt records a terraform command that failed with a transient
+// error and was run again.
+type RetryAttempt struct {
+	Command string        `json:"command"`
+	Retry   int           `json:"retry"`
+	Reason  string        `json:"reason"`
+	Error   string        `json:"error"`
+	Wait    time.Duration `json:"wait_ns"`
+}
+
+func (r RetryAttempt) WaitText() string {
+	return formatStageDuration(r.Wait)
+}
+
+// withRetries calls run until it succeeds, fails with an error that is not
+// transient, or terraformRetries is used up. run passes stderr on to the
+// terraform command it starts, which is how the failure is classified.
+// Retries are printed and recorded in runMetrics for the report.
+func withRetries(ctx context.Context, command string, run func(stderr io.Writer) error) error {
+	for retry := 1; ; retry++ {
+		var stderr bytes.Buffer
+		err := run(&stderr)
+		if err == nil || ctx.Err() != nil || retry > terraformRetries {
+			return err
+		}
+		reason := transientReason(stderr.String())
+		if reason == "" {
+			return err
+		}
+		wait := retryDelay(terraformRetryBackoff, retry)
+		fmt.Printf("🔁 %s failed (%s); retrying in %s (%d of %d)\n", command, reason, wait, retry, terraformRetries)
+		runMetrics.Retries = append(runMetrics.Retries, RetryAttempt{
+			Command: command,
+			Retry:   retry,
+			Reason:  reason,
+			Error:   lastLine(stderr.String()),
+			Wait:    wait,
+		})
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// lastLine returns the last non-empty line of terraform's error output,
+// which usually names the failing request.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// setRunFlag applies --retries, --retry-backoff or --timeout.
+func setRunFlag(name, value string) error {
+	if name == "--retries" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid --retries %q", value)
+		}
+		terraformRetries = n
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid %s duration %q", name, value)
+	}
+	if name == "--timeout" {
+		terraformTimeout = d
+	} else {
+		terraformRetryBackoff = d
+	}
+	return nil
+}
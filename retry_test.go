@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestTransientReason(t *testing.T) {
+	tests := map[string]string{
+		"Error: reading EC2 Instances: ThrottlingException: Rate exceeded":                                 "rate limited",
+		"Error: Failed to query available provider packages\n\ncould not connect to registry.terraform.io": "registry unreachable",
+		`Get "https://sts.amazonaws.com/": net/http: TLS handshake timeout`:                                "timeout",
+		"read tcp 10.0.0.2:443: connection reset by peer":                                                  "network error",
+		"Error: Unsupported argument\n\nAn argument named \"foo\" is not expected here.":                   "",
+		"Error: AccessDenied: not authorized to perform: ec2:DescribeVpcs":                                 "",
+	}
+	for output, want := range tests {
+		if got := transientReason(output); got != want {
+			t.Errorf("transientReason(%q) = %q, want %q", output, got, want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for retry, want := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 3: 40 * time.Second, 10: maxRetryBackoff} {
+		if got := retryDelay(10*time.Second, retry); got != want {
+			t.Errorf("retryDelay(10s, %d) = %s, want %s", retry, got, want)
+		}
+	}
+}
+
+func TestWithRetries(t *testing.T) {
+	defer func(n int, d time.Duration) { terraformRetries, terraformRetryBackoff = n, d }(terraformRetries, terraformRetryBackoff)
+	defer func(m *PlanMetrics) { runMetrics = m }(runMetrics)
+	terraformRetries, terraformRetryBackoff = 2, time.Millisecond
+	runMetrics = &PlanMetrics{}
+
+	calls := 0
+	err := withRetries(context.Background(), "terraform plan", func(stderr io.Writer) error {
+		calls++
+		if calls < 3 {
+			fmt.Fprintln(stderr, "Error: ThrottlingException: Rate exceeded")
+			return errors.New("exit status 1")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("got %v after %d calls, want success after 3", err, calls)
+	}
+	if len(runMetrics.Retries) != 2 || runMetrics.Retries[1].Wait != 2*time.Millisecond || runMetrics.Retries[0].Error != "Error: ThrottlingException: Rate exceeded" {
+		t.Errorf("unexpected retry log %+v", runMetrics.Retries)
+	}
+
+	calls = 0
+	err = withRetries(context.Background(), "terraform plan", func(stderr io.Writer) error {
+		calls++
+		fmt.Fprintln(stderr, "Error: Invalid reference")
+		return errors.New("exit status 1")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("expected a configuration error to fail without retrying, got %v after %d calls", err, calls)
+	}
+}
+
+func TestSetupTerraform_Retries(t *testing.T) {
+	defer func(n int, d time.Duration) { terraformRetries, terraformRetryBackoff = n, d }(terraformRetries, terraformRetryBackoff)
+	rest, err := setupTerraform(context.Background(), []string{"--retries", "3", "--retry-backoff=30s", "-g", "--", "--retries"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if terraformRetries != 3 || terraformRetryBackoff != 30*time.Second {
+		t.Errorf("retries = %d, backoff = %s", terraformRetries, terraformRetryBackoff)
+	}
+	if len(rest) != 3 || rest[0] != "-g" || rest[2] != "--retries" {
+		t.Errorf("rest = %v", rest)
+	}
+	if _, err := setupTerraform(context.Background(), []string{"--retries=-1"}); err == nil {
+		t.Error("expected an error for a negative --retries")
+	}
+}
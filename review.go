@@ -0,0 +1,199 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxReviewComment bounds a comment, which is stored and shown as is.
+const maxReviewComment = 4000
+
+// ReviewEntry is a comment on, or sign-off of, one resource change, made by
+// a reviewer in the served report.
+type ReviewEntry struct {
+	Address string `json:"address"`
+	// Action is comment, sign-off or revoke; the latest sign-off or revoke
+	// of a resource decides whether it is signed off.
+	Action  string `json:"action"`
+	Comment string `json:"comment,omitempty"`
+	Author  string `json:"author,omitempty"`
+	Time    string `json:"time"`
+}
+
+// reviewStore keeps the review entries of every plan in one JSON file,
+// keyed by planFingerprint so that re-planning without changes keeps the
+// review, while the entries of other plans are left alone.
+type reviewStore struct {
+	mu      sync.Mutex
+	path    string
+	plan    string
+	entries []ReviewEntry
+}
+
+// planFingerprint identifies a plan by its resource changes.
+func planFingerprint(plan TerraformPlan) string {
+	data, _ := json.Marshal(plan.ResourceChanges)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+func defaultReviewFile() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "tfviz", "reviews.json"), nil
+}
+
+// reviewStoreFor opens the review file given with --reviews, or the default
+// one. Without a cache directory the report is served without reviews.
+func reviewStoreFor(path string, plan TerraformPlan) (*reviewStore, error) {
+	if path == "" {
+		var err error
+		if path, err = defaultReviewFile(); err != nil {
+			fmt.Printf("⚠️  Reviews are disabled: %v\n", err)
+			return nil, nil
+		}
+	}
+	return openReviewStore(path, planFingerprint(plan))
+}
+
+func openReviewStore(path, plan string) (*reviewStore, error) {
+	all, err := readReviews(path)
+	if err != nil {
+		return nil, err
+	}
+	return &reviewStore{path: path, plan: plan, entries: all[plan]}, nil
+}
+
+func readReviews(path string) (map[string][]ReviewEntry, error) {
+	all := map[string][]ReviewEntry{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading reviews: %v", err)
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("error reading reviews from %s: %v", path, err)
+	}
+	return all, nil
+}
+
+func (s *reviewStore) list() []ReviewEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ReviewEntry{}, s.entries...)
+}
+
+// add records an entry and rewrites the file, re-reading it first so that
+// entries saved by other tfviz processes are kept.
+func (s *reviewStore) add(e ReviewEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := readReviews(s.path)
+	if err != nil {
+		return err
+	}
+	entries := append(all[s.plan], e)
+	all[s.plan] = entries
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("error saving reviews: %v", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("error saving reviews: %v", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("error saving reviews: %v", err)
+	}
+	s.entries = entries
+	return nil
+}
+
+// reviewsHandler lists the plan's review entries and records new ones. With
+// trustProxy, the author is taken from the authenticating proxy in front
+// of the server; otherwise it is the name the reviewer entered. Only JSON
+// is accepted, so other sites cannot post reviews through a plain form.
+func reviewsHandler(store *reviewStore, addresses map[string]bool, trustProxy bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			if ct, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";"); ct != "application/json" {
+				http.Error(w, "reviews must be posted as application/json", http.StatusUnsupportedMediaType)
+				return
+			}
+			var e ReviewEntry
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&e); err != nil {
+				http.Error(w, "invalid review: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := validateReviewEntry(e, addresses); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if user := requestUser(r); trustProxy && user != "" {
+				e.Author = user
+			}
+			e.Time = time.Now().UTC().Format(time.RFC3339)
+			if err := store.add(e); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		json.NewEncoder(w).Encode(map[string]interface{}{"entries": store.list()})
+	})
+}
+
+func validateReviewEntry(e ReviewEntry, addresses map[string]bool) error {
+	if !addresses[e.Address] {
+		return fmt.Errorf("unknown resource %q", e.Address)
+	}
+	switch e.Action {
+	case "comment":
+		if strings.TrimSpace(e.Comment) == "" {
+			return fmt.Errorf("comment is empty")
+		}
+		if len(e.Comment) > maxReviewComment {
+			return fmt.Errorf("comment is longer than %d bytes", maxReviewComment)
+		}
+	case "sign-off", "revoke":
+	default:
+		return fmt.Errorf("unknown review action %q (use comment, sign-off or revoke)", e.Action)
+	}
+	if len(e.Author) > 200 {
+		return fmt.Errorf("author is too long")
+	}
+	return nil
+}
+
+// analysisHandler serves the analysis with the review entries made so far.
+func analysisHandler(analyzed AnalyzedPlan, store *reviewStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		export := analyzed
+		export.Reviews = store.list()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		json.NewEncoder(w).Encode(export)
+	})
+}
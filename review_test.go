@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReviewsHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reviews.json")
+	store, err := openReviewStore(path, "plan-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Entries of other plans in the same file are kept
+	other, _ := openReviewStore(path, "plan-b")
+	if err := other.add(ReviewEntry{Address: "aws_s3_bucket.logs", Action: "sign-off", Time: "2024-01-01T00:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := reviewsHandler(store, map[string]bool{"aws_instance.web": true}, true)
+	post := func(body, user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/reviews.json", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if user != "" {
+			req.Header.Set("X-Forwarded-User", user)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post(`{"address": "aws_instance.web", "action": "comment", "comment": "Check the AMI", "author": "dana"}`, ""); rec.Code != http.StatusOK {
+		t.Fatalf("comment: status = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := post(`{"address": "aws_instance.web", "action": "sign-off", "author": "dana"}`, "alex"); rec.Code != http.StatusOK {
+		t.Fatalf("sign-off: status = %d: %s", rec.Code, rec.Body)
+	}
+	for _, bad := range []string{
+		`{"address": "aws_lb.other", "action": "sign-off"}`,
+		`{"address": "aws_instance.web", "action": "comment", "comment": "  "}`,
+		`{"address": "aws_instance.web", "action": "approve"}`,
+	} {
+		if rec := post(bad, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", bad, rec.Code)
+		}
+	}
+	req := httptest.NewRequest(http.MethodPost, "/reviews.json", strings.NewReader("address=aws_instance.web&action=sign-off"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("form post: status = %d, want 415", rec.Code)
+	}
+
+	// A restarted server sees the saved entries, with the proxy's user
+	reopened, err := openReviewStore(path, "plan-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := reopened.list()
+	if len(entries) != 2 || entries[0].Author != "dana" || entries[1].Author != "alex" || entries[1].Action != "sign-off" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	all, _ := readReviews(path)
+	if len(all["plan-b"]) != 1 {
+		t.Errorf("entries of another plan were lost: %+v", all)
+	}
+
+	rec = httptest.NewRecorder()
+	analysisHandler(AnalyzedPlan{Timestamp: "now"}, reopened).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/analysis.json", nil))
+	var exported AnalyzedPlan
+	if err := json.Unmarshal(rec.Body.Bytes(), &exported); err != nil || len(exported.Reviews) != 2 {
+		t.Errorf("analysis export does not include the reviews: %v %s", err, rec.Body)
+	}
+
+	// Without --trust-proxy-headers the header cannot sign off for someone
+	handler = reviewsHandler(reopened, map[string]bool{"aws_instance.web": true}, false)
+	if rec := post(`{"address": "aws_instance.web", "action": "sign-off", "author": "dana"}`, "alex"); rec.Code != http.StatusOK {
+		t.Fatalf("sign-off: status = %d: %s", rec.Code, rec.Body)
+	}
+	if entries := reopened.list(); entries[len(entries)-1].Author != "dana" {
+		t.Errorf("author = %q, want the entered name", entries[len(entries)-1].Author)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"path"
+)
+
+// riskNote explains to reviewers why a kind of change is risky. Type is a
+// glob pattern; Actions are create, update, replace or delete, and an empty
+// list matches any change.
+type riskNote struct {
+	Type    string   `json:"type"`
+	Actions []string `json:"actions,omitempty"`
+	Note    string   `json:"note"`
+}
+
+// builtinRiskNotes cover common changes whose consequences are easy to miss
+// in a diff. Project notes from the config are checked first.
+var builtinRiskNotes = []riskNote{
+	{"aws_eip", []string{"replace", "delete"}, "The public IP address changes. DNS records, firewall allow lists and partners that use the old address must be updated."},
+	{"aws_nat_gateway", []string{"replace", "delete"}, "Outbound traffic from the private subnets stops until the new gateway is up, and it gets a new public IP that partner allow lists must include."},
+	{"aws_instance", []string{"replace"}, "The instance is terminated and a new one launched: data on local disks is lost, its IP addresses change and anything not baked into the AMI or user data is gone."},
+	{"aws_db_instance", []string{"replace", "delete"}, "The database is destroyed. Unless a final snapshot is taken or the new one restores from a snapshot, its data is lost, and applications must follow the new endpoint."},
+	{"aws_rds_cluster", []string{"replace", "delete"}, "The cluster and its data are destroyed unless a final snapshot is taken; applications must follow the new endpoint."},
+	{"aws_dynamodb_table", []string{"replace", "delete"}, "The table and every item in it are destroyed. Point-in-time recovery backups are deleted with it."},
+	{"aws_ebs_volume", []string{"replace", "delete"}, "The volume and its data are destroyed unless a snapshot exists."},
+	{"aws_elasticache_*", []string{"replace"}, "The new cache starts empty, so the databases behind it see the full load until it warms up."},
+	{"aws_s3_bucket", []string{"replace", "delete"}, "Deleting a bucket fails while it holds objects, unless force_destroy is set, in which case every object is lost. The name may not be available again right away."},
+	{"aws_kms_key", []string{"replace", "delete"}, "Once the waiting period ends the key cannot be recovered, and everything encrypted with it becomes unreadable."},
+	{"aws_security_group", []string{"replace"}, "The group must be detached from every network interface before it can be deleted; the apply may hang, and traffic it allowed is blocked in between."},
+	{"aws_security_group_rule", []string{"replace", "delete"}, "Removing a rule blocks the traffic it allowed immediately, including for existing connections."},
+	{"aws_vpc_security_group_*_rule", []string{"replace", "delete"}, "Removing a rule blocks the traffic it allowed immediately, including for existing connections."},
+	{"aws_iam_role", []string{"replace", "delete"}, "Services and accounts that assume the role lose access, and trust policies that refer to the old role stop working even after it is recreated."},
+	{"aws_iam_*policy*", []string{"update", "delete"}, "Permission changes apply within seconds to every principal the policy is attached to."},
+	{"aws_route53_record", []string{"update", "replace", "delete"}, "Clients cache the old answer for the record's TTL, so the change, and a rollback, take that long to reach everyone."},
+	{"aws_lb", []string{"replace"}, "The new load balancer gets a new DNS name; aliases and clients that use the old one must follow."},
+	{"aws_cloudfront_distribution", []string{"replace"}, "The new distribution gets a new domain name and takes a while to deploy; DNS must be switched over."},
+	{"google_sql_database_instance", []string{"replace", "delete"}, "The instance and its data are destroyed, and its name cannot be reused for about a week."},
+	{"azurerm_public_ip", []string{"replace", "delete"}, "The public IP address changes. DNS records and allow lists that use the old address must be updated."},
+	{"kubernetes_namespace*", []string{"delete"}, "Deleting a namespace deletes every object in it."},
+}
+
+func validateRiskNotes(notes []riskNote) error {
+	for i, n := range notes {
+		if n.Type == "" || n.Note == "" {
+			return fmt.Errorf("risk_notes[%d]: type and note are required", i)
+		}
+		if _, err := path.Match(n.Type, ""); err != nil {
+			return fmt.Errorf("risk_notes[%d]: invalid pattern %q", i, n.Type)
+		}
+		for _, a := range n.Actions {
+			if a != "create" && a != "update" && a != "replace" && a != "delete" {
+				return fmt.Errorf("risk_notes[%d]: unknown action %q (use create, update, replace or delete)", i, a)
+			}
+		}
+	}
+	return nil
+}
+
+// riskNoteFor returns the first note matching the change, so specific
+// patterns should be listed before broad ones.
+func riskNoteFor(notes []riskNote, r ResourceAnalysis) string {
+	action := r.Action
+	if r.Replace {
+		action = "replace"
+	}
+	if action == "no-op" || action == "read" {
+		return ""
+	}
+	for _, n := range notes {
+		if ok, _ := path.Match(n.Type, r.Type); !ok {
+			continue
+		}
+		if len(n.Actions) == 0 {
+			return n.Note
+		}
+		for _, a := range n.Actions {
+			if a == action {
+				return n.Note
+			}
+		}
+	}
+	return ""
+}
+
+func applyRiskNotes(analyzed *AnalyzedPlan, notes []riskNote) {
+	notes = append(append([]riskNote{}, notes...), builtinRiskNotes...)
+	for i := range analyzed.Modules {
+		resources := analyzed.Modules[i].Resources
+		for j := range resources {
+			resources[j].RiskNote = riskNoteFor(notes, resources[j])
+		}
+	}
+}
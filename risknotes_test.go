@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyRiskNotes(t *testing.T) {
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Address: "root", Resources: []ResourceAnalysis{
+		{Address: "aws_eip.nat", Type: "aws_eip", Action: "update", Replace: true},
+		{Address: "aws_eip.web", Type: "aws_eip", Action: "update"},
+		{Address: "aws_db_instance.main", Type: "aws_db_instance", Action: "delete"},
+		{Address: "aws_sqs_queue.jobs", Type: "aws_sqs_queue", Action: "delete"},
+		{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Action: "no-op"},
+	}}}}
+	project := []riskNote{{Type: "aws_sqs_*", Actions: []string{"delete"}, Note: "Drain the queue first."}}
+	applyRiskNotes(&analyzed, project)
+
+	got := map[string]string{}
+	for _, r := range analyzed.Modules[0].Resources {
+		got[r.Address] = r.RiskNote
+	}
+	if !strings.Contains(got["aws_eip.nat"], "public IP address changes") {
+		t.Errorf("replaced EIP: %q", got["aws_eip.nat"])
+	}
+	if got["aws_eip.web"] != "" || got["aws_s3_bucket.logs"] != "" {
+		t.Errorf("notes on changes they do not describe: %v", got)
+	}
+	if !strings.Contains(got["aws_db_instance.main"], "final snapshot") {
+		t.Errorf("deleted database: %q", got["aws_db_instance.main"])
+	}
+	if got["aws_sqs_queue.jobs"] != "Drain the queue first." {
+		t.Errorf("project note not applied: %q", got["aws_sqs_queue.jobs"])
+	}
+}
+
+func TestValidateRiskNotes(t *testing.T) {
+	if err := validateRiskNotes(builtinRiskNotes); err != nil {
+		t.Errorf("built-in notes are invalid: %v", err)
+	}
+	if err := validateRiskNotes([]riskNote{{Type: "aws_eip", Actions: []string{"destroy"}, Note: "x"}}); err == nil {
+		t.Error("expected error for an unknown action")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModuleRollup aggregates the changes of one module so the graph and the
+// resource list can show where a plan's risk is without opening every card.
+// Replacements are counted as updates, like everywhere else in the report.
+type ModuleRollup struct {
+	Create   int    `json:"create"`
+	Update   int    `json:"update"`
+	Delete   int    `json:"delete"`
+	Replace  int    `json:"replace"`
+	HighRisk int    `json:"high_risk"`
+	Impact   string `json:"impact,omitempty"`
+}
+
+// Rollup is computed on demand because impacts can still change after the
+// analysis, e.g. through critical attribute rules in the project config.
+func (m ModuleAnalysis) Rollup() ModuleRollup {
+	var r ModuleRollup
+	for _, res := range m.Resources {
+		switch res.Action {
+		case "create":
+			r.Create++
+		case "update":
+			r.Update++
+		case "delete":
+			r.Delete++
+		default:
+			continue
+		}
+		if res.Replace {
+			r.Replace++
+		}
+		if res.Impact == "High" {
+			r.HighRisk++
+		}
+		if impactRank[res.Impact] > impactRank[r.Impact] {
+			r.Impact = res.Impact
+		}
+	}
+	return r
+}
+
+// Counts formats the rollup like Terraform's plan summary symbols, e.g.
+// "+2 ~1 -1". Zero counts are left out.
+func (r ModuleRollup) Counts() string {
+	var parts []string
+	if r.Create > 0 {
+		parts = append(parts, fmt.Sprintf("+%d", r.Create))
+	}
+	if r.Update > 0 {
+		parts = append(parts, fmt.Sprintf("~%d", r.Update))
+	}
+	if r.Delete > 0 {
+		parts = append(parts, fmt.Sprintf("-%d", r.Delete))
+	}
+	return strings.Join(parts, " ")
+}
+
+// graphLabel is the text shown on a module's compound node.
+func (r ModuleRollup) graphLabel(address string) string {
+	label := address
+	if counts := r.Counts(); counts != "" {
+		label += "\n" + counts
+	}
+	if r.Impact != "" {
+		label += " · " + r.Impact + " impact"
+	}
+	return label
+}
+
+// parentModule returns the address of the module that calls address, or ""
+// for a top-level module call.
+func parentModule(address string) string {
+	i := strings.LastIndex(address, ".module.")
+	if i < 0 {
+		return ""
+	}
+	return address[:i]
+}
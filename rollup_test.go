@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestModuleRollup(t *testing.T) {
+	m := ModuleAnalysis{Address: "module.db", Resources: []ResourceAnalysis{
+		{Action: "create", Impact: "Low"},
+		{Action: "create", Impact: "Low"},
+		{Action: "update", Impact: "Medium", Replace: true},
+		{Action: "delete", Impact: "High"},
+		{Action: "no-op", Impact: "High"},
+	}}
+	r := m.Rollup()
+	want := ModuleRollup{Create: 2, Update: 1, Delete: 1, Replace: 1, HighRisk: 1, Impact: "High"}
+	if r != want {
+		t.Errorf("Rollup() = %+v, want %+v", r, want)
+	}
+	if got := r.Counts(); got != "+2 ~1 -1" {
+		t.Errorf("Counts() = %q", got)
+	}
+	if got := (ModuleRollup{Update: 3}).Counts(); got != "~3" {
+		t.Errorf("Counts() = %q", got)
+	}
+}
+
+func TestBuildGraphJSON_ModuleRollups(t *testing.T) {
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{
+		{Address: "root", Resources: []ResourceAnalysis{{Address: "aws_vpc.main", Type: "aws_vpc", Name: "main", Action: "create", Impact: "Low"}}},
+		{Address: "module.app", Resources: []ResourceAnalysis{{Address: "module.app.aws_instance.web", Type: "aws_instance", Name: "web", Action: "delete", Impact: "High"}}},
+		{Address: "module.app.module.cache", Resources: []ResourceAnalysis{
+			{Address: "module.app.module.cache.aws_subnet.a", Type: "aws_subnet", Name: "a", Action: "create", Impact: "Low"},
+			{Address: "module.app.module.cache.aws_instance.node", Type: "aws_instance", Name: "node", Action: "update", Impact: "Medium"},
+		}},
+	}}
+	containment := map[string]string{"module.app.module.cache.aws_subnet.a": "aws_vpc.main"}
+
+	graphJSON, _, err := buildGraphJSON(analyzed, nil, containment, nil, graphOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var elements []struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(graphJSON), &elements); err != nil {
+		t.Fatal(err)
+	}
+	nodes := map[string]map[string]interface{}{}
+	for _, e := range elements {
+		nodes[e.Data["id"].(string)] = e.Data
+	}
+
+	if _, ok := nodes["root"]; ok {
+		t.Error("root module should not get a node")
+	}
+	app := nodes["module.app"]
+	if app == nil || app["impact"] != "High" || app["label"] != "module.app\n-1 · High impact" {
+		t.Errorf("module.app node = %v", app)
+	}
+	cache := nodes["module.app.module.cache"]
+	if cache == nil || cache["parent"] != "module.app" || cache["label"] != "module.app.module.cache\n+1 ~1 · Medium impact" {
+		t.Errorf("nested module node = %v", cache)
+	}
+	if p := nodes["module.app.aws_instance.web"]["parent"]; p != "module.app" {
+		t.Errorf("web parent = %v, want module.app", p)
+	}
+	// Containment takes precedence over module grouping
+	if p := nodes["module.app.module.cache.aws_subnet.a"]["parent"]; p != "aws_vpc.main" {
+		t.Errorf("subnet parent = %v, want aws_vpc.main", p)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"path"
+)
+
+// runbookLink maps resource types matching a glob pattern (e.g. "aws_iam_*")
+// to the operational procedure for them.
+type runbookLink struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+func validateRunbooks(runbooks []runbookLink) error {
+	for i, rb := range runbooks {
+		if rb.Type == "" || rb.URL == "" {
+			return fmt.Errorf("runbooks[%d]: type and url are required", i)
+		}
+		if _, err := path.Match(rb.Type, ""); err != nil {
+			return fmt.Errorf("runbooks[%d]: invalid pattern %q", i, rb.Type)
+		}
+	}
+	return nil
+}
+
+// runbookFor returns the URL of the first runbook whose pattern matches
+// resourceType, so specific patterns should be listed before broad ones.
+func runbookFor(runbooks []runbookLink, resourceType string) string {
+	for _, rb := range runbooks {
+		if ok, _ := path.Match(rb.Type, resourceType); ok {
+			return rb.URL
+		}
+	}
+	return ""
+}
+
+func applyRunbooks(analyzed *AnalyzedPlan, runbooks []runbookLink) {
+	if len(runbooks) == 0 {
+		return
+	}
+	for i := range analyzed.Modules {
+		resources := analyzed.Modules[i].Resources
+		for j := range resources {
+			resources[j].Runbook = runbookFor(runbooks, resources[j].Type)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunbookFor(t *testing.T) {
+	runbooks := []runbookLink{
+		{Type: "aws_iam_role", URL: "https://wiki/iam-role"},
+		{Type: "aws_iam_*", URL: "https://wiki/iam"},
+		{Type: "aws_db_*", URL: "https://wiki/db"},
+	}
+	tests := map[string]string{
+		"aws_iam_role":    "https://wiki/iam-role",
+		"aws_iam_policy":  "https://wiki/iam",
+		"aws_db_instance": "https://wiki/db",
+		"aws_s3_bucket":   "",
+	}
+	for resourceType, want := range tests {
+		if got := runbookFor(runbooks, resourceType); got != want {
+			t.Errorf("runbookFor(%q) = %q, want %q", resourceType, got, want)
+		}
+	}
+
+	if err := validateRunbooks([]runbookLink{{Type: "aws_[", URL: "x"}}); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+	if err := validateRunbooks([]runbookLink{{Type: "aws_*"}}); err == nil {
+		t.Error("expected error for missing url")
+	}
+}
+
+func TestApplyRunbooks(t *testing.T) {
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{
+		{Address: "aws_db_instance.main", Type: "aws_db_instance", Action: "create"},
+		{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Action: "update"},
+	}}}}
+	applyRunbooks(&analyzed, []runbookLink{{Type: "aws_db_*", URL: "https://wiki/db"}})
+
+	resources := analyzed.Modules[0].Resources
+	if resources[0].Runbook != "https://wiki/db" || resources[1].Runbook != "" {
+		t.Errorf("unexpected runbooks: %q, %q", resources[0].Runbook, resources[1].Runbook)
+	}
+	html := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+	if !strings.Contains(html, `href="https://wiki/db"`) {
+		t.Error("report does not link the runbook")
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// sgPermission is one direction/protocol/port-range/source tuple of a
+// security group, the unit in which access is compared.
+type sgPermission struct {
+	direction string
+	protocol  string // "all" or a lower-case protocol name or number
+	from, to  int
+	source    string // CIDR, security group ID, prefix list ID or "self"
+}
+
+func (p sgPermission) String() string {
+	ports := fmt.Sprintf("%d-%d", p.from, p.to)
+	if p.from == p.to {
+		ports = fmt.Sprint(p.from)
+	}
+	if p.protocol == "all" {
+		ports = "all ports"
+	}
+	word := "from"
+	if p.direction == "egress" {
+		word = "to"
+	}
+	return fmt.Sprintf("%s %s %s %s", p.protocol, ports, word, p.source)
+}
+
+func (p sgPermission) public() bool {
+	return p.direction == "ingress" && (p.source == "0.0.0.0/0" || p.source == "::/0")
+}
+
+// covers reports whether p already allows everything q allows.
+func (p sgPermission) covers(q sgPermission) bool {
+	if p.direction != q.direction {
+		return false
+	}
+	if p.protocol != "all" && p.protocol != q.protocol {
+		return false
+	}
+	if q.from < p.from || q.to > p.to {
+		return false
+	}
+	if p.source == q.source {
+		return true
+	}
+	pp, err1 := netip.ParsePrefix(p.source)
+	qp, err2 := netip.ParsePrefix(q.source)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return pp.Addr().Is4() == qp.Addr().Is4() && pp.Bits() <= qp.Bits() && pp.Contains(qp.Addr())
+}
+
+func normalizeProtocol(v interface{}) string {
+	p := strings.ToLower(fmt.Sprint(v))
+	if p == "-1" || p == "all" || p == "<nil>" || p == "" {
+		return "all"
+	}
+	return p
+}
+
+func intValue(v interface{}) int {
+	if f, ok := v.(float64); ok {
+		return int(f)
+	}
+	return 0
+}
+
+func stringList(v interface{}) []string {
+	var out []string
+	if list, ok := v.([]interface{}); ok {
+		for _, item := range list {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// expandRule turns one rule (an ingress/egress block or a rule resource)
+// into permissions, one per source.
+func expandRule(direction string, rule map[string]interface{}) []sgPermission {
+	protocol := normalizeProtocol(rule["protocol"])
+	if v, ok := rule["ip_protocol"]; ok {
+		protocol = normalizeProtocol(v)
+	}
+	base := sgPermission{direction: direction, protocol: protocol, from: intValue(rule["from_port"]), to: intValue(rule["to_port"])}
+	if protocol == "all" || base.from < 0 {
+		base.from, base.to = 0, 65535
+	}
+
+	var sources []string
+	for _, key := range []string{"cidr_blocks", "ipv6_cidr_blocks", "security_groups", "prefix_list_ids"} {
+		sources = append(sources, stringList(rule[key])...)
+	}
+	for _, key := range []string{"cidr_ipv4", "cidr_ipv6", "source_security_group_id", "referenced_security_group_id", "prefix_list_id"} {
+		if s, ok := rule[key].(string); ok && s != "" {
+			sources = append(sources, s)
+		}
+	}
+	if rule["self"] == true {
+		sources = append(sources, "self")
+	}
+
+	var perms []sgPermission
+	for _, s := range sources {
+		p := base
+		p.source = s
+		perms = append(perms, p)
+	}
+	return perms
+}
+
+func securityGroupPermissions(resourceType string, values map[string]interface{}) []sgPermission {
+	if values == nil {
+		return nil
+	}
+	switch resourceType {
+	case "aws_security_group":
+		var perms []sgPermission
+		for _, direction := range []string{"ingress", "egress"} {
+			if rules, ok := values[direction].([]interface{}); ok {
+				for _, r := range rules {
+					if m, ok := r.(map[string]interface{}); ok {
+						perms = append(perms, expandRule(direction, m)...)
+					}
+				}
+			}
+		}
+		return perms
+	case "aws_security_group_rule":
+		direction, _ := values["type"].(string)
+		return expandRule(direction, values)
+	case "aws_vpc_security_group_ingress_rule":
+		return expandRule("ingress", values)
+	case "aws_vpc_security_group_egress_rule":
+		return expandRule("egress", values)
+	}
+	return nil
+}
+
+// checkSecurityGroupWidening flags security group changes that allow
+// something the before state did not, rather than every rule edit.
+func checkSecurityGroupWidening(rc ResourceChange) []Finding {
+	after := securityGroupPermissions(rc.Type, rc.Change.After)
+	if len(after) == 0 {
+		return nil
+	}
+	before := securityGroupPermissions(rc.Type, rc.Change.Before)
+
+	var widened []sgPermission
+	public := false
+	for _, q := range after {
+		covered := false
+		for _, p := range before {
+			if p.covers(q) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			widened = append(widened, q)
+			public = public || q.public()
+		}
+	}
+	if len(widened) == 0 {
+		return nil
+	}
+
+	f := Finding{
+		Category: "network-exposure",
+		Severity: "Medium",
+		Address:  rc.Address,
+		Title:    "Security group access widened",
+		Detail:   "Allows traffic the current rules do not.",
+	}
+	if public {
+		f.Severity = "High"
+		f.Title = "Security group opened to the internet"
+	}
+	for _, p := range widened {
+		f.Attributes = append(f.Attributes, FindingValue{Name: p.direction, Value: p.String()})
+	}
+	return []Finding{f}
+}
@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func sgRule(from, to float64, protocol string, cidrs ...string) map[string]interface{} {
+	blocks := []interface{}{}
+	for _, c := range cidrs {
+		blocks = append(blocks, c)
+	}
+	return map[string]interface{}{"from_port": from, "to_port": to, "protocol": protocol, "cidr_blocks": blocks}
+}
+
+func TestCheckSecurityGroupWidening(t *testing.T) {
+	group := func(ingress ...map[string]interface{}) map[string]interface{} {
+		rules := []interface{}{}
+		for _, r := range ingress {
+			rules = append(rules, r)
+		}
+		return map[string]interface{}{"ingress": rules, "egress": []interface{}{}}
+	}
+	tests := []struct {
+		name          string
+		before, after map[string]interface{}
+		wantTitle     string
+		wantWidened   int
+	}{
+		{"description only", group(sgRule(443, 443, "tcp", "10.0.0.0/16")), group(sgRule(443, 443, "tcp", "10.0.0.0/16")), "", 0},
+		{"narrowed cidr", group(sgRule(443, 443, "tcp", "10.0.0.0/16")), group(sgRule(443, 443, "tcp", "10.0.1.0/24")), "", 0},
+		{"rule removed", group(sgRule(22, 22, "tcp", "10.0.0.0/8"), sgRule(443, 443, "tcp", "10.0.0.0/8")), group(sgRule(443, 443, "tcp", "10.0.0.0/8")), "", 0},
+		{"new cidr", group(sgRule(443, 443, "tcp", "10.0.0.0/16")), group(sgRule(443, 443, "tcp", "10.0.0.0/16", "192.168.0.0/24")), "Security group access widened", 1},
+		{"wider ports", group(sgRule(443, 443, "tcp", "10.0.0.0/16")), group(sgRule(0, 65535, "tcp", "10.0.0.0/16")), "Security group access widened", 1},
+		{"all protocols", group(sgRule(443, 443, "tcp", "10.0.0.0/16")), group(sgRule(0, 0, "-1", "10.0.0.0/16")), "Security group access widened", 1},
+		{"public", group(sgRule(22, 22, "tcp", "10.0.0.0/8")), group(sgRule(22, 22, "tcp", "0.0.0.0/0")), "Security group opened to the internet", 1},
+		{"new group", nil, group(sgRule(80, 80, "tcp", "0.0.0.0/0")), "Security group opened to the internet", 1},
+	}
+	for _, tt := range tests {
+		findings := checkSecurityGroupWidening(ResourceChange{Address: "aws_security_group.web", Type: "aws_security_group",
+			Change: Change{Actions: []string{"update"}, Before: tt.before, After: tt.after}})
+		if tt.wantTitle == "" {
+			if len(findings) != 0 {
+				t.Errorf("%s: unexpected findings %+v", tt.name, findings)
+			}
+			continue
+		}
+		if len(findings) != 1 || findings[0].Title != tt.wantTitle || len(findings[0].Attributes) != tt.wantWidened {
+			t.Errorf("%s: got %+v, want %q with %d widened permissions", tt.name, findings, tt.wantTitle, tt.wantWidened)
+		}
+	}
+}
+
+func TestCheckSecurityGroupWidening_RuleResources(t *testing.T) {
+	findings := checkSecurityGroupWidening(ResourceChange{Address: "aws_vpc_security_group_ingress_rule.ssh", Type: "aws_vpc_security_group_ingress_rule",
+		Change: Change{Actions: []string{"create"}, After: map[string]interface{}{"ip_protocol": "tcp", "from_port": 22.0, "to_port": 22.0, "cidr_ipv6": "::/0"}}})
+	if len(findings) != 1 || findings[0].Severity != "High" || findings[0].Attributes[0].Value != "tcp 22 from ::/0" {
+		t.Errorf("unexpected findings %+v", findings)
+	}
+
+	// Replacing a rule with the same values does not widen anything
+	rule := map[string]interface{}{"type": "egress", "protocol": "tcp", "from_port": 443.0, "to_port": 443.0, "source_security_group_id": "sg-123"}
+	findings = checkSecurityGroupWidening(ResourceChange{Address: "aws_security_group_rule.out", Type: "aws_security_group_rule",
+		Change: Change{Actions: []string{"delete", "create"}, Before: rule, After: rule}})
+	if len(findings) != 0 {
+		t.Errorf("unexpected findings %+v", findings)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// semVersion is a MAJOR.MINOR.PATCH[-prerelease] version as used by the
+// Terraform registry.
+type semVersion struct {
+	major, minor, patch int
+	pre                 string
+}
+
+func parseSemVersion(s string) (semVersion, error) {
+	var v semVersion
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	core, pre, _ := strings.Cut(s, "-")
+	core, _, _ = strings.Cut(core, "+")
+	v.pre = pre
+	parts := strings.Split(core, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	nums := []*int{&v.major, &v.minor, &v.patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+func (v semVersion) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+	if v.pre != "" {
+		s += "-" + v.pre
+	}
+	return s
+}
+
+// compare returns -1, 0 or 1. Prereleases sort before their release.
+func (v semVersion) compare(o semVersion) int {
+	for _, d := range []int{v.major - o.major, v.minor - o.minor, v.patch - o.patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.pre == o.pre:
+		return 0
+	case v.pre == "":
+		return 1
+	case o.pre == "":
+		return -1
+	case v.pre < o.pre:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// versionConstraint is a parsed Terraform version constraint such as
+// "~> 5.0, != 5.1.0".
+type versionConstraint []constraintPart
+
+type constraintPart struct {
+	op      string
+	version semVersion
+	parts   int // number of components written, for ~>
+}
+
+func parseVersionConstraint(s string) (versionConstraint, error) {
+	var c versionConstraint
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		op := "="
+		for _, candidate := range []string{"~>", ">=", "<=", "!=", ">", "<", "="} {
+			if strings.HasPrefix(item, candidate) {
+				op = candidate
+				item = strings.TrimSpace(item[len(candidate):])
+				break
+			}
+		}
+		v, err := parseSemVersion(item)
+		if err != nil {
+			return nil, err
+		}
+		core, _, _ := strings.Cut(strings.TrimPrefix(item, "v"), "-")
+		c = append(c, constraintPart{op: op, version: v, parts: len(strings.Split(core, "."))})
+	}
+	return c, nil
+}
+
+// allows reports whether v satisfies every part of the constraint.
+// Prereleases only match an exact constraint.
+func (c versionConstraint) allows(v semVersion) bool {
+	for _, part := range c {
+		cmp := v.compare(part.version)
+		ok := false
+		switch part.op {
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case "~>":
+			// ~> 1.2 allows >= 1.2, < 2.0; ~> 1.2.3 allows >= 1.2.3, < 1.3.0
+			upper := semVersion{major: part.version.major + 1}
+			if part.parts >= 3 {
+				upper = semVersion{major: part.version.major, minor: part.version.minor + 1}
+			}
+			ok = cmp >= 0 && v.compare(upper) < 0
+		}
+		if !ok {
+			return false
+		}
+		if v.pre != "" && part.op != "=" {
+			return false
+		}
+	}
+	return true
+}
+
+// latestVersion returns the highest release among versions, optionally
+// restricted to those the constraint allows.
+func latestVersion(versions []string, c versionConstraint) (semVersion, bool) {
+	var best semVersion
+	found := false
+	for _, s := range versions {
+		v, err := parseSemVersion(s)
+		if err != nil || v.pre != "" {
+			continue
+		}
+		if c != nil && !c.allows(v) {
+			continue
+		}
+		if !found || v.compare(best) > 0 {
+			best, found = v, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestVersionConstraintAllows(t *testing.T) {
+	tests := []struct {
+		constraint, version string
+		want                bool
+	}{
+		{"5.1.2", "5.1.2", true},
+		{"= 5.1.2", "5.1.3", false},
+		{"~> 5.0", "5.9.1", true},
+		{"~> 5.0", "6.0.0", false},
+		{"~> 5.1.0", "5.1.9", true},
+		{"~> 5.1.0", "5.2.0", false},
+		{">= 4.0, < 6.0", "5.3.0", true},
+		{">= 4.0, < 6.0", "6.0.0", false},
+		{">= 4.0, != 4.2.0", "4.2.0", false},
+		{">= 4.0", "5.0.0-beta1", false},
+		{"5.0.0-beta1", "5.0.0-beta1", true},
+		{"v1.2", "1.2.0", true},
+	}
+	for _, tt := range tests {
+		c, err := parseVersionConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("parseVersionConstraint(%q): %v", tt.constraint, err)
+		}
+		v, err := parseSemVersion(tt.version)
+		if err != nil {
+			t.Fatalf("parseSemVersion(%q): %v", tt.version, err)
+		}
+		if got := c.allows(v); got != tt.want {
+			t.Errorf("%q allows %q = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+
+	if _, err := parseVersionConstraint(">= banana"); err == nil {
+		t.Error("expected error for invalid version")
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	versions := []string{"4.9.0", "5.10.0", "5.2.0", "6.0.0-rc1", "garbage"}
+	if v, ok := latestVersion(versions, nil); !ok || v.String() != "5.10.0" {
+		t.Errorf("latestVersion = %v, %v; want 5.10.0", v, ok)
+	}
+	c, _ := parseVersionConstraint("~> 4.0")
+	if v, ok := latestVersion(versions, c); !ok || v.String() != "4.9.0" {
+		t.Errorf("latestVersion(~> 4.0) = %v, %v; want 4.9.0", v, ok)
+	}
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+type reportPage struct {
+	body    []byte
+	gzipped []byte
+	etag    string
+}
+
+func newReportPage(html string) *reportPage {
+	page := &reportPage{body: []byte(html)}
+
+	sum := sha256.Sum256(page.body)
+	page.etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err == nil {
+		if _, err := zw.Write(page.body); err == nil && zw.Close() == nil {
+			page.gzipped = buf.Bytes()
+		}
+	}
+	return page
+}
+
+func (p *reportPage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("ETag", p.etag)
+	h.Set("Vary", "Accept-Encoding")
+
+	if etagMatches(r.Header.Get("If-None-Match"), p.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body := p.body
+	if p.gzipped != nil && acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		h.Set("Content-Encoding", "gzip")
+		body = p.gzipped
+	}
+	h.Set("Content-Length", fmt.Sprint(len(body)))
+
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(body)
+}
+
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		candidate = strings.TrimPrefix(candidate, "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip. Brotli
+// is not offered, as the standard library has no encoder for it.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		params = strings.ReplaceAll(params, " ", "")
+		return params != "q=0" && params != "q=0.0" && params != "q=0.00" && params != "q=0.000"
+	}
+	return false
+}
+
+const defaultListenAddr = "127.0.0.1:9876"
+
+type serveOptions struct {
+	listen        string
+	auditLog      string
+	shutdownAfter time.Duration
+	shareTTL      time.Duration
+	tlsCert       string
+	tlsKey        string
+	tlsSelfSigned bool
+	// share names the tunnel provider that makes the report reachable from
+	// outside, see tunnelFor; tunnels are the project's own providers.
+	share   string
+	tunnels map[string]tunnelConfig
+	// browser is the command that opens the report, see browserCommand;
+	// noBrowser only prints the URL.
+	browser   string
+	noBrowser bool
+	// trustProxyHeaders takes the user from X-Forwarded-User and
+	// X-Auth-Request-User, see requestUser.
+	trustProxyHeaders bool
+}
+
+func (o serveOptions) useTLS() bool {
+	return o.tlsCert != "" || o.tlsSelfSigned
+}
+
+// browseURL returns the URL to open for a listen address. Wildcard and
+// empty hosts are reachable through localhost.
+func browseURL(listen string, useTLS bool) (string, error) {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "", fmt.Errorf("invalid listen address %q: %v", listen, err)
+	}
+	if isWildcardHost(host) {
+		host = "localhost"
+	}
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(host, port), nil
+}
+
+func isWildcardHost(host string) bool {
+	return host == "" || host == "0.0.0.0" || host == "::"
+}
+
+// idleTracker signals done once no connection has been active for the
+// configured duration. The countdown only starts after the first request.
+type idleTracker struct {
+	mu     sync.Mutex
+	idle   time.Duration
+	active map[net.Conn]bool
+	timer  *time.Timer
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newIdleTracker(idle time.Duration) *idleTracker {
+	return &idleTracker{
+		idle:   idle,
+		active: map[net.Conn]bool{},
+		done:   make(chan struct{}),
+	}
+}
+
+func (t *idleTracker) ConnState(c net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch state {
+	case http.StateNew, http.StateActive:
+		t.active[c] = true
+		if t.timer != nil {
+			t.timer.Stop()
+		}
+	case http.StateIdle, http.StateClosed, http.StateHijacked:
+		if !t.active[c] {
+			return
+		}
+		delete(t.active, c)
+		if len(t.active) == 0 {
+			if t.timer != nil {
+				t.timer.Stop()
+			}
+			t.timer = time.AfterFunc(t.idle, func() {
+				t.once.Do(func() { close(t.done) })
+			})
+		}
+	}
+}
+
+const shareCookieName = "tfviz_share"
+
+// shareGate only lets requests through that carry the share token, either in
+// the token query parameter or in the cookie set on first use, and refuses
+// every request once the link has expired.
+type shareGate struct {
+	token   string
+	expires time.Time
+	next    http.Handler
+}
+
+func newShareToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (g *shareGate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if time.Now().After(g.expires) {
+		http.Error(w, "This share link has expired.", http.StatusGone)
+		return
+	}
+
+	if q := r.URL.Query().Get("token"); q != "" && g.valid(q) {
+		http.SetCookie(w, &http.Cookie{
+			Name:     shareCookieName,
+			Value:    q,
+			Path:     "/",
+			Expires:  g.expires,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteStrictMode,
+		})
+		g.next.ServeHTTP(w, r)
+		return
+	}
+	if c, err := r.Cookie(shareCookieName); err == nil && g.valid(c.Value) {
+		g.next.ServeHTTP(w, r)
+		return
+	}
+	http.Error(w, "A valid share link is required to view this report.", http.StatusUnauthorized)
+}
+
+func (g *shareGate) valid(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(g.token)) == 1
+}
+
+// route is an additional endpoint served next to the report.
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+func jsonHandler(body []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(body)
+	})
+}
+
+// serveHTMLOnce serves the report until ctx is cancelled (for example by
+// Ctrl-C) or, when opts.shutdownAfter is set, until no connection has been
+// active for that long. The server is then shut down gracefully.
+func serveHTMLOnce(ctx context.Context, render func(reportView) string, opts serveOptions, routes ...route) error {
+	listen := opts.listen
+	if listen == "" {
+		listen = defaultListenAddr
+	}
+	url, err := browseURL(listen, opts.useTLS())
+	if err != nil {
+		return err
+	}
+
+	if opts.share != "" && opts.shareTTL == 0 {
+		opts.shareTTL = defaultTunnelShareTTL
+	}
+	var tunnel tunnelConfig
+	if opts.share != "" {
+		if tunnel, err = tunnelFor(opts.share, opts.tunnels); err != nil {
+			return err
+		}
+	}
+
+	pages := newViewPages(render)
+	page := pages.page(parseReportView(nil))
+	mux := http.NewServeMux()
+	mux.Handle("/", pages)
+	for _, r := range routes {
+		mux.Handle(r.pattern, r.handler)
+	}
+	server := &http.Server{Addr: listen, Handler: mux}
+
+	shareQuery := ""
+	if opts.shareTTL > 0 {
+		token, err := newShareToken()
+		if err != nil {
+			return fmt.Errorf("error generating share token: %v", err)
+		}
+		gate := &shareGate{token: token, expires: time.Now().Add(opts.shareTTL), next: mux}
+		server.Handler = gate
+		shareQuery = "/?token=" + token
+		url += shareQuery
+		fmt.Printf("🔗 Share link valid until %s\n", gate.expires.Format("2006-01-02 15:04:05"))
+	}
+
+	if opts.auditLog != "" {
+		log, err := openAuditLog(opts.auditLog)
+		if err != nil {
+			return err
+		}
+		defer log.Close()
+		server.Handler = auditHandler(log, strings.Trim(page.etag, `"`), opts.trustProxyHeaders, server.Handler)
+		fmt.Printf("📝 Recording report access to %s\n", opts.auditLog)
+	}
+
+	var idleDone <-chan struct{}
+	if opts.shutdownAfter > 0 {
+		tracker := newIdleTracker(opts.shutdownAfter)
+		server.ConnState = tracker.ConnState
+		idleDone = tracker.done
+	}
+
+	if opts.tlsSelfSigned {
+		hosts := []string{"localhost", "127.0.0.1", "::1"}
+		if host, _, _ := net.SplitHostPort(listen); !isWildcardHost(host) {
+			hosts = append(hosts, host)
+		}
+		cert, err := generateSelfSignedCert(hosts)
+		if err != nil {
+			return fmt.Errorf("error generating self-signed certificate: %v", err)
+		}
+		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		fmt.Println("🔒 Using a self-signed certificate; your browser will ask you to trust it.")
+	}
+
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("HTTP server error: %v", err)
+	}
+	if opts.share != "" {
+		_, port, _ := net.SplitHostPort(ln.Addr().String())
+		fmt.Printf("🌐 Opening a %s tunnel...\n", opts.share)
+		public, stop, err := startTunnel(ctx, tunnel, port)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		defer stop()
+		fmt.Printf("🌐 Link for reviewers: %s%s\n", strings.TrimSuffix(public, "/"), shareQuery)
+	}
+
+	if !opts.noBrowser {
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			if err := openBrowser(url, opts.browser); err != nil {
+				fmt.Printf("ℹ️  Could not open a browser (%v); open %s yourself.\n", err, url)
+			}
+		}()
+	}
+
+	if opts.shutdownAfter > 0 {
+		fmt.Printf("🚀 Serving report at %s. The server stops after %s without activity.\n", url, opts.shutdownAfter)
+	} else {
+		fmt.Printf("🚀 Serving report at %s. Press Ctrl-C to stop.\n", url)
+	}
+	if host, _, _ := net.SplitHostPort(listen); isWildcardHost(host) {
+		fmt.Println("⚠️  Listening on all interfaces; the plan is reachable from other hosts.")
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if opts.useTLS() {
+			serveErr <- server.ServeTLS(ln, opts.tlsCert, opts.tlsKey)
+		} else {
+			serveErr <- server.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("HTTP server error: %v", err)
+	case <-ctx.Done():
+		fmt.Println("\n🛑 Shutting down server...")
+	case <-idleDone:
+		fmt.Println("👋 No activity, shutting down server...")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// generateSelfSignedCert creates a short-lived in-memory certificate for the
+// given host names and IP addresses. Nothing is written to disk.
+func generateSelfSignedCert(hosts []string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	tmpl := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"tfviz"}, CommonName: hosts[0]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// openBrowser runs the browser command and reports whether it failed. The
+// server keeps running either way, so a missing browser only costs a click.
+func openBrowser(url, browser string) error {
+	args, err := browserCommand(url, browser, os.Getenv("BROWSER"), runtime.GOOS, os.Getenv)
+	if err != nil {
+		return err
+	}
+	return exec.Command(args[0], args[1:]...).Run()
+}
+
+// browserCommand picks the command that opens url: --browser, then the
+// $BROWSER convention of colon-separated commands where %s stands for the
+// URL, then the platform's opener. On Linux without a display there is no
+// point in trying xdg-open.
+func browserCommand(url, browser, envBrowser, goos string, getenv func(string) string) ([]string, error) {
+	if browser == "" && envBrowser != "" {
+		for _, candidate := range strings.Split(envBrowser, ":") {
+			if fields := strings.Fields(candidate); len(fields) > 0 {
+				if _, err := exec.LookPath(fields[0]); err == nil {
+					browser = candidate
+					break
+				}
+			}
+		}
+		if browser == "" {
+			return nil, fmt.Errorf("no command in $BROWSER was found")
+		}
+	}
+	if browser != "" {
+		args := strings.Fields(browser)
+		if len(args) == 0 {
+			return nil, fmt.Errorf("empty browser command")
+		}
+		substituted := false
+		for i, a := range args {
+			if strings.Contains(a, "%s") {
+				args[i] = strings.ReplaceAll(a, "%s", url)
+				substituted = true
+			}
+		}
+		if !substituted {
+			args = append(args, url)
+		}
+		return args, nil
+	}
+
+	switch goos {
+	case "darwin":
+		return []string{"open", url}, nil
+	case "windows":
+		return []string{"rundll32", "url.dll,FileProtocolHandler", url}, nil
+	}
+	if getenv("WSL_DISTRO_NAME") != "" {
+		if _, err := exec.LookPath("wslview"); err == nil {
+			return []string{"wslview", url}, nil
+		}
+		return []string{"cmd.exe", "/c", "start", "", url}, nil
+	}
+	if getenv("DISPLAY") == "" && getenv("WAYLAND_DISPLAY") == "" {
+		return nil, fmt.Errorf("no graphical display")
+	}
+	return []string{"xdg-open", url}, nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportPage_Gzip(t *testing.T) {
+	html := "<html><body>" + string(bytes.Repeat([]byte("resource "), 500)) + "</body></html>"
+	page := newReportPage(html)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip;q=0.8")
+	rec := httptest.NewRecorder()
+	page.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if rec.Body.Len() >= len(html) {
+		t.Errorf("compressed body (%d bytes) not smaller than original (%d bytes)", rec.Body.Len(), len(html))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	out, _ := io.ReadAll(zr)
+	if string(out) != html {
+		t.Error("decompressed body does not match original HTML")
+	}
+
+	// Clients that do not advertise gzip get the plain body
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0")
+	rec = httptest.NewRecorder()
+	page.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != html {
+		t.Error("expected uncompressed body when gzip is refused")
+	}
+}
+
+func TestReportPage_ETag(t *testing.T) {
+	page := newReportPage("<html></html>")
+
+	rec := httptest.NewRecorder()
+	page.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("missing ETag header")
+	}
+	if rec.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache", rec.Header().Get("Cache-Control"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	page.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("status = %d, want 304", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Error("304 response should have no body")
+	}
+}
+
+func TestGenerateSelfSignedCert(t *testing.T) {
+	cert, err := generateSelfSignedCert([]string{"localhost", "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("generateSelfSignedCert: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	if err := leaf.VerifyHostname("localhost"); err != nil {
+		t.Errorf("certificate not valid for localhost: %v", err)
+	}
+	if err := leaf.VerifyHostname("127.0.0.1"); err != nil {
+		t.Errorf("certificate not valid for 127.0.0.1: %v", err)
+	}
+}
+
+func TestBrowseURL(t *testing.T) {
+	tests := []struct {
+		listen string
+		tls    bool
+		want   string
+	}{
+		{"127.0.0.1:9876", false, "http://127.0.0.1:9876"},
+		{"0.0.0.0:8080", false, "http://localhost:8080"},
+		{":9876", true, "https://localhost:9876"},
+		{"[::]:9876", false, "http://localhost:9876"},
+	}
+	for _, tt := range tests {
+		got, err := browseURL(tt.listen, tt.tls)
+		if err != nil || got != tt.want {
+			t.Errorf("browseURL(%q, %v) = %q, %v; want %q", tt.listen, tt.tls, got, err, tt.want)
+		}
+	}
+	if _, err := browseURL("9876", false); err == nil {
+		t.Error("expected error for address without host:port form")
+	}
+}
+
+func TestBrowserCommand(t *testing.T) {
+	const url = "http://127.0.0.1:9876"
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+	tests := []struct {
+		name       string
+		browser    string
+		envBrowser string
+		goos       string
+		vars       map[string]string
+		want       string
+	}{
+		{"flag wins", "firefox --new-window", "chromium", "linux", nil, "firefox --new-window " + url},
+		{"placeholder", "open -a Safari %s", "", "darwin", nil, "open -a Safari " + url},
+		{"first BROWSER found", "", "tfviz-missing-browser:sh %s", "linux", nil, "sh " + url},
+		{"darwin", "", "", "darwin", nil, "open " + url},
+		{"linux desktop", "", "", "linux", map[string]string{"DISPLAY": ":0"}, "xdg-open " + url},
+		{"wsl", "", "", "linux", map[string]string{"WSL_DISTRO_NAME": "Ubuntu"}, ""},
+	}
+	for _, tt := range tests {
+		args, err := browserCommand(url, tt.browser, tt.envBrowser, tt.goos, env(tt.vars))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.want != "" && strings.Join(args, " ") != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, strings.Join(args, " "), tt.want)
+		}
+	}
+
+	if _, err := browserCommand(url, "", "", "linux", env(nil)); err == nil {
+		t.Error("expected an error without a display")
+	}
+	if _, err := browserCommand(url, "", "tfviz-missing-browser", "linux", env(map[string]string{"DISPLAY": ":0"})); err == nil {
+		t.Error("expected an error when no $BROWSER command exists")
+	}
+}
+
+func TestIdleTracker(t *testing.T) {
+	tracker := newIdleTracker(20 * time.Millisecond)
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	tracker.ConnState(a, http.StateNew)
+	tracker.ConnState(a, http.StateActive)
+	tracker.ConnState(b, http.StateActive)
+	tracker.ConnState(a, http.StateIdle)
+
+	// One connection is still active, so the tracker must not fire
+	select {
+	case <-tracker.done:
+		t.Fatal("tracker fired while a connection was active")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	tracker.ConnState(b, http.StateClosed)
+	select {
+	case <-tracker.done:
+	case <-time.After(time.Second):
+		t.Fatal("tracker did not fire after all connections went idle")
+	}
+}
+
+func TestShareGate(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("report")) })
+	gate := &shareGate{token: "secret", expires: time.Now().Add(time.Hour), next: ok}
+
+	rec := httptest.NewRecorder()
+	gate.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token: status = %d, want 401", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	gate.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?token=wrong", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token: status = %d, want 401", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	gate.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?token=secret", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("valid token: status = %d, want 200", rec.Code)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != shareCookieName {
+		t.Fatalf("expected share cookie, got %v", cookies)
+	}
+
+	// Reloading without the query parameter works through the cookie
+	req := httptest.NewRequest(http.MethodGet, "/badge.json", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	gate.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("cookie: status = %d, want 200", rec.Code)
+	}
+
+	gate.expires = time.Now().Add(-time.Minute)
+	rec = httptest.NewRecorder()
+	gate.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?token=secret", nil))
+	if rec.Code != http.StatusGone {
+		t.Errorf("expired: status = %d, want 410", rec.Code)
+	}
+}
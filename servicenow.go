@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// serviceNowConfig sets up --servicenow, which opens a change request for
+// the plan, or updates the open one from an earlier run, for teams whose
+// applies need an ITSM ticket.
+type serviceNowConfig struct {
+	// Instance is the base URL, e.g. https://example.service-now.com.
+	Instance string `json:"instance"`
+	// UserEnv and PasswordEnv name the environment variables holding the
+	// credentials for basic authentication; TokenEnv one holding an OAuth
+	// token, which is used instead when set.
+	UserEnv     string `json:"user_env,omitempty"`
+	PasswordEnv string `json:"password_env,omitempty"`
+	TokenEnv    string `json:"token_env,omitempty"`
+	// CorrelationID identifies the change request across runs. It defaults
+	// to the repository, directory and branch of the plan.
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Fields are set on every change request, e.g. assignment_group.
+	Fields map[string]string `json:"fields,omitempty"`
+	// Risk overrides the change risk for an impact level, see
+	// defaultChangeRisk.
+	Risk map[string]string `json:"risk,omitempty"`
+}
+
+// defaultChangeRisk maps the plan's risk level to the risk choices of the
+// change_request table: 2 is High, 3 Moderate and 4 Low. 1, Very High, is
+// left for projects to map to with the risk setting.
+var defaultChangeRisk = map[string]string{"High": "2", "Medium": "3", "Low": "4"}
+
+// changeImpact maps the risk level to the impact choices: 1 is High, 2
+// Medium and 3 Low.
+var changeImpact = map[string]string{"High": "1", "Medium": "2", "Low": "3"}
+
+func validateServiceNowConfig(c *serviceNowConfig) error {
+	if c == nil {
+		return nil
+	}
+	u, err := url.Parse(c.Instance)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("servicenow: instance must be a URL such as https://example.service-now.com")
+	}
+	for _, level := range sortedKeys(c.Risk) {
+		if _, ok := defaultChangeRisk[level]; !ok {
+			return fmt.Errorf("servicenow: unknown impact level %q in risk (use High, Medium or Low)", level)
+		}
+	}
+	return nil
+}
+
+// riskLevel rates the plan by the highest impact of its changes and the
+// highest severity of its findings.
+func riskLevel(analyzed AnalyzedPlan) string {
+	level := highestImpact(analyzed)
+	for _, f := range analyzed.Findings {
+		if impactRank[f.Severity] > impactRank[level] {
+			level = f.Severity
+		}
+	}
+	if level == "" {
+		level = "Low"
+	}
+	return level
+}
+
+// planLocation names the configuration the plan is for, e.g.
+// "github.com/acme/infra/network@main".
+func planLocation(analyzed AnalyzedPlan) string {
+	g := analyzed.Git
+	if g == nil {
+		wd, _ := os.Getwd()
+		return filepath.Base(wd)
+	}
+	location := g.Repository
+	if g.Path != "" && g.Path != "." {
+		location += "/" + g.Path
+	}
+	if g.Branch != "" {
+		location += "@" + g.Branch
+	}
+	return location
+}
+
+// changeRequestFields describes the plan in change_request fields.
+func changeRequestFields(cfg serviceNowConfig, analyzed AnalyzedPlan, reportURL string) map[string]string {
+	level := riskLevel(analyzed)
+	risk := defaultChangeRisk[level]
+	if r, ok := cfg.Risk[level]; ok {
+		risk = r
+	}
+	location := planLocation(analyzed)
+	actions := analyzed.Summary.Actions
+	counts := fmt.Sprintf("%d to create, %d to update, %d to destroy", actions["create"], actions["update"], actions["delete"])
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Terraform plan for %s: %s.\n", location, counts)
+	fmt.Fprintf(&sb, "Risk: %s (highest impact of the changes and severity of the findings).\n", level)
+	if g := analyzed.Git; g != nil && g.Commit != "" {
+		fmt.Fprintf(&sb, "Commit: %s\n", g.Commit)
+	}
+	if len(analyzed.Findings) > 0 {
+		fmt.Fprintf(&sb, "\nFindings:\n")
+		for _, f := range analyzed.Findings {
+			fmt.Fprintf(&sb, "- [%s] %s (%s)\n", f.Severity, f.Title, f.Address)
+		}
+	}
+	if reportURL != "" {
+		fmt.Fprintf(&sb, "\nReport: %s\n", reportURL)
+	}
+
+	correlationID := cfg.CorrelationID
+	if correlationID == "" {
+		correlationID = "tfviz:" + location
+	}
+	if len(correlationID) > 100 {
+		correlationID = correlationID[:100]
+	}
+
+	fields := map[string]string{}
+	for k, v := range cfg.Fields {
+		fields[k] = v
+	}
+	fields["short_description"] = fmt.Sprintf("Terraform: %s (%s)", counts, location)
+	fields["description"] = sb.String()
+	fields["risk"] = risk
+	fields["impact"] = changeImpact[level]
+	fields["correlation_id"] = correlationID
+	fields["correlation_display"] = "tfviz"
+	return fields
+}
+
+// changeRequest is the change request a plan was published to.
+type changeRequest struct {
+	SysID  string `json:"sys_id"`
+	Number string `json:"number"`
+}
+
+// publishChangeRequest updates the active change request with the plan's
+// correlation ID, or opens one when there is none, and reports whether it
+// was created.
+func publishChangeRequest(ctx context.Context, client *http.Client, cfg serviceNowConfig, fields map[string]string) (changeRequest, bool, error) {
+	table := strings.TrimSuffix(cfg.Instance, "/") + "/api/now/table/change_request"
+	query := url.Values{
+		"sysparm_query":  {"correlation_id=" + fields["correlation_id"] + "^active=true"},
+		"sysparm_fields": {"sys_id,number"},
+		"sysparm_limit":  {"1"},
+	}
+	var existing []changeRequest
+	if err := serviceNowRequest(ctx, client, cfg, http.MethodGet, table+"?"+query.Encode(), nil, &existing); err != nil {
+		return changeRequest{}, false, err
+	}
+
+	var cr changeRequest
+	if len(existing) > 0 {
+		err := serviceNowRequest(ctx, client, cfg, http.MethodPatch, table+"/"+url.PathEscape(existing[0].SysID), fields, &cr)
+		return cr, false, err
+	}
+	err := serviceNowRequest(ctx, client, cfg, http.MethodPost, table, fields, &cr)
+	return cr, true, err
+}
+
+// serviceNowRequest calls the Table API and decodes the result member of
+// its response into result.
+func serviceNowRequest(ctx context.Context, client *http.Client, cfg serviceNowConfig, method, endpoint string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if err := setServiceNowAuth(req, cfg); err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("%s %s: %s", method, cfg.Instance, resp.Status)
+	}
+	out := struct {
+		Result interface{} `json:"result"`
+	}{result}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return fmt.Errorf("error parsing response from %s: %v", cfg.Instance, err)
+	}
+	return nil
+}
+
+func setServiceNowAuth(req *http.Request, cfg serviceNowConfig) error {
+	if cfg.TokenEnv != "" {
+		token := os.Getenv(cfg.TokenEnv)
+		if token == "" {
+			return fmt.Errorf("%s is not set", cfg.TokenEnv)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+	userEnv, passwordEnv := cfg.UserEnv, cfg.PasswordEnv
+	if userEnv == "" {
+		userEnv = "SERVICENOW_USER"
+	}
+	if passwordEnv == "" {
+		passwordEnv = "SERVICENOW_PASSWORD"
+	}
+	user, password := os.Getenv(userEnv), os.Getenv(passwordEnv)
+	if user == "" || password == "" {
+		return fmt.Errorf("%s and %s must be set", userEnv, passwordEnv)
+	}
+	req.SetBasicAuth(user, password)
+	return nil
+}
+
+// openChangeRequest publishes the plan for --servicenow.
+func openChangeRequest(ctx context.Context, cfg *serviceNowConfig, analyzed AnalyzedPlan, reportURL string) error {
+	if cfg == nil {
+		return fmt.Errorf("--servicenow needs a servicenow section in the config file")
+	}
+	fields := changeRequestFields(*cfg, analyzed, reportURL)
+	cr, created, err := publishChangeRequest(ctx, &http.Client{Timeout: time.Minute}, *cfg, fields)
+	if err != nil {
+		return fmt.Errorf("error publishing the change request: %v", err)
+	}
+	verb := "Updated"
+	if created {
+		verb = "Opened"
+	}
+	fmt.Printf("🎫 %s change request %s (risk %s)\n", verb, cr.Number, riskLevel(analyzed))
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChangeRequestFields(t *testing.T) {
+	analyzed := AnalyzedPlan{
+		Summary: PlanSummary{Actions: map[string]int{"create": 2, "update": 1}},
+		Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{{Address: "aws_instance.web", Action: "update", Impact: "Medium"}}}},
+		Git:     &GitContext{Repository: "github.com/acme/infra", Path: "network", Branch: "main", Commit: "abc123"},
+	}
+	cfg := serviceNowConfig{Fields: map[string]string{"assignment_group": "platform"}}
+
+	fields := changeRequestFields(cfg, analyzed, "https://ci.example.com/report.html")
+	if fields["risk"] != "3" || fields["impact"] != "2" {
+		t.Errorf("risk, impact = %s, %s; want 3 (Moderate) and 2 (Medium)", fields["risk"], fields["impact"])
+	}
+	if fields["correlation_id"] != "tfviz:github.com/acme/infra/network@main" || fields["assignment_group"] != "platform" {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+	if !strings.Contains(fields["description"], "Report: https://ci.example.com/report.html") {
+		t.Errorf("description does not link the report:\n%s", fields["description"])
+	}
+
+	analyzed.Findings = []Finding{{Severity: "High", Address: "aws_iam_role.ci", Title: "Privilege escalation"}}
+	cfg.Risk = map[string]string{"High": "1"}
+	fields = changeRequestFields(cfg, analyzed, "")
+	if fields["risk"] != "1" || !strings.Contains(fields["description"], "- [High] Privilege escalation (aws_iam_role.ci)") {
+		t.Errorf("expected the finding to raise the risk to the configured Very High, got %v", fields)
+	}
+}
+
+func TestPublishChangeRequest(t *testing.T) {
+	for _, existing := range []bool{false, true} {
+		var methods []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			methods = append(methods, r.Method+" "+r.URL.Path)
+			if user, password, _ := r.BasicAuth(); user != "tfviz" || password != "secret" {
+				t.Errorf("credentials = %s, %s", user, password)
+			}
+			switch r.Method {
+			case http.MethodGet:
+				if q := r.URL.Query().Get("sysparm_query"); q != "correlation_id=tfviz:infra^active=true" {
+					t.Errorf("sysparm_query = %q", q)
+				}
+				if existing {
+					io.WriteString(w, `{"result": [{"sys_id": "abc", "number": "CHG0001"}]}`)
+				} else {
+					io.WriteString(w, `{"result": []}`)
+				}
+			default:
+				var fields map[string]string
+				json.NewDecoder(r.Body).Decode(&fields)
+				if fields["risk"] != "2" {
+					t.Errorf("risk = %q", fields["risk"])
+				}
+				w.WriteHeader(http.StatusCreated)
+				io.WriteString(w, `{"result": {"sys_id": "abc", "number": "CHG0001"}}`)
+			}
+		}))
+		t.Setenv("SERVICENOW_USER", "tfviz")
+		t.Setenv("SERVICENOW_PASSWORD", "secret")
+
+		cfg := serviceNowConfig{Instance: srv.URL}
+		cr, created, err := publishChangeRequest(context.Background(), srv.Client(), cfg, map[string]string{"correlation_id": "tfviz:infra", "risk": "2"})
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cr.Number != "CHG0001" || created == existing {
+			t.Errorf("got %+v, created %v", cr, created)
+		}
+		want := "POST /api/now/table/change_request"
+		if existing {
+			want = "PATCH /api/now/table/change_request/abc"
+		}
+		if len(methods) != 2 || methods[1] != want {
+			t.Errorf("requests = %v, want a lookup and %s", methods, want)
+		}
+	}
+}
+
+func TestValidateServiceNowConfig(t *testing.T) {
+	if err := validateServiceNowConfig(&serviceNowConfig{Instance: "example.service-now.com"}); err == nil {
+		t.Error("expected an error for an instance without a scheme")
+	}
+	if err := validateServiceNowConfig(&serviceNowConfig{Instance: "https://x.service-now.com", Risk: map[string]string{"Critical": "1"}}); err == nil {
+		t.Error("expected an error for an unknown impact level")
+	}
+}
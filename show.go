@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// planTab is one plan of a multi-plan report.
+type planTab struct {
+	Name    string
+	File    string
+	Summary PlanSummary
+	Badge   shieldsBadge
+	Impact  string
+	// Report is the plan's own report, shown in an iframe so its scripts
+	// and element IDs do not clash with those of the other plans.
+	Report string
+}
+
+// TabID is the element ID of the tab's panel.
+func (t planTab) TabID() string {
+	return hashID("plan-", t.File)
+}
+
+func handleShow(ctx context.Context, args []string) error {
+	opts, files, err := parseOptions(args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("usage: tfviz show [options] <plan.json>...")
+	}
+	if stdin := slices.Index(files, "-"); stdin >= 0 && slices.Index(files[stdin+1:], "-") >= 0 {
+		return fmt.Errorf("stdin (-) can only be read once")
+	}
+	if len(files) == 1 {
+		return generateHTMLFromJSON(ctx, files[0], opts)
+	}
+	return presentPlans(ctx, files, opts)
+}
+
+// readPlanFile reads plan JSON from a file, or from stdin when path is "-"
+// so pipelines can run terraform show -json tfplan | tfviz show -.
+func readPlanFile(path string) ([]byte, error) {
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("error reading plan from stdin: %w", err)
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan file: %w", err)
+	}
+	return data, nil
+}
+
+// presentPlans serves one report with a tab per plan file, for stacks split
+// into layers such as network, data and app, and a summary across them.
+func presentPlans(ctx context.Context, files []string, opts cliOptions) error {
+	var data [][]byte
+	var tabNames []string
+	names := map[string]bool{}
+	for _, file := range files {
+		d, err := readPlanFile(file)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		if file == "-" {
+			name = "stdin"
+		}
+		if names[name] {
+			name = file
+		}
+		names[name] = true
+		data = append(data, d)
+		tabNames = append(tabNames, name)
+	}
+	return presentPlanSet(ctx, tabNames, files, data, false, opts)
+}
+
+// presentPlanSet analyzes the JSON of several plans and serves them as tabs
+// of one report. With compare, the plans are of the same configuration, such
+// as its workspaces, and the report shows where they differ.
+func presentPlanSet(ctx context.Context, tabNames, files []string, data [][]byte, compare bool, opts cliOptions) error {
+	cfg, err := loadConfig(opts.configFile, opts.profile)
+	if err != nil {
+		return err
+	}
+	opts.serve.tunnels = cfg.Tunnels
+	sortBy := opts.sort
+	if sortBy == "" {
+		sortBy = cfg.Sort
+	}
+
+	accepted, err := readBaseline(opts.baselineFile)
+	if err != nil {
+		return err
+	}
+
+	registry := newRegistryClient()
+	var plans []TerraformPlan
+	var analyses []*AnalyzedPlan
+	for i, file := range files {
+		fmt.Printf("📊 Analyzing %s...\n", file)
+		plan, err := parsePlanJSON(data[i])
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		analyzed := analyzePlanWith(plan, opts.analysis)
+		if err := opts.graph.validate(analyzed); err != nil {
+			fmt.Printf("⚠️  %s: %v\n", file, err)
+		}
+		if err := applyConfig(&analyzed, cfg, time.Now()); err != nil {
+			fmt.Printf("⚠️  %s: %v\n", file, err)
+		}
+		applyBaseline(&analyzed, accepted)
+		if opts.checkUpdates {
+			checkUpdates(ctx, registry, &analyzed, cfg)
+		}
+		if opts.summarize {
+			addNarrative(ctx, &analyzed, cfg.Summary)
+		}
+
+		plans = append(plans, plan)
+		analyses = append(analyses, &analyzed)
+	}
+	linkStackOutputs(tabNames, analyses)
+
+	var tabs []planTab
+	var combined AnalyzedPlan
+	combined.Summary.Actions = map[string]int{}
+	for i, analyzed := range analyses {
+		tabs = append(tabs, planTab{
+			Name:    tabNames[i],
+			File:    files[i],
+			Summary: analyzed.Summary,
+			Badge:   buildBadge(*analyzed),
+			Impact:  highestImpact(*analyzed),
+			Report:  renderPlan(plans[i], *analyzed, opts.showGraph, opts.graph, sortBy)(reportView{}),
+		})
+
+		combined.Summary.TotalResources += analyzed.Summary.TotalResources
+		for action, n := range analyzed.Summary.Actions {
+			combined.Summary.Actions[action] += n
+		}
+		combined.Modules = append(combined.Modules, analyzed.Modules...)
+	}
+
+	badge, err := json.Marshal(buildBadge(combined))
+	if err != nil {
+		return err
+	}
+	if opts.badgeFile != "" {
+		if err := writeChecksummed(opts.badgeFile, badge, 0644, opts.signKey); err != nil {
+			return fmt.Errorf("error writing badge file: %v", err)
+		}
+		fmt.Printf("🏷️  Badge written to %s\n", opts.badgeFile)
+	}
+
+	var matrix *PlanMatrix
+	if compare {
+		matrix = buildPlanMatrix(tabNames, analyses)
+	}
+	html, err := generateMultiPlanHTML(tabs, combined.Summary, highestImpact(combined), matrix)
+	if err != nil {
+		return err
+	}
+	return serveHTMLOnce(ctx, func(reportView) string { return html }, opts.serve,
+		route{"/badge.json", jsonHandler(badge)})
+}
+
+func generateMultiPlanHTML(tabs []planTab, total PlanSummary, impact string, matrix *PlanMatrix) (string, error) {
+	data := struct {
+		Plans     []planTab
+		Total     PlanSummary
+		Impact    string
+		Matrix    *PlanMatrix
+		Timestamp string
+	}{tabs, total, impact, matrix, time.Now().Format("2006-01-02 15:04:05")}
+
+	htmlTemplate := `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1" />
+  <title>Terraform Plans</title>
+  <style>
+` + reportStyles + `    .plan-summary { width: 100%; border-collapse: collapse; margin-bottom: 24px; }
+    .plan-summary th, .plan-summary td { text-align: left; padding: 6px 10px; border-bottom: 1px solid var(--border-color); }
+    .plan-summary tfoot td { font-weight: bold; }
+    .plan-tab[aria-selected="true"] { background-color: var(--accent-color); color: white; }
+    .plan-frame { width: 100%; height: 85vh; border: 1px solid var(--border-color); border-radius: 6px; }
+    .plan-matrix tr.action-differs td { font-weight: bold; }
+  </style>
+</head>
+<body>
+  <div class="container">
+    <div class="header">
+      <h1>Terraform Plans</h1>
+      <div class="subtitle">{{len .Plans}} plans · {{.Timestamp}}</div>
+    </div>
+    <table class="plan-summary">
+      <thead><tr><th>Plan</th><th>Resources</th><th>Create</th><th>Update</th><th>Delete</th><th>Highest impact</th></tr></thead>
+      <tbody>
+        {{range .Plans}}
+        <tr><td>{{.Name}}</td><td>{{.Summary.TotalResources}}</td><td>{{index .Summary.Actions "create"}}</td><td>{{index .Summary.Actions "update"}}</td><td>{{index .Summary.Actions "delete"}}</td><td>{{with .Impact}}{{.}}{{else}}-{{end}}</td></tr>
+        {{end}}
+      </tbody>
+      <tfoot>
+        <tr><td>All plans</td><td>{{.Total.TotalResources}}</td><td>{{index .Total.Actions "create"}}</td><td>{{index .Total.Actions "update"}}</td><td>{{index .Total.Actions "delete"}}</td><td>{{with .Impact}}{{.}}{{else}}-{{end}}</td></tr>
+      </tfoot>
+    </table>
+    {{with .Matrix}}
+    <details class="module-inventory plan-matrix"{{if .Differences}} open{{end}}>
+      <summary>Differences between workspaces ({{.Differences}} of {{len .Rows}} changed resources)</summary>
+      <table>
+        <tr><th>Resource</th>{{range .Plans}}<th>{{.}}</th>{{end}}</tr>
+        {{range .Rows}}
+        <tr{{if .Differs}} class="action-differs"{{end}}><td><code>{{.Address}}</code></td>{{range .Actions}}<td>{{with .}}{{.}}{{else}}-{{end}}</td>{{end}}</tr>
+        {{end}}
+      </table>
+    </details>
+    {{end}}
+    <div class="layout-controls" role="tablist" aria-label="Plans">
+      {{range $i, $p := .Plans}}
+      <button type="button" role="tab" class="ctrl-btn plan-tab" id="{{.TabID}}-tab" aria-controls="{{.TabID}}" aria-selected="{{if eq $i 0}}true{{else}}false{{end}}" onclick="selectPlan(this)">{{.Name}} <span class="module-count">({{.Badge.Message}})</span></button>
+      {{end}}
+    </div>
+    {{range $i, $p := .Plans}}
+    <div role="tabpanel" id="{{.TabID}}" aria-labelledby="{{.TabID}}-tab"{{if $i}} hidden{{end}}>
+      <iframe class="plan-frame" title="Report for {{.Name}}" srcdoc="{{.Report}}"></iframe>
+    </div>
+    {{end}}
+  </div>
+  <script>
+    function selectPlan(tab) {
+      document.querySelectorAll('.plan-tab').forEach(function(t) {
+        var selected = t === tab;
+        t.setAttribute('aria-selected', selected ? 'true' : 'false');
+        document.getElementById(t.getAttribute('aria-controls')).hidden = !selected;
+      });
+    }
+  </script>
+</body>
+</html>`
+
+	tmpl, err := template.New("plans").Parse(htmlTemplate)
+	if err != nil {
+		return "", fmt.Errorf("error parsing HTML template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error executing template: %v", err)
+	}
+	return buf.String(), nil
+}
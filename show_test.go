@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGenerateMultiPlanHTML(t *testing.T) {
+	tabs := []planTab{
+		{Name: "network", File: "plans/network.json", Summary: PlanSummary{TotalResources: 2, Actions: map[string]int{"update": 2}}, Impact: "Medium", Report: `<p class="x">network report</p>`},
+		{Name: "app", File: "plans/app.json", Summary: PlanSummary{TotalResources: 1, Actions: map[string]int{"delete": 1}}, Impact: "High", Report: "app report"},
+	}
+	total := PlanSummary{TotalResources: 3, Actions: map[string]int{"update": 2, "delete": 1}}
+	html, err := generateMultiPlanHTML(tabs, total, "High", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<tr><td>All plans</td><td>3</td><td>0</td><td>2</td><td>1</td><td>High</td></tr>`,
+		`srcdoc="&lt;p class=&#34;x&#34;&gt;network report&lt;/p&gt;"`,
+		`aria-controls="` + tabs[1].TabID() + `" aria-selected="false"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report does not contain %q", want)
+		}
+	}
+	if !strings.Contains(html, `id="`+tabs[1].TabID()+`" aria-labelledby="`+tabs[1].TabID()+`-tab" hidden>`) {
+		t.Error("only the first plan should be visible")
+	}
+}
+
+func TestHandleShow_NoFiles(t *testing.T) {
+	if err := handleShow(t.Context(), []string{"--graph"}); err == nil || !strings.Contains(err.Error(), "usage") {
+		t.Errorf("got %v, want a usage error", err)
+	}
+}
+
+func TestReadPlanFile_Stdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+	w.WriteString(`{"format_version": "1.2"}`)
+	w.Close()
+
+	data, err := readPlanFile("-")
+	if err != nil || string(data) != `{"format_version": "1.2"}` {
+		t.Errorf("readPlanFile(-) = %q, %v", data, err)
+	}
+
+	if err := handleShow(t.Context(), []string{"-", "-"}); err == nil || !strings.Contains(err.Error(), "only be read once") {
+		t.Errorf("got %v, want an error for reading stdin twice", err)
+	}
+}
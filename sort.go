@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// resourceSorts are the orders the resource list can be sorted in. The
+// analyzer's own order, address with no-ops last, is the default.
+var resourceSorts = []string{"address", "impact", "action", "type"}
+
+// actionRank orders actions from the most to the least destructive;
+// replacements, which the analyzer reports as updates, come right after
+// deletes.
+var actionRank = map[string]int{"delete": 0, "replace": 1, "update": 2, "create": 3}
+
+func validateSort(by string) error {
+	if by != "" && !slices.Contains(resourceSorts, by) {
+		return fmt.Errorf("unknown sort %q (use %s)", by, strings.Join(resourceSorts, ", "))
+	}
+	return nil
+}
+
+// SortKey returns a key that sorts the resource into the given order when
+// compared as a string. The same keys order the list on the server and in
+// the page, so both agree. Fields are separated by spaces, which sort
+// before any character of a type or address.
+func (r ResourceAnalysis) SortKey(by string) string {
+	unchanged := 0
+	if r.Action == "no-op" {
+		unchanged = 1
+	}
+	switch by {
+	case "impact":
+		return fmt.Sprintf("%d %d %s", unchanged, 9-impactRank[r.Impact], r.Address)
+	case "action":
+		action := r.Action
+		if r.Replace {
+			action = "replace"
+		}
+		rank, ok := actionRank[action]
+		if !ok {
+			rank = len(actionRank)
+		}
+		return fmt.Sprintf("%d %s", rank, r.Address)
+	case "type":
+		return fmt.Sprintf("%s %s", r.Type, r.Address)
+	default:
+		return fmt.Sprintf("%d %s", unchanged, r.Address)
+	}
+}
+
+// SortKey orders modules with changes first. For impact and action the
+// module's most significant resource decides, so the riskiest module comes
+// first; otherwise modules keep their address order.
+func (m ModuleAnalysis) SortKey(by string) string {
+	if by == "impact" || by == "action" {
+		key := ""
+		for i, r := range m.Resources {
+			if k := r.SortKey(by); i == 0 || k < key {
+				key = k
+			}
+		}
+		return key
+	}
+	if hasChanges(m) {
+		return "0 " + m.Address
+	}
+	return "1 " + m.Address
+}
+
+// sortResources returns a copy of modules with the modules and their
+// resources in the given order.
+func sortResources(modules []ModuleAnalysis, by string) []ModuleAnalysis {
+	if by == "" {
+		return modules
+	}
+	sorted := make([]ModuleAnalysis, len(modules))
+	for i, m := range modules {
+		m.Resources = slices.Clone(m.Resources)
+		slices.SortStableFunc(m.Resources, func(a, b ResourceAnalysis) int {
+			return strings.Compare(a.SortKey(by), b.SortKey(by))
+		})
+		sorted[i] = m
+	}
+	slices.SortStableFunc(sorted, func(a, b ModuleAnalysis) int {
+		return strings.Compare(a.SortKey(by), b.SortKey(by))
+	})
+	return sorted
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSortResources(t *testing.T) {
+	modules := []ModuleAnalysis{
+		{Address: "module.app", Resources: []ResourceAnalysis{
+			{Address: "module.app.aws_instance.web", Type: "aws_instance", Action: "update", Impact: "Medium"},
+			{Address: "module.app.aws_s3_bucket.logs", Type: "aws_s3_bucket", Action: "create", Impact: "Low"},
+		}},
+		{Address: "root", Resources: []ResourceAnalysis{
+			{Address: "aws_db_instance.main", Type: "aws_db_instance", Action: "update", Replace: true, Impact: "High"},
+			{Address: "aws_iam_role.ci", Type: "aws_iam_role", Action: "no-op", Impact: "Low"},
+			{Address: "aws_s3_bucket_policy.logs", Type: "aws_s3_bucket_policy", Action: "delete", Impact: "Medium"},
+		}},
+	}
+	order := func(modules []ModuleAnalysis) string {
+		var addrs []string
+		for _, m := range modules {
+			for _, r := range m.Resources {
+				addrs = append(addrs, r.Address)
+			}
+		}
+		return strings.Join(addrs, ",")
+	}
+
+	tests := map[string]string{
+		"impact": "aws_db_instance.main,aws_s3_bucket_policy.logs,aws_iam_role.ci,module.app.aws_instance.web,module.app.aws_s3_bucket.logs",
+		"action": "aws_s3_bucket_policy.logs,aws_db_instance.main,aws_iam_role.ci,module.app.aws_instance.web,module.app.aws_s3_bucket.logs",
+		"type":   "module.app.aws_instance.web,module.app.aws_s3_bucket.logs,aws_db_instance.main,aws_iam_role.ci,aws_s3_bucket_policy.logs",
+	}
+	for by, want := range tests {
+		if got := order(sortResources(modules, by)); got != want {
+			t.Errorf("sortResources(%s) = %s, want %s", by, got, want)
+		}
+	}
+	if got := order(modules); !strings.HasPrefix(got, "module.app.aws_instance.web,") {
+		t.Errorf("sortResources changed its input: %s", got)
+	}
+
+	bucket := ResourceAnalysis{Address: "a", Type: "aws_s3_bucket"}
+	policy := ResourceAnalysis{Address: "a", Type: "aws_s3_bucket_policy"}
+	if bucket.SortKey("type") >= policy.SortKey("type") {
+		t.Error("aws_s3_bucket does not sort before aws_s3_bucket_policy")
+	}
+}
+
+func TestParseOptions_Sort(t *testing.T) {
+	opts, _, err := parseOptions([]string{"--sort=impact"})
+	if err != nil || opts.sort != "impact" {
+		t.Errorf("got %q, %v; want impact", opts.sort, err)
+	}
+	if _, _, err := parseOptions([]string{"--sort", "size"}); err == nil {
+		t.Error("expected an error for an unknown sort")
+	}
+}
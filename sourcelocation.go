@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SourceLocation is where a resource is declared.
+type SourceLocation struct {
+	// File is relative to the Terraform directory, e.g. modules/network/main.tf.
+	File string `json:"file"`
+	Line int    `json:"line"`
+	// ModuleSource is the source of the module call that declares the
+	// resource, for resources in modules.
+	ModuleSource string `json:"module_source,omitempty"`
+	// URL opens the file at the plan's commit on GitHub or GitLab.
+	URL string `json:"url,omitempty"`
+}
+
+func (l SourceLocation) String() string {
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+var (
+	modulePrefixRe = regexp.MustCompile(`^((?:module\.[^.\[]+(?:\[[^\]]*\])?\.)*)`)
+	blockHeaderRe  = regexp.MustCompile(`^\s*(resource|data)\s+"([^"]+)"\s+"([^"]+)"`)
+)
+
+// moduleKey turns the module part of a resource address into the key used
+// in .terraform/modules/modules.json: module.app[0].module.net.x.y → app.net.
+func moduleKey(address string) string {
+	prefix := strings.TrimSuffix(modulePrefixRe.FindString(address), ".")
+	if prefix == "" {
+		return ""
+	}
+	var calls []string
+	for _, part := range strings.Split(stripIndex(prefix), ".module.") {
+		calls = append(calls, strings.TrimPrefix(part, "module."))
+	}
+	return strings.Join(calls, ".")
+}
+
+// moduleDirectories maps module keys to their directories relative to dir,
+// from the manifest terraform init writes, falling back to the local paths
+// of module calls in the configuration.
+func moduleDirectories(dir string, config PlanConfiguration) (dirs, sources map[string]string) {
+	dirs = map[string]string{"": "."}
+	sources = map[string]string{}
+	var walk func(mod ConfigModule, key, modDir string)
+	walk = func(mod ConfigModule, key, modDir string) {
+		for _, name := range sortedKeys(mod.ModuleCalls) {
+			call := mod.ModuleCalls[name]
+			child := name
+			if key != "" {
+				child = key + "." + name
+			}
+			sources[child] = call.Source
+			childDir := ""
+			if modDir != "" && (strings.HasPrefix(call.Source, "./") || strings.HasPrefix(call.Source, "../")) {
+				childDir = path.Join(modDir, call.Source)
+				dirs[child] = childDir
+			}
+			walk(call.Module, child, childDir)
+		}
+	}
+	walk(config.RootModule, "", ".")
+
+	var manifest struct {
+		Modules []struct {
+			Key string `json:"Key"`
+			Dir string `json:"Dir"`
+		} `json:"Modules"`
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, ".terraform", "modules", "modules.json")); err == nil && json.Unmarshal(data, &manifest) == nil {
+		for _, m := range manifest.Modules {
+			if m.Dir != "" {
+				dirs[m.Key] = path.Clean(filepath.ToSlash(m.Dir))
+			}
+		}
+	}
+	return dirs, sources
+}
+
+// declarations indexes the resource and data blocks of the .tf files in a
+// module directory by "type.name" or "data.type.name".
+func declarations(dir, modDir string) map[string]SourceLocation {
+	index := map[string]SourceLocation{}
+	files, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(modDir), "*.tf"))
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+		rel := path.Join(modDir, filepath.Base(file))
+		scanner := bufio.NewScanner(f)
+		for line := 1; scanner.Scan(); line++ {
+			m := blockHeaderRe.FindStringSubmatch(scanner.Text())
+			if m == nil {
+				continue
+			}
+			key := m[2] + "." + m[3]
+			if m[1] == "data" {
+				key = "data." + key
+			}
+			if _, ok := index[key]; !ok {
+				index[key] = SourceLocation{File: rel, Line: line}
+			}
+		}
+		f.Close()
+	}
+	return index
+}
+
+// applySourceLocations records where each resource is declared, reading the
+// configuration in dir. Resources whose declaration cannot be found, e.g.
+// in modules that were not initialised, are left without a location.
+func applySourceLocations(analyzed *AnalyzedPlan, config PlanConfiguration, dir string) {
+	dirs, sources := moduleDirectories(dir, config)
+	indexes := map[string]map[string]SourceLocation{}
+	for i := range analyzed.Modules {
+		resources := analyzed.Modules[i].Resources
+		for j := range resources {
+			r := &resources[j]
+			key := moduleKey(r.Address)
+			modDir, ok := dirs[key]
+			if !ok {
+				continue
+			}
+			index, ok := indexes[key]
+			if !ok {
+				index = declarations(dir, modDir)
+				indexes[key] = index
+			}
+			local := stripIndex(strings.TrimPrefix(r.Address, modulePrefixRe.FindString(r.Address)))
+			loc, ok := index[local]
+			if !ok {
+				continue
+			}
+			loc.ModuleSource = sources[key]
+			if !strings.HasPrefix(loc.File, ".terraform/") {
+				loc.URL = sourceURL(analyzed.Git, loc)
+			}
+			r.Source = &loc
+		}
+	}
+}
+
+// sourceURL links to a file at the plan's commit for repositories hosted on
+// GitHub or GitLab. Uncommitted changes may make the link inaccurate, so
+// there is none for a dirty checkout.
+func sourceURL(g *GitContext, loc SourceLocation) string {
+	if g == nil || g.Dirty || g.Commit == "" {
+		return ""
+	}
+	repo := strings.TrimSuffix(g.Repository, ".git")
+	if rest, ok := strings.CutPrefix(repo, "git@"); ok {
+		host, p, _ := strings.Cut(rest, ":")
+		repo = host + "/" + p
+	}
+	for _, scheme := range []string{"https://", "http://", "ssh://"} {
+		repo = strings.TrimPrefix(repo, scheme)
+	}
+	file := path.Join(g.Path, loc.File)
+	switch {
+	case strings.HasPrefix(repo, "github.com/"):
+		return fmt.Sprintf("https://%s/blob/%s/%s#L%d", repo, g.Commit, file, loc.Line)
+	case strings.HasPrefix(repo, "gitlab.com/"):
+		return fmt.Sprintf("https://%s/-/blob/%s/%s#L%d", repo, g.Commit, file, loc.Line)
+	}
+	return ""
+}
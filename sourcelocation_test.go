@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplySourceLocations(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "modules", "net"), 0755)
+	os.WriteFile(filepath.Join(dir, "main.tf"), []byte("module \"net\" {\n  source = \"./modules/net\"\n}\n\nresource \"aws_instance\" \"web\" {\n}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "modules", "net", "subnets.tf"), []byte("# Subnets\nresource \"aws_subnet\" \"a\" {\n  count = 2\n}\n"), 0644)
+
+	plan := TerraformPlan{
+		ResourceChanges: []ResourceChange{
+			{Address: "aws_instance.web", Mode: "managed", Type: "aws_instance", Name: "web", Change: Change{Actions: []string{"create"}}},
+			{Address: "module.net.aws_subnet.a[1]", ModuleAddress: "module.net", Mode: "managed", Type: "aws_subnet", Name: "a", Change: Change{Actions: []string{"create"}}},
+			{Address: "module.vpc.aws_vpc.this", ModuleAddress: "module.vpc", Mode: "managed", Type: "aws_vpc", Name: "this", Change: Change{Actions: []string{"create"}}},
+		},
+		Configuration: PlanConfiguration{RootModule: ConfigModule{ModuleCalls: map[string]ConfigModuleCall{
+			"net": {Source: "./modules/net"},
+			"vpc": {Source: "terraform-aws-modules/vpc/aws"},
+		}}},
+	}
+	git := &GitContext{Repository: "git@github.com:acme/infra.git", Commit: "0123456789abcdef", Path: "stacks/prod"}
+	analyzed := analyzePlanWith(plan, analysisOptions{git: git})
+	applySourceLocations(&analyzed, plan.Configuration, dir)
+
+	locations := map[string]*SourceLocation{}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			locations[r.Address] = r.Source
+		}
+	}
+	if l := locations["aws_instance.web"]; l == nil || l.String() != "main.tf:5" ||
+		l.URL != "https://github.com/acme/infra/blob/0123456789abcdef/stacks/prod/main.tf#L5" {
+		t.Errorf("unexpected location of aws_instance.web: %+v", l)
+	}
+	if l := locations["module.net.aws_subnet.a[1]"]; l == nil || l.String() != "modules/net/subnets.tf:2" || l.ModuleSource != "./modules/net" {
+		t.Errorf("unexpected location of the subnet: %+v", l)
+	}
+	// Registry modules are only found once terraform init has fetched them
+	if l := locations["module.vpc.aws_vpc.this"]; l != nil {
+		t.Errorf("unexpected location of an uninitialised module: %+v", l)
+	}
+}
+
+func TestSourceURL(t *testing.T) {
+	loc := SourceLocation{File: "main.tf", Line: 3}
+	tests := []struct {
+		git  GitContext
+		want string
+	}{
+		{GitContext{Repository: "https://gitlab.com/acme/infra.git", Commit: "abc"}, "https://gitlab.com/acme/infra/-/blob/abc/main.tf#L3"},
+		{GitContext{Repository: "ssh://github.com/acme/infra.git", Commit: "abc"}, "https://github.com/acme/infra/blob/abc/main.tf#L3"},
+		{GitContext{Repository: "https://github.com/acme/infra.git", Commit: "abc", Dirty: true}, ""},
+		{GitContext{Repository: "https://git.example.com/acme/infra.git", Commit: "abc"}, ""},
+	}
+	for _, tt := range tests {
+		if got := sourceURL(&tt.git, loc); got != tt.want {
+			t.Errorf("sourceURL(%+v) = %q, want %q", tt.git, got, tt.want)
+		}
+	}
+	if got := moduleKey("module.app[\"eu\"].module.net.aws_subnet.a[0]"); got != "app.net" {
+		t.Errorf("moduleKey = %q, want app.net", got)
+	}
+}
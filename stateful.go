@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"path"
+)
+
+func matchesAnyPattern(patterns []string, resourceType string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, resourceType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePatterns checks resource type glob patterns from the config
+// setting named field.
+func validatePatterns(field string, patterns []string) error {
+	for i, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("%s[%d]: invalid pattern %q", field, i, pattern)
+		}
+	}
+	return nil
+}
+
+// markStateful flags resources of the configured extra stateful types; the
+// built-in ones are flagged by analyzePlan.
+func markStateful(analyzed *AnalyzedPlan, extra []string) {
+	if len(extra) == 0 {
+		return
+	}
+	for i := range analyzed.Modules {
+		resources := analyzed.Modules[i].Resources
+		for j := range resources {
+			if matchesAnyPattern(extra, resources[j].Type) {
+				resources[j].Stateful = true
+			}
+		}
+	}
+}
+
+// StatefulDestroys lists stateful resources that the plan deletes or
+// replaces. The report asks reviewers to confirm them explicitly.
+func (a AnalyzedPlan) StatefulDestroys() []ResourceAnalysis {
+	var result []ResourceAnalysis
+	for _, m := range a.Modules {
+		for _, r := range m.Resources {
+			if r.Stateful && (r.Action == "delete" || r.Replace) {
+				result = append(result, r)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStatefulDestroys(t *testing.T) {
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{
+		{Address: "aws_db_instance.main", Type: "aws_db_instance", Action: "delete", Stateful: isStateful("aws_db_instance")},
+		{Address: "aws_ebs_volume.data", Type: "aws_ebs_volume", Action: "update", Replace: true, Stateful: isStateful("aws_ebs_volume")},
+		{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Action: "update", Stateful: isStateful("aws_s3_bucket")},
+		{Address: "vault_mount.kv", Type: "vault_mount", Action: "delete"},
+		{Address: "aws_instance.web", Type: "aws_instance", Action: "delete"},
+	}}}}
+	markStateful(&analyzed, []string{"vault_*"})
+
+	var got []string
+	for _, r := range analyzed.StatefulDestroys() {
+		got = append(got, r.Address)
+	}
+	want := "aws_db_instance.main,aws_ebs_volume.data,vault_mount.kv"
+	if strings.Join(got, ",") != want {
+		t.Errorf("StatefulDestroys = %v, want %s", got, want)
+	}
+
+	html := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+	if !strings.Contains(html, "destroys 3 stateful resource(s)") {
+		t.Error("report does not show the stateful confirmation banner")
+	}
+
+	plan := TerraformPlan{ResourceChanges: []ResourceChange{
+		{Address: "aws_db_instance.main", Type: "aws_db_instance", Change: Change{Actions: []string{"create", "delete"}}},
+	}}
+	if destroys := analyzePlan(plan).StatefulDestroys(); len(destroys) != 1 || !destroys[0].Replace {
+		t.Errorf("a create_before_destroy replacement is a stateful destroy, got %+v", destroys)
+	}
+
+	if err := validatePatterns("stateful_types", []string{"aws_["}); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+}
+
+func TestHandleCICompare_FailOnStateful(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	plan := `{"format_version": "1.2", "resource_changes": [{"address": "aws_db_instance.main", "mode": "managed",
+		"type": "aws_db_instance", "name": "main", "change": {"actions": ["delete"], "before": {"engine": "postgres"}, "after": null}}]}`
+	planFile := filepath.Join(dir, "plan.json")
+	os.WriteFile(planFile, []byte(plan), 0644)
+	saved := filepath.Join(dir, "analysis.json")
+
+	// The first run has no previous analysis and fails on the new delete
+	if err := handleCICompare(t.Context(), []string{"--previous", filepath.Join(dir, "missing.json"), "--plan", planFile, "--save", saved}); err == nil {
+		t.Fatal("expected failure for new destructive change")
+	}
+	if info, err := os.Stat(saved); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("saved analysis has mode %v, want it only readable by the user", info.Mode().Perm())
+	}
+	// The delete is no longer new, so only --fail-on-stateful fails the run
+	if err := handleCICompare(t.Context(), []string{"--previous", saved, "--plan", planFile}); err != nil {
+		t.Errorf("unexpected failure without --fail-on-stateful: %v", err)
+	}
+	err := handleCICompare(t.Context(), []string{"--previous", saved, "--plan", planFile, "--fail-on-stateful"})
+	if err == nil || !strings.Contains(err.Error(), "stateful") {
+		t.Errorf("expected stateful failure, got %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// summaryConfig selects the language model --summarize asks for a summary.
+// Without one, a local Ollama server is used so the plan does not leave the
+// machine unless the project opts in to a hosted endpoint.
+type summaryConfig struct {
+	// API is "ollama" or "openai"; the latter covers any OpenAI-compatible
+	// chat completions endpoint.
+	API      string `json:"api,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+	Model    string `json:"model,omitempty"`
+	// APIKeyEnv names the environment variable holding the API key.
+	APIKeyEnv string `json:"api_key_env,omitempty"`
+}
+
+var defaultSummaryConfig = summaryConfig{API: "ollama", Endpoint: "http://localhost:11434", Model: "llama3.1"}
+
+func (c summaryConfig) withDefaults() summaryConfig {
+	if c.API == "" {
+		c.API = defaultSummaryConfig.API
+	}
+	if c.Endpoint == "" {
+		switch c.API {
+		case "openai":
+			c.Endpoint = "https://api.openai.com/v1"
+		default:
+			c.Endpoint = defaultSummaryConfig.Endpoint
+		}
+	}
+	if c.Model == "" && c.API == "ollama" {
+		c.Model = defaultSummaryConfig.Model
+	}
+	if c.APIKeyEnv == "" && c.API == "openai" {
+		c.APIKeyEnv = "OPENAI_API_KEY"
+	}
+	return c
+}
+
+func validateSummaryConfig(c *summaryConfig) error {
+	if c == nil {
+		return nil
+	}
+	if c.API != "" && c.API != "ollama" && c.API != "openai" {
+		return fmt.Errorf("summary: unknown api %q (use ollama or openai)", c.API)
+	}
+	if c.API == "openai" && c.Model == "" {
+		return fmt.Errorf("summary: model is required for the openai api")
+	}
+	return nil
+}
+
+// PlanNarrative is the model's summary of the plan. It is shown as
+// generated text that may be wrong, never as tfviz's own analysis.
+type PlanNarrative struct {
+	Model string `json:"model"`
+	Text  string `json:"text"`
+}
+
+// maxSummaryResources caps the changed resources sent to the model to keep
+// the prompt small for very large plans; the counts still cover everything.
+const maxSummaryResources = 200
+
+type summaryResource struct {
+	Address      string   `json:"address"`
+	Type         string   `json:"type"`
+	Action       string   `json:"action"`
+	Replace      bool     `json:"replace,omitempty"`
+	Impact       string   `json:"impact"`
+	ImpactReason string   `json:"impact_reason,omitempty"`
+	Stateful     bool     `json:"stateful,omitempty"`
+	Changed      []string `json:"changed_attributes,omitempty"`
+}
+
+type summaryFinding struct {
+	Severity string `json:"severity"`
+	Address  string `json:"address"`
+	Title    string `json:"title"`
+}
+
+type summaryInput struct {
+	Actions   map[string]int    `json:"actions"`
+	Resources []summaryResource `json:"resources"`
+	Omitted   int               `json:"omitted_resources,omitempty"`
+	Findings  []summaryFinding  `json:"findings,omitempty"`
+	Outside   bool              `json:"outside_change_window,omitempty"`
+}
+
+// buildSummaryInput describes the plan for the model by addresses, actions,
+// impacts and the names of changed attributes. No attribute values are
+// included, so sensitive values cannot leak into the prompt.
+func buildSummaryInput(analyzed AnalyzedPlan) summaryInput {
+	in := summaryInput{Actions: analyzed.Summary.Actions}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			if r.Action == "no-op" {
+				continue
+			}
+			if len(in.Resources) == maxSummaryResources {
+				in.Omitted++
+				continue
+			}
+			in.Resources = append(in.Resources, summaryResource{
+				Address:      r.Address,
+				Type:         r.Type,
+				Action:       r.Action,
+				Replace:      r.Replace,
+				Impact:       r.Impact,
+				ImpactReason: r.ImpactReason,
+				Stateful:     r.Stateful,
+				Changed:      r.changedAttributes(),
+			})
+		}
+	}
+	for _, f := range analyzed.Findings {
+		in.Findings = append(in.Findings, summaryFinding{Severity: f.Severity, Address: f.Address, Title: f.Title})
+	}
+	in.Outside = analyzed.ChangeWindow != nil && !analyzed.ChangeWindow.Inside
+	return in
+}
+
+const summaryPrompt = `You review Terraform plans for a change approval board. The user message is a JSON description of a plan: resource addresses, actions, impact ratings and the names of changed attributes, without their values. Write a short executive summary (two or three sentences) of what the plan does, followed by a risk narrative: which changes could cause downtime or data loss and what to check before applying. Use plain text, no Markdown. Do not invent changes that are not in the input.`
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// summarizePlan asks the configured model for a summary of the plan.
+func summarizePlan(ctx context.Context, client *http.Client, cfg summaryConfig, analyzed AnalyzedPlan) (*PlanNarrative, error) {
+	cfg = cfg.withDefaults()
+	input, err := json.Marshal(buildSummaryInput(analyzed))
+	if err != nil {
+		return nil, err
+	}
+	messages := []chatMessage{{Role: "system", Content: summaryPrompt}, {Role: "user", Content: string(input)}}
+
+	endpoint := strings.TrimSuffix(cfg.Endpoint, "/")
+	var body interface{}
+	if cfg.API == "openai" {
+		endpoint += "/chat/completions"
+		body = map[string]interface{}{"model": cfg.Model, "messages": messages, "temperature": 0.2}
+	} else {
+		endpoint += "/api/chat"
+		body = map[string]interface{}{"model": cfg.Model, "messages": messages, "stream": false}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.APIKeyEnv != "" {
+		key := os.Getenv(cfg.APIKeyEnv)
+		if key == "" {
+			return nil, fmt.Errorf("%s is not set", cfg.APIKeyEnv)
+		}
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", endpoint, resp.Status)
+	}
+
+	var out struct {
+		Choices []struct {
+			Message chatMessage `json:"message"`
+		} `json:"choices"`
+		Message chatMessage `json:"message"`
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return nil, fmt.Errorf("error parsing response from %s: %v", endpoint, err)
+	}
+	text := out.Message.Content
+	if len(out.Choices) > 0 {
+		text = out.Choices[0].Message.Content
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return nil, fmt.Errorf("%s returned an empty summary", endpoint)
+	}
+	return &PlanNarrative{Model: cfg.Model, Text: text}, nil
+}
+
+// addNarrative fills in the plan's summary for --summarize. The report is
+// still served when the model cannot be reached.
+func addNarrative(ctx context.Context, analyzed *AnalyzedPlan, cfg *summaryConfig) {
+	c := defaultSummaryConfig
+	if cfg != nil {
+		c = *cfg
+	}
+	c = c.withDefaults()
+	fmt.Printf("🤖 Asking %s at %s for a plan summary...\n", c.Model, c.Endpoint)
+	narrative, err := summarizePlan(ctx, &http.Client{Timeout: 2 * time.Minute}, c, *analyzed)
+	if err != nil {
+		fmt.Printf("⚠️  Could not summarize the plan: %v\n", err)
+		return
+	}
+	analyzed.Narrative = narrative
+}
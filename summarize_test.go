@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func summaryTestPlan() AnalyzedPlan {
+	return AnalyzedPlan{
+		Summary: PlanSummary{Actions: map[string]int{"update": 1}},
+		Modules: []ModuleAnalysis{{Address: "root", Resources: []ResourceAnalysis{
+			{Address: "aws_db_instance.main", Type: "aws_db_instance", Action: "update", Impact: "High",
+				Before:  map[string]interface{}{"password": "hunter2"},
+				After:   map[string]interface{}{"password": "correct horse"},
+				Changes: []ChangeDetail{{Field: "password", Before: "hunter2", After: "correct horse", Action: "update"}}},
+			{Address: "aws_iam_role.ci", Type: "aws_iam_role", Action: "no-op", Impact: "Low"},
+		}}},
+	}
+}
+
+func TestSummarizePlan(t *testing.T) {
+	tests := []struct {
+		api, path, response string
+	}{
+		{"ollama", "/api/chat", `{"message": {"role": "assistant", "content": " Rotates the database password. "}}`},
+		{"openai", "/v1/chat/completions", `{"choices": [{"message": {"role": "assistant", "content": "Rotates the database password."}}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.api, func(t *testing.T) {
+			var sent string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					t.Errorf("request to %s, want %s", r.URL.Path, tt.path)
+				}
+				if tt.api == "openai" && r.Header.Get("Authorization") != "Bearer sk-test" {
+					t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+				}
+				body, _ := io.ReadAll(r.Body)
+				sent = string(body)
+				io.WriteString(w, tt.response)
+			}))
+			defer srv.Close()
+			t.Setenv("OPENAI_API_KEY", "sk-test")
+
+			endpoint := srv.URL
+			if tt.api == "openai" {
+				endpoint += "/v1"
+			}
+			cfg := summaryConfig{API: tt.api, Endpoint: endpoint, Model: "test-model"}
+			narrative, err := summarizePlan(context.Background(), srv.Client(), cfg, summaryTestPlan())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if narrative.Text != "Rotates the database password." || narrative.Model != "test-model" {
+				t.Errorf("unexpected narrative %+v", narrative)
+			}
+			if strings.Contains(sent, "hunter2") || strings.Contains(sent, "correct horse") {
+				t.Error("attribute values were sent to the model")
+			}
+			if !strings.Contains(sent, "aws_db_instance.main") || strings.Contains(sent, "aws_iam_role.ci") {
+				t.Error("request does not describe exactly the changed resources")
+			}
+		})
+	}
+}
+
+func TestBuildSummaryInput_ChangedAttributes(t *testing.T) {
+	in := buildSummaryInput(summaryTestPlan())
+	data, _ := json.Marshal(in)
+	if !strings.Contains(string(data), `"changed_attributes":["password"]`) {
+		t.Errorf("changed attribute names missing from %s", data)
+	}
+}
+
+func TestSummarizePlan_MissingKey(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	cfg := summaryConfig{API: "openai", Endpoint: "http://127.0.0.1:0", Model: "m"}
+	if _, err := summarizePlan(context.Background(), http.DefaultClient, cfg, summaryTestPlan()); err == nil || !strings.Contains(err.Error(), "OPENAI_API_KEY") {
+		t.Errorf("got %v, want an error naming OPENAI_API_KEY", err)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxKeyChanges is how many changed attributes a row of the table view
+// names before summarising the rest.
+const maxKeyChanges = 3
+
+// changedAttributes returns the attributes an update changes, those with
+// more than formatting changes first, each group sorted by name. Creates and
+// deletes touch every attribute, so they have none.
+func (r ResourceAnalysis) changedAttributes() []string {
+	if r.Action != "update" {
+		return nil
+	}
+	changes := slices.Clone(r.Changes)
+	slices.SortFunc(changes, func(a, b ChangeDetail) int {
+		if a.Cosmetic != b.Cosmetic {
+			if b.Cosmetic {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Field, b.Field)
+	})
+	fields := make([]string, 0, len(changes))
+	for _, c := range changes {
+		fields = append(fields, c.Field)
+	}
+	return fields
+}
+
+// KeyChanges summarises the changed attributes for the table view, e.g.
+// "instance_type, tags, user_data +2 more".
+func (r ResourceAnalysis) KeyChanges() string {
+	fields := r.changedAttributes()
+	if len(fields) <= maxKeyChanges {
+		return strings.Join(fields, ", ")
+	}
+	return fmt.Sprintf("%s +%d more", strings.Join(fields[:maxKeyChanges], ", "), len(fields)-maxKeyChanges)
+}
+
+// ChangesSortKey orders table rows by how many attributes they change.
+func (r ResourceAnalysis) ChangesSortKey() string {
+	return fmt.Sprintf("%06d %s", len(r.changedAttributes()), r.Address)
+}
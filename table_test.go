@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyChanges(t *testing.T) {
+	r := ResourceAnalysis{Action: "update", Changes: []ChangeDetail{
+		{Field: "tags", Action: "update"},
+		{Field: "policy", Action: "update", Cosmetic: true},
+		{Field: "user_data", Action: "update"},
+		{Field: "instance_type", Action: "update"},
+		{Field: "ami", Action: "remove"},
+	}}
+	if got, want := r.KeyChanges(), "ami, instance_type, tags +2 more"; got != want {
+		t.Errorf("KeyChanges() = %q, want %q", got, want)
+	}
+	r.Changes = r.Changes[:2]
+	if got, want := r.KeyChanges(), "tags, policy"; got != want {
+		t.Errorf("KeyChanges() = %q, want %q", got, want)
+	}
+	r.Action = "create"
+	if got := r.KeyChanges(); got != "" {
+		t.Errorf("KeyChanges() for a create = %q, want empty", got)
+	}
+}
+
+func TestGenerateHTML_TableLayout(t *testing.T) {
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Address: "root", Resources: []ResourceAnalysis{
+		{Address: "aws_instance.web", Type: "aws_instance", Action: "update", Impact: "Medium",
+			Changes: []ChangeDetail{{Field: "instance_type", Action: "update"}}},
+	}}}}
+
+	cards := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{})
+	if !strings.Contains(cards, `id="changeTable" hidden`) {
+		t.Error("table is shown in the card layout")
+	}
+
+	table := generateHTML(analyzed, false, nil, nil, nil, graphOptions{}, reportView{Layout: "table"})
+	for _, want := range []string{
+		`<div class="resource-list" id="resources" tabindex="-1" hidden>`,
+		`<div class="change-table-wrap" id="changeTable">`,
+		`<td><code>instance_type</code></td>`,
+		`<td><span class="rollup-impact Medium">Medium</span></td>`,
+	} {
+		if !strings.Contains(table, want) {
+			t.Errorf("table layout is missing %s", want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// matchesTarget reports whether address is selected by pattern: a glob
+// over the whole address, or a module or resource prefix such as
+// "module.app" or "aws_instance.web" (which selects every instance).
+func matchesTarget(pattern, address string) bool {
+	if ok, _ := path.Match(pattern, address); ok {
+		return true
+	}
+	return strings.HasPrefix(address, pattern+".") || strings.HasPrefix(address, pattern+"[")
+}
+
+// selectTargets returns the changed resources matching any of patterns (all
+// changed resources when there are none) whose action is in actions (any
+// action when empty).
+func selectTargets(analyzed AnalyzedPlan, patterns, actions []string) []string {
+	var targets []string
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			if r.Action == "no-op" {
+				continue
+			}
+			if len(actions) > 0 && !slices.Contains(actions, r.Action) {
+				continue
+			}
+			selected := len(patterns) == 0
+			for _, p := range patterns {
+				if matchesTarget(p, r.Address) {
+					selected = true
+					break
+				}
+			}
+			if selected {
+				targets = append(targets, r.Address)
+			}
+		}
+	}
+	return targets
+}
+
+// targetArgs turns addresses into -target arguments for terraform.
+func targetArgs(addresses []string) []string {
+	args := make([]string, 0, len(addresses))
+	for _, a := range addresses {
+		args = append(args, "-target="+a)
+	}
+	return args
+}
+
+// targetCommand renders a terraform command line that can be pasted into a
+// POSIX shell; addresses with for_each keys contain quotes and brackets.
+func targetCommand(subcommand string, addresses []string) string {
+	parts := []string{"terraform", subcommand}
+	for _, arg := range targetArgs(addresses) {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+func shellQuote(s string) string {
+	if strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.=/:", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// handleTargets prints a terraform apply command limited to the selected
+// changes, or re-plans with those targets and serves the report.
+func handleTargets(ctx context.Context, args []string) error {
+	planFile := ""
+	var patterns, actions []string
+	replan := false
+	rest := []string{}
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		switch name {
+		case "--replan":
+			replan = true
+			continue
+		case "--plan", "--match", "--action":
+		default:
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return fmt.Errorf("flag %s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "--plan":
+			planFile = value
+		case "--match":
+			patterns = append(patterns, value)
+		default:
+			for _, a := range strings.Split(value, ",") {
+				switch a = strings.TrimSpace(a); a {
+				case "create", "update", "delete":
+					actions = append(actions, a)
+				default:
+					return fmt.Errorf("unknown action %q (use create, update or delete)", a)
+				}
+			}
+		}
+	}
+
+	opts, tfArgs, err := parseOptions(rest)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if planFile != "" {
+		data, err = os.ReadFile(planFile)
+		if err != nil {
+			return fmt.Errorf("error reading plan file: %v", err)
+		}
+	} else {
+		data, err = runTerraformPlan(ctx, "", tfArgs)
+		if err != nil {
+			return err
+		}
+	}
+	plan, err := parsePlanJSON(data)
+	if err != nil {
+		return err
+	}
+
+	targets := selectTargets(analyzePlan(plan), patterns, actions)
+	if len(targets) == 0 {
+		return fmt.Errorf("no changed resources match the selection")
+	}
+	fmt.Printf("🎯 %d resources selected\n", len(targets))
+	if !replan {
+		fmt.Println(targetCommand("apply", targets))
+		return nil
+	}
+
+	out, err := runTerraformPlan(ctx, "", append(tfArgs, targetArgs(targets)...))
+	if err != nil {
+		return err
+	}
+	targeted, err := parsePlanJSON(out)
+	if err != nil {
+		return err
+	}
+	return presentPlan(ctx, targeted, opts)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectTargets(t *testing.T) {
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{
+		{Address: "root", Resources: []ResourceAnalysis{
+			{Address: "aws_instance.web[0]", Action: "update"},
+			{Address: "aws_instance.web[1]", Action: "no-op"},
+			{Address: "aws_s3_bucket.logs", Action: "delete"},
+		}},
+		{Address: "module.app", Resources: []ResourceAnalysis{
+			{Address: `module.app.aws_iam_role.this["api"]`, Action: "create"},
+			{Address: "module.app.aws_iam_role_policy.this", Action: "create"},
+		}},
+	}}
+
+	tests := []struct {
+		patterns, actions []string
+		want              []string
+	}{
+		{nil, nil, []string{"aws_instance.web[0]", "aws_s3_bucket.logs", `module.app.aws_iam_role.this["api"]`, "module.app.aws_iam_role_policy.this"}},
+		{[]string{"module.app"}, nil, []string{`module.app.aws_iam_role.this["api"]`, "module.app.aws_iam_role_policy.this"}},
+		{[]string{"module.app.aws_iam_role.this"}, nil, []string{`module.app.aws_iam_role.this["api"]`}},
+		{[]string{"aws_instance.web"}, nil, []string{"aws_instance.web[0]"}},
+		{[]string{"*.aws_iam_*"}, nil, []string{`module.app.aws_iam_role.this["api"]`, "module.app.aws_iam_role_policy.this"}},
+		{nil, []string{"delete", "update"}, []string{"aws_instance.web[0]", "aws_s3_bucket.logs"}},
+		{[]string{"aws_db_instance.main"}, nil, nil},
+	}
+	for _, tt := range tests {
+		got := selectTargets(analyzed, tt.patterns, tt.actions)
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("selectTargets(%v, %v) = %v, want %v", tt.patterns, tt.actions, got, tt.want)
+		}
+	}
+}
+
+func TestTargetCommand(t *testing.T) {
+	got := targetCommand("apply", []string{"aws_instance.web[0]", `module.app.aws_iam_role.this["it's"]`, "aws_s3_bucket.logs"})
+	want := `terraform apply '-target=aws_instance.web[0]' '-target=module.app.aws_iam_role.this["it'\''s"]' -target=aws_s3_bucket.logs`
+	if got != want {
+		t.Errorf("targetCommand =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+)
+
+// TestReport is the outcome of terraform test, read from its -json output.
+type TestReport struct {
+	Files   []*TestFile
+	Summary *TestSummary
+}
+
+type TestSummary struct {
+	Status  string `json:"status"`
+	Passed  int    `json:"passed"`
+	Failed  int    `json:"failed"`
+	Errored int    `json:"errored"`
+	Skipped int    `json:"skipped"`
+}
+
+type TestFile struct {
+	Path   string
+	Status string
+	Runs   []*TestRun
+	// Diagnostics are reported for the file rather than a run, e.g. while
+	// tearing down.
+	Diagnostics []Diagnostic
+}
+
+type TestRun struct {
+	Name        string
+	Status      string
+	Diagnostics []Diagnostic
+	// Changes are the resources the run's plan changes, with -verbose.
+	Changes []ResourceAnalysis
+	// State lists the resources in the state after an apply run.
+	State []string
+}
+
+// testEvent is one line of terraform test -json output. Only the fields
+// tfviz shows are decoded.
+type testEvent struct {
+	Type     string              `json:"type"`
+	TestFile string              `json:"@testfile"`
+	TestRun  string              `json:"@testrun"`
+	Abstract map[string][]string `json:"test_abstract"`
+	File     *struct {
+		Path   string `json:"path"`
+		Status string `json:"status"`
+	} `json:"test_file"`
+	Run *struct {
+		Path   string `json:"path"`
+		Run    string `json:"run"`
+		Status string `json:"status"`
+	} `json:"test_run"`
+	Diagnostic *Diagnostic     `json:"diagnostic"`
+	Plan       json.RawMessage `json:"test_plan"`
+	State      *struct {
+		Values struct {
+			RootModule Module `json:"root_module"`
+		} `json:"values"`
+	} `json:"test_state"`
+	Summary *TestSummary `json:"test_summary"`
+}
+
+// parseTestOutput reads the events of terraform test -json. Lines that are
+// not JSON, such as provider logs, are skipped.
+func parseTestOutput(data []byte) (TestReport, error) {
+	var report TestReport
+	files := map[string]*TestFile{}
+	file := func(path string) *TestFile {
+		f, ok := files[path]
+		if !ok {
+			f = &TestFile{Path: path}
+			files[path] = f
+			report.Files = append(report.Files, f)
+		}
+		return f
+	}
+	run := func(path, name string) *TestRun {
+		f := file(path)
+		for _, r := range f.Runs {
+			if r.Name == name {
+				return r
+			}
+		}
+		r := &TestRun{Name: name}
+		f.Runs = append(f.Runs, r)
+		return r
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64<<20)
+	for scanner.Scan() {
+		var ev testEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil || ev.Type == "" {
+			continue
+		}
+		switch {
+		case ev.Abstract != nil:
+			for _, path := range sortedKeys(ev.Abstract) {
+				for _, name := range ev.Abstract[path] {
+					run(path, name)
+				}
+			}
+		case ev.File != nil:
+			if ev.File.Status != "" {
+				file(ev.File.Path).Status = ev.File.Status
+			}
+		case ev.Run != nil:
+			if ev.Run.Status != "" {
+				run(ev.Run.Path, ev.Run.Run).Status = ev.Run.Status
+			}
+		case ev.Diagnostic != nil && ev.TestFile != "":
+			if ev.TestRun != "" {
+				r := run(ev.TestFile, ev.TestRun)
+				r.Diagnostics = append(r.Diagnostics, *ev.Diagnostic)
+			} else {
+				f := file(ev.TestFile)
+				f.Diagnostics = append(f.Diagnostics, *ev.Diagnostic)
+			}
+		case len(ev.Plan) > 0 && ev.TestRun != "":
+			plan, err := parsePlanJSON(ev.Plan)
+			if err != nil {
+				return report, fmt.Errorf("run %s: %v", ev.TestRun, err)
+			}
+			r := run(ev.TestFile, ev.TestRun)
+			for _, m := range analyzePlan(plan).Modules {
+				for _, res := range m.Resources {
+					if res.Action != "no-op" {
+						r.Changes = append(r.Changes, res)
+					}
+				}
+			}
+		case ev.State != nil && ev.TestRun != "":
+			r := run(ev.TestFile, ev.TestRun)
+			r.State = nil
+			for _, res := range collectAllPlannedResources(ev.State.Values.RootModule) {
+				r.State = append(r.State, res.Address)
+			}
+		case ev.Summary != nil:
+			report.Summary = ev.Summary
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return report, err
+	}
+	if report.Summary == nil && len(report.Files) == 0 {
+		return report, fmt.Errorf("no test results in terraform test output")
+	}
+	return report, nil
+}
+
+func handleTest(ctx context.Context, args []string) error {
+	opts, args, err := parseOptions(args)
+	if err != nil {
+		return err
+	}
+
+	if err := preflight(ctx, ""); err != nil {
+		return err
+	}
+	fmt.Println("🧪 Running terraform test...")
+	cmd := terraformCommand(ctx, append([]string{"test", "-json", "-verbose"}, args...)...)
+	cmd.Stderr = os.Stderr
+	out, runErr := cmd.Output()
+	if ctx.Err() != nil {
+		return fmt.Errorf("terraform test interrupted")
+	}
+
+	// terraform test exits non-zero when a test fails but still prints its
+	// results, so only fail if nothing could be parsed.
+	report, err := parseTestOutput(out)
+	if err != nil {
+		if runErr != nil {
+			return fmt.Errorf("error running terraform test: %v", runErr)
+		}
+		return fmt.Errorf("error parsing terraform test output: %v", err)
+	}
+
+	printTestSummary(report)
+	html, err := generateTestHTML(report)
+	if err != nil {
+		return err
+	}
+	return serveHTMLOnce(ctx, func(reportView) string { return html }, opts.serve)
+}
+
+func printTestSummary(report TestReport) {
+	if s := report.Summary; s != nil {
+		if s.Status == "pass" {
+			fmt.Printf("✅ Tests passed: %d passed, %d skipped\n", s.Passed, s.Skipped)
+		} else {
+			fmt.Printf("❌ Tests failed: %d passed, %d failed, %d errored, %d skipped\n", s.Passed, s.Failed, s.Errored, s.Skipped)
+		}
+	}
+	for _, f := range report.Files {
+		for _, r := range f.Runs {
+			if r.Status == "fail" || r.Status == "error" {
+				fmt.Printf("  [%s] %s: run %q\n", strings.ToUpper(r.Status), f.Path, r.Name)
+			}
+		}
+	}
+}
+
+func generateTestHTML(report TestReport) (string, error) {
+	data := struct {
+		TestReport
+		Timestamp string
+	}{report, time.Now().Format("2006-01-02 15:04:05")}
+
+	htmlTemplate := `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1" />
+  <title>Terraform Test</title>
+  <style>
+` + reportStyles + `    .test-pass { border-left: 4px solid var(--create-color); }
+    .test-fail, .test-error { border-left: 4px solid var(--delete-color); }
+    .test-skip, .test-pending { border-left: 4px solid var(--text-secondary-color); }
+    .test-run .details { display: block; }
+    .test-file { margin-top: 24px; }
+    .diagnostic-highlight { background-color: #ffeef0; text-decoration: underline wavy var(--delete-color); }
+  </style>
+</head>
+<body>
+  <div class="container">
+    <div class="header">
+      <h1>Terraform Test</h1>
+      <div class="subtitle">{{.Timestamp}}</div>
+    </div>
+    {{with .Summary}}
+    <div class="summary">
+      <div class="summary-item">
+        <h2 style="color: {{if eq .Status "pass"}}var(--create-color){{else}}var(--delete-color){{end}}">{{if eq .Status "pass"}}Passed{{else}}Failed{{end}}</h2>
+        <p>Result</p>
+      </div>
+      <div class="summary-item">
+        <h2 style="color: var(--create-color)">{{.Passed}}</h2>
+        <p>Passed</p>
+      </div>
+      <div class="summary-item">
+        <h2 style="color: var(--delete-color)">{{.Failed}}</h2>
+        <p>Failed</p>
+      </div>
+      <div class="summary-item">
+        <h2 style="color: var(--delete-color)">{{.Errored}}</h2>
+        <p>Errored</p>
+      </div>
+      <div class="summary-item">
+        <h2>{{.Skipped}}</h2>
+        <p>Skipped</p>
+      </div>
+    </div>
+    {{end}}
+    {{range .Files}}
+    <div class="test-file">
+      <h2>{{.Path}}{{with .Status}} · {{.}}{{end}}</h2>
+      {{range .Diagnostics}}<p><strong>{{.Summary}}</strong>{{with .Detail}}: {{.}}{{end}}</p>{{end}}
+      <div class="resource-list">
+        {{range .Runs}}
+        <div class="resource test-run test-{{if .Status}}{{.Status}}{{else}}pending{{end}}">
+          <div class="resource-info">
+            <h3>run "{{.Name}}"</h3>
+            <p>{{if .Status}}{{.Status}}{{else}}did not run{{end}}{{with .Changes}} · {{len .}} planned change(s){{end}}{{with .State}} · {{len .}} resource(s) in state{{end}}</p>
+          </div>
+          <div class="details">
+            {{range .Diagnostics}}
+            <div class="diagnostic diagnostic-{{.Severity}}">
+              <p><strong>{{.Summary}}</strong>{{with .Location}} · {{.}}{{end}}</p>
+              {{with .SnippetParts}}<pre>{{index . 0}}<span class="diagnostic-highlight">{{index . 1}}</span>{{index . 2}}</pre>{{end}}
+              {{if .Detail}}<p>{{.Detail}}</p>{{end}}
+            </div>
+            {{end}}
+            {{range .Changes}}
+            <h4>{{.Address}} ({{.Action}})</h4>
+            <pre>{{range .DiffLines}}<div class="diff-line-{{.Type}}">{{.Text}}</div>{{end}}</pre>
+            {{end}}
+            {{with .State}}<p>State: {{range $i, $a := .}}{{if $i}}, {{end}}<code>{{$a}}</code>{{end}}</p>{{end}}
+          </div>
+        </div>
+        {{end}}
+      </div>
+    </div>
+    {{else}}
+    <div class="resource"><p>No test files found.</p></div>
+    {{end}}
+  </div>
+</body>
+</html>`
+
+	tmpl, err := template.New("test").Parse(htmlTemplate)
+	if err != nil {
+		return "", fmt.Errorf("error parsing HTML template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error executing template: %v", err)
+	}
+	return buf.String(), nil
+}
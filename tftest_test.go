@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleTestOutput = `{"@level":"info","@message":"Terraform 1.9.0","type":"version"}
+{"@level":"info","@message":"Found 1 file and 2 run blocks","test_abstract":{"main.tftest.hcl":["plan_bucket","check_tags"]},"type":"test_abstract"}
+{"@level":"info","@message":"main.tftest.hcl... in progress","@testfile":"main.tftest.hcl","test_file":{"path":"main.tftest.hcl","progress":"starting"},"type":"test_file"}
+{"@level":"info","@message":"-verbose plan","@testfile":"main.tftest.hcl","@testrun":"plan_bucket","test_plan":{"format_version":"1.2","resource_changes":[{"address":"aws_s3_bucket.logs","mode":"managed","type":"aws_s3_bucket","name":"logs","change":{"actions":["create"],"before":null,"after":{"bucket":"logs"}}}]},"type":"test_plan"}
+{"@level":"info","@message":"  \"plan_bucket\"... pass","@testfile":"main.tftest.hcl","@testrun":"plan_bucket","test_run":{"path":"main.tftest.hcl","run":"plan_bucket","progress":"complete","status":"pass"},"type":"test_run"}
+{"@level":"error","@message":"Error: Test assertion failed","@testfile":"main.tftest.hcl","@testrun":"check_tags","diagnostic":{"severity":"error","summary":"Test assertion failed","detail":"bucket must be tagged"},"type":"diagnostic"}
+{"@level":"info","@message":"-verbose state","@testfile":"main.tftest.hcl","@testrun":"check_tags","test_state":{"values":{"root_module":{"resources":[{"address":"aws_s3_bucket.logs","mode":"managed","type":"aws_s3_bucket","name":"logs"}]}}},"type":"test_state"}
+{"@level":"info","@message":"  \"check_tags\"... fail","@testfile":"main.tftest.hcl","@testrun":"check_tags","test_run":{"path":"main.tftest.hcl","run":"check_tags","progress":"complete","status":"fail"},"type":"test_run"}
+not json from a provider
+{"@level":"info","@message":"main.tftest.hcl... fail","@testfile":"main.tftest.hcl","test_file":{"path":"main.tftest.hcl","progress":"complete","status":"fail"},"type":"test_file"}
+{"@level":"info","@message":"Failure! 1 passed, 1 failed.","test_summary":{"status":"fail","passed":1,"failed":1,"errored":0,"skipped":0},"type":"test_summary"}
+`
+
+func TestParseTestOutput(t *testing.T) {
+	report, err := parseTestOutput([]byte(sampleTestOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Summary == nil || report.Summary.Passed != 1 || report.Summary.Failed != 1 {
+		t.Fatalf("unexpected summary %+v", report.Summary)
+	}
+	if len(report.Files) != 1 || report.Files[0].Status != "fail" || len(report.Files[0].Runs) != 2 {
+		t.Fatalf("unexpected files %+v", report.Files)
+	}
+	plan, check := report.Files[0].Runs[0], report.Files[0].Runs[1]
+	if plan.Name != "plan_bucket" || plan.Status != "pass" || len(plan.Changes) != 1 || plan.Changes[0].Address != "aws_s3_bucket.logs" {
+		t.Errorf("unexpected run %+v", plan)
+	}
+	if check.Status != "fail" || len(check.Diagnostics) != 1 || len(check.State) != 1 {
+		t.Errorf("unexpected run %+v", check)
+	}
+
+	html, err := generateTestHTML(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`class="resource test-run test-fail"`, "bucket must be tagged", `<div class="diff-line-added">`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report does not contain %q", want)
+		}
+	}
+
+	if _, err := parseTestOutput([]byte("Error: no tests\n")); err == nil {
+		t.Error("expected error for output without results")
+	}
+}
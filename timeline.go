@@ -0,0 +1,423 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultDurations are rough times for creating resources that are known to
+// be slow. Everything else is assumed to take defaultDuration.
+var defaultDurations = map[string]time.Duration{
+	"aws_acm_certificate_validation":    2 * time.Minute,
+	"aws_autoscaling_group":             2 * time.Minute,
+	"aws_cloudfront_distribution":       5 * time.Minute,
+	"aws_db_instance":                   10 * time.Minute,
+	"aws_docdb_cluster":                 10 * time.Minute,
+	"aws_ec2_transit_gateway":           3 * time.Minute,
+	"aws_ecs_service":                   2 * time.Minute,
+	"aws_eks_cluster":                   10 * time.Minute,
+	"aws_eks_node_group":                5 * time.Minute,
+	"aws_elasticache_cluster":           8 * time.Minute,
+	"aws_elasticache_replication_group": 10 * time.Minute,
+	"aws_elasticsearch_domain":          15 * time.Minute,
+	"aws_instance":                      time.Minute,
+	"aws_lb":                            3 * time.Minute,
+	"aws_msk_cluster":                   25 * time.Minute,
+	"aws_nat_gateway":                   2 * time.Minute,
+	"aws_opensearch_domain":             15 * time.Minute,
+	"aws_rds_cluster":                   10 * time.Minute,
+	"aws_rds_cluster_instance":          8 * time.Minute,
+	"aws_redshift_cluster":              10 * time.Minute,
+	"azurerm_kubernetes_cluster":        8 * time.Minute,
+	"google_container_cluster":          8 * time.Minute,
+	"google_sql_database_instance":      10 * time.Minute,
+}
+
+const defaultDuration = 10 * time.Second
+
+// durationEstimate overrides the built-in estimate for resource types
+// matching a glob pattern, e.g. {"type": "aws_db_*", "duration": "20m"}.
+type durationEstimate struct {
+	Type     string `json:"type"`
+	Duration string `json:"duration"`
+
+	d time.Duration
+}
+
+func (e *durationEstimate) parse() error {
+	if _, err := path.Match(e.Type, ""); err != nil || e.Type == "" {
+		return fmt.Errorf("invalid pattern %q", e.Type)
+	}
+	d, err := time.ParseDuration(e.Duration)
+	if err != nil || d < 0 {
+		return fmt.Errorf("invalid duration %q", e.Duration)
+	}
+	e.d = d
+	return nil
+}
+
+// estimateDuration returns how long applying action to a resource of the
+// given type is expected to take. The first matching configured estimate
+// wins. Updates are assumed to take half as long as creation and
+// replacements to delete and create in turn.
+func estimateDuration(estimates []durationEstimate, resourceType, action string, replace bool) time.Duration {
+	d, ok := defaultDurations[resourceType]
+	if !ok {
+		d = defaultDuration
+	}
+	for _, e := range estimates {
+		if match, _ := path.Match(e.Type, resourceType); match {
+			d = e.d
+			break
+		}
+	}
+	switch {
+	case action == "no-op":
+		return 0
+	case replace:
+		return 2 * d
+	case action == "update":
+		return d / 2
+	}
+	return d
+}
+
+// defaultParallelism matches terraform apply's -parallelism default.
+const defaultParallelism = 10
+
+// maxTimelineChains is how many of the longest dependency chains are
+// reported.
+const maxTimelineChains = 3
+
+// TimelineEntry is one changed resource on the apply timeline. Start and
+// Duration are offsets from the beginning of the apply.
+type TimelineEntry struct {
+	Address  string        `json:"address"`
+	Action   string        `json:"action"`
+	Start    time.Duration `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Critical bool          `json:"critical,omitempty"`
+}
+
+// TimelineChain is a sequence of changes that each wait for the previous
+// one, with the time they take together.
+type TimelineChain struct {
+	Resources []string      `json:"resources"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// Timeline estimates when each change happens when Terraform applies the
+// plan with the given parallelism. Unlimited is the time the dependencies
+// alone would allow; the gap between the two shows how much splitting the
+// plan or raising -parallelism could gain.
+type Timeline struct {
+	Parallelism int             `json:"parallelism"`
+	Total       time.Duration   `json:"total"`
+	Unlimited   time.Duration   `json:"unlimited"`
+	Entries     []TimelineEntry `json:"entries"`
+	Chains      []TimelineChain `json:"chains"`
+}
+
+// timelineGraph holds the apply order constraints between resources.
+type timelineGraph struct {
+	resources map[string]ResourceAnalysis
+	order     []string
+	waitsFor  map[string][]string // resources that must finish first
+	duration  map[string]time.Duration
+}
+
+// newTimelineGraph orders resources along their references and depends_on.
+// Unchanged resources take no time but still pass dependencies on. Deletes
+// run in reverse: a resource is destroyed only after the resources that
+// depend on it.
+func newTimelineGraph(analyzed AnalyzedPlan, estimates []durationEstimate) *timelineGraph {
+	g := &timelineGraph{
+		resources: map[string]ResourceAnalysis{},
+		waitsFor:  map[string][]string{},
+		duration:  map[string]time.Duration{},
+	}
+	instances := map[string][]string{}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			g.resources[r.Address] = r
+			g.duration[r.Address] = estimateDuration(estimates, r.Type, r.Action, r.Replace)
+			instances[stripIndex(r.Address)] = append(instances[stripIndex(r.Address)], r.Address)
+			g.order = append(g.order, r.Address)
+		}
+	}
+	sort.Strings(g.order)
+
+	seen := map[string]bool{}
+	addDependency := func(user, used string) {
+		u, ok := g.resources[used]
+		if !ok || used == user {
+			return
+		}
+		userDeletes, usedDeletes := g.resources[user].Action == "delete", u.Action == "delete"
+		first, then := used, user
+		switch {
+		case userDeletes && usedDeletes:
+			first, then = user, used
+		case userDeletes || usedDeletes:
+			return
+		}
+		if !seen[first+" "+then] {
+			seen[first+" "+then] = true
+			g.waitsFor[then] = append(g.waitsFor[then], first)
+		}
+	}
+	for _, addr := range g.order {
+		r := g.resources[addr]
+		for _, ref := range r.References {
+			for _, target := range instances[ref.Target] {
+				addDependency(addr, target)
+			}
+		}
+		for _, dep := range r.DependsOn {
+			for _, target := range instances[stripIndex(dep)] {
+				addDependency(addr, target)
+			}
+		}
+	}
+	return g
+}
+
+// longestPaths returns when each resource could finish if only the
+// dependencies limited the apply, and for each the dependency that
+// finishes last.
+func (g *timelineGraph) longestPaths() (map[string]time.Duration, map[string]string) {
+	end := map[string]time.Duration{}
+	after := map[string]string{}
+	state := map[string]int{} // 1 while visiting, 2 when done
+	var visit func(addr string)
+	visit = func(addr string) {
+		if state[addr] != 0 {
+			// A cycle can only come from approximated references; the
+			// back edge is ignored.
+			return
+		}
+		state[addr] = 1
+		var start time.Duration
+		for _, dep := range g.waitsFor[addr] {
+			visit(dep)
+			if state[dep] == 2 && end[dep] > start {
+				start = end[dep]
+				after[addr] = dep
+			}
+		}
+		end[addr] = start + g.duration[addr]
+		state[addr] = 2
+	}
+	for _, addr := range g.order {
+		visit(addr)
+	}
+	return end, after
+}
+
+// simulate runs the apply with at most parallelism changes in flight; zero
+// means no limit. Ready resources start in the order they became ready,
+// then by address. Unchanged resources do not take a slot.
+func (g *timelineGraph) simulate(parallelism int) (map[string]time.Duration, map[string]time.Duration) {
+	start := map[string]time.Duration{}
+	end := map[string]time.Duration{}
+	pending := map[string]int{}
+	dependents := map[string][]string{}
+	for _, addr := range g.order {
+		pending[addr] = len(g.waitsFor[addr])
+		for _, dep := range g.waitsFor[addr] {
+			dependents[dep] = append(dependents[dep], addr)
+		}
+	}
+
+	readyAt := map[string]time.Duration{}
+	var ready, running []string
+	for _, addr := range g.order {
+		if pending[addr] == 0 {
+			ready = append(ready, addr)
+		}
+	}
+	finish := func(addr string, now time.Duration) {
+		end[addr] = now
+		for _, d := range dependents[addr] {
+			if pending[d]--; pending[d] == 0 {
+				readyAt[d] = now
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	var now time.Duration
+	done := 0
+	for done < len(g.order) {
+		sort.SliceStable(ready, func(i, j int) bool {
+			if readyAt[ready[i]] != readyAt[ready[j]] {
+				return readyAt[ready[i]] < readyAt[ready[j]]
+			}
+			return ready[i] < ready[j]
+		})
+		for started := true; started; {
+			started = false
+			for i, addr := range ready {
+				if g.duration[addr] > 0 && parallelism > 0 && len(running) >= parallelism {
+					continue
+				}
+				ready = append(ready[:i], ready[i+1:]...)
+				start[addr] = now
+				if g.duration[addr] == 0 {
+					finish(addr, now)
+					done++
+				} else {
+					running = append(running, addr)
+				}
+				started = true
+				break
+			}
+		}
+
+		if len(running) == 0 {
+			if done < len(g.order) {
+				// Only a dependency cycle can leave nothing to run; release
+				// the first blocked resource.
+				for _, addr := range g.order {
+					if _, started := start[addr]; !started && pending[addr] > 0 {
+						pending[addr] = 0
+						readyAt[addr] = now
+						ready = append(ready, addr)
+						break
+					}
+				}
+			}
+			continue
+		}
+
+		// Advance to the next completion.
+		next := running[0]
+		for _, addr := range running[1:] {
+			if start[addr]+g.duration[addr] < start[next]+g.duration[next] {
+				next = addr
+			}
+		}
+		now = start[next] + g.duration[next]
+		remaining := running[:0]
+		for _, addr := range running {
+			if start[addr]+g.duration[addr] == now {
+				finish(addr, now)
+				done++
+			} else {
+				remaining = append(remaining, addr)
+			}
+		}
+		running = remaining
+	}
+	return start, end
+}
+
+// buildTimeline estimates the apply with the given parallelism (zero means
+// defaultParallelism). It returns nil when nothing changes.
+func buildTimeline(analyzed AnalyzedPlan, estimates []durationEstimate, parallelism int) *Timeline {
+	if parallelism == 0 {
+		parallelism = defaultParallelism
+	}
+	g := newTimelineGraph(analyzed, estimates)
+	start, end := g.simulate(parallelism)
+	chainEnd, after := g.longestPaths()
+
+	timeline := &Timeline{Parallelism: parallelism, Entries: []TimelineEntry{}, Chains: []TimelineChain{}}
+	var changed []string
+	for _, addr := range g.order {
+		if g.resources[addr].Action == "no-op" {
+			continue
+		}
+		changed = append(changed, addr)
+		timeline.Entries = append(timeline.Entries, TimelineEntry{
+			Address:  addr,
+			Action:   g.resources[addr].Action,
+			Start:    start[addr],
+			Duration: g.duration[addr],
+		})
+		if end[addr] > timeline.Total {
+			timeline.Total = end[addr]
+		}
+		if chainEnd[addr] > timeline.Unlimited {
+			timeline.Unlimited = chainEnd[addr]
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	// The longest chains end at the resources finishing last; a resource
+	// already on a reported chain does not start another one.
+	sort.SliceStable(changed, func(i, j int) bool { return chainEnd[changed[i]] > chainEnd[changed[j]] })
+	onChain := map[string]bool{}
+	for _, last := range changed {
+		if len(timeline.Chains) == maxTimelineChains {
+			break
+		}
+		if onChain[last] {
+			continue
+		}
+		chain := TimelineChain{Duration: chainEnd[last]}
+		for addr := last; addr != ""; addr = after[addr] {
+			onChain[addr] = true
+			if g.resources[addr].Action != "no-op" {
+				chain.Resources = append([]string{addr}, chain.Resources...)
+			}
+		}
+		timeline.Chains = append(timeline.Chains, chain)
+	}
+
+	critical := map[string]bool{}
+	for _, addr := range timeline.Chains[0].Resources {
+		critical[addr] = true
+	}
+	for i := range timeline.Entries {
+		timeline.Entries[i].Critical = critical[timeline.Entries[i].Address]
+	}
+	sort.SliceStable(timeline.Entries, func(i, j int) bool {
+		return timeline.Entries[i].Start < timeline.Entries[j].Start
+	})
+	return timeline
+}
+
+// Bars returns the entries with their position on a 0-100 scale for the
+// report.
+func (t Timeline) Bars() []timelineBar {
+	bars := make([]timelineBar, 0, len(t.Entries))
+	for _, e := range t.Entries {
+		bar := timelineBar{TimelineEntry: e, Left: 0, Width: 100}
+		if t.Total > 0 {
+			bar.Left = float64(e.Start) * 100 / float64(t.Total)
+			bar.Width = float64(e.Duration) * 100 / float64(t.Total)
+		}
+		bars = append(bars, bar)
+	}
+	return bars
+}
+
+type timelineBar struct {
+	TimelineEntry
+	Left, Width float64
+}
+
+func (t Timeline) TotalText() string         { return formatEstimate(t.Total) }
+func (t Timeline) UnlimitedText() string     { return formatEstimate(t.Unlimited) }
+func (c TimelineChain) DurationText() string { return formatEstimate(c.Duration) }
+func (e TimelineEntry) StartText() string    { return formatEstimate(e.Start) }
+func (e TimelineEntry) DurationText() string { return formatEstimate(e.Duration) }
+
+// formatEstimate renders a duration estimate the way people say it, e.g.
+// "12m30s" or "45s".
+func formatEstimate(d time.Duration) string {
+	s := d.Round(time.Second).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	ref := func(attr, target string) ResourceReference {
+		return ResourceReference{Attribute: attr, Expression: target + ".id", Target: target}
+	}
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Address: "root", Resources: []ResourceAnalysis{
+		{Address: "aws_vpc.main", Type: "aws_vpc", Action: "no-op"},
+		{Address: "aws_subnet.a[0]", Type: "aws_subnet", Action: "create", References: []ResourceReference{ref("vpc_id", "aws_vpc.main")}},
+		{Address: "aws_subnet.a[1]", Type: "aws_subnet", Action: "create", References: []ResourceReference{ref("vpc_id", "aws_vpc.main")}},
+		{Address: "aws_db_subnet_group.db", Type: "aws_db_subnet_group", Action: "create", References: []ResourceReference{ref("subnet_ids", "aws_subnet.a")}},
+		{Address: "aws_db_instance.db", Type: "aws_db_instance", Action: "create", References: []ResourceReference{ref("db_subnet_group_name", "aws_db_subnet_group.db")}},
+		{Address: "aws_instance.app", Type: "aws_instance", Action: "update", Replace: true, References: []ResourceReference{ref("subnet_id", "aws_subnet.a")}},
+		{Address: "aws_route53_record.app", Type: "aws_route53_record", Action: "update", DependsOn: []string{"aws_instance.app"}},
+		// Deletes run in reverse: the listener goes before the load balancer
+		{Address: "aws_lb.old", Type: "aws_lb", Action: "delete"},
+		{Address: "aws_lb_listener.old", Type: "aws_lb_listener", Action: "delete", References: []ResourceReference{ref("load_balancer_arn", "aws_lb.old")}},
+	}}}}
+
+	timeline := buildTimeline(analyzed, []durationEstimate{{Type: "aws_db_*", d: 20 * time.Minute}}, 0)
+	if timeline == nil {
+		t.Fatal("expected a timeline")
+	}
+	entries := map[string]TimelineEntry{}
+	for _, e := range timeline.Entries {
+		entries[e.Address] = e
+	}
+	if _, ok := entries["aws_vpc.main"]; ok {
+		t.Error("unchanged resources should not be on the timeline")
+	}
+
+	s := 10 * time.Second
+	want := map[string][2]time.Duration{ // start, duration
+		"aws_subnet.a[0]":        {0, s},
+		"aws_subnet.a[1]":        {0, s},
+		"aws_db_subnet_group.db": {s, 20 * time.Minute},
+		"aws_db_instance.db":     {s + 20*time.Minute, 20 * time.Minute},
+		"aws_instance.app":       {s, 2 * time.Minute},
+		"aws_route53_record.app": {s + 2*time.Minute, s / 2},
+		"aws_lb_listener.old":    {0, s},
+		"aws_lb.old":             {s, 3 * time.Minute},
+	}
+	for addr, w := range want {
+		if e := entries[addr]; e.Start != w[0] || e.Duration != w[1] {
+			t.Errorf("%s: start %s, duration %s; want %s, %s", addr, e.Start, e.Duration, w[0], w[1])
+		}
+	}
+	if timeline.Total != s+40*time.Minute || timeline.Unlimited != timeline.Total || timeline.Parallelism != defaultParallelism {
+		t.Errorf("total = %s, unlimited = %s, parallelism = %d", timeline.Total, timeline.Unlimited, timeline.Parallelism)
+	}
+	var chains []string
+	for _, c := range timeline.Chains {
+		chains = append(chains, c.DurationText()+" "+strings.Join(c.Resources, " → "))
+	}
+	wantChains := []string{
+		"40m10s aws_subnet.a[0] → aws_db_subnet_group.db → aws_db_instance.db",
+		"3m10s aws_lb_listener.old → aws_lb.old",
+		"2m15s aws_subnet.a[0] → aws_instance.app → aws_route53_record.app",
+	}
+	if strings.Join(chains, "\n") != strings.Join(wantChains, "\n") {
+		t.Errorf("chains:\n%s\nwant:\n%s", strings.Join(chains, "\n"), strings.Join(wantChains, "\n"))
+	}
+	if !entries["aws_db_instance.db"].Critical || entries["aws_instance.app"].Critical {
+		t.Error("critical flags do not match the critical path")
+	}
+	if timeline.TotalText() != "40m10s" || formatEstimate(2*time.Minute) != "2m" || formatEstimate(time.Hour) != "1h" {
+		t.Errorf("formatting: %s %s %s", timeline.TotalText(), formatEstimate(2*time.Minute), formatEstimate(time.Hour))
+	}
+
+	noChanges := AnalyzedPlan{Modules: []ModuleAnalysis{{Resources: []ResourceAnalysis{{Address: "aws_vpc.main", Action: "no-op"}}}}}
+	if buildTimeline(noChanges, nil, 0) != nil {
+		t.Error("a plan without changes should have no timeline")
+	}
+}
+
+func TestDurationEstimateParse(t *testing.T) {
+	for _, e := range []durationEstimate{{Type: "", Duration: "1m"}, {Type: "[", Duration: "1m"}, {Type: "aws_*", Duration: "soon"}} {
+		if err := e.parse(); err == nil {
+			t.Errorf("%+v: expected an error", e)
+		}
+	}
+}
+
+func TestBuildTimeline_Parallelism(t *testing.T) {
+	// Four independent 1m instances and a 10s record that depends on one
+	var resources []ResourceAnalysis
+	for _, name := range []string{"a", "b", "c", "d"} {
+		resources = append(resources, ResourceAnalysis{Address: "aws_instance." + name, Type: "aws_instance", Action: "create"})
+	}
+	resources = append(resources, ResourceAnalysis{Address: "aws_route53_record.d", Type: "aws_route53_record", Action: "create",
+		References: []ResourceReference{{Attribute: "records", Target: "aws_instance.d"}}})
+	analyzed := AnalyzedPlan{Modules: []ModuleAnalysis{{Address: "root", Resources: resources}}}
+
+	timeline := buildTimeline(analyzed, nil, 2)
+	if timeline.Total != 2*time.Minute+10*time.Second || timeline.Unlimited != time.Minute+10*time.Second {
+		t.Errorf("total = %s, unlimited = %s", timeline.Total, timeline.Unlimited)
+	}
+	starts := map[string]time.Duration{}
+	for _, e := range timeline.Entries {
+		starts[e.Address] = e.Start
+	}
+	// a and b take the two slots first; c and d follow when they finish
+	if starts["aws_instance.b"] != 0 || starts["aws_instance.c"] != time.Minute || starts["aws_route53_record.d"] != 2*time.Minute {
+		t.Errorf("starts = %v", starts)
+	}
+
+	if unlimited := buildTimeline(analyzed, nil, 100); unlimited.Total != unlimited.Unlimited {
+		t.Errorf("without contention total %s should equal %s", unlimited.Total, unlimited.Unlimited)
+	}
+}
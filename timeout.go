@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// terraformTimeout limits how long terraform plan and show may take
+// together, retries included, set by --timeout. Zero means no limit.
+var terraformTimeout time.Duration
+
+// terraformStopDelay is how long terraform gets to release state locks and
+// stop its providers after being interrupted before it is killed.
+const terraformStopDelay = 10 * time.Second
+
+// withTerraformTimeout applies terraformTimeout to ctx.
+func withTerraformTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if terraformTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, terraformTimeout)
+}
+
+// stoppedError describes why a terraform command was stopped by ctx.
+func stoppedError(ctx context.Context, command string) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s did not finish within --timeout %s", command, terraformTimeout)
+	}
+	return fmt.Errorf("%s interrupted", command)
+}
+
+// signalCause is the cause of the context notifySignals cancels.
+type signalCause struct {
+	signal os.Signal
+}
+
+func (c signalCause) Error() string {
+	return c.signal.String() + " received"
+}
+
+// notifySignals is signal.NotifyContext for SIGINT and SIGTERM, with the
+// signal received as the cause of the context.
+func notifySignals(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			cancel(signalCause{sig})
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel(nil)
+	}
+}
+
+// interruptedFromTerminal reports whether ctx was stopped by SIGINT, which
+// Ctrl-C sends to every process in the terminal's foreground group, rather
+// than by SIGTERM or --timeout.
+func interruptedFromTerminal(ctx context.Context) bool {
+	var cause signalCause
+	return errors.As(context.Cause(ctx), &cause) && cause.signal == os.Interrupt
+}
+
+// terraformCmd is a terraform command that, once interrupted in a process
+// group of its own, kills the group if terraform does not stop in time.
+type terraformCmd struct {
+	*exec.Cmd
+	// killTimer is set by Cancel, which returns before Wait does.
+	killTimer *time.Timer
+}
+
+// Wait waits for terraform to exit and stops the kill timer, so it cannot
+// signal a process group whose ID has been reused.
+func (c *terraformCmd) Wait() error {
+	err := c.Cmd.Wait()
+	if c.killTimer != nil {
+		c.killTimer.Stop()
+	}
+	return err
+}
+
+func (c *terraformCmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+func (c *terraformCmd) Output() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+	var stdout bytes.Buffer
+	c.Stdout = &stdout
+	err := c.Run()
+	return stdout.Bytes(), err
+}
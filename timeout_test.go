@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestTerraformCommand_Timeout(t *testing.T) {
+	defer func(b string, d time.Duration) { terraformBinary, terraformTimeout = b, d }(terraformBinary, terraformTimeout)
+	terraformBinary, terraformTimeout = "sh", 100*time.Millisecond
+
+	// The shell only stops quickly if the interrupt reaches its children as
+	// well, which needs a process group of its own.
+	script := "exec sleep 30"
+	if ownProcessGroup(exec.Command("true")) {
+		script = "sleep 30 & sleep 30"
+	}
+	ctx, cancel := withTerraformTimeout(context.Background())
+	defer cancel()
+	start := time.Now()
+	cmd := terraformCommand(ctx, "-c", script)
+	err := cmd.Run()
+	if err == nil {
+		t.Fatal("expected the command to be stopped")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("command took %s to stop", elapsed)
+	}
+	if cmd.killTimer != nil && cmd.killTimer.Stop() {
+		t.Error("the kill timer is still armed after terraform exited")
+	}
+	if err := stoppedError(ctx, "terraform plan"); !strings.Contains(err.Error(), "did not finish within --timeout 100ms") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestStoppedError_Interrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := stoppedError(ctx, "terraform show"); err.Error() != "terraform show interrupted" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestInterruptedFromTerminal(t *testing.T) {
+	for _, tc := range []struct {
+		cause error
+		want  bool
+	}{
+		{signalCause{os.Interrupt}, true},
+		{signalCause{syscall.SIGTERM}, false},
+		{context.DeadlineExceeded, false},
+	} {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(tc.cause)
+		child, stop := withTerraformTimeout(ctx)
+		if got := interruptedFromTerminal(child); got != tc.want {
+			t.Errorf("interruptedFromTerminal after %v = %v, want %v", tc.cause, got, tc.want)
+		}
+		stop()
+	}
+}
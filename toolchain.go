@@ -0,0 +1,376 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// terraformBinary is the terraform-compatible binary tfviz runs. With
+// --install-terraform it is replaced by a release tfviz downloaded.
+var terraformBinary = "terraform"
+
+// releaseSource describes where releases of a terraform-compatible binary
+// are published.
+type releaseSource struct {
+	name        string
+	versionsURL string
+	// versions extracts the published version numbers from versionsURL.
+	versions func(data []byte) ([]string, error)
+	// baseURL is the directory holding the archives and SHA256SUMS of a
+	// version.
+	baseURL func(version string) string
+	// pinFile is the tfenv-style file that pins an exact version.
+	pinFile string
+}
+
+var releaseSources = map[string]releaseSource{
+	"terraform": {
+		name:        "terraform",
+		versionsURL: "https://releases.hashicorp.com/terraform/index.json",
+		versions: func(data []byte) ([]string, error) {
+			var index struct {
+				Versions map[string]json.RawMessage `json:"versions"`
+			}
+			if err := json.Unmarshal(data, &index); err != nil {
+				return nil, err
+			}
+			return sortedKeys(index.Versions), nil
+		},
+		baseURL: func(version string) string {
+			return "https://releases.hashicorp.com/terraform/" + version
+		},
+		pinFile: ".terraform-version",
+	},
+	"tofu": {
+		name:        "tofu",
+		versionsURL: "https://get.opentofu.org/tofu/api.json",
+		versions: func(data []byte) ([]string, error) {
+			var index struct {
+				Versions []struct {
+					ID string `json:"id"`
+				} `json:"versions"`
+			}
+			if err := json.Unmarshal(data, &index); err != nil {
+				return nil, err
+			}
+			var versions []string
+			for _, v := range index.Versions {
+				versions = append(versions, v.ID)
+			}
+			return versions, nil
+		},
+		baseURL: func(version string) string {
+			return "https://github.com/opentofu/opentofu/releases/download/v" + version
+		},
+		pinFile: ".opentofu-version",
+	},
+}
+
+func (s releaseSource) archiveName(version string) string {
+	return fmt.Sprintf("%s_%s_%s_%s.zip", s.name, version, runtime.GOOS, runtime.GOARCH)
+}
+
+func (s releaseSource) binaryName() string {
+	if runtime.GOOS == "windows" {
+		return s.name + ".exe"
+	}
+	return s.name
+}
+
+// setupTerraform handles the flags that choose how terraform is run, which
+// may be given to any command, and removes them from args:
+// --install-terraform[=terraform|tofu], --init, --retries, --retry-backoff
+// and --timeout. When the binary on PATH
+// is missing or does not satisfy the configuration's required_version, a
+// matching release is downloaded into the user cache and used instead.
+func setupTerraform(ctx context.Context, args []string) ([]string, error) {
+	var rest []string
+	flavour := ""
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		if a == "--init" {
+			autoInit = true
+			continue
+		}
+		name, value, hasValue := strings.Cut(a, "=")
+		if name == "--retries" || name == "--retry-backoff" || name == "--timeout" {
+			if !hasValue {
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("%s requires a value", name)
+				}
+				i++
+				value = args[i]
+			}
+			if err := setRunFlag(name, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if name != "--install-terraform" {
+			rest = append(rest, a)
+			continue
+		}
+		flavour = "terraform"
+		if hasValue {
+			flavour = value
+		}
+	}
+	if flavour == "" {
+		return rest, nil
+	}
+	source, ok := releaseSources[flavour]
+	if !ok {
+		return nil, fmt.Errorf("unsupported --install-terraform %q (use terraform or tofu)", flavour)
+	}
+
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("error locating cache directory: %v", err)
+	}
+	installer := &toolInstaller{
+		source:   source,
+		client:   &http.Client{Timeout: 5 * time.Minute},
+		cacheDir: filepath.Join(base, "tfviz", "toolchains"),
+	}
+	path, err := installer.ensure(ctx, ".")
+	if err != nil {
+		return nil, err
+	}
+	terraformBinary = path
+	return rest, nil
+}
+
+// toolInstaller finds or downloads a release of one terraform-compatible
+// binary.
+type toolInstaller struct {
+	source   releaseSource
+	client   *http.Client
+	cacheDir string
+}
+
+// ensure returns a binary satisfying the version wanted by the
+// configuration in dir, preferring the one on PATH.
+func (t *toolInstaller) ensure(ctx context.Context, dir string) (string, error) {
+	pinned, constraint, err := wantedVersion(dir, t.source.pinFile)
+	if err != nil {
+		return "", err
+	}
+	if path, err := exec.LookPath(t.source.name); err == nil {
+		if v, err := binaryVersion(path); err == nil && versionSatisfies(v, pinned, constraint) {
+			return path, nil
+		}
+	}
+
+	version := pinned
+	if version == "" {
+		version, err = t.latest(ctx, constraint)
+		if err != nil {
+			// Offline: fall back to the newest matching release already
+			// downloaded.
+			cached, ok := latestVersion(t.cachedVersions(), constraint)
+			if !ok {
+				return "", err
+			}
+			version = cached.String()
+		}
+	}
+	path := filepath.Join(t.cacheDir, t.source.name, version, t.source.binaryName())
+	if _, err := os.Stat(path); err == nil {
+		fmt.Printf("🧰 Using %s %s from %s\n", t.source.name, version, path)
+		return path, nil
+	}
+	fmt.Printf("⬇️  Downloading %s %s...\n", t.source.name, version)
+	if err := t.install(ctx, version, path); err != nil {
+		return "", fmt.Errorf("error installing %s %s: %v", t.source.name, version, err)
+	}
+	return path, nil
+}
+
+func versionSatisfies(version, pinned string, constraint versionConstraint) bool {
+	if pinned != "" {
+		return strings.TrimPrefix(version, "v") == pinned
+	}
+	v, err := parseSemVersion(version)
+	return err == nil && constraint.allows(v)
+}
+
+func (t *toolInstaller) cachedVersions() []string {
+	entries, _ := os.ReadDir(filepath.Join(t.cacheDir, t.source.name))
+	var versions []string
+	for _, e := range entries {
+		if _, err := os.Stat(filepath.Join(t.cacheDir, t.source.name, e.Name(), t.source.binaryName())); err == nil {
+			versions = append(versions, e.Name())
+		}
+	}
+	return versions
+}
+
+// latest returns the newest release the constraint allows.
+func (t *toolInstaller) latest(ctx context.Context, constraint versionConstraint) (string, error) {
+	data, err := t.get(ctx, t.source.versionsURL)
+	if err != nil {
+		return "", fmt.Errorf("error listing %s releases: %v", t.source.name, err)
+	}
+	versions, err := t.source.versions(data)
+	if err != nil {
+		return "", fmt.Errorf("error listing %s releases: %v", t.source.name, err)
+	}
+	v, ok := latestVersion(versions, constraint)
+	if !ok {
+		return "", fmt.Errorf("no %s release satisfies the required_version constraint", t.source.name)
+	}
+	return v.String(), nil
+}
+
+// install downloads a release archive, checks it against the published
+// SHA256SUMS and extracts the binary to path.
+func (t *toolInstaller) install(ctx context.Context, version, path string) error {
+	base := t.source.baseURL(version)
+	archive := t.source.archiveName(version)
+	sums, err := t.get(ctx, fmt.Sprintf("%s/%s_%s_SHA256SUMS", base, t.source.name, version))
+	if err != nil {
+		return err
+	}
+	want := checksumFor(sums, archive)
+	if want == "" {
+		return fmt.Errorf("no checksum published for %s", archive)
+	}
+	data, err := t.get(ctx, base+"/"+archive)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", archive, got, want)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if f.Name != t.source.binaryName() {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		tmp, err := os.CreateTemp(filepath.Dir(path), ".download-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		if _, err := io.Copy(tmp, rc); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+		if err := os.Chmod(tmp.Name(), 0755); err != nil {
+			return err
+		}
+		return os.Rename(tmp.Name(), path)
+	}
+	return fmt.Errorf("%s does not contain %s", archive, t.source.binaryName())
+}
+
+func (t *toolInstaller) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 512<<20))
+}
+
+// checksumFor finds a file's digest in a SHA256SUMS document.
+func checksumFor(sums []byte, file string) string {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == file {
+			return fields[0]
+		}
+	}
+	return ""
+}
+
+var requiredVersionRe = regexp.MustCompile(`(?m)^\s*required_version\s*=\s*"([^"]*)"`)
+
+// requiredVersion combines the required_version constraints of the
+// configuration's .tf files in dir, or returns "" when there are none.
+func requiredVersion(dir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	var constraints []string
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return "", fmt.Errorf("error reading %s: %v", f, err)
+		}
+		for _, m := range requiredVersionRe.FindAllSubmatch(data, -1) {
+			constraints = append(constraints, string(m[1]))
+		}
+	}
+	return strings.Join(constraints, ", "), nil
+}
+
+// wantedVersion returns the exact version pinned by pinFile, if any, and
+// the required_version constraint of the configuration in dir.
+func wantedVersion(dir, pinFile string) (string, versionConstraint, error) {
+	required, err := requiredVersion(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	constraint, err := parseVersionConstraint(required)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid required_version %q: %v", required, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, pinFile))
+	if os.IsNotExist(err) {
+		return "", constraint, nil
+	} else if err != nil {
+		return "", nil, fmt.Errorf("error reading %s: %v", pinFile, err)
+	}
+	pinned := strings.TrimPrefix(strings.TrimSpace(string(data)), "v")
+	if _, err := parseSemVersion(pinned); err != nil {
+		return "", nil, fmt.Errorf("%s: %v", pinFile, err)
+	}
+	return pinned, constraint, nil
+}
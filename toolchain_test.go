@@ -0,0 +1,128 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWantedVersion(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "versions.tf"), []byte("terraform {\n  required_version = \">= 1.5\"\n}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.tf"), []byte("terraform {\n  required_version = \"< 1.8\"\n}\n"), 0644)
+
+	pinned, c, err := wantedVersion(dir, ".terraform-version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pinned != "" || len(c) != 2 {
+		t.Errorf("pinned = %q, constraint = %v", pinned, c)
+	}
+	if !versionSatisfies("1.7.5", "", c) || versionSatisfies("1.8.0", "", c) {
+		t.Error("constraint should allow 1.7.5 and reject 1.8.0")
+	}
+
+	os.WriteFile(filepath.Join(dir, ".terraform-version"), []byte("v1.6.2\n"), 0644)
+	if pinned, _, err := wantedVersion(dir, ".terraform-version"); err != nil || pinned != "1.6.2" {
+		t.Errorf("pinned = %q, %v", pinned, err)
+	}
+}
+
+// releaseServer serves a fake release index, SHA256SUMS and archives
+// containing a binary whose content is its version.
+func releaseServer(t *testing.T, versions ...string) (*httptest.Server, releaseSource) {
+	source := releaseSource{name: "terraform", pinFile: ".terraform-version"}
+	archives := map[string][]byte{}
+	sums := map[string]string{}
+	for _, v := range versions {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, _ := zw.Create(source.binaryName())
+		w.Write([]byte(v))
+		w, _ = zw.Create("LICENSE.txt")
+		w.Write([]byte("license"))
+		zw.Close()
+		archives[v] = buf.Bytes()
+		sum := sha256.Sum256(buf.Bytes())
+		sums[v] = fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), source.archiveName(v))
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.json" {
+			fmt.Fprintf(w, `{"versions": {"%s": {}}}`, strings.Join(versions, `": {}, "`))
+			return
+		}
+		for _, v := range versions {
+			switch r.URL.Path {
+			case "/" + v + "/terraform_" + v + "_SHA256SUMS":
+				w.Write([]byte(sums[v]))
+				return
+			case "/" + v + "/" + source.archiveName(v):
+				w.Write(archives[v])
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	source.versionsURL = srv.URL + "/index.json"
+	source.versions = releaseSources["terraform"].versions
+	source.baseURL = func(v string) string { return srv.URL + "/" + v }
+	return srv, source
+}
+
+func TestToolInstaller_DownloadsMatchingRelease(t *testing.T) {
+	srv, source := releaseServer(t, "1.6.6", "1.7.5", "1.8.0", "1.9.0-beta1")
+	t.Setenv("PATH", t.TempDir())
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "versions.tf"), []byte(`  required_version = "~> 1.7.0"`), 0644)
+
+	installer := &toolInstaller{source: source, client: srv.Client(), cacheDir: t.TempDir()}
+	path, err := installer.ensure(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "1.7.5" {
+		t.Errorf("installed %q, want release 1.7.5", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("installed binary is not executable: %v", err)
+	}
+
+	// A cached release is reused when the release index is unreachable.
+	srv.Close()
+	if again, err := installer.ensure(context.Background(), dir); err != nil || again != path {
+		t.Errorf("second ensure = %q, %v", again, err)
+	}
+}
+
+func TestToolInstaller_ChecksumMismatch(t *testing.T) {
+	srv, source := releaseServer(t, "1.7.5")
+	good := source.archiveName("1.7.5")
+	installer := &toolInstaller{source: source, client: srv.Client(), cacheDir: t.TempDir()}
+
+	// Publish a checksum that does not match the archive.
+	tampered := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "_SHA256SUMS") {
+			fmt.Fprintf(w, "%s  %s\n", strings.Repeat("0", 64), good)
+			return
+		}
+		http.Redirect(w, r, srv.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer tampered.Close()
+	installer.source.baseURL = func(v string) string { return tampered.URL + "/" + v }
+
+	err := installer.install(context.Background(), "1.7.5", filepath.Join(installer.cacheDir, "terraform"))
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+}
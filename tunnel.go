@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultTunnelProvider is used by --share without a value.
+const defaultTunnelProvider = "cloudflared"
+
+// defaultTunnelShareTTL limits how long a tunnelled report stays reachable
+// when --share-ttl is not given.
+const defaultTunnelShareTTL = 8 * time.Hour
+
+// tunnelStartTimeout is how long to wait for the provider to print its URL.
+const tunnelStartTimeout = 30 * time.Second
+
+// tunnelConfig runs a program that exposes the local report server on a
+// public URL. "{port}" in Command is replaced with the server's port, and
+// the first match of URLPattern in the program's output (or of its first
+// group, if it has one) is the public URL.
+type tunnelConfig struct {
+	Command    []string `json:"command"`
+	URLPattern string   `json:"url_pattern"`
+}
+
+var builtinTunnels = map[string]tunnelConfig{
+	"cloudflared": {
+		Command:    []string{"cloudflared", "tunnel", "--no-autoupdate", "--url", "http://127.0.0.1:{port}"},
+		URLPattern: `https://[a-z0-9-]+\.trycloudflare\.com`,
+	},
+	"ngrok": {
+		Command:    []string{"ngrok", "http", "127.0.0.1:{port}", "--log", "stdout", "--log-format", "logfmt"},
+		URLPattern: `url=(https://\S+)`,
+	},
+	"ssh": {
+		Command:    []string{"ssh", "-o", "StrictHostKeyChecking=accept-new", "-o", "ServerAliveInterval=30", "-R", "80:127.0.0.1:{port}", "nokey@localhost.run"},
+		URLPattern: `https://[a-z0-9-]+\.lhr\.life`,
+	},
+}
+
+func validateTunnels(tunnels map[string]tunnelConfig) error {
+	for _, name := range sortedKeys(tunnels) {
+		t := tunnels[name]
+		if len(t.Command) == 0 || t.URLPattern == "" {
+			return fmt.Errorf("tunnels.%s: command and url_pattern are required", name)
+		}
+		if _, err := regexp.Compile(t.URLPattern); err != nil {
+			return fmt.Errorf("tunnels.%s: invalid url_pattern: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// tunnelFor looks up a provider, preferring the project's own definitions.
+func tunnelFor(name string, custom map[string]tunnelConfig) (tunnelConfig, error) {
+	if t, ok := custom[name]; ok {
+		return t, nil
+	}
+	if t, ok := builtinTunnels[name]; ok {
+		return t, nil
+	}
+	return tunnelConfig{}, fmt.Errorf("unknown tunnel provider %q (use cloudflared, ngrok or ssh, or define it under tunnels in the config file)", name)
+}
+
+// startTunnel runs the tunnel program for the local port and returns its
+// public URL. The program runs until stop is called or ctx is cancelled.
+func startTunnel(ctx context.Context, t tunnelConfig, port string) (url string, stop func(), err error) {
+	pattern, err := regexp.Compile(t.URLPattern)
+	if err != nil {
+		return "", nil, err
+	}
+	args := make([]string, len(t.Command))
+	for i, a := range t.Command {
+		args[i] = strings.ReplaceAll(a, "{port}", port)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	out, w := io.Pipe()
+	cmd.Stdout = w
+	cmd.Stderr = w
+	// Children of the program may keep the output open after it is killed.
+	cmd.WaitDelay = 2 * time.Second
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return "", nil, fmt.Errorf("error starting %s: %v", args[0], err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		w.Close()
+		close(exited)
+	}()
+	stop = func() {
+		cancel()
+		<-exited
+	}
+
+	found := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(out)
+		sent := false
+		for scanner.Scan() {
+			if m := pattern.FindStringSubmatch(scanner.Text()); m != nil && !sent {
+				found <- m[len(m)-1]
+				sent = true
+			}
+		}
+		// Keep draining so the program never blocks on a full pipe.
+		io.Copy(io.Discard, out)
+	}()
+
+	select {
+	case url := <-found:
+		return url, stop, nil
+	case <-exited:
+		stop()
+		return "", nil, fmt.Errorf("%s exited without printing a public URL", args[0])
+	case <-time.After(tunnelStartTimeout):
+		stop()
+		return "", nil, fmt.Errorf("%s did not print a public URL within %s", args[0], tunnelStartTimeout)
+	}
+}
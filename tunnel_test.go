@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestStartTunnel(t *testing.T) {
+	tunnel := tunnelConfig{
+		Command:    []string{"sh", "-c", "echo starting; echo 'url=https://x.example/{port}'; exec sleep 5"},
+		URLPattern: `url=(https://\S+)`,
+	}
+	url, stop, err := startTunnel(context.Background(), tunnel, "9876")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	if url != "https://x.example/9876" {
+		t.Errorf("url = %q, want https://x.example/9876", url)
+	}
+}
+
+func TestStartTunnel_ExitsWithoutURL(t *testing.T) {
+	tunnel := tunnelConfig{Command: []string{"sh", "-c", "echo login required"}, URLPattern: `https://\S+`}
+	_, _, err := startTunnel(context.Background(), tunnel, "9876")
+	if err == nil || !strings.Contains(err.Error(), "without printing a public URL") {
+		t.Errorf("expected an error for a tunnel that exits, got %v", err)
+	}
+}
+
+func TestTunnelFor(t *testing.T) {
+	custom := map[string]tunnelConfig{"cloudflared": {Command: []string{"my-cloudflared"}, URLPattern: "x"}}
+	if tunnel, err := tunnelFor("cloudflared", custom); err != nil || tunnel.Command[0] != "my-cloudflared" {
+		t.Errorf("expected the project's provider to win, got %v, %v", tunnel, err)
+	}
+	if _, err := tunnelFor("ngrok", custom); err != nil {
+		t.Errorf("expected the built-in ngrok provider, got %v", err)
+	}
+	if _, err := tunnelFor("carrier-pigeon", custom); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+	if err := validateTunnels(map[string]tunnelConfig{"bad": {Command: []string{"x"}, URLPattern: "("}}); err == nil {
+		t.Error("expected an error for an invalid url_pattern")
+	}
+}
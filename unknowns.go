@@ -0,0 +1,178 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// unknownAttributes lists the top-level attributes whose value, or any part
+// of it, is only known after apply.
+func unknownAttributes(afterUnknown map[string]interface{}) []string {
+	var attrs []string
+	for key, v := range afterUnknown {
+		if containsUnknown(v) {
+			attrs = append(attrs, key)
+		}
+	}
+	sort.Strings(attrs)
+	return attrs
+}
+
+func containsUnknown(v interface{}) bool {
+	switch val := v.(type) {
+	case bool:
+		return val
+	case map[string]interface{}:
+		for _, item := range val {
+			if containsUnknown(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range val {
+			if containsUnknown(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// referencedAttributes maps each resource (without index) to the attributes
+// other resources read from it, e.g. aws_subnet.a → {id}. References that
+// go through module variables do not name the attribute and are skipped.
+func referencedAttributes(refs map[string][]ResourceReference) map[string]map[string]bool {
+	result := map[string]map[string]bool{}
+	for _, list := range refs {
+		for _, r := range list {
+			attr := referencedAttribute(r.Expression)
+			if attr == "" {
+				continue
+			}
+			target := stripIndex(r.Target)
+			if result[target] == nil {
+				result[target] = map[string]bool{}
+			}
+			result[target][attr] = true
+		}
+	}
+	return result
+}
+
+// referencedAttribute returns the attribute a resource reference reads,
+// e.g. "id" for aws_subnet.a[0].id, or "" for a reference to the whole
+// resource.
+func referencedAttribute(expr string) string {
+	if strings.HasPrefix(expr, "var.") || strings.HasPrefix(expr, "module.") {
+		return ""
+	}
+	rest, ok := strings.CutPrefix(expr, normalizeRef(expr))
+	if !ok || !strings.HasPrefix(rest, ".") {
+		return ""
+	}
+	attr := rest[1:]
+	if i := strings.IndexAny(attr, ".["); i >= 0 {
+		attr = attr[:i]
+	}
+	return attr
+}
+
+// UnknownProvenance traces an unknown attribute back through the
+// configuration: the upstream attribute it is read from, the attribute that
+// one is read from if it is unknown too, and so on.
+type UnknownProvenance struct {
+	Attribute string   `json:"attribute"`
+	Sources   []string `json:"sources"`
+}
+
+func (p UnknownProvenance) String() string {
+	return strings.Join(append([]string{p.Attribute}, p.Sources...), " ← ")
+}
+
+// maxProvenanceDepth stops tracing long or cyclic chains of references.
+const maxProvenanceDepth = 5
+
+// unknownProvenance explains each unknown attribute of a resource that is
+// read from another resource whose value is unknown as well. unknown maps
+// resources without index to their unknown attributes.
+func unknownProvenance(address string, attrs []string, refs map[string][]ResourceReference, unknown map[string]map[string]bool) []UnknownProvenance {
+	var result []UnknownProvenance
+	for _, attr := range attrs {
+		for _, ref := range attributeReferences(refs[stripIndex(address)], attr) {
+			p := UnknownProvenance{Attribute: attr}
+			seen := map[string]bool{stripIndex(address) + "." + attr: true}
+			for next := ref; len(p.Sources) < maxProvenanceDepth; {
+				target, targetAttr := stripIndex(next.Target), referencedAttribute(next.Expression)
+				if targetAttr == "" {
+					if len(unknown[target]) > 0 {
+						p.Sources = append(p.Sources, target)
+					}
+					break
+				}
+				source := target + "." + targetAttr
+				if !unknown[target][targetAttr] || seen[source] {
+					break
+				}
+				seen[source] = true
+				p.Sources = append(p.Sources, source)
+				upstream := attributeReferences(refs[target], targetAttr)
+				if len(upstream) == 0 {
+					break
+				}
+				next = upstream[0]
+			}
+			if len(p.Sources) > 0 {
+				result = append(result, p)
+			}
+		}
+	}
+	return result
+}
+
+// attributeReferences returns the references made by an attribute,
+// including those inside its nested blocks.
+func attributeReferences(refs []ResourceReference, attr string) []ResourceReference {
+	var result []ResourceReference
+	for _, r := range refs {
+		if r.Attribute == attr || strings.HasPrefix(r.Attribute, attr+".") {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// markUnknowns records the attributes of every resource that are known only
+// after apply, which of them other resources depend on, where they come
+// from, and the totals for the summary.
+func markUnknowns(analyzed *AnalyzedPlan, plan TerraformPlan, refs map[string][]ResourceReference) {
+	unknown := map[string][]string{}
+	unknownByResource := map[string]map[string]bool{}
+	for _, rc := range plan.ResourceChanges {
+		unknown[rc.Address] = unknownAttributes(rc.Change.AfterUnknown)
+		for _, attr := range unknown[rc.Address] {
+			base := stripIndex(rc.Address)
+			if unknownByResource[base] == nil {
+				unknownByResource[base] = map[string]bool{}
+			}
+			unknownByResource[base][attr] = true
+		}
+	}
+	referenced := referencedAttributes(refs)
+	for i := range analyzed.Modules {
+		for j := range analyzed.Modules[i].Resources {
+			r := &analyzed.Modules[i].Resources[j]
+			r.Unknown = unknown[r.Address]
+			r.UnknownReferenced = nil
+			for _, attr := range r.Unknown {
+				if referenced[stripIndex(r.Address)][attr] {
+					r.UnknownReferenced = append(r.UnknownReferenced, attr)
+				}
+			}
+			r.UnknownSources = unknownProvenance(r.Address, r.Unknown, refs, unknownByResource)
+			if len(r.Unknown) > 0 {
+				analyzed.Summary.UnknownResources++
+				analyzed.Summary.UnknownAttributes += len(r.Unknown)
+			}
+		}
+	}
+}
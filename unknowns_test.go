@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestReferencedAttribute(t *testing.T) {
+	tests := map[string]string{
+		"aws_subnet.a.id":           "id",
+		"aws_subnet.a[0].id":        "id",
+		"aws_lb.web.subnet_mapping": "subnet_mapping",
+		"aws_iam_role.ci.tags.Name": "tags",
+		"aws_subnet.a":              "",
+		"var.subnet_id":             "",
+		"module.net.vpc_id":         "",
+	}
+	for expr, want := range tests {
+		if got := referencedAttribute(expr); got != want {
+			t.Errorf("referencedAttribute(%q) = %q, want %q", expr, got, want)
+		}
+	}
+}
+
+func TestMarkUnknowns(t *testing.T) {
+	var plan TerraformPlan
+	err := json.Unmarshal([]byte(`{
+	  "resource_changes": [
+	    {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a",
+	     "change": {"actions": ["create"], "after": {"cidr_block": "10.0.0.0/24"}, "after_unknown": {"id": true, "arn": true, "tags_all": {}, "cidr_block": false}}},
+	    {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web",
+	     "change": {"actions": ["create"], "after": {}, "after_unknown": {"id": true, "subnet_id": true, "ebs_block_device": [{"volume_id": true}]}}},
+	    {"address": "aws_s3_bucket.logs", "mode": "managed", "type": "aws_s3_bucket", "name": "logs",
+	     "change": {"actions": ["no-op"], "before": {"id": "logs"}, "after": {"id": "logs"}}}
+	  ],
+	  "configuration": {"root_module": {"resources": [
+	    {"address": "aws_instance.web", "type": "aws_instance", "name": "web",
+	     "expressions": {"subnet_id": {"references": ["aws_subnet.a.id", "aws_subnet.a"]}}}
+	  ]}}
+	}`), &plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	analyzed := analyzePlan(plan)
+	if analyzed.Summary.UnknownResources != 2 || analyzed.Summary.UnknownAttributes != 5 {
+		t.Errorf("summary = %d resources, %d attributes; want 2, 5", analyzed.Summary.UnknownResources, analyzed.Summary.UnknownAttributes)
+	}
+	want := map[string][2][]string{
+		"aws_subnet.a":       {{"arn", "id"}, {"id"}},
+		"aws_instance.web":   {{"ebs_block_device", "id", "subnet_id"}, nil},
+		"aws_s3_bucket.logs": {nil, nil},
+	}
+	for _, m := range analyzed.Modules {
+		for _, r := range m.Resources {
+			if got := [2][]string{r.Unknown, r.UnknownReferenced}; !reflect.DeepEqual(got, want[r.Address]) {
+				t.Errorf("%s: unknown, referenced = %v, want %v", r.Address, got, want[r.Address])
+			}
+		}
+	}
+}
+
+func TestUnknownProvenance(t *testing.T) {
+	var plan TerraformPlan
+	err := json.Unmarshal([]byte(`{
+	  "resource_changes": [
+	    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main",
+	     "change": {"actions": ["create"], "after": {}, "after_unknown": {"id": true}}},
+	    {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a",
+	     "change": {"actions": ["create"], "after": {}, "after_unknown": {"id": true, "vpc_id": true}}},
+	    {"address": "aws_security_group.web", "mode": "managed", "type": "aws_security_group", "name": "web",
+	     "change": {"actions": ["no-op"], "before": {"id": "sg-1"}, "after": {"id": "sg-1"}}},
+	    {"address": "aws_lb_target_group.web", "mode": "managed", "type": "aws_lb_target_group", "name": "web",
+	     "change": {"actions": ["create"], "after": {}, "after_unknown": {"id": true, "vpc_id": true, "tags": true}}}
+	  ],
+	  "configuration": {"root_module": {"resources": [
+	    {"address": "aws_subnet.a", "type": "aws_subnet", "name": "a",
+	     "expressions": {"vpc_id": {"references": ["aws_vpc.main.id", "aws_vpc.main"]}}},
+	    {"address": "aws_lb_target_group.web", "type": "aws_lb_target_group", "name": "web",
+	     "expressions": {
+	       "vpc_id": {"references": ["aws_subnet.a.vpc_id", "aws_subnet.a"]},
+	       "tags": {"references": ["aws_security_group.web.name", "aws_security_group.web"]}}}
+	  ]}}
+	}`), &plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string][]string{}
+	for _, m := range analyzePlan(plan).Modules {
+		for _, r := range m.Resources {
+			for _, p := range r.UnknownSources {
+				got[r.Address] = append(got[r.Address], p.String())
+			}
+		}
+	}
+	want := map[string][]string{
+		"aws_subnet.a":            {"vpc_id ← aws_vpc.main.id"},
+		"aws_lb_target_group.web": {"vpc_id ← aws_subnet.a.vpc_id ← aws_vpc.main.id"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unknown sources = %v, want %v", got, want)
+	}
+}
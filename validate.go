@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+)
+
+type ValidateResult struct {
+	FormatVersion string       `json:"format_version"`
+	Valid         bool         `json:"valid"`
+	ErrorCount    int          `json:"error_count"`
+	WarningCount  int          `json:"warning_count"`
+	Diagnostics   []Diagnostic `json:"diagnostics"`
+}
+
+type Diagnostic struct {
+	Severity string             `json:"severity"`
+	Summary  string             `json:"summary"`
+	Detail   string             `json:"detail"`
+	Range    *DiagnosticRange   `json:"range,omitempty"`
+	Snippet  *DiagnosticSnippet `json:"snippet,omitempty"`
+}
+
+type DiagnosticRange struct {
+	Filename string        `json:"filename"`
+	Start    DiagnosticPos `json:"start"`
+	End      DiagnosticPos `json:"end"`
+}
+
+type DiagnosticPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Byte   int `json:"byte"`
+}
+
+type DiagnosticSnippet struct {
+	Context              string `json:"context"`
+	Code                 string `json:"code"`
+	StartLine            int    `json:"start_line"`
+	HighlightStartOffset int    `json:"highlight_start_offset"`
+	HighlightEndOffset   int    `json:"highlight_end_offset"`
+}
+
+// Location renders the diagnostic position as file:line:column.
+func (d Diagnostic) Location() string {
+	if d.Range == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d:%d", d.Range.Filename, d.Range.Start.Line, d.Range.Start.Column)
+}
+
+// SnippetParts splits the snippet code around the highlighted range so the
+// template can mark it up without building HTML by hand.
+func (d Diagnostic) SnippetParts() []string {
+	if d.Snippet == nil {
+		return nil
+	}
+	code := d.Snippet.Code
+	start, end := d.Snippet.HighlightStartOffset, d.Snippet.HighlightEndOffset
+	if start < 0 || end > len(code) || start > end {
+		return []string{code, "", ""}
+	}
+	return []string{code[:start], code[start:end], code[end:]}
+}
+
+func handleValidate(ctx context.Context, args []string) error {
+	opts, args, err := parseOptions(args)
+	if err != nil {
+		return err
+	}
+
+	if err := preflight(ctx, ""); err != nil {
+		return err
+	}
+	fmt.Println("🔍 Running terraform validate...")
+	cmd := terraformCommand(ctx, append([]string{"validate", "-json"}, args...)...)
+	cmd.Stderr = os.Stderr
+	out, runErr := cmd.Output()
+	if ctx.Err() != nil {
+		return fmt.Errorf("terraform validate interrupted")
+	}
+
+	// terraform validate exits non-zero when the configuration is invalid but
+	// still prints the JSON result, so only fail if nothing could be parsed.
+	var result ValidateResult
+	if err := json.Unmarshal(out, &result); err != nil {
+		if runErr != nil {
+			return fmt.Errorf("error running terraform validate: %v", runErr)
+		}
+		return fmt.Errorf("error parsing terraform validate output: %v", err)
+	}
+
+	printValidateSummary(result)
+	html, err := generateValidateHTML(result)
+	if err != nil {
+		return err
+	}
+	return serveHTMLOnce(ctx, func(reportView) string { return html }, opts.serve)
+}
+
+func printValidateSummary(result ValidateResult) {
+	if result.Valid {
+		fmt.Printf("✅ Configuration is valid (%d warnings)\n", result.WarningCount)
+	} else {
+		fmt.Printf("❌ Configuration is invalid: %d errors, %d warnings\n", result.ErrorCount, result.WarningCount)
+	}
+	for _, d := range result.Diagnostics {
+		loc := d.Location()
+		if loc != "" {
+			loc = " (" + loc + ")"
+		}
+		fmt.Printf("  [%s] %s%s\n", strings.ToUpper(d.Severity), d.Summary, loc)
+	}
+}
+
+func generateValidateHTML(result ValidateResult) (string, error) {
+	data := struct {
+		ValidateResult
+		Timestamp string
+	}{result, time.Now().Format("2006-01-02 15:04:05")}
+
+	htmlTemplate := `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1" />
+  <title>Terraform Validate</title>
+  <style>
+` + reportStyles + `    .diagnostic-error { border-left: 4px solid var(--delete-color); }
+    .diagnostic-warning { border-left: 4px solid var(--update-color); }
+    .diagnostic .details { display: block; }
+    .diagnostic-highlight { background-color: #ffeef0; text-decoration: underline wavy var(--delete-color); }
+  </style>
+</head>
+<body>
+  <div class="container">
+    <div class="header">
+      <h1>Terraform Validate</h1>
+      <div class="subtitle">{{.Timestamp}}</div>
+    </div>
+    <div class="summary">
+      <div class="summary-item">
+        <h2 style="color: {{if .Valid}}var(--create-color){{else}}var(--delete-color){{end}}">{{if .Valid}}Valid{{else}}Invalid{{end}}</h2>
+        <p>Result</p>
+      </div>
+      <div class="summary-item">
+        <h2 style="color: var(--delete-color)">{{.ErrorCount}}</h2>
+        <p>Errors</p>
+      </div>
+      <div class="summary-item">
+        <h2 style="color: var(--update-color)">{{.WarningCount}}</h2>
+        <p>Warnings</p>
+      </div>
+    </div>
+    <div class="resource-list">
+      {{range .Diagnostics}}
+      <div class="resource diagnostic diagnostic-{{.Severity}}">
+        <div class="resource-info">
+          <h3>{{.Summary}}</h3>
+          <p>{{.Severity}}{{with .Location}} · {{.}}{{end}}{{with .Snippet}}{{if .Context}} · in {{.Context}}{{end}}{{end}}</p>
+        </div>
+        <div class="details">
+          {{with .SnippetParts}}<pre>{{index . 0}}<span class="diagnostic-highlight">{{index . 1}}</span>{{index . 2}}</pre>{{end}}
+          {{if .Detail}}<p>{{.Detail}}</p>{{end}}
+        </div>
+      </div>
+      {{else}}
+      <div class="resource"><p>No diagnostics reported.</p></div>
+      {{end}}
+    </div>
+  </div>
+</body>
+</html>`
+
+	tmpl, err := template.New("validate").Parse(htmlTemplate)
+	if err != nil {
+		return "", fmt.Errorf("error parsing HTML template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error executing template: %v", err)
+	}
+	return buf.String(), nil
+}
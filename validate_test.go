@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const sampleValidateJSON = `{
+  "format_version": "1.0",
+  "valid": false,
+  "error_count": 1,
+  "warning_count": 0,
+  "diagnostics": [
+    {
+      "severity": "error",
+      "summary": "Unsupported argument",
+      "detail": "An argument named \"ami_id\" is not expected here.",
+      "range": {
+        "filename": "main.tf",
+        "start": {"line": 3, "column": 3, "byte": 40},
+        "end": {"line": 3, "column": 9, "byte": 46}
+      },
+      "snippet": {
+        "context": "resource \"aws_instance\" \"web\"",
+        "code": "  ami_id = \"ami-123\"",
+        "start_line": 3,
+        "highlight_start_offset": 2,
+        "highlight_end_offset": 8,
+        "values": []
+      }
+    }
+  ]
+}`
+
+func TestGenerateValidateHTML(t *testing.T) {
+	var result ValidateResult
+	if err := json.Unmarshal([]byte(sampleValidateJSON), &result); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	d := result.Diagnostics[0]
+	if loc := d.Location(); loc != "main.tf:3:3" {
+		t.Errorf("Location() = %q", loc)
+	}
+	if parts := d.SnippetParts(); parts[1] != "ami_id" {
+		t.Errorf("highlighted part = %q, want ami_id", parts[1])
+	}
+
+	html, err := generateValidateHTML(result)
+	if err != nil {
+		t.Fatalf("generateValidateHTML: %v", err)
+	}
+	for _, want := range []string{"Unsupported argument", "main.tf:3:3", `<span class="diagnostic-highlight">ami_id</span>`, "Invalid"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered HTML missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Build metadata, injected at build time:
+//
+//	go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
+// supportedPlanFormats lists the `terraform show -json` format_version values
+// tfviz has been tested against.
+var supportedPlanFormats = []string{"0.1", "0.2", "1.0", "1.1", "1.2"}
+
+type binaryInfo struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func handleVersion(args []string) error {
+	asJSON := false
+	for _, a := range args {
+		if a == "--json" {
+			asJSON = true
+		} else {
+			return fmt.Errorf("unknown flag for version: %s", a)
+		}
+	}
+
+	binaries := detectTerraformBinaries()
+
+	if asJSON {
+		out, err := json.MarshalIndent(struct {
+			Version     string       `json:"version"`
+			Commit      string       `json:"commit"`
+			BuildDate   string       `json:"build_date"`
+			GoVersion   string       `json:"go_version"`
+			PlanFormats []string     `json:"plan_format_versions"`
+			Binaries    []binaryInfo `json:"binaries"`
+		}{version, commit, buildDate, runtime.Version(), supportedPlanFormats, binaries}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	fmt.Printf("tfviz %s (commit %s, built %s, %s)\n", version, commit, buildDate, runtime.Version())
+	fmt.Printf("Supported plan format versions: %s\n", strings.Join(supportedPlanFormats, ", "))
+	if len(binaries) == 0 {
+		fmt.Println("No terraform or tofu binary found on PATH")
+	}
+	for _, b := range binaries {
+		if b.Error != "" {
+			fmt.Printf("%s: %s (version unknown: %s)\n", b.Name, b.Path, b.Error)
+		} else {
+			fmt.Printf("%s: %s (v%s)\n", b.Name, b.Path, b.Version)
+		}
+	}
+	return nil
+}
+
+func detectTerraformBinaries() []binaryInfo {
+	var found []binaryInfo
+	for _, name := range []string{"terraform", "tofu"} {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		info := binaryInfo{Name: name, Path: path}
+		v, err := binaryVersion(path)
+		if err != nil {
+			info.Error = err.Error()
+		} else {
+			info.Version = v
+		}
+		found = append(found, info)
+	}
+	return found
+}
+
+// binaryVersion asks a terraform-compatible binary for its version.
+// Both terraform and tofu report it under "terraform_version".
+func binaryVersion(path string) (string, error) {
+	out, err := exec.Command(path, "version", "-json").Output()
+	if err != nil {
+		return "", err
+	}
+	return parseVersionJSON(out)
+}
+
+func parseVersionJSON(data []byte) (string, error) {
+	var v struct {
+		TerraformVersion string `json:"terraform_version"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return "", err
+	}
+	if v.TerraformVersion == "" {
+		return "", fmt.Errorf("no terraform_version in output")
+	}
+	return v.TerraformVersion, nil
+}
@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+func TestParseVersionJSON(t *testing.T) {
+	got, err := parseVersionJSON([]byte(`{"terraform_version":"1.6.3","platform":"linux_amd64","provider_selections":{},"terraform_outdated":false}`))
+	if err != nil || got != "1.6.3" {
+		t.Errorf("parseVersionJSON = %q, %v; want 1.6.3", got, err)
+	}
+	if _, err := parseVersionJSON([]byte(`{}`)); err == nil {
+		t.Error("expected error when terraform_version is missing")
+	}
+	if _, err := parseVersionJSON([]byte(`Terraform v1.6.3`)); err == nil {
+		t.Error("expected error for non-JSON output")
+	}
+}
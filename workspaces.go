@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// workspacesFlag removes --workspaces dev,stage,prod from the arguments of
+// tfviz plan and returns the workspaces, or nil when it is not given.
+func workspacesFlag(args []string) (rest, workspaces []string, err error) {
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name != "--workspaces" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("flag %s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		workspaces = nil
+		for _, ws := range strings.Split(value, ",") {
+			ws = strings.TrimSpace(ws)
+			if ws == "" || slices.Contains(workspaces, ws) {
+				return nil, nil, fmt.Errorf("invalid --workspaces %q: list each workspace once, separated by commas", value)
+			}
+			workspaces = append(workspaces, ws)
+		}
+	}
+	return rest, workspaces, nil
+}
+
+// PlanMatrix compares the changes of plans of one configuration, e.g. of
+// its workspaces, for reviewing a promotion from one environment to the
+// next.
+type PlanMatrix struct {
+	Plans []string    `json:"plans"`
+	Rows  []MatrixRow `json:"rows"`
+}
+
+// MatrixRow is a resource that changes in at least one of the plans, with
+// its action in each of them: "" where the plan does not have it, and
+// "replace" for replacements.
+type MatrixRow struct {
+	Address string   `json:"address"`
+	Actions []string `json:"actions"`
+	Differs bool     `json:"differs"`
+}
+
+// Differences counts the resources whose change is not the same in every
+// plan.
+func (m PlanMatrix) Differences() int {
+	n := 0
+	for _, r := range m.Rows {
+		if r.Differs {
+			n++
+		}
+	}
+	return n
+}
+
+func buildPlanMatrix(names []string, analyses []*AnalyzedPlan) *PlanMatrix {
+	actions := map[string][]string{}
+	for i, analyzed := range analyses {
+		for _, m := range analyzed.Modules {
+			for _, r := range m.Resources {
+				if actions[r.Address] == nil {
+					actions[r.Address] = make([]string, len(analyses))
+				}
+				action := r.Action
+				if r.Replace {
+					action = "replace"
+				}
+				actions[r.Address][i] = action
+			}
+		}
+	}
+
+	matrix := &PlanMatrix{Plans: names}
+	for _, address := range sortedKeys(actions) {
+		row := MatrixRow{Address: address, Actions: actions[address]}
+		changed := false
+		for _, a := range row.Actions {
+			changed = changed || a != "" && a != "no-op"
+			row.Differs = row.Differs || a != row.Actions[0]
+		}
+		if changed {
+			matrix.Rows = append(matrix.Rows, row)
+		}
+	}
+	sort.SliceStable(matrix.Rows, func(i, j int) bool {
+		return matrix.Rows[i].Differs && !matrix.Rows[j].Differs
+	})
+	return matrix
+}
+
+// planWorkspaces plans the configuration in the current directory once per
+// workspace and serves the plans side by side with a comparison matrix.
+// The workspace is chosen with TF_WORKSPACE rather than terraform workspace
+// select, so the selected workspace of the checkout is left alone and an
+// interrupted run cannot leave it pointing at another environment. The
+// plans run one after another, as they share the .terraform directory.
+func planWorkspaces(ctx context.Context, workspaces, args []string, opts cliOptions) error {
+	if opts.cache {
+		return fmt.Errorf("--cache cannot be combined with --workspaces")
+	}
+	previous, hadPrevious := os.LookupEnv("TF_WORKSPACE")
+	defer func() {
+		if hadPrevious {
+			os.Setenv("TF_WORKSPACE", previous)
+		} else {
+			os.Unsetenv("TF_WORKSPACE")
+		}
+	}()
+
+	var data [][]byte
+	for _, ws := range workspaces {
+		fmt.Printf("🗂️  Workspace %s\n", ws)
+		os.Setenv("TF_WORKSPACE", ws)
+		out, err := runTerraformPlan(ctx, "", args)
+		if err != nil {
+			return fmt.Errorf("workspace %s: %v", ws, err)
+		}
+		data = append(data, out)
+	}
+	opts.analysis.git = gitContext(ctx, "")
+	return presentPlanSet(ctx, workspaces, workspaces, data, true, opts)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWorkspacesFlag(t *testing.T) {
+	rest, workspaces, err := workspacesFlag([]string{"-g", "--workspaces", "dev, stage,prod", "--", "--workspaces=x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(workspaces, []string{"dev", "stage", "prod"}) || !reflect.DeepEqual(rest, []string{"-g", "--", "--workspaces=x"}) {
+		t.Errorf("got workspaces %v, rest %v", workspaces, rest)
+	}
+	if _, workspaces, _ := workspacesFlag([]string{"-g"}); workspaces != nil {
+		t.Errorf("expected no workspaces, got %v", workspaces)
+	}
+	for _, bad := range []string{"--workspaces=dev,,prod", "--workspaces=dev,dev"} {
+		if _, _, err := workspacesFlag([]string{bad}); err == nil {
+			t.Errorf("expected an error for %s", bad)
+		}
+	}
+}
+
+func TestBuildPlanMatrix(t *testing.T) {
+	plan := func(resources ...ResourceAnalysis) *AnalyzedPlan {
+		return &AnalyzedPlan{Modules: []ModuleAnalysis{{Address: "root", Resources: resources}}}
+	}
+	dev := plan(
+		ResourceAnalysis{Address: "aws_instance.web", Action: "update"},
+		ResourceAnalysis{Address: "aws_s3_bucket.logs", Action: "no-op"},
+		ResourceAnalysis{Address: "aws_sqs_queue.jobs", Action: "create"},
+	)
+	prod := plan(
+		ResourceAnalysis{Address: "aws_instance.web", Action: "update"},
+		ResourceAnalysis{Address: "aws_s3_bucket.logs", Action: "no-op"},
+		ResourceAnalysis{Address: "aws_db_instance.main", Action: "delete", Replace: true},
+	)
+	matrix := buildPlanMatrix([]string{"dev", "prod"}, []*AnalyzedPlan{dev, prod})
+	want := []MatrixRow{
+		{Address: "aws_db_instance.main", Actions: []string{"", "replace"}, Differs: true},
+		{Address: "aws_sqs_queue.jobs", Actions: []string{"create", ""}, Differs: true},
+		{Address: "aws_instance.web", Actions: []string{"update", "update"}},
+	}
+	if !reflect.DeepEqual(matrix.Rows, want) {
+		t.Errorf("rows = %+v, want %+v", matrix.Rows, want)
+	}
+	if matrix.Differences() != 2 {
+		t.Errorf("differences = %d, want 2", matrix.Differences())
+	}
+
+	html, err := generateMultiPlanHTML(nil, PlanSummary{}, "", matrix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html, `<tr class="action-differs"><td><code>aws_db_instance.main</code></td><td>-</td><td>replace</td></tr>`) {
+		t.Error("report does not show the differing replacement")
+	}
+}